@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accessLogEntry 一条结构化访问日志（每个请求一行JSON，便于日志系统采集）
+type accessLogEntry struct {
+	Time      string  `json:"time"`            // 请求完成时间（RFC3339，毫秒精度）
+	Method    string  `json:"method"`          // 请求方法
+	Path      string  `json:"path"`            // 请求路径（不含查询参数）
+	Route     string  `json:"route,omitempty"` // 匹配的路由模板（如 /api/stock/:code）
+	Query     string  `json:"query,omitempty"` // 查询参数
+	Status    int     `json:"status"`          // 响应状态码
+	LatencyMs float64 `json:"latency_ms"`      // 耗时（毫秒）
+	ClientIP  string  `json:"client_ip"`       // 来源IP
+	Size      int     `json:"size"`            // 响应体大小（字节）
+	Error     string  `json:"error,omitempty"` // 处理过程中记录的错误
+}
+
+// accessLogSkipPaths 不记录访问日志的路径（健康检查频繁且无排查价值）
+var accessLogSkipPaths = map[string]bool{
+	"/health":     true,
+	"/api/health": true,
+}
+
+// accessLogMiddleware 结构化访问日志中间件，替代gin默认的文本日志
+// 跳过健康检查和成功的CORS预检请求；4xx/5xx状态码始终记录
+func accessLogMiddleware() gin.HandlerFunc {
+	var mutex sync.Mutex
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest &&
+			(accessLogSkipPaths[c.Request.URL.Path] || c.Request.Method == http.MethodOptions) {
+			return
+		}
+
+		entry := accessLogEntry{
+			Time:      time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Route:     c.FullPath(),
+			Query:     c.Request.URL.RawQuery,
+			Status:    status,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			ClientIP:  c.ClientIP(),
+			Size:      c.Writer.Size(),
+			Error:     c.Errors.ByType(gin.ErrorTypePrivate).String(),
+		}
+		if entry.Size < 0 {
+			entry.Size = 0 // 未写入响应体
+		}
+
+		mutex.Lock()
+		encoder.Encode(entry)
+		mutex.Unlock()
+	}
+}
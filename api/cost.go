@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetAICost 查看AI调用的token数与估算费用：按天、按股票汇总最近days天（默认7天，0表示全部保留的记录）
+func (s *StockAPIServer) handleGetAICost(c *gin.Context) {
+	if s.costTracker == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    -1,
+			"message": "AI费用统计未初始化",
+		})
+		return
+	}
+
+	days := 7
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": "days必须是非负整数",
+			})
+			return
+		}
+		days = parsed
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    s.costTracker.Report(days),
+	})
+}
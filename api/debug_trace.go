@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleSetDebugTrace 开启/关闭单只股票的调试追踪（需要Token认证，请求头 X-API-Token）
+// 查询参数 on=true/false；开启后该股票每轮分析的完整提示词、AI原始响应和解析过程写入
+// <log_dir>/debug/<代码>/ 下的独立文件，便于针对性调优提示词。状态不持久化，重启后恢复关闭
+func (s *StockAPIServer) handleSetDebugTrace(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	on, err := strconv.ParseBool(c.Query("on"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "请提供查询参数 on=true 或 on=false",
+		})
+		return
+	}
+
+	code := c.Param("code")
+	dir, err := s.managerFor(c).SetDebugTrace(code, on)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := "调试追踪已关闭"
+	if on {
+		message = "调试追踪已开启"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"stock_code": code,
+			"debug":      on,
+			"debug_dir":  dir,
+		},
+	})
+}
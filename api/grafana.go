@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/stock"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Grafana JSON数据源（SimpleJSON / Infinity插件）接口：按插件约定直接返回数组，不包裹code/message
+// 指标名格式为 "<股票代码>.<指标>"，如 600519.confidence、000001.price，数据来自内存中的分析历史
+
+// grafanaSearchRequest /grafana/search 请求体
+type grafanaSearchRequest struct {
+	Target string `json:"target"` // 按子串筛选指标名（为空时返回全部）
+}
+
+// grafanaQueryRequest /grafana/query 请求体
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+	MaxDataPoints int `json:"maxDataPoints"`
+}
+
+// grafanaSeries 一条时间序列，datapoints每项为 [数值, 毫秒时间戳]
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// handleGrafanaTest 数据源连通性测试（Grafana保存数据源时请求根路径，返回200即可）
+func (s *StockAPIServer) handleGrafanaTest(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// handleGrafanaSearch 返回可查询的指标名（监控中的每只股票 × 分析结果字段）
+func (s *StockAPIServer) handleGrafanaSearch(c *gin.Context) {
+	var req grafanaSearchRequest
+	// 请求体可为空
+	_ = c.ShouldBindJSON(&req)
+
+	codes := make([]string, 0)
+	for code := range s.managerFor(c).GetAllAnalyzers() {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	targets := make([]string, 0, len(codes)*len(stock.ResultMetrics))
+	for _, code := range codes {
+		for _, metric := range stock.ResultMetrics {
+			target := code + "." + metric
+			if req.Target == "" || strings.Contains(target, req.Target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, targets)
+}
+
+// handleGrafanaQuery 按时间范围返回各指标的时间序列（按时间升序，超过maxDataPoints时保留最新的点）
+// 除search列出的字段外，也可以查询技术指标，如 600519.rsi14、600519.change_percent
+func (s *StockAPIServer) handleGrafanaQuery(c *gin.Context) {
+	var req grafanaQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+
+	history := s.managerFor(c).ExportHistory()
+	series := make([]grafanaSeries, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		// 指标名不含"."，按最后一个"."拆分（代码可以写成 600519.SH 形式）
+		dot := strings.LastIndex(target.Target, ".")
+		if dot <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("指标名格式错误: %s（应为 股票代码.指标，如 600519.confidence）", target.Target),
+			})
+			return
+		}
+
+		code, metric := config.NormalizeStockCode(target.Target[:dot]), target.Target[dot+1:]
+
+		// 历史记录按最新在前存储，倒序遍历得到时间升序
+		results := history[code]
+		datapoints := make([][2]float64, 0)
+		for i := len(results) - 1; i >= 0; i-- {
+			result := results[i]
+			if !req.Range.From.IsZero() && result.Timestamp.Before(req.Range.From) {
+				continue
+			}
+			if !req.Range.To.IsZero() && result.Timestamp.After(req.Range.To) {
+				continue
+			}
+			if value, ok := stock.ResultMetricValue(result, metric); ok {
+				datapoints = append(datapoints, [2]float64{value, float64(result.Timestamp.UnixMilli())})
+			}
+		}
+		if req.MaxDataPoints > 0 && len(datapoints) > req.MaxDataPoints {
+			datapoints = datapoints[len(datapoints)-req.MaxDataPoints:]
+		}
+		series = append(series, grafanaSeries{Target: target.Target, Datapoints: datapoints})
+	}
+	c.JSON(http.StatusOK, series)
+}
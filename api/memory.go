@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetMemory 查看分析历史的内存占用（按JSON序列化长度估算，按组合/股票细分）及进程内存统计
+func (s *StockAPIServer) handleGetMemory(c *gin.Context) {
+	if s.historyMemory == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    -1,
+			"message": "分析历史内存统计未初始化",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    s.historyMemory.Status(),
+	})
+}
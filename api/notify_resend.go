@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resendTimeLayouts 补发时间范围支持的时间格式
+var resendTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", time.RFC3339}
+
+// parseResendTime 解析补发时间范围的起止时间（按本地时区）
+func parseResendTime(value string) (time.Time, error) {
+	for _, layout := range resendTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("时间 %q 格式应为 YYYY-MM-DD HH:MM", value)
+}
+
+// handleResendNotifications 补发时间范围内达阈值结果的通知（需要Token认证，请求头 X-API-Token）
+// 请求体 {"from": "2024-05-10 09:30", "to": "2024-05-10 15:00", "codes": ["600519"], "dry_run": true}，
+// codes 为空时为全部股票；dry_run 为true时只预览将要补发的记录，不推送
+func (s *StockAPIServer) handleResendNotifications(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	var req struct {
+		From   string   `json:"from"`
+		To     string   `json:"to"`
+		Codes  []string `json:"codes"`
+		DryRun bool     `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+	if req.From == "" || req.To == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "请提供补发的时间范围 from 和 to",
+		})
+		return
+	}
+	from, err := parseResendTime(req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+	to, err := parseResendTime(req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "结束时间 to 不能早于开始时间 from",
+		})
+		return
+	}
+
+	items, err := s.managerFor(c).ResendNotifications(req.Codes, from, to, req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	sent := 0
+	for _, item := range items {
+		if item.Sent {
+			sent++
+		}
+	}
+	message := fmt.Sprintf("已补发 %d/%d 条通知", sent, len(items))
+	if req.DryRun {
+		message = fmt.Sprintf("预览：将补发 %d 条通知", len(items))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"from":    from,
+			"to":      to,
+			"dry_run": req.DryRun,
+			"sent":    sent,
+			"total":   len(items),
+			"items":   items,
+		},
+	})
+}
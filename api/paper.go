@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetPaperAccount 获取模拟盘账户：可用资金、虚拟持仓和最近的成交记录（limit默认50）
+func (s *StockAPIServer) handleGetPaperAccount(c *gin.Context) {
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	account, ok := s.managerFor(c).GetPaperAccount(limit)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": "未启用模拟盘（paper_trading.enabled）",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    account,
+	})
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/stock"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPositionFileSize 持仓CSV文件大小上限
+const maxPositionFileSize = 1 << 20
+
+// handleImportPositions 导入券商App（东方财富、同花顺等）导出的持仓CSV（需要Token认证，请求头 X-API-Token）
+// 以 multipart/form-data 的 file 字段上传，或直接把CSV内容作为请求体；
+// 已监控的股票立即更新持仓，未监控的股票写入配置文件（重启后生效），并把持仓写回配置文件的 position_quantity/buy_price
+func (s *StockAPIServer) handleImportPositions(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": "请在 file 字段上传持仓CSV文件",
+			})
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("读取上传文件失败: %v", err),
+			})
+			return
+		}
+		defer opened.Close()
+		reader = opened
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxPositionFileSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("读取持仓文件失败: %v", err),
+		})
+		return
+	}
+	if len(data) > maxPositionFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "持仓文件不能超过1MB",
+		})
+		return
+	}
+
+	positions, err := stock.ParsePositionCSV(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("解析持仓文件失败: %v", err),
+		})
+		return
+	}
+	if len(positions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "持仓文件中没有持仓记录",
+		})
+		return
+	}
+	for i := range positions {
+		positions[i].Code = config.NormalizeStockCode(positions[i].Code)
+	}
+
+	manager := s.managerFor(c)
+	results, err := manager.ImportPositions(positions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Action]++
+	}
+
+	// 持久化到配置文件（失败不影响已生效的更新）
+	portfolioID, _ := manager.GetPortfolioInfo()["id"].(string)
+	persisted := true
+	message := fmt.Sprintf("已更新%d只、新增%d只、跳过%d只", counts[stock.PositionImportUpdated], counts[stock.PositionImportCreated], counts[stock.PositionImportSkipped])
+	if counts[stock.PositionImportUpdated]+counts[stock.PositionImportCreated] > 0 {
+		if err := s.persistPositions(portfolioID, results); err != nil {
+			log.Printf("⚠️  保存导入的持仓到配置文件失败: %v", err)
+			persisted = false
+			message += fmt.Sprintf("，但保存到配置文件失败（重启后恢复原持仓，新增的股票不会生效）: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"updated":   counts[stock.PositionImportUpdated],
+			"created":   counts[stock.PositionImportCreated],
+			"skipped":   counts[stock.PositionImportSkipped],
+			"persisted": persisted,
+			"items":     results,
+		},
+	})
+}
+
+// persistPositions 把导入的持仓写回配置文件（按原始JSON修改，保留其余字段）：已有的股票更新持仓字段，未配置的股票追加到该组合的stocks中
+// 配置中已有但未启用的股票只更新持仓，不改变启用状态
+func (s *StockAPIServer) persistPositions(portfolioID string, results []stock.PositionImportResult) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	err := updateRawConfig(func(raw map[string]interface{}) error {
+		// 默认组合的股票在顶层stocks中，其他组合在portfolios[].stocks中
+		container := raw
+		if portfolioID != "" && portfolioID != config.DefaultPortfolioID {
+			container = nil
+			portfolios, _ := raw["portfolios"].([]interface{})
+			for _, item := range portfolios {
+				if portfolio, ok := item.(map[string]interface{}); ok && portfolio["id"] == portfolioID {
+					container = portfolio
+					break
+				}
+			}
+			if container == nil {
+				return fmt.Errorf("配置文件中未找到组合 %s", portfolioID)
+			}
+		}
+		stocks, _ := container["stocks"].([]interface{})
+
+		for _, result := range results {
+			if result.Action == stock.PositionImportSkipped {
+				continue
+			}
+			var stockItem map[string]interface{}
+			for _, item := range stocks {
+				if candidate, ok := item.(map[string]interface{}); ok && matchesStockCode(candidate["code"], result.Code) {
+					stockItem = candidate
+					break
+				}
+			}
+			if stockItem == nil {
+				stockItem = map[string]interface{}{
+					"code":    result.Code,
+					"name":    result.Name,
+					"enabled": true,
+				}
+				stocks = append(stocks, stockItem)
+			}
+			if result.Quantity > 0 {
+				stockItem["position_quantity"] = result.Quantity
+				stockItem["buy_price"] = result.CostPrice
+			} else {
+				delete(stockItem, "position_quantity")
+				delete(stockItem, "buy_price")
+			}
+		}
+		container["stocks"] = stocks
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.effectiveConfig != nil {
+		items := &s.effectiveConfig.Stocks
+		if portfolioID != "" && portfolioID != config.DefaultPortfolioID {
+			items = nil
+			for i := range s.effectiveConfig.Portfolios {
+				if s.effectiveConfig.Portfolios[i].ID == portfolioID {
+					items = &s.effectiveConfig.Portfolios[i].Stocks
+				}
+			}
+		}
+		if items != nil {
+			for _, result := range results {
+				if result.Action != stock.PositionImportUpdated {
+					continue
+				}
+				for i := range *items {
+					if (*items)[i].Code == result.Code {
+						(*items)[i].PositionQuantity = result.Quantity
+						(*items)[i].BuyPrice = result.CostPrice
+						if result.Quantity == 0 {
+							(*items)[i].BuyPrice = 0
+						}
+					}
+				}
+			}
+		}
+	}
+
+	log.Printf("✓ 导入的持仓已保存到配置文件（组合 %s）", portfolioID)
+	return nil
+}
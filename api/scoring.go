@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/stock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetScoringWeights 查看当前的技术指标健康度权重（及默认权重）
+func (s *StockAPIServer) handleGetScoringWeights(c *gin.Context) {
+	weights := stock.DefaultScoringWeights()
+	if s.scoringModel != nil {
+		weights = s.scoringModel.Weights()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"weights":  weights,
+			"defaults": stock.DefaultScoringWeights(),
+		},
+	})
+}
+
+// handleSetScoringWeights 运行时调整技术指标健康度权重（需要Token认证，请求头 X-API-Token）
+// 请求体 {"ma": 30, "rsi": 25, "macd": 30, "volume": 15}，未填写的权重保持当前值；下一轮分析立即生效，并写回配置文件的scoring_weights（重启后仍生效）
+func (s *StockAPIServer) handleSetScoringWeights(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+	if s.scoringModel == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    -1,
+			"message": "健康度评分模型未初始化",
+		})
+		return
+	}
+
+	var patch stock.ScoringWeightsPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+	if patch.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "请至少提供一项权重（ma、rsi、macd、volume）",
+		})
+		return
+	}
+	previous, weights, err := s.scoringModel.UpdateWeights(patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+	log.Printf("⚖️  健康度权重已调整: MA %.0f, RSI %.0f, MACD %.0f, 量能 %.0f", weights.MA, weights.RSI, weights.MACD, weights.Volume)
+
+	// 持久化到配置文件（失败不影响已生效的调整）
+	persisted := true
+	message := "健康度权重已调整并保存到配置文件"
+	if err := s.persistScoringWeights(weights); err != nil {
+		log.Printf("⚠️  保存健康度权重到配置文件失败: %v", err)
+		persisted = false
+		message = fmt.Sprintf("健康度权重已调整，但保存到配置文件失败（重启后恢复原权重）: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"previous":  previous,
+			"weights":   weights,
+			"persisted": persisted,
+		},
+	})
+}
+
+// persistScoringWeights 把健康度权重写回配置文件，并同步内存中的生效配置
+func (s *StockAPIServer) persistScoringWeights(weights stock.ScoringWeights) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	err := updateRawConfig(func(raw map[string]interface{}) error {
+		raw["scoring_weights"] = weights
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.effectiveConfig != nil {
+		s.effectiveConfig.ScoringWeights = &config.ScoringWeightsConfig{
+			MA:     weights.MA,
+			RSI:    weights.RSI,
+			MACD:   weights.MACD,
+			Volume: weights.Volume,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/stock"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// snapshotVersion 快照格式版本，格式不兼容时递增
+const snapshotVersion = 1
+
+// Snapshot 配置与分析历史的完整快照（用于备份和迁移服务器）
+// 配置为脱敏后的生效配置，仅供参考，导入时不会写回配置文件（密钥需在新服务器上重新填写）
+type Snapshot struct {
+	Version    int                                           `json:"version"`          // 快照格式版本
+	ExportedAt time.Time                                     `json:"exported_at"`      // 导出时间
+	Config     *config.StockConfig                           `json:"config,omitempty"` // 脱敏后的生效配置
+	History    map[string]map[string][]*stock.AnalysisResult `json:"history"`          // 组合ID -> 股票代码 -> 分析历史（最新的在前）
+}
+
+// handleExportSnapshot 导出快照：脱敏后的生效配置 + 所有组合的全部分析历史（JSON文件下载，需要Token认证）
+func (s *StockAPIServer) handleExportSnapshot(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	snapshot := Snapshot{
+		Version:    snapshotVersion,
+		ExportedAt: time.Now(),
+		History:    make(map[string]map[string][]*stock.AnalysisResult),
+	}
+
+	if s.effectiveConfig != nil {
+		redacted, err := s.redactedEffectiveConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("生成脱敏配置失败: %v", err),
+			})
+			return
+		}
+		snapshot.Config = redacted
+	}
+
+	records := 0
+	for _, id := range s.portfolioIDs {
+		history := s.portfolios[id].ExportHistory()
+		for _, results := range history {
+			records += len(results)
+			for i, result := range results {
+				results[i] = stock.VersionedResult(result)
+			}
+		}
+		snapshot.History[id] = history
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("序列化快照失败: %v", err),
+		})
+		return
+	}
+
+	log.Printf("📦 已导出快照: %d个组合，%d条分析记录", len(snapshot.History), records)
+	filename := fmt.Sprintf("snapshot-%s.json", snapshot.ExportedAt.Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// handleImportSnapshot 导入快照中的分析历史（需要Token认证，请求头 X-API-Token）
+// 与现有历史按时间戳合并去重，每个股票仍按分析历史条数上限保留最新的记录；当前不存在的组合和未监控的股票跳过
+// 导入的历史只在运行时生效（分析历史本身不落盘），重启后需重新导入
+func (s *StockAPIServer) handleImportSnapshot(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	var snapshot Snapshot
+	if err := c.ShouldBindJSON(&snapshot); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("快照格式错误: %v", err),
+		})
+		return
+	}
+	if snapshot.Version != snapshotVersion {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("不支持的快照版本: %d（当前版本 %d）", snapshot.Version, snapshotVersion),
+		})
+		return
+	}
+
+	imported := make(map[string]int)
+	skippedCodes := make(map[string][]string)
+	var skipped []string
+	total := 0
+	for id, history := range snapshot.History {
+		manager, ok := s.portfolios[id]
+		if !ok {
+			skipped = append(skipped, id)
+			continue
+		}
+		count, unknown := manager.ImportHistory(history)
+		imported[id] = count
+		if len(unknown) > 0 {
+			skippedCodes[id] = unknown
+		}
+		total += count
+	}
+
+	log.Printf("📥 已导入快照（导出于 %s）: %d条分析记录", snapshot.ExportedAt.Format("2006-01-02 15:04:05"), total)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("已导入%d条分析记录（仅本次运行有效，配置需手动迁移）", total),
+		"data": gin.H{
+			"imported":           imported,
+			"skipped_portfolios": skipped,
+			"skipped_codes":      skippedCodes,
+		},
+	})
+}
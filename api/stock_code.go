@@ -0,0 +1,25 @@
+package api
+
+import (
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stockCodeMiddleware 统一路径参数中的股票代码写法（sh600519、600519.SH -> 600519），与配置加载时的规范化保持一致
+func stockCodeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i, param := range c.Params {
+			if param.Key == "code" {
+				c.Params[i].Value = config.NormalizeStockCode(param.Value)
+			}
+		}
+		c.Next()
+	}
+}
+
+// matchesStockCode 判断配置文件中原样保存的代码（可能带市场前缀/后缀）是否为该股票
+func matchesStockCode(raw interface{}, code string) bool {
+	rawCode, ok := raw.(string)
+	return ok && config.NormalizeStockCode(rawCode) == code
+}
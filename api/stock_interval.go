@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/config"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 运行时可调整的扫描间隔范围（分钟）
+const (
+	minScanIntervalMinutes = 1
+	maxScanIntervalMinutes = 60
+)
+
+// handleSetScanInterval 运行时调整单只股票的扫描间隔（需要Token认证，请求头 X-API-Token）
+// 请求体 {"minutes": 1}，范围1-60分钟；立即按新间隔重新计时，并写回配置文件的scan_interval_minutes（重启后仍生效）
+func (s *StockAPIServer) handleSetScanInterval(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	code := c.Param("code")
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+	if req.Minutes < minScanIntervalMinutes || req.Minutes > maxScanIntervalMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("扫描间隔需在%d-%d分钟之间", minScanIntervalMinutes, maxScanIntervalMinutes),
+		})
+		return
+	}
+
+	manager := s.managerFor(c)
+	previous, err := manager.SetScanInterval(code, time.Duration(req.Minutes)*time.Minute)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 持久化到配置文件（失败不影响已生效的调整）
+	portfolioID, _ := manager.GetPortfolioInfo()["id"].(string)
+	persisted := true
+	message := "扫描间隔已调整并保存到配置文件"
+	if err := s.persistScanInterval(portfolioID, code, req.Minutes); err != nil {
+		log.Printf("⚠️  保存扫描间隔到配置文件失败: %v", err)
+		persisted = false
+		message = fmt.Sprintf("扫描间隔已调整，但保存到配置文件失败（重启后恢复原间隔）: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"stock_code":       code,
+			"previous_minutes": int(previous / time.Minute),
+			"minutes":          req.Minutes,
+			"persisted":        persisted,
+		},
+	})
+}
+
+// persistScanInterval 把股票的扫描间隔写回配置文件（按原始JSON修改，保留其余字段），并同步内存中的生效配置
+func (s *StockAPIServer) persistScanInterval(portfolioID, code string, minutes int) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	err := updateRawConfig(func(raw map[string]interface{}) error {
+		// 默认组合的股票在顶层stocks中，其他组合在portfolios[].stocks中
+		stocks, _ := raw["stocks"].([]interface{})
+		if portfolioID != "" && portfolioID != config.DefaultPortfolioID {
+			stocks = nil
+			portfolios, _ := raw["portfolios"].([]interface{})
+			for _, item := range portfolios {
+				if portfolio, ok := item.(map[string]interface{}); ok && portfolio["id"] == portfolioID {
+					stocks, _ = portfolio["stocks"].([]interface{})
+					break
+				}
+			}
+		}
+
+		found := false
+		for _, item := range stocks {
+			if stockItem, ok := item.(map[string]interface{}); ok && matchesStockCode(stockItem["code"], code) {
+				stockItem["scan_interval_minutes"] = minutes
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("配置文件中未找到股票 %s", code)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.effectiveConfig != nil {
+		for i := range s.effectiveConfig.Stocks {
+			if portfolioID == config.DefaultPortfolioID && s.effectiveConfig.Stocks[i].Code == code {
+				s.effectiveConfig.Stocks[i].ScanIntervalMinutes = minutes
+			}
+		}
+		for i := range s.effectiveConfig.Portfolios {
+			portfolio := &s.effectiveConfig.Portfolios[i]
+			if portfolio.ID != portfolioID {
+				continue
+			}
+			for j := range portfolio.Stocks {
+				if portfolio.Stocks[j].Code == code {
+					portfolio.Stocks[j].ScanIntervalMinutes = minutes
+				}
+			}
+		}
+	}
+
+	log.Printf("✓ 股票 %s 扫描间隔已保存到配置文件: %d分钟", code, minutes)
+	return nil
+}
+
+// configMutex 串行化配置文件的读-改-写和内存中生效配置（effectiveConfig）的读写，避免并发请求互相覆盖
+// 写回配置的各接口在调用updateRawConfig和修改生效配置期间持有该锁
+var configMutex sync.Mutex
+
+// updateRawConfig 按原始JSON修改配置文件（保留未知字段和其余配置），update返回错误时不写入
+// 先写临时文件再重命名，避免写到一半时留下损坏的配置文件；调用方需持有configMutex
+func updateRawConfig(update func(raw map[string]interface{}) error) error {
+	configFile := "config_stock.json"
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("配置文件格式错误: %w", err)
+	}
+	if err := update(raw); err != nil {
+		return err
+	}
+
+	updated, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	tmp := configFile + ".tmp"
+	if err := os.WriteFile(tmp, updated, 0644); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, configFile); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	return nil
+}
@@ -3,10 +3,17 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"nofx/config"
+	"nofx/notifier"
 	"nofx/stock"
 	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -15,53 +22,175 @@ import (
 
 // StockAPIServer 股票分析API服务器
 type StockAPIServer struct {
-	router      *gin.Engine
-	manager     AnalyzerManagerInterface
-	port        int
-	apiToken    string // API认证Token
-	restartFunc func() // 重启函数（由main函数提供）
+	router       *gin.Engine
+	manager      AnalyzerManagerInterface            // 默认组合的管理器（/api/... 路径使用）
+	portfolios   map[string]AnalyzerManagerInterface // 组合ID -> 管理器（/api/portfolio/:portfolio_id/... 路径使用）
+	portfolioIDs []string                            // 组合ID（按添加顺序）
+	port         int
+	apiToken     string // API认证Token
+	restartFunc  func() // 重启函数（由main函数提供）
+
+	effectiveConfig *config.StockConfig      // 内存中实际生效的配置（含默认值和环境变量覆盖）
+	scoringModel    *stock.ScoringModel      // 技术指标健康度权重（运行时可调整）
+	errorReporter   *notifier.SentryReporter // Sentry错误上报（可选）
+	costTracker     *stock.AICostTracker     // AI调用token数与费用统计
+	historyMemory   *stock.HistoryMemory     // 分析历史内存占用
+
+	analyzeLinkMutex sync.Mutex
+	usedAnalyzeLinks map[string]time.Time // 已使用的"重新分析"链接签名 -> 过期时间（链接一次有效）
 }
 
 // AnalyzerManagerInterface 分析器管理器接口
 type AnalyzerManagerInterface interface {
 	GetAnalyzer(code string) interface{}
 	GetAllAnalyzers() map[string]interface{}
-	TriggerAnalysis(code string) (interface{}, error) // 手动触发分析
-	GetAnalysisHistory(code string, limit int) interface{} // 获取分析历史
-	GetAllRecentAnalysis(limit int) interface{} // 获取所有股票的最近分析记录
+	TriggerAnalysis(code string) (interface{}, error)                                                // 手动触发分析
+	WhatIfAnalysis(code string, price float64) (interface{}, error)                                  // 假设分析（不入历史）
+	ReplayAnalysis(code string, at time.Time) (interface{}, error)                                   // 历史回放分析（不入历史）
+	GetAnalysisHistory(code string, limit int) interface{}                                           // 获取分析历史
+	GetAnalysisHistoryPage(code string, offset, limit int) (interface{}, int)                        // 分页获取分析历史（返回当前页和总数）
+	DeleteAnalysisHistory(code string, timestamp *time.Time) (int, error)                            // 删除分析历史（timestamp为nil时清空）
+	GetAnalysisDiff(code string, from, to *time.Time) (map[string]interface{}, error)                // 对比两条分析历史（from/to为nil时对比最近两条）
+	GetAllRecentAnalysis(limit int, filter stock.ResultFilter) interface{}                           // 获取所有股票的最近分析记录（按条件筛选）
+	GetRuntimeStatus() map[string]interface{}                                                        // 获取运行时状态（并发占用、排队数等）
+	TriggerAllAnalysis() (string, error)                                                             // 异步批量触发所有股票分析，返回批次ID
+	GetBatchStatus(batchID string) (map[string]interface{}, bool)                                    // 获取批量分析进度
+	GetPortfolioInfo() map[string]interface{}                                                        // 获取所属组合信息（ID、名称、股票数量）
+	GetIndicatorSeries(code, name string, limit int) (map[string]interface{}, error)                 // 获取指标时间序列
+	SubscribeAIStream(code string) (<-chan stock.AIStreamEvent, func(), error)                       // 订阅AI实时输出
+	ExportHistory() map[string][]*stock.AnalysisResult                                               // 导出全部分析历史（股票代码 -> 记录）
+	ImportHistory(history map[string][]*stock.AnalysisResult) (int, []string)                        // 导入分析历史（按时间戳合并去重，仅内存），返回新增记录数和跳过的未监控代码
+	SetScanInterval(code string, interval time.Duration) (time.Duration, error)                      // 运行时调整扫描间隔（立即重新计时），返回原间隔
+	SetStockState(code string, paused, muted *bool) (stock.StockState, error)                        // 设置暂停/静音状态（nil表示不修改）并持久化
+	GetStockState(code string) (stock.StockState, bool)                                              // 获取暂停/静音状态
+	GetPaperAccount(limit int) (*stock.PaperAccountSnapshot, bool)                                   // 获取模拟盘账户（未启用时返回false）
+	SetDebugTrace(code string, on bool) (string, error)                                              // 开启/关闭调试追踪（不持久化），返回调试文件目录
+	ResendNotifications(codes []string, from, to time.Time, dryRun bool) ([]stock.ResendItem, error) // 补发时间范围内达阈值结果的通知
+	ImportPositions(positions []stock.ImportedPosition) ([]stock.PositionImportResult, error)        // 按券商导出的持仓批量更新持仓
 }
 
 // NewStockAPIServer 创建股票API服务器
-func NewStockAPIServer(manager AnalyzerManagerInterface, port int, apiToken string) *StockAPIServer {
+func NewStockAPIServer(manager AnalyzerManagerInterface, port int, apiToken string, corsAllowOrigins []string) *StockAPIServer {
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
-
-	// 配置CORS
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Token"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	router := gin.New()
 
 	server := &StockAPIServer{
-		router:   router,
-		manager:  manager,
-		port:     port,
-		apiToken: apiToken,
+		router:     router,
+		manager:    manager,
+		portfolios: make(map[string]AnalyzerManagerInterface),
+		port:       port,
+		apiToken:   apiToken,
+
+		usedAnalyzeLinks: make(map[string]time.Time),
 	}
+	router.Use(accessLogMiddleware(), gin.CustomRecovery(server.handlePanic), stockCodeMiddleware())
+
+	// 配置CORS
+	router.Use(cors.New(newCORSConfig(corsAllowOrigins)))
 
 	server.setupRoutes()
 	return server
 }
 
+// newCORSConfig 根据来源白名单生成CORS配置
+// 浏览器不允许 Access-Control-Allow-Origin: * 与携带凭证同时使用，
+// 因此白名单包含 "*" 时允许所有来源但关闭凭证，否则只回显白名单内的具体来源并允许凭证
+func newCORSConfig(allowOrigins []string) cors.Config {
+	config := cors.Config{
+		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Token"},
+		ExposeHeaders: []string{"Content-Length"},
+		MaxAge:        12 * time.Hour,
+	}
+
+	for _, origin := range allowOrigins {
+		if origin == "*" {
+			config.AllowAllOrigins = true
+			log.Printf("⚠️  CORS已允许所有来源（不允许携带凭证），建议在生产环境中配置具体的来源白名单")
+			return config
+		}
+	}
+
+	config.AllowOrigins = allowOrigins
+	config.AllowCredentials = true
+	log.Printf("✓ CORS来源白名单: %s", strings.Join(allowOrigins, ", "))
+	return config
+}
+
+// AddPortfolio 注册组合的分析器管理器
+func (s *StockAPIServer) AddPortfolio(id string, manager AnalyzerManagerInterface) {
+	if _, exists := s.portfolios[id]; !exists {
+		s.portfolioIDs = append(s.portfolioIDs, id)
+	}
+	s.portfolios[id] = manager
+}
+
+// managerFor 获取当前请求对应的管理器：组合路径使用对应组合，其余使用默认组合
+func (s *StockAPIServer) managerFor(c *gin.Context) AnalyzerManagerInterface {
+	if manager, ok := c.Get("portfolio_manager"); ok {
+		return manager.(AnalyzerManagerInterface)
+	}
+	return s.manager
+}
+
+// portfolioMiddleware 根据路径中的组合ID选择管理器，组合不存在时返回404
+func (s *StockAPIServer) portfolioMiddleware(c *gin.Context) {
+	id := c.Param("portfolio_id")
+	manager, ok := s.portfolios[id]
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("组合 %s 不存在", id),
+		})
+		return
+	}
+	c.Set("portfolio_manager", manager)
+	c.Next()
+}
+
 // SetRestartFunc 设置重启函数（由main函数提供）
 func (s *StockAPIServer) SetRestartFunc(fn func()) {
 	s.restartFunc = fn
 }
 
+// SetEffectiveConfig 设置实际生效的配置（由main函数提供）
+func (s *StockAPIServer) SetEffectiveConfig(cfg *config.StockConfig) {
+	s.effectiveConfig = cfg
+}
+
+// SetErrorReporter 设置Sentry错误上报（由main函数提供）
+func (s *StockAPIServer) SetErrorReporter(reporter *notifier.SentryReporter) {
+	s.errorReporter = reporter
+}
+
+// handlePanic 请求处理中发生panic时上报Sentry并返回500
+func (s *StockAPIServer) handlePanic(c *gin.Context, recovered interface{}) {
+	s.errorReporter.CapturePanic(recovered, debug.Stack(), map[string]string{
+		"stage":  "api",
+		"method": c.Request.Method,
+		"path":   c.FullPath(),
+	})
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+		"code":    -1,
+		"message": "服务器内部错误",
+	})
+}
+
+// SetHistoryMemory 设置分析历史内存上限（由main函数提供）
+func (s *StockAPIServer) SetHistoryMemory(memory *stock.HistoryMemory) {
+	s.historyMemory = memory
+}
+
+// SetCostTracker 设置AI费用统计（由main函数提供）
+func (s *StockAPIServer) SetCostTracker(tracker *stock.AICostTracker) {
+	s.costTracker = tracker
+}
+
+// SetScoringModel 设置健康度评分模型（由main函数提供）
+func (s *StockAPIServer) SetScoringModel(model *stock.ScoringModel) {
+	s.scoringModel = model
+}
+
 // setupRoutes 设置路由
 func (s *StockAPIServer) setupRoutes() {
 	// 健康检查（兼容两种路径）
@@ -83,26 +212,31 @@ func (s *StockAPIServer) setupRoutes() {
 	{
 		// 配置管理接口
 		api.GET("/config", s.handleGetConfig)
+		api.GET("/config/effective", s.handleGetEffectiveConfig)
+		api.GET("/config/schema", s.handleGetConfigSchema)
 		api.POST("/config", s.handleSaveConfig)
 
-		// 获取所有监控股票列表
-		api.GET("/stocks", s.handleGetStocks)
+		// 快照导出/导入（配置 + 全部分析历史，用于备份和迁移）
+		api.GET("/export/snapshot", s.handleExportSnapshot)
+		api.POST("/import/snapshot", s.handleImportSnapshot)
+
+		// 技术指标健康度权重（所有组合共享，PUT需要Token认证）
+		api.GET("/scoring/weights", s.handleGetScoringWeights)
+		api.PUT("/scoring/weights", s.handleSetScoringWeights)
 
-		// 获取单个股票的最新分析结果
-		api.GET("/stock/:code/latest", s.handleGetLatestAnalysis)
+		// AI调用token数与估算费用（所有组合合计，按天/按股票）
+		api.GET("/cost", s.handleGetAICost)
 
-		// 获取单个股票的历史分析记录
-		api.GET("/stock/:code/history", s.handleGetAnalysisHistory)
+		// 分析历史内存占用（所有组合，按组合/股票）
+		api.GET("/memory", s.handleGetMemory)
 
-		// 获取所有股票的最近分析记录
-		api.GET("/analysis/recent", s.handleGetRecentAnalysis)
+		// 分析相关接口（默认组合）
+		s.setupAnalysisRoutes(api)
 
-		// 手动触发分析
-		api.POST("/stock/:code/analyze", s.handleTriggerAnalysis)
+		// 组合列表，以及按组合隔离的分析接口：/api/portfolio/:portfolio_id/...
+		api.GET("/portfolios", s.handleGetPortfolios)
+		s.setupAnalysisRoutes(api.Group("/portfolio/:portfolio_id", s.portfolioMiddleware))
 
-		// 获取系统统计信息
-		api.GET("/statistics", s.handleGetStatistics)
-		
 		// 系统测试接口
 		api.POST("/test", s.handleSystemTest)
 		api.POST("/test/tdx", s.handleTestTDX)
@@ -114,6 +248,92 @@ func (s *StockAPIServer) setupRoutes() {
 	}
 }
 
+// setupAnalysisRoutes 注册与组合相关的分析接口
+func (s *StockAPIServer) setupAnalysisRoutes(group *gin.RouterGroup) {
+	// 获取所有监控股票列表
+	group.GET("/stocks", s.handleGetStocks)
+
+	// 获取单个股票的最新分析结果
+	group.GET("/stock/:code/latest", s.handleGetLatestAnalysis)
+
+	// 获取单个股票的历史分析记录
+	group.GET("/stock/:code/history", s.handleGetAnalysisHistory)
+
+	// 删除单个股票的历史分析记录（需要Token认证）
+	group.DELETE("/stock/:code/history", s.handleDeleteAnalysisHistory)
+
+	// 对比两条分析历史记录的差异
+	group.GET("/stock/:code/diff", s.handleGetAnalysisDiff)
+
+	// 获取单个股票的指标时间序列
+	group.GET("/stock/:code/indicators", s.handleGetIndicatorSeries)
+
+	// 实时查看AI分析输出（SSE，需开启 ai_config.stream）
+	group.GET("/stock/:code/stream", s.handleAIStream)
+
+	// 获取所有股票的最近分析记录
+	group.GET("/analysis/recent", s.handleGetRecentAnalysis)
+
+	// 手动触发分析
+	group.POST("/stock/:code/analyze", s.handleTriggerAnalysis)
+	group.GET("/stock/:code/analyze", s.handleTriggerAnalysis) // 供通知卡片的"重新分析"按钮直接打开（需带签名的一次性链接）
+
+	// 假设分析：用手动输入的假设现价跑一次分析，结果不入历史（需要Token认证）
+	group.POST("/stock/:code/whatif", s.handleWhatIfAnalysis)
+
+	// 历史回放：用历史某一时刻之前可得的数据重新分析，结果不入历史（需要Token认证）
+	group.POST("/stock/:code/replay", s.handleReplayAnalysis)
+
+	// 运行时调整扫描间隔并写回配置文件（需要Token认证）
+	group.PATCH("/stock/:code/interval", s.handleSetScanInterval)
+
+	// 暂停/静音单只股票，状态持久化，重启后恢复（需要Token认证）
+	group.PATCH("/stock/:code/state", s.handleSetStockState)
+
+	// 开启/关闭单只股票的调试追踪，每轮提示词、AI原始响应和解析过程写入独立文件（需要Token认证）
+	group.POST("/stock/:code/debug", s.handleSetDebugTrace)
+
+	// 批量触发所有股票分析（异步），并查询批次进度
+	group.POST("/analyze/all", s.handleTriggerAllAnalysis)
+	group.GET("/analyze/batch", s.handleGetBatchStatus)
+	group.GET("/analyze/batch/:id", s.handleGetBatchStatus)
+
+	// 补发时间范围内达阈值结果的通知（需要Token认证）
+	group.POST("/notify/resend", s.handleResendNotifications)
+
+	// 获取系统统计信息
+	group.GET("/statistics", s.handleGetStatistics)
+
+	// 获取运行时状态（并发占用、排队情况）
+	group.GET("/runtime", s.handleGetRuntime)
+
+	// 导入券商App导出的持仓CSV，批量建立/更新持仓模式股票（需要Token认证）
+	group.POST("/positions/import", s.handleImportPositions)
+
+	// 模拟盘账户（资金、虚拟持仓、成交记录）
+	group.GET("/paper/account", s.handleGetPaperAccount)
+
+	// Grafana JSON数据源（SimpleJSON/Infinity插件）：连通性测试、指标列表、时间序列查询
+	group.GET("/grafana", s.handleGrafanaTest)
+	group.GET("/grafana/", s.handleGrafanaTest)
+	group.POST("/grafana/search", s.handleGrafanaSearch)
+	group.POST("/grafana/query", s.handleGrafanaQuery)
+}
+
+// handleGetPortfolios 获取所有组合
+func (s *StockAPIServer) handleGetPortfolios(c *gin.Context) {
+	portfolios := []map[string]interface{}{}
+	for _, id := range s.portfolioIDs {
+		portfolios = append(portfolios, s.portfolios[id].GetPortfolioInfo())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    portfolios,
+	})
+}
+
 // handleHealth 健康检查
 func (s *StockAPIServer) handleHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -124,15 +344,19 @@ func (s *StockAPIServer) handleHealth(c *gin.Context) {
 
 // handleGetStocks 获取所有监控股票
 func (s *StockAPIServer) handleGetStocks(c *gin.Context) {
-	analyzers := s.manager.GetAllAnalyzers()
+	manager := s.managerFor(c)
+	analyzers := manager.GetAllAnalyzers()
 
 	stocks := []gin.H{}
 	for code := range analyzers {
+		state, _ := manager.GetStockState(code)
 		// TODO: 获取每个分析器的配置信息
 		stocks = append(stocks, gin.H{
 			"code":    code,
 			"name":    "", // 需要从analyzer获取
 			"enabled": true,
+			"paused":  state.Paused,
+			"muted":   state.Muted,
 		})
 	}
 
@@ -150,7 +374,7 @@ func (s *StockAPIServer) handleGetStocks(c *gin.Context) {
 func (s *StockAPIServer) handleGetLatestAnalysis(c *gin.Context) {
 	code := c.Param("code")
 
-	analyzer := s.manager.GetAnalyzer(code)
+	analyzer := s.managerFor(c).GetAnalyzer(code)
 	if analyzer == nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    -1,
@@ -160,7 +384,7 @@ func (s *StockAPIServer) handleGetLatestAnalysis(c *gin.Context) {
 	}
 
 	// 获取该股票的最新分析结果
-	historyInterface := s.manager.GetAnalysisHistory(code, 1)
+	historyInterface := s.managerFor(c).GetAnalysisHistory(code, 1)
 	history, ok := historyInterface.([]*stock.AnalysisResult)
 	if !ok || len(history) == 0 {
 		c.JSON(http.StatusOK, gin.H{
@@ -191,7 +415,18 @@ func (s *StockAPIServer) handleGetAnalysisHistory(c *gin.Context) {
 		}
 	}
 
-	analyzer := s.manager.GetAnalyzer(code)
+	// 分页参数：支持 offset+limit，或 page+page_size（page从1开始，优先级更高）
+	offset := parseIntQuery(c, "offset", 0, 0, -1)
+	pageSize := parseIntQuery(c, "page_size", limit, 1, 100)
+	page := parseIntQuery(c, "page", 0, 1, -1)
+	if page > 0 {
+		limit = pageSize
+		offset = (page - 1) * pageSize
+	} else {
+		page = offset/limit + 1
+	}
+
+	analyzer := s.managerFor(c).GetAnalyzer(code)
 	if analyzer == nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    -1,
@@ -200,24 +435,216 @@ func (s *StockAPIServer) handleGetAnalysisHistory(c *gin.Context) {
 		return
 	}
 
-	historyInterface := s.manager.GetAnalysisHistory(code, limit)
+	historyInterface, total := s.managerFor(c).GetAnalysisHistoryPage(code, offset, limit)
 	history, ok := historyInterface.([]*stock.AnalysisResult)
 	if !ok {
 		history = []*stock.AnalysisResult{}
 	}
-	
+
+	totalPages := (total + limit - 1) / limit
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data": gin.H{
+			"stock_code":  code,
+			"count":       len(history),
+			"limit":       limit,
+			"offset":      offset,
+			"page":        page,
+			"page_size":   limit,
+			"total":       total,
+			"total_pages": totalPages,
+			"has_more":    offset+len(history) < total,
+			"records":     history,
+		},
+	})
+}
+
+// parseIntQuery 解析整数查询参数，解析失败或超出范围时返回默认值（max<0表示不限制上限）
+func parseIntQuery(c *gin.Context, key string, defaultValue, min, max int) int {
+	valueStr := c.Query(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var value int
+	if n, err := fmt.Sscanf(valueStr, "%d", &value); err != nil || n != 1 {
+		return defaultValue
+	}
+	if value < min || (max >= 0 && value > max) {
+		return defaultValue
+	}
+	return value
+}
+
+// handleGetIndicatorSeries 获取指标时间序列
+// 查询参数：name 指标名（如 rsi14、ma5、macd_dif），limit 返回点数（默认60，最大250）
+func (s *StockAPIServer) handleGetIndicatorSeries(c *gin.Context) {
+	code := c.Param("code")
+	if s.managerFor(c).GetAnalyzer(code) == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": "未找到该股票的分析器",
+		})
+		return
+	}
+
+	name := c.Query("name")
+	if !stock.IsSeriesIndicator(name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("不支持的指标 '%s'（可选：ma5/ma10/ma20/ma60/rsi14/volatility_20d/macd_dif/macd_dea/macd_hist/kdj_k/kdj_d/kdj_j）", name),
+		})
+		return
+	}
+	limit := parseIntQuery(c, "limit", 60, 1, 250)
+
+	series, err := s.managerFor(c).GetIndicatorSeries(code, name, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("获取指标序列失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    series,
+	})
+}
+
+// handleAIStream 以SSE推送某股票分析时的AI实时输出
+// 事件名为 start/delta/done/error，delta 事件的数据为AI输出片段；空闲时每30秒发送一次 ping 保活
+func (s *StockAPIServer) handleAIStream(c *gin.Context) {
+	code := c.Param("code")
+	events, cancel, err := s.managerFor(c).SubscribeAIStream(code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx缓冲
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-events:
+			c.SSEvent(event.Type, event.Content)
+			return true
+		case <-time.After(30 * time.Second):
+			c.SSEvent("ping", time.Now().Format("15:04:05"))
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// handleDeleteAnalysisHistory 删除单个股票的历史分析记录（需要Token认证）
+// 查询参数：timestamp=<分析结果的timestamp> 删除单条，或 all=true 清空该股票全部记录
+func (s *StockAPIServer) handleDeleteAnalysisHistory(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+	code := c.Param("code")
+
+	var timestamp *time.Time
+	if value := c.Query("timestamp"); value != "" {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("timestamp格式错误（应为分析结果中的timestamp，如 2024-05-10T14:30:00.123+08:00）: %v", err),
+			})
+			return
+		}
+		timestamp = &t
+	} else if c.Query("all") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "请指定 timestamp 删除单条记录，或 all=true 清空全部记录",
+		})
+		return
+	}
+
+	deleted, err := s.managerFor(c).DeleteAnalysisHistory(code, timestamp)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("删除分析记录失败: %v", err),
+			"data": gin.H{
+				"deleted": deleted,
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
 		"data": gin.H{
 			"stock_code": code,
-			"count":      len(history),
-			"limit":      limit,
-			"records":    history,
+			"deleted":    deleted,
 		},
 	})
 }
 
+// handleGetAnalysisDiff 对比两条分析历史记录（信号、信心度、目标价、指标变化）
+func (s *StockAPIServer) handleGetAnalysisDiff(c *gin.Context) {
+	code := c.Param("code")
+
+	var from, to *time.Time
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("%s格式错误（应为分析结果中的timestamp，如 2024-05-10T14:30:00.123+08:00）: %v", param.name, err),
+			})
+			return
+		}
+		*param.target = &t
+	}
+	if (from == nil) != (to == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "from 和 to 需同时指定（都不指定时对比最近两条记录）",
+		})
+		return
+	}
+
+	diff, err := s.managerFor(c).GetAnalysisDiff(code, from, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    diff,
+	})
+}
+
 // handleGetRecentAnalysis 获取所有股票的最近分析记录
 func (s *StockAPIServer) handleGetRecentAnalysis(c *gin.Context) {
 	limit := 10 // 默认返回最近10条
@@ -230,7 +657,43 @@ func (s *StockAPIServer) handleGetRecentAnalysis(c *gin.Context) {
 		}
 	}
 
-	recentAnalysisInterface := s.manager.GetAllRecentAnalysis(limit)
+	// 筛选条件：min_confidence=最低信心度，signals=BUY,SELL（只看有操作价值的信号）
+	var filter stock.ResultFilter
+	if value := c.Query("min_confidence"); value != "" {
+		minConfidence, err := strconv.Atoi(value)
+		if err != nil || minConfidence < 0 || minConfidence > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": "min_confidence 应为0-100的整数",
+			})
+			return
+		}
+		filter.MinConfidence = minConfidence
+	}
+	if value := c.Query("signals"); value != "" {
+		signals, ok := stock.ParseSignals(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("signals 参数无效: %s（可选：BUY/SELL/HOLD，逗号分隔）", value),
+			})
+			return
+		}
+		filter.Signals = signals
+	}
+	// 排序：sort=time（默认）/limit_up/limit_down（按AI判断的短期触及涨停/跌停可能性从高到低）
+	if value := c.Query("sort"); value != "" {
+		if !stock.ValidResultSort(value) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("sort 参数无效: %s（可选：time/limit_up/limit_down）", value),
+			})
+			return
+		}
+		filter.SortBy = value
+	}
+
+	recentAnalysisInterface := s.managerFor(c).GetAllRecentAnalysis(limit, filter)
 	recentAnalysis, ok := recentAnalysisInterface.([]*stock.AnalysisResult)
 	if !ok {
 		recentAnalysis = []*stock.AnalysisResult{}
@@ -248,10 +711,22 @@ func (s *StockAPIServer) handleGetRecentAnalysis(c *gin.Context) {
 }
 
 // handleTriggerAnalysis 手动触发分析
+// POST需要Token认证；GET供通知卡片的"重新分析"按钮打开，需带通知中生成的签名参数（expires、sig），链接一次有效
 func (s *StockAPIServer) handleTriggerAnalysis(c *gin.Context) {
 	code := c.Param("code")
+	if c.Request.Method == http.MethodGet {
+		if err := s.useAnalyzeLink(code, c.Query("expires"), c.Query("sig")); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    -1,
+				"message": fmt.Sprintf("重新分析链接无效: %v", err),
+			})
+			return
+		}
+	} else if !s.requireAPIToken(c) {
+		return
+	}
 
-	result, err := s.manager.TriggerAnalysis(code)
+	result, err := s.managerFor(c).TriggerAnalysis(code)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    -1,
@@ -267,9 +742,163 @@ func (s *StockAPIServer) handleTriggerAnalysis(c *gin.Context) {
 	})
 }
 
+// useAnalyzeLink 校验并消费"重新分析"签名链接（每个链接只能使用一次），顺便清理已过期的记录
+func (s *StockAPIServer) useAnalyzeLink(code, expires, sig string) error {
+	now := time.Now()
+	if err := stock.VerifyAnalyzeLink(s.apiToken, code, expires, sig, now); err != nil {
+		return err
+	}
+
+	s.analyzeLinkMutex.Lock()
+	defer s.analyzeLinkMutex.Unlock()
+	for used, expiresAt := range s.usedAnalyzeLinks {
+		if now.After(expiresAt) {
+			delete(s.usedAnalyzeLinks, used)
+		}
+	}
+	if _, used := s.usedAnalyzeLinks[sig]; used {
+		return fmt.Errorf("链接已使用过")
+	}
+	s.usedAnalyzeLinks[sig] = now.Add(stock.AnalyzeLinkTTL)
+	return nil
+}
+
+// handleWhatIfAnalysis 假设分析
+// 请求体：{"price": 12.34}，price 为假设现价（元）
+func (s *StockAPIServer) handleWhatIfAnalysis(c *gin.Context) {
+	// 与手动触发分析一样会调用AI，需要Token认证
+	if !s.requireAPIToken(c) {
+		return
+	}
+	code := c.Param("code")
+
+	var req struct {
+		Price float64 `json:"price"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+	if req.Price <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "假设现价 price 必须大于0",
+		})
+		return
+	}
+
+	result, err := s.managerFor(c).WhatIfAnalysis(code, req.Price)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("假设分析失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "假设分析完成",
+		"data":    result,
+	})
+}
+
+// handleReplayAnalysis 历史回放分析
+// 请求体：{"time": "2024-05-10 14:30"}，time 为回放时刻（只填日期时按当日收盘15:00回放）
+func (s *StockAPIServer) handleReplayAnalysis(c *gin.Context) {
+	// 与手动触发分析一样会调用AI，需要Token认证
+	if !s.requireAPIToken(c) {
+		return
+	}
+	code := c.Param("code")
+
+	var req struct {
+		Time string `json:"time"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+
+	at, err := time.ParseInLocation("2006-01-02 15:04", req.Time, time.Local)
+	if err != nil {
+		date, dateErr := time.ParseInLocation("2006-01-02", req.Time, time.Local)
+		if dateErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    -1,
+				"message": "回放时刻 time 格式应为 YYYY-MM-DD HH:MM 或 YYYY-MM-DD",
+			})
+			return
+		}
+		at = date.Add(15 * time.Hour)
+	}
+
+	result, err := s.managerFor(c).ReplayAnalysis(code, at)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("历史回放失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "历史回放完成",
+		"data":    result,
+	})
+}
+
+// handleTriggerAllAnalysis 批量触发所有股票分析
+func (s *StockAPIServer) handleTriggerAllAnalysis(c *gin.Context) {
+	batchID, err := s.managerFor(c).TriggerAllAnalysis()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+			"data": gin.H{
+				"batch_id": batchID,
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": "批量分析已开始",
+		"data": gin.H{
+			"batch_id": batchID,
+		},
+	})
+}
+
+// handleGetBatchStatus 获取批量分析进度（不指定ID时返回最近一个批次）
+func (s *StockAPIServer) handleGetBatchStatus(c *gin.Context) {
+	status, ok := s.managerFor(c).GetBatchStatus(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": "未找到该批次",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
 // handleGetStatistics 获取系统统计
 func (s *StockAPIServer) handleGetStatistics(c *gin.Context) {
-	analyzers := s.manager.GetAllAnalyzers()
+	analyzers := s.managerFor(c).GetAllAnalyzers()
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -282,6 +911,58 @@ func (s *StockAPIServer) handleGetStatistics(c *gin.Context) {
 	})
 }
 
+// handleGetRuntime 获取运行时状态
+func (s *StockAPIServer) handleGetRuntime(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    s.managerFor(c).GetRuntimeStatus(),
+	})
+}
+
+// handleGetEffectiveConfig 获取内存中实际生效的配置（敏感字段脱敏）
+// 与 handleGetConfig 读取磁盘文件不同，这里包含校验时填充的默认值和环境变量覆盖
+func (s *StockAPIServer) handleGetEffectiveConfig(c *gin.Context) {
+	if s.effectiveConfig == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    -1,
+			"message": "生效配置不可用",
+		})
+		return
+	}
+
+	redacted, err := s.redactedEffectiveConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("生成脱敏配置失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    redacted,
+	})
+}
+
+// redactedEffectiveConfig 生效配置的脱敏副本（持有configMutex，避免与写回配置的接口并发读写）
+func (s *StockAPIServer) redactedEffectiveConfig() (*config.StockConfig, error) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	return s.effectiveConfig.Redacted()
+}
+
+// handleGetConfigSchema 获取配置文件的JSON Schema（可用于编辑器自动补全和校验）
+func (s *StockAPIServer) handleGetConfigSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    config.Schema(),
+	})
+}
+
 // handleGetConfig 获取配置
 func (s *StockAPIServer) handleGetConfig(c *gin.Context) {
 	// 读取配置文件
@@ -314,8 +995,8 @@ func (s *StockAPIServer) handleGetConfig(c *gin.Context) {
 
 // handleSaveConfig 保存配置
 func (s *StockAPIServer) handleSaveConfig(c *gin.Context) {
-	var config map[string]interface{}
-	if err := c.ShouldBindJSON(&config); err != nil {
+	var newConfig map[string]interface{}
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    -1,
 			"message": fmt.Sprintf("请求数据格式错误: %v", err),
@@ -324,7 +1005,7 @@ func (s *StockAPIServer) handleSaveConfig(c *gin.Context) {
 	}
 
 	// 转换为格式化的JSON
-	data, err := json.MarshalIndent(config, "", "  ")
+	data, err := json.MarshalIndent(newConfig, "", "  ")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    -1,
@@ -333,8 +1014,31 @@ func (s *StockAPIServer) handleSaveConfig(c *gin.Context) {
 		return
 	}
 
-	// 备份原配置文件
+	// 按JSON Schema校验字段名和类型，避免写入启动时无法加载的配置
+	if err := config.ValidateSchema(data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 与写回配置的接口串行，避免覆盖彼此的修改
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	// 先写临时文件，再备份原配置文件并把临时文件重命名为配置文件，任一步失败都不会留下缺失或写了一半的配置
 	configFile := "config_stock.json"
+	tmp := configFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("保存配置文件失败: %v", err),
+		})
+		return
+	}
+
+	// 备份原配置文件
 	backupFile := fmt.Sprintf("config_stock.json.backup.%s", time.Now().Format("20060102150405"))
 	if err := os.Rename(configFile, backupFile); err != nil {
 		log.Printf("⚠️  备份配置文件失败: %v", err)
@@ -343,7 +1047,7 @@ func (s *StockAPIServer) handleSaveConfig(c *gin.Context) {
 	}
 
 	// 写入新配置
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
+	if err := os.Rename(tmp, configFile); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    -1,
 			"message": fmt.Sprintf("保存配置文件失败: %v", err),
@@ -422,7 +1126,7 @@ func (s *StockAPIServer) handleSystemTest(c *gin.Context) {
 
 	// 4. 测试分析器状态
 	testResult["total"] = testResult["total"].(int) + 1
-	analyzers := s.manager.GetAllAnalyzers()
+	analyzers := s.managerFor(c).GetAllAnalyzers()
 	if len(analyzers) > 0 {
 		tests = append(tests, gin.H{
 			"name":    "分析器状态",
@@ -610,7 +1314,7 @@ func (s *StockAPIServer) handleTestAI(c *gin.Context) {
 func (s *StockAPIServer) handleTestStock(c *gin.Context) {
 	code := c.Param("code")
 
-	result, err := s.manager.TriggerAnalysis(code)
+	result, err := s.managerFor(c).TriggerAnalysis(code)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    -1,
@@ -634,24 +1338,16 @@ func (s *StockAPIServer) handleTestStock(c *gin.Context) {
 	})
 }
 
-// handleRestart 重启后端服务（需要Token认证）
-func (s *StockAPIServer) handleRestart(c *gin.Context) {
-	// 验证Token
+// requireAPIToken 验证API Token（请求头 X-API-Token 或请求体 token 字段），验证失败时写入错误响应并返回false
+func (s *StockAPIServer) requireAPIToken(c *gin.Context) bool {
+	// 只从请求头读取：读取请求体会导致处理函数自己解析请求体时读到EOF
 	token := c.GetHeader("X-API-Token")
-	if token == "" {
-		// 尝试从请求体获取
-		var body map[string]string
-		if err := c.ShouldBindJSON(&body); err == nil {
-			token = body["token"]
-		}
-	}
-
 	if token == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    -1,
-			"message": "未提供API Token，请在请求头中添加 'X-API-Token' 或在请求体中提供 'token' 字段",
+			"message": "未提供API Token，请在请求头中添加 'X-API-Token'",
 		})
-		return
+		return false
 	}
 
 	// 验证Token是否正确
@@ -660,13 +1356,22 @@ func (s *StockAPIServer) handleRestart(c *gin.Context) {
 			"code":    -1,
 			"message": "API Token验证失败",
 		})
+		return false
+	}
+	return true
+}
+
+// handleRestart 重启后端服务（需要Token认证）
+func (s *StockAPIServer) handleRestart(c *gin.Context) {
+	// 验证Token
+	if !s.requireAPIToken(c) {
 		return
 	}
 
 	// 如果Token为空或匹配，执行重启
 	if s.restartFunc != nil {
 		log.Printf("🔄 收到重启请求，准备重启服务...")
-		
+
 		// 先返回响应，再执行重启（避免客户端等待）
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
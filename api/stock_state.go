@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleSetStockState 设置单只股票的暂停/静音状态（需要Token认证，请求头 X-API-Token）
+// 请求体 {"paused": true} 或 {"muted": false}，未提供的字段保持不变；状态写入日志目录下的状态文件，重启后恢复
+func (s *StockAPIServer) handleSetStockState(c *gin.Context) {
+	if !s.requireAPIToken(c) {
+		return
+	}
+
+	code := c.Param("code")
+	var req struct {
+		Paused *bool `json:"paused"`
+		Muted  *bool `json:"muted"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": fmt.Sprintf("请求数据格式错误: %v", err),
+		})
+		return
+	}
+	if req.Paused == nil && req.Muted == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    -1,
+			"message": "请至少提供 paused 或 muted 字段",
+		})
+		return
+	}
+
+	manager := s.managerFor(c)
+	if _, exists := manager.GetStockState(code); !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    -1,
+			"message": "未找到该股票的分析器",
+		})
+		return
+	}
+
+	state, err := manager.SetStockState(code, req.Paused, req.Muted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    -1,
+			"message": err.Error(),
+			"data":    state,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "股票状态已更新",
+		"data": gin.H{
+			"stock_code": code,
+			"paused":     state.Paused,
+			"muted":      state.Muted,
+		},
+	})
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+)
+
+// Redacted 返回配置的脱敏副本（API密钥、Token、Webhook地址等敏感字段打码），用于对外展示生效配置
+// 敏感字段由结构体标签标注：secret:"key" 保留首尾各4位，secret:"url" 只保留协议和主机；
+// 标签可用于字符串、字符串切片和字符串map（按值打码），新增的凭证字段加上标签即可，无需修改此处
+func (c *StockConfig) Redacted() (*StockConfig, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var redacted StockConfig
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		return nil, err
+	}
+	redactValue(reflect.ValueOf(&redacted).Elem())
+	return &redacted, nil
+}
+
+// redactValue 递归遍历结构体（含指针、切片、map中的结构体），按secret标签打码
+func redactValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			redactValue(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if kind := field.Tag.Get("secret"); kind != "" {
+				maskValue(v.Field(i), kind)
+				continue
+			}
+			redactValue(v.Field(i))
+		}
+	}
+}
+
+// maskValue 按打码方式处理字符串、字符串切片或字符串map
+func maskValue(v reflect.Value, kind string) {
+	mask := maskSecret
+	if kind == "url" {
+		mask = maskURL
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(mask(v.String()))
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if item := v.Index(i); item.Kind() == reflect.String {
+				item.SetString(mask(item.String()))
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.ValueOf(mask(v.MapIndex(key).String())).Convert(v.Type().Elem()))
+		}
+	}
+}
+
+// maskSecret 密钥打码：保留首尾各4位，过短时全部打码
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "****" + secret[len(secret)-4:]
+}
+
+// maskURL 地址打码：只保留协议和主机（去掉账号密码、路径和查询参数）
+func maskURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "****"
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 配置文件的JSON Schema由StockConfig结构体反射生成，字段增减时无需手工维护
+// 加载配置时先按Schema校验字段名和类型，再交给Validate做取值范围等业务校验
+
+// maxSchemaErrors 最多报告的Schema错误数
+const maxSchemaErrors = 20
+
+// schemaEnums 字段的可选值（类型名.字段名 -> 可选值），与Validate中的校验保持一致
+var schemaEnums = map[string][]string{
+	"AIConfig.Provider":                 {"deepseek", "qwen", "custom"},
+	"AIConfig.IndicatorsInPrompt":       sortedKeys(validPromptIndicators),
+	"StockItem.KlinePeriods":            sortedKeys(validKlinePeriods),
+	"BrokerFeeConfig.Template":          append([]string{""}, sortedKeys(validFeeTemplates)...),
+	"SMSConfig.Provider":                {"", "aliyun", "tencent"},
+	"TableConfig.Provider":              {"", "feishu", "dingtalk"},
+	"ComplianceConfig.Action":           {"", "replace", "append"},
+	"NotificationConfig.ThresholdBasis": {"", "confidence", "system_score"},
+}
+
+// schemaTypeNames JSON Schema类型的中文名称（用于错误提示）
+var schemaTypeNames = map[string]string{
+	"object":  "对象",
+	"array":   "数组",
+	"string":  "字符串",
+	"integer": "整数",
+	"number":  "数字",
+	"boolean": "布尔值（true/false）",
+	"null":    "null",
+}
+
+// Schema 返回配置文件的JSON Schema（draft 2020-12）
+func Schema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(StockConfig{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "股票分析系统配置（config_stock.json）"
+	return schema
+}
+
+// schemaFor 生成Go类型对应的Schema，enumKey为字段的"类型名.字段名"（用于查找可选值）
+func schemaFor(t reflect.Type, enumKey string) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := schemaFor(t.Elem(), enumKey)
+		schema["type"] = []string{schema["type"].(string), "null"}
+		return schema
+
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			properties[name] = schemaFor(field.Type, t.Name()+"."+field.Name)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": schemaFor(t.Elem(), enumKey),
+		}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 []string{"object", "null"},
+			"additionalProperties": schemaFor(t.Elem(), ""),
+		}
+
+	case reflect.String:
+		schema := map[string]interface{}{"type": "string"}
+		if values, ok := schemaEnums[enumKey]; ok {
+			schema["enum"] = values
+		}
+		return schema
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// jsonFieldName 返回结构体字段在JSON中的名称，不参与序列化的字段返回false
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// ValidateSchema 按Schema校验配置文件内容，返回所有字段名和类型错误
+// 如"字段 stocks[0].min_confidence 类型应为整数（实际为字符串）""未知字段 stocks[0].scan_interval"
+func ValidateSchema(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("JSON格式错误: %w", err)
+	}
+
+	var errs []string
+	validateSchemaValue("", value, Schema(), &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) > maxSchemaErrors {
+		errs = append(errs[:maxSchemaErrors], fmt.Sprintf("……共%d处错误，仅列出前%d处", len(errs), maxSchemaErrors))
+	}
+	return fmt.Errorf("配置文件字段错误:\n  - %s", strings.Join(errs, "\n  - "))
+}
+
+// validateSchemaValue 递归校验一个值（支持type/properties/additionalProperties/items/enum）
+func validateSchemaValue(path string, value interface{}, schema map[string]interface{}, errs *[]string) {
+	actual := jsonValueType(value)
+	if !schemaAllowsType(schema["type"], actual) {
+		expected := schemaTypeText(schema["type"])
+		*errs = append(*errs, fmt.Sprintf("字段 %s 类型应为%s（实际为%s）", displayPath(path), expected, schemaTypeNames[actual]))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			if child, ok := properties[key].(map[string]interface{}); ok {
+				validateSchemaValue(childPath, v[key], child, errs)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*errs = append(*errs, fmt.Sprintf("未知字段 %s%s", childPath, suggestField(key, properties)))
+				}
+			case map[string]interface{}:
+				validateSchemaValue(childPath, v[key], additional, errs)
+			}
+		}
+
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), item, items, errs)
+			}
+		}
+
+	case string:
+		if values, ok := schema["enum"].([]string); ok && !containsString(values, v) {
+			*errs = append(*errs, fmt.Sprintf("字段 %s 的值 '%s' 无效（可选：%s）", displayPath(path), v, strings.Join(nonEmpty(values), "/")))
+		}
+	}
+}
+
+// jsonValueType 返回解码后值的JSON Schema类型（没有小数点和指数的数字为integer）
+func jsonValueType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	default:
+		return ""
+	}
+}
+
+// schemaAllowsType 判断Schema的type是否接受该类型（integer也是number；未声明type时接受任意类型）
+func schemaAllowsType(schemaType interface{}, actual string) bool {
+	var allowed []string
+	switch t := schemaType.(type) {
+	case string:
+		allowed = []string{t}
+	case []string:
+		allowed = t
+	default:
+		return true
+	}
+	for _, expected := range allowed {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypeText Schema类型的中文描述（可为null的字段只说明非null类型）
+func schemaTypeText(schemaType interface{}) string {
+	switch t := schemaType.(type) {
+	case string:
+		return schemaTypeNames[t]
+	case []string:
+		return schemaTypeNames[t[0]]
+	default:
+		return ""
+	}
+}
+
+// suggestField 未知字段与已知字段只差大小写或下划线时给出拼写提示
+func suggestField(key string, properties map[string]interface{}) string {
+	normalized := strings.ReplaceAll(strings.ToLower(key), "_", "")
+	for name := range properties {
+		if strings.ReplaceAll(name, "_", "") == normalized {
+			return fmt.Sprintf("（是否应为 %s？）", name)
+		}
+	}
+	return ""
+}
+
+// displayPath 错误提示中的字段路径（根节点显示为"配置文件"）
+func displayPath(path string) string {
+	if path == "" {
+		return "配置文件"
+	}
+	return path
+}
+
+// sortedKeys 返回map的键（排序后）
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmpty 去掉空字符串（可选值提示中不展示表示默认值的空串）
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
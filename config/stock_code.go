@@ -0,0 +1,87 @@
+package config
+
+import "strings"
+
+// 股票代码统一使用6位纯数字作为内部key（如 600519），配置和API中也可以写成 sh600519、SH600519、600519.SH 等形式
+
+// stockMarkets 支持的市场标记
+var stockMarkets = []string{"SH", "SZ", "BJ"}
+
+// splitStockCode 拆分代码中的市场标记（前缀或后缀，不区分大小写），返回大写的市场标记和6位代码
+// 不是"市场标记+6位数字"或6位数字形式的代码返回ok=false
+func splitStockCode(code string) (market, pureCode string, ok bool) {
+	code = strings.TrimSpace(code)
+	upper := strings.ToUpper(code)
+	pureCode = code
+	for _, m := range stockMarkets {
+		if strings.HasPrefix(upper, m) {
+			market, pureCode = m, code[len(m):]
+			break
+		}
+		if strings.HasSuffix(upper, "."+m) {
+			market, pureCode = m, code[:len(code)-len(m)-1]
+			break
+		}
+	}
+	if len(pureCode) != 6 || strings.Trim(pureCode, "0123456789") != "" {
+		return "", code, false
+	}
+	return market, pureCode, true
+}
+
+// inferStockMarket 按代码首位推断市场
+func inferStockMarket(pureCode string) string {
+	if strings.HasPrefix(pureCode, "11") {
+		return "SH" // 沪市可转债
+	}
+	if strings.HasPrefix(pureCode, "92") {
+		return "BJ" // 北交所新代码段（920xxx），与沪市B股（900xxx）同为9开头
+	}
+	switch pureCode[0] {
+	case '6', '9', '5':
+		return "SH" // 沪市主板/科创板、沪市B股、沪市基金
+	case '0', '2', '3', '1':
+		return "SZ" // 深市主板/创业板、深市B股、深市基金、深市可转债
+	case '4', '8':
+		return "BJ" // 北交所
+	default:
+		return ""
+	}
+}
+
+// StockMarket 判断股票所属市场（SH/SZ/BJ）并返回6位代码：显式写出的市场标记（前缀或后缀）优先，否则按代码推断
+// 各处判断市场都应使用该函数，保证同一只股票的不同写法归到同一个市场；无法识别时市场为空
+func StockMarket(code string) (market, pureCode string) {
+	market, pureCode, ok := splitStockCode(code)
+	if !ok {
+		return "", strings.TrimSpace(code)
+	}
+	if market == "" {
+		market = inferStockMarket(pureCode)
+	}
+	return market, pureCode
+}
+
+// NormalizeStockCode 统一股票代码写法：去掉市场前缀/后缀和首尾空格，返回6位代码
+// 显式写出的市场与按代码推断的市场不同时（如上证指数 sh000001，与平安银行 000001 代码相同）保留小写的市场前缀；
+// 非股票代码形式的代码（如虚拟组合的自定义代码）只去掉首尾空格
+func NormalizeStockCode(code string) string {
+	market, pureCode, ok := splitStockCode(code)
+	if !ok {
+		return strings.TrimSpace(code)
+	}
+	if market != "" && market != inferStockMarket(pureCode) {
+		return strings.ToLower(market) + pureCode
+	}
+	return pureCode
+}
+
+// normalizeStockCodes 规范化股票列表（含虚拟组合成分股）中的代码，使同一只股票的不同写法使用相同的key
+func normalizeStockCodes(stocks []StockItem) {
+	for i := range stocks {
+		stocks[i].Code = NormalizeStockCode(stocks[i].Code)
+		for j := range stocks[i].Basket {
+			stocks[i].Basket[j].Code = NormalizeStockCode(stocks[i].Basket[j].Code)
+		}
+	}
+}
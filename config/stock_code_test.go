@@ -0,0 +1,37 @@
+package config
+
+import "testing"
+
+func TestStockMarket(t *testing.T) {
+	cases := []struct {
+		code, market, pureCode string
+	}{
+		{"600519", "SH", "600519"},
+		{"sh600519", "SH", "600519"},
+		{"000001.SZ", "SZ", "000001"},
+		{"900901", "SH", "900901"}, // 沪市B股
+		{"920001", "BJ", "920001"}, // 北交所新代码段
+		{"bj920001", "BJ", "920001"},
+		{"430047", "BJ", "430047"},
+		{"113050", "SH", "113050"}, // 沪市可转债
+		{"basket", "", "basket"},
+	}
+	for _, tc := range cases {
+		market, pureCode := StockMarket(tc.code)
+		if market != tc.market || pureCode != tc.pureCode {
+			t.Errorf("StockMarket(%q) = %q, %q，期望 %q, %q", tc.code, market, pureCode, tc.market, tc.pureCode)
+		}
+	}
+}
+
+func TestNormalizeStockCodeBeijing(t *testing.T) {
+	// 同一只北交所股票的不同写法必须归一为同一个key
+	for _, code := range []string{"920001", "bj920001", "BJ920001", "920001.BJ"} {
+		if got := NormalizeStockCode(code); got != "920001" {
+			t.Errorf("NormalizeStockCode(%q) = %q，期望 920001", code, got)
+		}
+	}
+	if got := NormalizeStockCode("sh000001"); got != "sh000001" {
+		t.Errorf("上证指数应保留市场前缀，实际 %q", got)
+	}
+}
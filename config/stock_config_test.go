@@ -0,0 +1,49 @@
+package config
+
+import "testing"
+
+// minimalConfig 能通过校验的最小配置
+func minimalConfig() *StockConfig {
+	return &StockConfig{
+		TDXAPIUrl: "http://127.0.0.1:8080",
+		AIConfig:  AIConfig{Provider: "deepseek", DeepSeekKey: "sk-test"},
+		Stocks:    []StockItem{{Code: "000001", Name: "平安银行", Enabled: true, ScanIntervalMinutes: 5}},
+	}
+}
+
+func TestValidateKlineCountDefaults(t *testing.T) {
+	cfg := minimalConfig()
+	cfg.MinKlineDays = 100
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("最小配置校验失败: %v", err)
+	}
+	if cfg.DayKlineCount != 60 || cfg.MinuteKlineCount != 100 {
+		t.Fatalf("K线数量默认值不符: day=%d minute=%d", cfg.DayKlineCount, cfg.MinuteKlineCount)
+	}
+	if cfg.MinKlineDays != 60 {
+		t.Fatalf("min_kline_days 应截断为日K线数量60，实际 %d", cfg.MinKlineDays)
+	}
+}
+
+func TestValidateClampsKlineCount(t *testing.T) {
+	cases := []struct {
+		name                string
+		day, minute         int
+		wantDay, wantMinute int
+	}{
+		{"超过上限时截断", 10000, 1001, MaxDayKlineCount, MaxMinuteKlineCount},
+		{"负数取默认值", -1, -1, 60, 100},
+		{"上限值保持不变", MaxDayKlineCount, MaxMinuteKlineCount, MaxDayKlineCount, MaxMinuteKlineCount},
+	}
+	for _, tc := range cases {
+		cfg := minimalConfig()
+		cfg.DayKlineCount, cfg.MinuteKlineCount = tc.day, tc.minute
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s: 校验失败: %v", tc.name, err)
+			continue
+		}
+		if cfg.DayKlineCount != tc.wantDay || cfg.MinuteKlineCount != tc.wantMinute {
+			t.Errorf("%s: 期望 day=%d minute=%d，实际 day=%d minute=%d", tc.name, tc.wantDay, tc.wantMinute, cfg.DayKlineCount, cfg.MinuteKlineCount)
+		}
+	}
+}
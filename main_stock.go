@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"nofx/api"
 	"nofx/config"
 	"nofx/mcp"
@@ -11,10 +13,16 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/robfig/cron/v3"
 )
 
 func main() {
@@ -49,15 +57,87 @@ func main() {
 	}
 	log.Printf("✓ AI客户端已初始化 (%s)", strings.ToUpper(cfg.AIConfig.Provider))
 
+	// 创建新闻/公告客户端（可选，各组合共享缓存）
+	var newsClient *stock.NewsClient
+	if cfg.News.Enabled {
+		newsClient = stock.NewNewsClient(
+			cfg.News.URL,
+			cfg.News.Headers,
+			cfg.News.Limit,
+			time.Duration(cfg.News.MaxAgeDays)*24*time.Hour,
+			time.Duration(cfg.News.CacheMinutes)*time.Minute,
+			time.Duration(cfg.News.TimeoutSeconds)*time.Second,
+		)
+		log.Printf("✓ 新闻/公告摘要已启用（每只股票最多%d条，缓存%d分钟）", cfg.News.Limit, cfg.News.CacheMinutes)
+	}
+
+	// 创建多数据源行情校验器（可选）
+	var quoteVerifier *stock.QuoteVerifier
+	if cfg.TDXVerify.Enabled {
+		quoteVerifier = stock.NewQuoteVerifier(cfg.TDXVerify.URLs, cfg.TDXVerify.MaxDiffPercent, cfg.TDXVerify.Always)
+		scope := "仅手动触发分析时"
+		if cfg.TDXVerify.Always {
+			scope = "每次分析"
+		}
+		log.Printf("✓ 多TDX数据源行情校验已启用（%d个数据源，%s校验，偏差阈值%.1f%%）", len(cfg.TDXVerify.URLs)+1, scope, cfg.TDXVerify.MaxDiffPercent)
+	}
+
+	// 创建通知重投队列（发送失败的通知持久化，后台定期重投）
+	// 试运行模式：通知只打日志（也不启用重投队列，避免重投之前积压的通知）
+	if cfg.DryRun.Enabled {
+		if cfg.DryRun.RuleBasedAI {
+			log.Printf("🧪 试运行模式已启用：通知只打印日志不发送，AI调用由本地规则代替")
+		} else {
+			log.Printf("🧪 试运行模式已启用：通知只打印日志不发送")
+		}
+	}
+
+	var retryQueue *notifier.RetryQueue
+	if cfg.NotifyRetry.Enabled && !cfg.DryRun.Enabled {
+		retryQueue, err = notifier.NewRetryQueue(
+			filepath.Join(cfg.LogDir, "notify_retry_queue.json"),
+			time.Duration(cfg.NotifyRetry.IntervalSeconds)*time.Second,
+			cfg.NotifyRetry.MaxAttempts,
+			time.Duration(cfg.NotifyRetry.MaxAgeHours)*time.Hour,
+		)
+		if err != nil {
+			log.Fatalf("❌ 创建通知重投队列失败: %v", err)
+		}
+		log.Printf("✓ 通知重投队列已启用（间隔%ds，最多%d次）", cfg.NotifyRetry.IntervalSeconds, cfg.NotifyRetry.MaxAttempts)
+	}
+
 	// 创建通知器
 	var notif notifier.Notifier
 	if cfg.Notification.Enabled {
-		notif = createNotifier(&cfg.Notification)
+		// 试运行模式只创建打日志的通知器，不创建带凭证的真实通知客户端
+		if cfg.DryRun.Enabled {
+			notif = notifier.NewDryRunNotifier()
+		} else {
+			notif = createNotifier(&cfg.Notification, retryQueue, config.DefaultPortfolioID)
+		}
 		log.Printf("✓ 通知系统已初始化")
 	} else {
 		log.Printf("⏭️  通知系统未启用")
 	}
 
+	// Sentry错误上报（未配置DSN时为nil，上报调用直接忽略）
+	var errorReporter *notifier.SentryReporter
+	if cfg.Sentry.DSN != "" {
+		errorReporter, err = notifier.NewSentryReporter(cfg.Sentry.DSN, cfg.Sentry.Environment)
+		if err != nil {
+			log.Printf("⚠️  Sentry错误上报初始化失败: %v，将不上报错误", err)
+		} else {
+			log.Printf("✓ Sentry错误上报已启用")
+		}
+	}
+
+	// 补充配置的休市日期（内置节假日表逐年更新，未覆盖的年份需配置）
+	if err := stock.RegisterMarketHolidays(cfg.TradingTime.Holidays); err != nil {
+		log.Printf("⚠️  加载休市日期失败: %v", err)
+	} else if len(cfg.TradingTime.Holidays) > 0 {
+		log.Printf("✓ 已加载%d个配置的休市日期", len(cfg.TradingTime.Holidays))
+	}
+
 	// 创建交易时间检查器
 	tradingTimeConfig := stock.TradingTimeConfig{
 		EnableTradingTimeCheck: cfg.TradingTime.EnableCheck,
@@ -71,7 +151,7 @@ func main() {
 	} else if cfg.TradingTime.EnableCheck {
 		log.Printf("✓ 交易时间检查已启用")
 		log.Printf("  交易时段: %v", cfg.TradingTime.TradingHours)
-		status := tradingTimeChecker.GetTradingTimeStatus(time.Now())
+		status := tradingTimeChecker.GetTradingTimeStatus(tradingTimeChecker.Now())
 		log.Printf("  当前状态: 交易日=%v, 交易时段=%v",
 			status["is_trading_day"], status["is_trading_time"])
 	} else {
@@ -83,14 +163,44 @@ func main() {
 		log.Printf("⚠️  创建日志目录失败: %v", err)
 	}
 
+	// K线磁盘缓存（内存为L1，磁盘为L2），内存未命中时先查磁盘，重启后沿用上次运行保存的K线
+	if cfg.KlineDiskCache {
+		cacheDir := filepath.Join(cfg.LogDir, "kline_cache")
+		// 收盘后保存的K线到下次开盘前不会再变，期间直接使用磁盘缓存；交易时段内保存的只作为增量更新的基础
+		var validUntil func(time.Time) time.Time
+		if tradingTimeChecker != nil && cfg.TradingTime.EnableCheck {
+			validUntil = func(savedAt time.Time) time.Time {
+				if tradingTimeChecker.IsTradingTime(savedAt) {
+					return savedAt
+				}
+				return tradingTimeChecker.NextMarketOpen(savedAt)
+			}
+		}
+		if loaded, err := tdxClient.EnableDiskCache(cacheDir, validUntil); err != nil {
+			log.Printf("⚠️  启用K线磁盘缓存失败: %v", err)
+		} else {
+			log.Printf("✓ K线磁盘缓存已启用: %s（%d条可用缓存）", cacheDir, loaded)
+		}
+	}
+
 	fmt.Println()
 	fmt.Println("📊 监控股票列表:")
-	enabledStocks := []config.StockItem{}
-	for _, stockItem := range cfg.Stocks {
-		if stockItem.Enabled {
-			enabledStocks = append(enabledStocks, stockItem)
-			fmt.Printf("  • %s(%s) - 扫描间隔: %d分钟, 信心阈值: %d%%\n",
-				stockItem.Name, stockItem.Code, stockItem.ScanIntervalMinutes, stockItem.MinConfidence)
+	portfolios := cfg.GetPortfolios()
+	for _, portfolio := range portfolios {
+		if len(portfolios) > 1 {
+			fmt.Printf("  [%s] %s\n", portfolio.ID, portfolio.Name)
+		}
+		for _, stockItem := range portfolio.Stocks {
+			if !stockItem.Enabled {
+				continue
+			}
+			if len(stockItem.Cron) > 0 {
+				fmt.Printf("  • %s(%s) - 定时计划: %s, 信心阈值: %d%%\n",
+					stockItem.Name, stockItem.Code, strings.Join(stockItem.Cron, " | "), stockItem.MinConfidence)
+			} else {
+				fmt.Printf("  • %s(%s) - 扫描间隔: %d分钟, 信心阈值: %d%%\n",
+					stockItem.Name, stockItem.Code, stockItem.ScanIntervalMinutes, stockItem.MinConfidence)
+			}
 		}
 	}
 
@@ -107,53 +217,108 @@ func main() {
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println()
 
-	// 创建分析器管理器
-	// 使用配置文件中的分析历史记录数量限制（最小3，最大100，默认20）
-	maxHistorySize := cfg.AnalysisHistoryLimit
-	if maxHistorySize < 3 {
-		maxHistorySize = 3
-	} else if maxHistorySize > 100 {
-		maxHistorySize = 100
+	// 技术指标健康度权重（所有组合共享，可通过API运行时调整）
+	scoringWeights := stock.DefaultScoringWeights()
+	if cfg.ScoringWeights != nil {
+		scoringWeights = stock.ScoringWeights{MA: cfg.ScoringWeights.MA, RSI: cfg.ScoringWeights.RSI, MACD: cfg.ScoringWeights.MACD, Volume: cfg.ScoringWeights.Volume}
 	}
-	analyzerManager := &AnalyzerManager{
-		analyzers:           make(map[string]*stock.StockAnalyzer),
-		stopChans:           make(map[string]chan struct{}),
-		analysisHistory:     make(map[string][]*stock.AnalysisResult),
-		maxHistorySize:      maxHistorySize,      // 从配置文件读取，每个股票最多保存的分析记录数
-		analysisMode:        cfg.AnalysisMode,    // 分析模式：smart/concurrent/polling
-		maxConcurrent:       cfg.MaxConcurrentAnalysis, // 最大并发分析数
-		stockCount:          len(enabledStocks),  // 启用的股票数量
+	scoringModel := stock.NewScoringModel(scoringWeights)
+
+	// AI费用统计（所有组合共享，按各provider的单价估算）
+	var aiPrice stock.AIPrice
+	if price, ok := cfg.AIConfig.Pricing[cfg.AIConfig.Provider]; ok {
+		aiPrice = stock.AIPrice{Prompt: price.Prompt, Completion: price.Completion}
 	}
-	log.Printf("✓ 分析历史记录配置: 每个股票最多保存 %d 条记录", maxHistorySize)
+	costTracker := stock.NewAICostTracker(cfg.AIConfig.Provider, aiPrice)
 
-	// 为每只启用的股票创建分析器
-	for _, stockItem := range enabledStocks {
-		analysisConfig := &stock.AnalysisConfig{
-			StockCode:          stockItem.Code,
-			StockName:          stockItem.Name,
-			ScanInterval:       stockItem.GetScanInterval(),
-			EnableNotification: cfg.Notification.Enabled,
-			MinConfidence:      stockItem.MinConfidence,
-			
-			// 新增：持仓信息（如果填写了）
-			PositionQuantity: stockItem.PositionQuantity,
-			BuyPrice:         stockItem.BuyPrice,
-			BuyDate:          parseBuyDate(stockItem.BuyDate),
+	// 分析历史的全局内存上限（所有组合合计，未配置时只统计占用）
+	historyMemory := stock.NewHistoryMemory(int64(cfg.HistoryMemoryLimitMB) * 1024 * 1024)
+	if cfg.HistoryMemoryLimitMB > 0 {
+		log.Printf("✓ 分析历史内存上限: %dMB（接近上限时优先淘汰低信心HOLD、最旧的记录）", cfg.HistoryMemoryLimitMB)
+	}
+
+	// 为每个组合创建独立的分析器管理器（持仓、通知、分析历史互相隔离）
+	// AI并发信号量所有组合共享，max_concurrent_analysis是整个进程的AI并发上限
+	managers := make(map[string]*AnalyzerManager)
+	var defaultManager *AnalyzerManager
+	aiSemaphore := stock.NewPrioritySemaphore(cfg.MaxConcurrentAnalysis)
+	for _, portfolio := range portfolios {
+		manager := newAnalyzerManager(cfg, portfolio, tdxClient, mcpClient, newsClient, quoteVerifier, notif, retryQueue, tradingTimeChecker, scoringModel, errorReporter, costTracker)
+		manager.semaphore = aiSemaphore
+		manager.historyMemory = historyMemory
+		historyMemory.Register(portfolio.ID, manager)
+		managers[portfolio.ID] = manager
+		if defaultManager == nil {
+			defaultManager = manager
 		}
+	}
 
-		analyzer := stock.NewStockAnalyzer(tdxClient, mcpClient, notif, analysisConfig, tradingTimeChecker)
-		analyzerManager.AddAnalyzer(stockItem.Code, analyzer)
+	// 开盘前暖机（预拉所有股票的K线到缓存）
+	if cfg.Warmup.Enabled {
+		if tradingTimeChecker == nil {
+			log.Printf("⚠️  交易时间检查器不可用，暖机未启用")
+		} else {
+			var codes []string
+			seen := make(map[string]bool)
+			for _, portfolio := range portfolios {
+				for _, stockItem := range portfolio.Stocks {
+					if !stockItem.Enabled {
+						continue
+					}
+					// 虚拟组合预拉各成分股的K线
+					memberCodes := []string{stockItem.Code}
+					if len(stockItem.Basket) > 0 {
+						memberCodes = memberCodes[:0]
+						for _, member := range stockItem.Basket {
+							memberCodes = append(memberCodes, member.Code)
+						}
+					}
+					for _, code := range memberCodes {
+						if !seen[code] {
+							seen[code] = true
+							codes = append(codes, code)
+						}
+					}
+				}
+			}
+			warmer := &stock.KlineWarmer{
+				TDXClient:          tdxClient,
+				TradingTimeChecker: tradingTimeChecker,
+				Codes:              codes,
+				DayKlineCount:      cfg.DayKlineCount,
+				MinuteKlineCount:   cfg.MinuteKlineCount,
+				BeforeOpen:         time.Duration(cfg.Warmup.MinutesBeforeOpen) * time.Minute,
+				ValidAfterOpen:     time.Duration(cfg.Warmup.ValidMinutes) * time.Minute,
+			}
+			warmer.Start()
+			log.Printf("✓ 开盘前暖机已启用: 开盘前%d分钟预拉 %d 只股票的K线", cfg.Warmup.MinutesBeforeOpen, len(codes))
+		}
+	}
+
+	// 启动通知重投（所有渠道已注册，可补发上次进程未发送成功的通知）
+	if retryQueue != nil {
+		retryQueue.Start()
 	}
 
 	// 创建并启动API服务器
-	apiServer := api.NewStockAPIServer(analyzerManager, cfg.APIServerPort, cfg.APIToken)
-	
+	apiServer := api.NewStockAPIServer(defaultManager, cfg.APIServerPort, cfg.APIToken, cfg.CORSAllowOrigins)
+	for _, portfolio := range portfolios {
+		apiServer.AddPortfolio(portfolio.ID, managers[portfolio.ID])
+	}
+
 	// 设置重启函数（优雅重启）
+	apiServer.SetEffectiveConfig(cfg)
+	apiServer.SetScoringModel(scoringModel)
+	apiServer.SetErrorReporter(errorReporter)
+	apiServer.SetCostTracker(costTracker)
+	apiServer.SetHistoryMemory(historyMemory)
 	apiServer.SetRestartFunc(func() {
 		log.Printf("🔄 收到重启指令，开始优雅关闭...")
-		analyzerManager.StopAll()
+		for _, manager := range managers {
+			manager.StopAll()
+		}
 		log.Printf("✅ 所有分析器已停止")
-		
+
 		// 尝试通过管理脚本自动重启
 		// 获取当前工作目录或可执行文件所在目录
 		workDir := "."
@@ -165,18 +330,18 @@ func main() {
 				workDir = exeDir
 			}
 		}
-		
+
 		// 尝试多个可能的脚本路径（相对路径优先）
 		scriptPaths := []string{
 			"./manage_backend.sh",
 			fmt.Sprintf("%s/manage_backend.sh", workDir),
 		}
-		
+
 		// 如果当前目录就是脚本目录，添加绝对路径
 		if cwd, err := os.Getwd(); err == nil {
 			scriptPaths = append(scriptPaths, fmt.Sprintf("%s/manage_backend.sh", cwd))
 		}
-		
+
 		scriptFound := false
 		for _, scriptPath := range scriptPaths {
 			if _, err := os.Stat(scriptPath); err == nil {
@@ -189,7 +354,7 @@ func main() {
 				cmd.Stdin = nil
 				cmd.Stdout = nil
 				cmd.Stderr = nil
-				
+
 				if err := cmd.Start(); err == nil {
 					log.Printf("✅ 已触发重启脚本，服务将在后台重启")
 					// 不等待命令完成，让脚本独立运行
@@ -203,16 +368,16 @@ func main() {
 				}
 			}
 		}
-		
+
 		if !scriptFound {
 			log.Printf("⚠️  未找到管理脚本，程序将退出")
 			log.Printf("💡 提示：请手动执行 './manage_backend.sh restart' 或使用 systemd/supervisor 管理，服务将自动重启")
 		}
-		
+
 		log.Printf("👋 程序退出")
 		os.Exit(0) // 退出程序，由脚本或外部进程管理器重启
 	})
-	
+
 	go func() {
 		if err := apiServer.Start(); err != nil {
 			log.Printf("❌ API服务器错误: %v", err)
@@ -228,15 +393,19 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// 启动所有分析器
-	analyzerManager.StartAll()
+	// 启动所有组合的分析器
+	for _, manager := range managers {
+		manager.StartAll()
+	}
 
 	// 等待退出信号
 	<-sigChan
 	fmt.Println()
 	fmt.Println()
 	log.Println("📛 收到退出信号，正在停止所有分析器...")
-	analyzerManager.StopAll()
+	for _, manager := range managers {
+		manager.StopAll()
+	}
 
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI股票分析系统！")
@@ -253,23 +422,61 @@ func createMCPClient(aiConfig *config.AIConfig) (*mcp.Client, error) {
 		client.SetQwenAPIKey(aiConfig.QwenKey, "")
 	case "custom":
 		client.SetCustomAPI(aiConfig.CustomAPIURL, aiConfig.CustomAPIKey, aiConfig.CustomModelName)
+		client.ResponsePath = aiConfig.CustomResponsePath
 	default:
 		return nil, fmt.Errorf("不支持的AI提供商: %s", aiConfig.Provider)
 	}
+	client.Stream = aiConfig.Stream
 
 	return client, nil
 }
 
+// brokerFeeRates 将券商费率配置转换为交易费率（custom时使用自定义费率，否则使用内置模板）
+func brokerFeeRates(brokerFee *config.BrokerFeeConfig) stock.FeeRates {
+	if brokerFee.Template == stock.FeeTemplateCustom {
+		return stock.FeeRates{
+			CommissionRate:  brokerFee.CommissionRate,
+			MinCommission:   brokerFee.MinCommission,
+			StampDutyRate:   brokerFee.StampDutyRate,
+			TransferFeeRate: brokerFee.TransferFeeRate,
+		}
+	}
+	if rates, ok := stock.FeeTemplates[brokerFee.Template]; ok {
+		return rates
+	}
+	return stock.DefaultFeeRates()
+}
+
 // createNotifier 创建通知器
-func createNotifier(notifConfig *config.NotificationConfig) notifier.Notifier {
+// retryQueue 不为nil时每个渠道发送失败都会入队重投，channelPrefix 用于区分不同组合的同名渠道
+func createNotifier(notifConfig *config.NotificationConfig, retryQueue *notifier.RetryQueue, channelPrefix string) notifier.Notifier {
 	var notifiers []notifier.Notifier
+	// 启用合并通知时聊天类渠道（钉钉/飞书/企业微信）先收集，最后整体包一层合并通知器；表格、短信、消息队列等仍逐条推送
+	var chatNotifiers []notifier.Notifier
+	wrap := func(channel string, n notifier.Notifier) notifier.Notifier {
+		if retryQueue != nil {
+			n = retryQueue.Wrap(channelPrefix+"/"+channel, n)
+		}
+		return n
+	}
+	add := func(channel string, n notifier.Notifier) {
+		notifiers = append(notifiers, wrap(channel, n))
+	}
+	addChat := func(channel string, n notifier.Notifier) {
+		if notifConfig.Merge.Enabled {
+			chatNotifiers = append(chatNotifiers, wrap(channel, n))
+			return
+		}
+		add(channel, n)
+	}
 
 	if notifConfig.DingTalk.Enabled {
 		ding := notifier.NewDingTalkNotifier(
 			notifConfig.DingTalk.WebhookURL,
 			notifConfig.DingTalk.Secret,
 		)
-		notifiers = append(notifiers, ding)
+		ding.MessageType = notifConfig.DingTalk.MessageType
+		addChat("dingtalk", ding)
 		log.Printf("  ✓ 钉钉通知已启用")
 	}
 
@@ -278,10 +485,86 @@ func createNotifier(notifConfig *config.NotificationConfig) notifier.Notifier {
 			notifConfig.Feishu.WebhookURL,
 			notifConfig.Feishu.Secret,
 		)
-		notifiers = append(notifiers, feishu)
+		addChat("feishu", feishu)
 		log.Printf("  ✓ 飞书通知已启用")
 	}
 
+	if notifConfig.WeCom.Enabled {
+		wecom := notifier.NewWeComAppNotifier(
+			notifConfig.WeCom.CorpID,
+			notifConfig.WeCom.CorpSecret,
+			notifConfig.WeCom.AgentID,
+			notifConfig.WeCom.ToUsers,
+			notifConfig.WeCom.ToParties,
+			notifConfig.WeCom.ToTags,
+		)
+		addChat("wecom", wecom)
+		log.Printf("  ✓ 企业微信应用消息已启用（AgentId %d）", notifConfig.WeCom.AgentID)
+	}
+
+	if notifConfig.MQ.Enabled {
+		nats := notifier.NewNATSNotifier(
+			notifConfig.MQ.URL,
+			notifConfig.MQ.SubjectPrefix,
+		)
+		nats.TLS = notifConfig.MQ.TLS
+		nats.TLSSkipVerify = notifConfig.MQ.TLSSkipVerify
+		add("mq", nats)
+		log.Printf("  ✓ 消息队列推送已启用 (NATS: %s)", notifConfig.MQ.URL)
+	}
+
+	// 仅信号翻转时回调的Webhook由AnalyzerManager单独调用，不加入常规通知链
+	if notifConfig.Webhook.Enabled && !notifConfig.Webhook.OnlySignalChange {
+		webhook := notifier.NewGenericWebhookNotifier(
+			notifConfig.Webhook.URL,
+			notifConfig.Webhook.Headers,
+			false,
+		)
+		add("webhook", webhook)
+		log.Printf("  ✓ Webhook通知已启用: %s", notifConfig.Webhook.URL)
+	}
+
+	if notifConfig.SMS.Enabled {
+		sms := notifier.NewSMSNotifier(
+			notifConfig.SMS.Provider,
+			notifConfig.SMS.AccessKeyID,
+			notifConfig.SMS.AccessKeySecret,
+			notifConfig.SMS.SignName,
+			notifConfig.SMS.TemplateCode,
+			notifConfig.SMS.PhoneNumbers,
+		)
+		sms.SDKAppID = notifConfig.SMS.SDKAppID
+		sms.Region = notifConfig.SMS.Region
+		add("sms", sms)
+		log.Printf("  ✓ 短信通知已启用（%s，%d个号码，仅紧急信号）", notifConfig.SMS.Provider, len(notifConfig.SMS.PhoneNumbers))
+	}
+
+	if notifConfig.Table.Enabled {
+		table := notifier.NewTableNotifier(
+			notifConfig.Table.Provider,
+			notifConfig.Table.AppID,
+			notifConfig.Table.AppSecret,
+			notifConfig.Table.AppToken,
+			notifConfig.Table.TableID,
+		)
+		table.OperatorID = notifConfig.Table.OperatorID
+		add("table", table)
+		log.Printf("  ✓ 表格记录已启用（%s，表格 %s）", notifConfig.Table.Provider, notifConfig.Table.TableID)
+	}
+
+	if len(chatNotifiers) > 0 {
+		window := time.Duration(notifConfig.Merge.WindowSeconds) * time.Second
+		if window <= 0 {
+			window = 60 * time.Second
+		}
+		var chat notifier.Notifier = notifier.NewMultiNotifier(chatNotifiers...)
+		if len(chatNotifiers) == 1 {
+			chat = chatNotifiers[0]
+		}
+		notifiers = append(notifiers, notifier.NewMergingNotifier(chat, window))
+		log.Printf("  ✓ 合并通知已启用（窗口 %v）", window)
+	}
+
 	if len(notifiers) == 0 {
 		return nil
 	}
@@ -293,6 +576,330 @@ func createNotifier(notifConfig *config.NotificationConfig) notifier.Notifier {
 	return notifier.NewMultiNotifier(notifiers...)
 }
 
+// newAnalyzerManager 为一个组合创建分析器管理器及其股票分析器
+// 组合配置了独立通知时创建专属通知器，否则共用顶层通知器
+func newAnalyzerManager(cfg *config.StockConfig, portfolio config.PortfolioConfig, tdxClient *stock.TDXClient, mcpClient *mcp.Client, newsClient *stock.NewsClient, quoteVerifier *stock.QuoteVerifier, defaultNotif notifier.Notifier, retryQueue *notifier.RetryQueue, tradingTimeChecker *stock.TradingTimeChecker, scoringModel *stock.ScoringModel, errorReporter *notifier.SentryReporter, costTracker *stock.AICostTracker) *AnalyzerManager {
+	notifConfig := &cfg.Notification
+	notif := defaultNotif
+	if portfolio.Notification != nil {
+		notifConfig = portfolio.Notification
+		notif = nil
+		if notifConfig.Enabled {
+			log.Printf("✓ 组合 [%s] 使用独立通知配置", portfolio.ID)
+			if cfg.DryRun.Enabled {
+				notif = notifier.NewDryRunNotifier()
+			} else {
+				notif = createNotifier(notifConfig, retryQueue, portfolio.ID)
+			}
+		}
+	}
+
+	brokerFee := &cfg.BrokerFee
+	if portfolio.BrokerFee != nil {
+		brokerFee = portfolio.BrokerFee
+	}
+	feeRates := brokerFeeRates(brokerFee)
+
+	// 模拟盘（每个组合一个账户，默认组合为 paper_account.json，其他组合按组合ID区分）
+	var paperTrader *stock.PaperTrader
+	var autoTrade *stock.AutoTrade
+	if cfg.PaperTrading.Enabled {
+		accountFile := filepath.Join(cfg.LogDir, "paper_account.json")
+		if portfolio.ID != config.DefaultPortfolioID {
+			accountFile = filepath.Join(cfg.LogDir, fmt.Sprintf("paper_account_%s.json", portfolio.ID))
+		}
+		trader, err := stock.NewPaperTrader(accountFile, cfg.PaperTrading.InitialCash, feeRates)
+		if err != nil {
+			log.Printf("⚠️  [%s] 加载模拟盘账户失败: %v，模拟盘未启用", portfolio.ID, err)
+		} else {
+			paperTrader = trader
+			autoTrade = &stock.AutoTrade{Trader: trader, OrderAmount: cfg.PaperTrading.OrderAmount}
+			log.Printf("✓ [%s] 模拟盘已启用: %s（可用资金%.2f元，每次买入%.2f元）", portfolio.ID, accountFile, trader.Snapshot(0).Cash, cfg.PaperTrading.OrderAmount)
+		}
+	}
+
+	// 组合中有股票配置了持仓或成交记录时按持仓跟踪，SELL信号与持仓状态联动；纯监控的组合不变（模拟盘的虚拟持仓不算）
+	trackPositions := false
+	for _, stockItem := range portfolio.Stocks {
+		if stockItem.Enabled && (stockItem.IsPositionMode() || stockItem.TradesFile != "") {
+			trackPositions = true
+		}
+	}
+
+	var adaptiveConfidence *stock.AdaptiveConfidence
+	if cfg.AdaptiveConfidence.Enabled {
+		adaptiveConfidence = &stock.AdaptiveConfidence{
+			BaseVolatility:   cfg.AdaptiveConfidence.BaseVolatility,
+			PointsPerPercent: cfg.AdaptiveConfidence.PointsPerPercent,
+			MaxAdjust:        cfg.AdaptiveConfidence.MaxAdjust,
+		}
+	}
+
+	var accuracyWeighting *stock.AccuracyWeighting
+	if cfg.AccuracyWeighting.Enabled {
+		// 命中统计持久化（默认组合为 accuracy.json，其他组合按组合ID区分）
+		accuracyFile := filepath.Join(cfg.LogDir, "accuracy.json")
+		if portfolio.ID != config.DefaultPortfolioID {
+			accuracyFile = filepath.Join(cfg.LogDir, fmt.Sprintf("accuracy_%s.json", portfolio.ID))
+		}
+		accuracyStore, err := stock.NewAccuracyStore(accuracyFile)
+		if err != nil {
+			log.Printf("⚠️  [%s] 加载命中统计失败: %v", portfolio.ID, err)
+		}
+		accuracyWeighting = &stock.AccuracyWeighting{
+			HorizonDays: cfg.AccuracyWeighting.HorizonDays,
+			Window:      cfg.AccuracyWeighting.Window,
+			MinSamples:  cfg.AccuracyWeighting.MinSamples,
+			MaxAdjust:   cfg.AccuracyWeighting.MaxAdjust,
+			Store:       accuracyStore,
+		}
+	}
+
+	var complianceFilter *notifier.ComplianceFilter
+	if notifConfig.Compliance.Enabled {
+		complianceFilter = notifier.NewComplianceFilter(notifConfig.Compliance.Words, !notifConfig.Compliance.DisableBuiltin, notifConfig.Compliance.Action)
+		log.Printf("✓ [%s] 合规过滤已启用: %d个敏感词，处理方式 %s", portfolio.ID, len(complianceFilter.Words), complianceFilter.Action)
+	}
+
+	var translator *stock.ReasoningTranslator
+	if notifConfig.Translation.Enabled && !cfg.DryRun.RuleBasedAI {
+		targetLanguage := notifConfig.Translation.TargetLanguage
+		if targetLanguage == "" {
+			targetLanguage = "中文"
+		}
+		translator = &stock.ReasoningTranslator{Client: mcpClient, TargetLanguage: targetLanguage}
+		log.Printf("✓ [%s] 分析理由翻译已启用: 目标语言 %s", portfolio.ID, targetLanguage)
+	}
+
+	// 通知卡片按钮链接的API地址（非默认组合带组合前缀）
+	apiBaseURL := ""
+	if cfg.PublicURL != "" {
+		apiBaseURL = cfg.PublicURL + "/api"
+		if portfolio.ID != config.DefaultPortfolioID {
+			apiBaseURL += "/portfolio/" + portfolio.ID
+		}
+	}
+
+	enabledStocks := []config.StockItem{}
+	for _, stockItem := range portfolio.Stocks {
+		if stockItem.Enabled {
+			enabledStocks = append(enabledStocks, stockItem)
+		}
+	}
+
+	// 使用配置文件中的分析历史记录数量限制（最小3，最大100，配置内存上限时最大1000，默认20）
+	maxHistorySize := cfg.AnalysisHistoryLimit
+	if maxHistorySize < 3 {
+		maxHistorySize = 3
+	} else if limit := config.MaxAnalysisHistoryLimit(cfg.HistoryMemoryLimitMB); maxHistorySize > limit {
+		maxHistorySize = limit
+	}
+	analyzerManager := &AnalyzerManager{
+		portfolioID:     portfolio.ID,
+		portfolioName:   portfolio.Name,
+		analyzers:       make(map[string]*stock.StockAnalyzer),
+		stopChans:       make(map[string]chan struct{}),
+		intervalChans:   make(map[string]chan struct{}),
+		paused:          make(map[string]bool),
+		paperTrader:     paperTrader,
+		tradesFiles:     make(map[string]string),
+		analysisHistory: make(map[string][]*stock.AnalysisResult),
+		maxHistorySize:  maxHistorySize,            // 从配置文件读取，每个股票最多保存的分析记录数
+		analysisMode:    cfg.AnalysisMode,          // 分析模式：smart/concurrent/polling
+		maxConcurrent:   cfg.MaxConcurrentAnalysis, // 最大并发分析数
+		stockCount:      len(enabledStocks),        // 启用的股票数量
+		errorReporter:   errorReporter,
+	}
+	if notifConfig.Enabled && notifConfig.Webhook.Enabled && notifConfig.Webhook.OnlySignalChange && !cfg.DryRun.Enabled {
+		analyzerManager.signalChangeWebhook = notifier.NewGenericWebhookNotifier(
+			notifConfig.Webhook.URL,
+			notifConfig.Webhook.Headers,
+			true,
+		)
+		log.Printf("✓ [%s] 信号翻转Webhook已启用: %s", portfolio.ID, notifConfig.Webhook.URL)
+	}
+	log.Printf("✓ [%s] 分析历史记录配置: 每个股票最多保存 %d 条记录", portfolio.ID, maxHistorySize)
+	if cfg.ArchiveResults {
+		// 默认组合直接归档到 archive/ 下，其他组合按组合ID分目录，避免同一股票的归档混在一起
+		archiveDir := filepath.Join(cfg.LogDir, "archive")
+		if portfolio.ID != config.DefaultPortfolioID {
+			archiveDir = filepath.Join(archiveDir, portfolio.ID)
+		}
+		analyzerManager.archiver = stock.NewResultArchiver(archiveDir)
+		log.Printf("✓ [%s] 分析结果JSON归档已启用: %s", portfolio.ID, archiveDir)
+	}
+	if tradingTimeChecker != nil {
+		analyzerManager.cronLocation = tradingTimeChecker.Location
+	}
+	if notifConfig.SessionSummary && notif != nil && tradingTimeChecker != nil {
+		analyzerManager.tradingTimeChecker = tradingTimeChecker
+		analyzerManager.summaryNotifier = notif
+		analyzerManager.summaryStop = make(chan struct{})
+	}
+	analyzerManager.notifier = notif
+	if cfg.FailureBackoff.Enabled {
+		analyzerManager.backoffThreshold = cfg.FailureBackoff.Threshold
+		analyzerManager.backoffMaxInterval = time.Duration(cfg.FailureBackoff.MaxIntervalMinutes) * time.Minute
+		analyzerManager.failureCounts = make(map[string]int)
+		analyzerManager.backoffAlerted = make(map[string]bool)
+		analyzerManager.alertNotifier = notif
+	}
+	if cfg.AdaptiveInterval.Enabled {
+		analyzerManager.adaptiveInterval = &stock.AdaptiveInterval{
+			Min: time.Duration(cfg.AdaptiveInterval.MinIntervalMinutes) * time.Minute,
+			Max: time.Duration(cfg.AdaptiveInterval.MaxIntervalMinutes) * time.Minute,
+		}
+		analyzerManager.adaptiveIntervals = make(map[string]adaptiveIntervalState)
+	}
+	if cfg.SlowThreshold > 0 {
+		analyzerManager.slowThreshold = time.Duration(cfg.SlowThreshold) * time.Second
+		analyzerManager.slowAlertedAt = make(map[string]time.Time)
+		analyzerManager.alertNotifier = notif
+	}
+
+	// 为每只启用的股票创建分析器
+	for _, stockItem := range enabledStocks {
+		analysisConfig := &stock.AnalysisConfig{
+			StockCode:           stockItem.Code,
+			StockName:           stockItem.Name,
+			ScanInterval:        stockItem.GetScanInterval(),
+			CronSchedules:       stockItem.Cron,
+			EnableNotification:  notifConfig.Enabled,
+			MinConfidence:       stockItem.MinConfidence,
+			AdaptiveConfidence:  adaptiveConfidence,
+			AccuracyWeighting:   accuracyWeighting,
+			MuteLowPriority:     notifConfig.MuteLowPriority,
+			NotifyCooldown:      time.Duration(notifConfig.CooldownMinutes) * time.Minute,
+			MinRiskReward:       notifConfig.MinRiskReward,
+			ConsensusBoost:      notifConfig.ConsensusBoost,
+			ThresholdBasis:      notifConfig.ThresholdBasis,
+			ComplianceFilter:    complianceFilter,
+			Translator:          translator,
+			CostTracker:         costTracker,
+			Scoring:             scoringModel,
+			ErrorReporter:       errorReporter,
+			EnableMACrossAlert:  notifConfig.MACrossAlert,
+			AlertRules:          alertRulesFor(cfg.AlertRules, stockItem.Code),
+			ChartProvider:       notifConfig.ChartProvider,
+			APIBaseURL:          apiBaseURL,
+			AnalyzeLinkSecret:   cfg.APIToken,
+			RequireConfirmation: stockItem.RequireConfirmation,
+			KlinePeriods:        stockItem.KlinePeriods,
+			PlainPrompt:         cfg.AIConfig.PlainPrompt,
+			IndicatorsInPrompt:  cfg.AIConfig.IndicatorsInPrompt,
+			MaxReasoningChars:   cfg.AIConfig.MaxReasoningChars,
+			SkipSuspensionGaps:  cfg.SkipSuspensionGaps,
+			MinKlineDays:        cfg.MinKlineDays,
+			DayKlineCount:       cfg.DayKlineCount,
+			MinuteKlineCount:    cfg.MinuteKlineCount,
+			BenchmarkIndex:      cfg.BenchmarkIndex,
+			News:                newsClient,
+			QuoteVerifier:       quoteVerifier,
+			RuleBasedAI:         cfg.DryRun.Enabled && cfg.DryRun.RuleBasedAI,
+			FloatShares:         stockItem.FloatSharesWan * 10000,
+			ExRightsDates:       stockItem.ExRightsDates,
+			QuietHours:          stockItem.NotificationQuietHours,
+			QuietDays:           stockItem.NotificationQuietDays,
+
+			// 新增：持仓信息（如果填写了）
+			TrackPositions:   trackPositions,
+			PositionQuantity: stockItem.PositionQuantity,
+			BuyPrice:         stockItem.BuyPrice,
+			BuyDate:          parseBuyDate(stockItem.BuyDate),
+
+			FeeRates:    feeRates,
+			FeeTemplate: brokerFee.Template,
+		}
+
+		// 虚拟组合成分股，锚定的指数基准持久化到 basket_base/<组合ID>.json（其他组合按组合ID分目录）
+		for _, member := range stockItem.Basket {
+			analysisConfig.Basket = append(analysisConfig.Basket, stock.BasketMember{
+				Code:   member.Code,
+				Name:   member.Name,
+				Weight: member.Weight,
+			})
+		}
+		if len(stockItem.Basket) > 0 {
+			analysisConfig.BasketBaseDate = stockItem.BasketBaseDate
+			baseDir := filepath.Join(cfg.LogDir, "basket_base")
+			if portfolio.ID != config.DefaultPortfolioID {
+				baseDir = filepath.Join(baseDir, portfolio.ID)
+			}
+			analysisConfig.BasketBaseFile = filepath.Join(baseDir, stockItem.Code+".json")
+		}
+
+		// 导入成交记录时，以成交记录计算的净持仓和成本为准
+		if stockItem.TradesFile != "" {
+			applyTradeRecords(analysisConfig, stockItem.TradesFile)
+			analyzerManager.tradesFiles[stockItem.Code] = stockItem.TradesFile
+		}
+
+		analyzer := stock.NewStockAnalyzer(tdxClient, mcpClient, notif, analysisConfig, tradingTimeChecker)
+		if paperTrader != nil {
+			// 按信号自动模拟下单，虚拟持仓由模拟盘维护，不覆盖配置中的真实持仓
+			analysisConfig.AutoTrade = autoTrade
+		}
+		analyzerManager.AddAnalyzer(stockItem.Code, analyzer)
+	}
+
+	// 恢复上次运行时设置的暂停/静音状态（默认组合为 stock_state.json，其他组合按组合ID区分）
+	stateFile := filepath.Join(cfg.LogDir, "stock_state.json")
+	if portfolio.ID != config.DefaultPortfolioID {
+		stateFile = filepath.Join(cfg.LogDir, fmt.Sprintf("stock_state_%s.json", portfolio.ID))
+	}
+	stateStore, err := stock.NewStockStateStore(stateFile)
+	if err != nil {
+		log.Printf("⚠️  [%s] 加载股票启停状态失败: %v", portfolio.ID, err)
+	}
+	analyzerManager.stateStore = stateStore
+
+	// 调试追踪文件目录（默认组合为 debug/<代码>/，其他组合按组合ID分目录）
+	analyzerManager.debugDir = filepath.Join(cfg.LogDir, "debug")
+	if portfolio.ID != config.DefaultPortfolioID {
+		analyzerManager.debugDir = filepath.Join(analyzerManager.debugDir, portfolio.ID)
+	}
+	for code, analyzer := range analyzerManager.analyzers {
+		state := stateStore.Get(code)
+		if state.Paused {
+			analyzerManager.paused[code] = true
+			log.Printf("⏸️  [%s] 股票 %s 保持暂停状态", portfolio.ID, code)
+		}
+		if state.Muted {
+			analyzer.SetMuted(true)
+			log.Printf("🔕 [%s] 股票 %s 保持静音状态", portfolio.ID, code)
+		}
+	}
+
+	return analyzerManager
+}
+
+// alertRulesFor 筛选适用于指定股票的指标预警规则（规则未限定股票时对所有股票生效）
+func alertRulesFor(rules []config.AlertRuleConfig, code string) []stock.AlertRule {
+	var result []stock.AlertRule
+	for _, rule := range rules {
+		applies := len(rule.Stocks) == 0
+		for _, ruleCode := range rule.Stocks {
+			if ruleCode == code {
+				applies = true
+				break
+			}
+		}
+		if !applies {
+			continue
+		}
+		alertRule := stock.AlertRule{Name: rule.Name, Logic: strings.ToLower(rule.Logic)}
+		for _, condition := range rule.Conditions {
+			alertRule.Conditions = append(alertRule.Conditions, stock.AlertCondition{
+				Indicator: condition.Indicator,
+				Operator:  condition.Operator,
+				Value:     condition.Value,
+			})
+		}
+		result = append(result, alertRule)
+	}
+	return result
+}
+
 // parseBuyDate 解析购买日期字符串为time.Time
 func parseBuyDate(dateStr string) time.Time {
 	if dateStr == "" {
@@ -306,17 +913,172 @@ func parseBuyDate(dateStr string) time.Time {
 	return t
 }
 
-// AnalyzerManager 分析器管理器
+// applyTradeRecords 读取成交记录CSV，用计算出的净持仓、移动加权成本和已实现盈亏覆盖手填的持仓信息
+// 读取失败时保留手填配置
+func applyTradeRecords(analysisConfig *stock.AnalysisConfig, tradesFile string) {
+	records, err := stock.LoadTradeRecords(tradesFile)
+	if err != nil {
+		log.Printf("⚠️  [%s] 读取成交记录失败: %v，将使用手填的持仓信息", analysisConfig.StockName, err)
+		return
+	}
+	summary, err := stock.CalculateTradeSummary(records)
+	if err != nil {
+		log.Printf("⚠️  [%s] 计算成交记录失败: %v，将使用手填的持仓信息", analysisConfig.StockName, err)
+		return
+	}
+
+	analysisConfig.PositionQuantity = summary.Quantity
+	analysisConfig.BuyPrice = summary.AvgCost
+	analysisConfig.BuyDate = summary.FirstBuyDate
+	analysisConfig.RealizedProfitLoss = summary.RealizedProfitLoss
+	log.Printf("✓ [%s] 已导入%d笔成交记录: 净持仓%d股, 移动加权成本%.3f元, 已实现盈亏%.2f元, 累计费用%.2f元",
+		analysisConfig.StockName, summary.TradeCount, summary.Quantity, summary.AvgCost,
+		summary.RealizedProfitLoss, summary.TotalFees)
+}
+
+// AnalyzerManager 分析器管理器（每个组合一个，互相隔离）
 type AnalyzerManager struct {
-	analyzers        map[string]*stock.StockAnalyzer
-	stopChans        map[string]chan struct{}
-	analysisHistory  map[string][]*stock.AnalysisResult // 存储最近的分析结果（每个股票代码对应一个结果列表）
-	maxHistorySize   int                                  // 每个股票最多保存的分析记录数
-	analysisMode     string                               // 分析模式：smart/concurrent/polling
-	maxConcurrent    int                                  // 最大并发分析数
-	stockCount       int                                  // 启用的股票数量
-	mutex            sync.RWMutex
-	semaphore        chan struct{}                        // 并发控制信号量（用于限制并发数）
+	portfolioID     string // 所属组合ID
+	portfolioName   string // 所属组合名称
+	analyzers       map[string]*stock.StockAnalyzer
+	stopChans       map[string]chan struct{}
+	intervalChans   map[string]chan struct{}           // 扫描间隔调整通知（重建该股票的计时器）
+	paused          map[string]bool                    // 已暂停定时分析的股票
+	stateStore      *stock.StockStateStore             // 暂停/静音状态的持久化存储（重启后恢复）
+	paperTrader     *stock.PaperTrader                 // 模拟盘账户（可选）
+	tradesFiles     map[string]string                  // 股票代码 -> 成交记录文件（持仓按成交记录计算，不接受导入）
+	analysisHistory map[string][]*stock.AnalysisResult // 存储最近的分析结果（每个股票代码对应一个结果列表）
+	maxHistorySize  int                                // 每个股票最多保存的分析记录数
+	analysisMode    string                             // 分析模式：smart/concurrent/polling
+	maxConcurrent   int                                // 最大并发分析数
+	stockCount      int                                // 启用的股票数量
+	mutex           sync.RWMutex
+	semaphore       *stock.PrioritySemaphore // AI并发信号量（所有组合共享，按手动 > 信号翻转复查 > 常规定时的优先级出队）
+	actualMode      string                   // 实际生效的分析模式（StartAll时确定）
+
+	// 运行时统计（原子操作）
+	waitingCount   int64 // 正在排队等待信号量的分析数
+	runningCount   int64 // 正在执行的分析数
+	totalAnalysis  int64 // 累计执行的分析次数
+	failedAnalysis int64 // 累计失败（含非交易时段跳过）的分析次数
+	slowAnalysis   int64 // 累计超过slow_threshold的慢分析次数
+
+	// 慢分析告警（slowThreshold为0表示未启用）
+	slowThreshold time.Duration // 单次分析耗时告警阈值（不含排队等待）
+	slowMutex     sync.Mutex
+	slowAlertedAt map[string]time.Time // 股票代码 -> 上次推送慢分析告警的时间
+
+	// 连续失败降频退避（threshold为0表示未启用）
+	backoffThreshold   int           // 连续失败多少次后开始降频
+	backoffMaxInterval time.Duration // 扫描间隔上限
+	backoffMutex       sync.Mutex
+	failureCounts      map[string]int    // 股票代码 -> 连续失败次数
+	backoffAlerted     map[string]bool   // 股票代码 -> 已发送达到上限的告警
+	alertNotifier      notifier.Notifier // 发送退避告警、慢分析告警的通知渠道（可选）
+	notifier           notifier.Notifier // 组合的通知器（停止时推送合并窗口内尚未发出的信号，可为nil）
+
+	// 扫描间隔按市场活跃度自适应（nil表示未启用）
+	adaptiveInterval  *stock.AdaptiveInterval
+	adaptiveMutex     sync.Mutex
+	adaptiveIntervals map[string]adaptiveIntervalState // 股票代码 -> 最近一次计算的活跃度和间隔
+
+	errorReporter *notifier.SentryReporter // Sentry错误上报（可选，nil时不上报）
+	historyMemory *stock.HistoryMemory     // 分析历史的全局内存上限（所有组合共享，nil时只按条数保留）
+
+	// 批量分析批次（POST /api/analyze/all）
+	batches        map[string]*AnalysisBatch // 批次ID -> 批次信息（只保留最近的批次）
+	batchOrder     []string                  // 批次创建顺序，用于淘汰旧批次
+	runningBatchID string                    // 正在运行的批次ID（为空表示没有运行中的批次）
+
+	signalChangeWebhook *notifier.GenericWebhookNotifier // 仅信号翻转时回调的Webhook（可选）
+	archiver            *stock.ResultArchiver            // 分析结果JSON文件归档器（可选）
+	debugDir            string                           // 调试追踪文件的根目录（按股票代码分子目录）
+
+	// 定时分析计划（配置了cron的股票由cron调度器触发，不参与间隔扫描）
+	cron         *cron.Cron
+	cronLocation *time.Location // cron表达式使用的时区（与交易时间配置一致，为空时使用本地时区）
+
+	// 交易时段汇总（每个交易时段结束时推送时段内的信号汇总，可选）
+	tradingTimeChecker *stock.TradingTimeChecker
+	summaryNotifier    notifier.Notifier
+	summaryStop        chan struct{}
+}
+
+// sessionSummaryDelay 时段结束后延迟多久推送汇总（等待收盘前最后一轮分析完成）
+const sessionSummaryDelay = time.Minute
+
+// maxBatchRecords 最多保留的批量分析批次记录数
+const maxBatchRecords = 10
+
+// AnalysisBatch 批量分析批次
+type AnalysisBatch struct {
+	ID         string            `json:"id"`
+	Status     string            `json:"status"` // running/completed
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Total      int               `json:"total"`
+	Completed  int               `json:"completed"`
+	Succeeded  int               `json:"succeeded"`
+	Failed     int               `json:"failed"`
+	Skipped    int               `json:"skipped"` // 已暂停而跳过的股票数
+	Results    map[string]string `json:"results"` // 股票代码 -> pending/running/success/skipped/失败原因
+	mutex      sync.Mutex
+}
+
+// markRunning 标记某只股票开始分析
+func (b *AnalysisBatch) markRunning(code string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.Results[code] = "running"
+}
+
+// markDone 记录某只股票的分析结果
+func (b *AnalysisBatch) markDone(code string, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.Completed++
+	if errors.Is(err, ErrStockPaused) {
+		b.Skipped++
+		b.Results[code] = "skipped: paused"
+	} else if err != nil {
+		b.Failed++
+		b.Results[code] = fmt.Sprintf("failed: %v", err)
+	} else {
+		b.Succeeded++
+		b.Results[code] = "success"
+	}
+}
+
+// snapshot 获取批次状态快照（用于API返回，避免并发读写）
+func (b *AnalysisBatch) snapshot() map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	results := make(map[string]string, len(b.Results))
+	for code, status := range b.Results {
+		results[code] = status
+	}
+	progress := 0.0
+	if b.Total > 0 {
+		progress = float64(b.Completed) / float64(b.Total) * 100
+	}
+
+	snapshot := map[string]interface{}{
+		"id":         b.ID,
+		"status":     b.Status,
+		"started_at": b.StartedAt,
+		"total":      b.Total,
+		"completed":  b.Completed,
+		"succeeded":  b.Succeeded,
+		"failed":     b.Failed,
+		"skipped":    b.Skipped,
+		"progress":   fmt.Sprintf("%.0f%%", progress),
+		"results":    results,
+	}
+	if b.FinishedAt != nil {
+		snapshot["finished_at"] = *b.FinishedAt
+	}
+	return snapshot
 }
 
 // AddAnalyzer 添加分析器
@@ -325,63 +1087,555 @@ func (m *AnalyzerManager) AddAnalyzer(code string, analyzer *stock.StockAnalyzer
 	defer m.mutex.Unlock()
 	m.analyzers[code] = analyzer
 	m.stopChans[code] = make(chan struct{})
+	m.intervalChans[code] = make(chan struct{}, 1)
 }
 
-// GetAnalyzer 获取分析器
-func (m *AnalyzerManager) GetAnalyzer(code string) interface{} {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	return m.analyzers[code]
-}
+// SetScanInterval 运行时调整股票的扫描间隔，立即重建该股票的计时器（从现在起按新间隔计时）
+// 使用定时计划（cron）调度的股票不按间隔扫描，不支持调整
+func (m *AnalyzerManager) SetScanInterval(code string, interval time.Duration) (time.Duration, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-// TriggerAnalysis 手动触发分析
-func (m *AnalyzerManager) TriggerAnalysis(code string) (interface{}, error) {
-	m.mutex.RLock()
 	analyzer, exists := m.analyzers[code]
-	m.mutex.RUnlock()
-	
 	if !exists {
-		return nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+		return 0, fmt.Errorf("股票代码 %s 的分析器不存在", code)
 	}
-	
-	result, err := analyzer.Analyze()
-	if err != nil {
-		return nil, err
+	if len(analyzer.AnalysisConfig.CronSchedules) > 0 {
+		return 0, fmt.Errorf("股票 %s 使用定时计划调度，不支持调整扫描间隔", code)
 	}
-	
-	// 保存分析结果到历史记录
-	if result != nil {
-		m.saveAnalysisResult(code, result)
+
+	previous := analyzer.AnalysisConfig.ScanInterval
+	analyzer.AnalysisConfig.ScanInterval = interval
+	select {
+	case m.intervalChans[code] <- struct{}{}:
+	default: // 已有未处理的调整通知
 	}
-	
-	return result, nil
+	log.Printf("⏱️  股票 %s 扫描间隔已调整: %v → %v", code, previous, interval)
+	return previous, nil
 }
 
-// saveAnalysisResult 保存分析结果到历史记录
-func (m *AnalyzerManager) saveAnalysisResult(code string, result *stock.AnalysisResult) {
+// SetStockState 设置股票的暂停/静音状态（nil表示不修改）并持久化，返回更新后的状态
+// 暂停：跳过定时和批量分析，手动触发仍可分析；静音：照常分析，但不推送任何通知
+func (m *AnalyzerManager) SetStockState(code string, paused, muted *bool) (stock.StockState, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	if m.analysisHistory == nil {
-		m.analysisHistory = make(map[string][]*stock.AnalysisResult)
+	analyzer, exists := m.analyzers[code]
+	if !exists {
+		return stock.StockState{}, fmt.Errorf("股票代码 %s 的分析器不存在", code)
 	}
-
-	history := m.analysisHistory[code]
-	if history == nil {
-		history = []*stock.AnalysisResult{}
+	if paused != nil {
+		m.paused[code] = *paused
 	}
-
-	// 添加到列表开头（最新的在前面）
-	history = append([]*stock.AnalysisResult{result}, history...)
-
-	// 限制历史记录数量
-	if len(history) > m.maxHistorySize {
-		history = history[:m.maxHistorySize]
+	if muted != nil {
+		analyzer.SetMuted(*muted)
+	}
+
+	state := stock.StockState{Paused: m.paused[code], Muted: analyzer.IsMuted()}
+	log.Printf("🔧 股票 %s 状态已更新: 暂停=%v 静音=%v", code, state.Paused, state.Muted)
+	if m.stateStore != nil {
+		if err := m.stateStore.Set(code, state); err != nil {
+			return state, fmt.Errorf("状态已生效，但保存失败（重启后恢复原状态）: %w", err)
+		}
+	}
+	return state, nil
+}
+
+// SetDebugTrace 开启/关闭股票的调试追踪（不持久化，重启后恢复关闭），返回调试文件目录
+// 开启后每轮分析把完整提示词、AI原始响应和解析过程写入该目录下的独立文件
+func (m *AnalyzerManager) SetDebugTrace(code string, on bool) (string, error) {
+	m.mutex.RLock()
+	analyzer, exists := m.analyzers[code]
+	m.mutex.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	dir := ""
+	if on {
+		dir = filepath.Join(m.debugDir, code)
+	}
+	analyzer.SetDebugTrace(dir)
+	if on {
+		log.Printf("🐞 股票 %s 已开启调试追踪: %s", code, dir)
+	} else {
+		log.Printf("🐞 股票 %s 已关闭调试追踪", code)
+	}
+	return dir, nil
+}
+
+// ResendNotifications 补发 [from, to] 期间达到通知阈值的历史结果（codes为空时为全部股票），按分析时间先后推送，
+// dryRun为true时只返回将要补发的记录；超过 stock.MaxResendCount 条时不补发并返回错误
+func (m *AnalyzerManager) ResendNotifications(codes []string, from, to time.Time, dryRun bool) ([]stock.ResendItem, error) {
+	type pending struct {
+		analyzer *stock.StockAnalyzer
+		result   *stock.AnalysisResult
+	}
+
+	m.mutex.RLock()
+	if len(codes) == 0 {
+		for code := range m.analyzers {
+			codes = append(codes, code)
+		}
+	}
+	var matched []pending
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		code = config.NormalizeStockCode(code) // 与路径参数一致，支持 sh600519、600519.SH 等写法
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		analyzer, exists := m.analyzers[code]
+		if !exists {
+			m.mutex.RUnlock()
+			return nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+		}
+		for _, result := range m.analysisHistory[code] {
+			if result.Timestamp.Before(from) || result.Timestamp.After(to) || !analyzer.ResendEligible(result) {
+				continue
+			}
+			matched = append(matched, pending{analyzer: analyzer, result: result})
+		}
+	}
+	m.mutex.RUnlock()
+
+	if len(matched) > stock.MaxResendCount {
+		return nil, fmt.Errorf("匹配到 %d 条达阈值的结果，超过单次补发上限 %d 条，请缩小时间范围或指定股票", len(matched), stock.MaxResendCount)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].result.Timestamp.Before(matched[j].result.Timestamp)
+	})
+
+	items := make([]stock.ResendItem, 0, len(matched))
+	sent := 0
+	for i, entry := range matched {
+		result := entry.result
+		item := stock.ResendItem{
+			StockCode:  result.StockCode,
+			StockName:  result.StockName,
+			Signal:     result.Signal,
+			Confidence: result.Confidence,
+			Timestamp:  result.Timestamp,
+			TraceID:    result.TraceID,
+		}
+		if !dryRun {
+			if i > 0 {
+				time.Sleep(stock.ResendInterval)
+			}
+			if err := entry.analyzer.ResendNotification(result); err != nil {
+				item.Error = err.Error()
+			} else {
+				item.Sent = true
+				sent++
+			}
+		}
+		items = append(items, item)
+	}
+	if !dryRun {
+		log.Printf("📮 已补发 %s ~ %s 期间的通知 %d/%d 条", from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"), sent, len(items))
+	}
+	return items, nil
+}
+
+// ImportPositions 按券商导出的持仓批量更新持仓模式股票，返回每条持仓的处理结果
+// 已在监控的股票立即按新的持仓数量和成本价分析（持仓为0时回到监控模式）；未监控且有持仓的股票标记为created，
+// 由调用方写入配置文件，重启后开始监控（模拟盘的虚拟持仓独立维护，不受导入影响）
+func (m *AnalyzerManager) ImportPositions(positions []stock.ImportedPosition) ([]stock.PositionImportResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	results := make([]stock.PositionImportResult, 0, len(positions))
+	for _, position := range positions {
+		result := stock.PositionImportResult{ImportedPosition: position}
+		analyzer, exists := m.analyzers[position.Code]
+		switch {
+		case position.Quantity > 0 && position.CostPrice <= 0:
+			result.Action = stock.PositionImportSkipped
+			result.Message = "成本价无效（摊薄成本为负时请改用参考成本价列）"
+		case !exists && position.Quantity == 0:
+			result.Action = stock.PositionImportSkipped
+			result.Message = "未监控且无持仓"
+		case !exists:
+			result.Action = stock.PositionImportCreated
+			result.Message = "新增到配置文件，重启后开始监控"
+		case analyzer.IsBasket():
+			result.Action = stock.PositionImportSkipped
+			result.Message = "虚拟组合不支持持仓"
+		case m.tradesFiles[position.Code] != "":
+			result.Action = stock.PositionImportSkipped
+			result.Message = fmt.Sprintf("持仓按成交记录文件 %s 计算，请更新成交记录", m.tradesFiles[position.Code])
+		default:
+			// 只有导入的股票开启按持仓跟踪（SELL信号与持仓联动）
+			buyPrice := position.CostPrice
+			if position.Quantity == 0 {
+				buyPrice = 0
+			}
+			analyzer.SetPosition(position.Quantity, buyPrice)
+			result.Action = stock.PositionImportUpdated
+			result.Message = "持仓已更新，下一轮分析生效"
+			log.Printf("📥 股票 %s 持仓已导入: %d股，成本价 %.3f元", position.Code, position.Quantity, position.CostPrice)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetPaperAccount 获取模拟盘账户快照（未启用模拟盘时返回false）
+func (m *AnalyzerManager) GetPaperAccount(limit int) (*stock.PaperAccountSnapshot, bool) {
+	if m.paperTrader == nil {
+		return nil, false
+	}
+	snapshot := m.paperTrader.Snapshot(limit)
+	return &snapshot, true
+}
+
+// GetStockState 获取股票的暂停/静音状态
+func (m *AnalyzerManager) GetStockState(code string) (stock.StockState, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	analyzer, exists := m.analyzers[code]
+	if !exists {
+		return stock.StockState{}, false
+	}
+	return stock.StockState{Paused: m.paused[code], Muted: analyzer.IsMuted()}, true
+}
+
+// isPaused 股票是否已暂停定时分析
+func (m *AnalyzerManager) isPaused(code string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.paused[code]
+}
+
+// baseInterval 股票配置的扫描间隔（可在运行时调整，读取时加锁）
+func (m *AnalyzerManager) baseInterval(analyzer *stock.StockAnalyzer) time.Duration {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return analyzer.AnalysisConfig.ScanInterval
+}
+
+// GetAnalyzer 获取分析器
+func (m *AnalyzerManager) GetAnalyzer(code string) interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.analyzers[code]
+}
+
+// TriggerAnalysis 手动触发分析
+func (m *AnalyzerManager) TriggerAnalysis(code string) (interface{}, error) {
+	m.mutex.RLock()
+	analyzer, exists := m.analyzers[code]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	// 手动触发以最高优先级排队，插在定时分析之前
+	if m.semaphore != nil {
+		m.acquireSemaphore(stock.AnalysisPriorityManual)
+		defer m.semaphore.Release()
+	}
+
+	result, err := analyzer.AnalyzeManual()
+	if err != nil {
+		return nil, err
+	}
+
+	// 保存分析结果到历史记录
+	if result != nil {
+		m.saveAnalysisResult(code, result)
+	}
+
+	return result, nil
+}
+
+// WhatIfAnalysis 以假设现价执行一次假设分析（结果不保存到历史记录）
+func (m *AnalyzerManager) WhatIfAnalysis(code string, price float64) (interface{}, error) {
+	m.mutex.RLock()
+	analyzer, exists := m.analyzers[code]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	// 假设分析同样调用AI，与手动触发一样以最高优先级排队
+	if m.semaphore != nil {
+		m.acquireSemaphore(stock.AnalysisPriorityManual)
+		defer m.semaphore.Release()
+	}
+
+	return analyzer.AnalyzeWhatIf(price)
+}
+
+// DeleteAnalysisHistory 删除分析历史记录：timestamp为nil时清空该股票的全部记录，否则删除时间戳相同的单条记录
+// 启用结果归档时同步删除对应的归档文件，返回删除的记录数
+func (m *AnalyzerManager) DeleteAnalysisHistory(code string, timestamp *time.Time) (int, error) {
+	m.mutex.Lock()
+	if _, exists := m.analyzers[code]; !exists {
+		m.mutex.Unlock()
+		return 0, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	history := m.analysisHistory[code]
+	if timestamp == nil {
+		delete(m.analysisHistory, code)
+		m.mutex.Unlock()
+		// 归档文件的IO在释放锁之后进行，避免阻塞其他股票的分析
+		if m.archiver != nil {
+			if err := m.archiver.RemoveAll(code); err != nil {
+				return len(history), err
+			}
+		}
+		log.Printf("🗑️  已清空股票 %s 的 %d 条分析记录", code, len(history))
+		return len(history), nil
+	}
+
+	var removed []*stock.AnalysisResult
+	remaining := make([]*stock.AnalysisResult, 0, len(history))
+	for _, result := range history {
+		if result.Timestamp.Equal(*timestamp) {
+			removed = append(removed, result)
+		} else {
+			remaining = append(remaining, result)
+		}
+	}
+	if len(removed) == 0 {
+		m.mutex.Unlock()
+		return 0, fmt.Errorf("股票 %s 没有时间为 %s 的分析记录", code, timestamp.Format(time.RFC3339Nano))
+	}
+	m.analysisHistory[code] = remaining
+	m.mutex.Unlock()
+
+	if m.archiver != nil {
+		for _, result := range removed {
+			if err := m.archiver.Remove(result); err != nil {
+				return len(removed), err
+			}
+		}
+	}
+	log.Printf("🗑️  已删除股票 %s 的 %d 条分析记录（%s）", code, len(removed), timestamp.Format("2006-01-02 15:04:05"))
+	return len(removed), nil
+}
+
+// ReplayAnalysis 历史回放分析（结果不入历史记录，不发送通知）
+func (m *AnalyzerManager) ReplayAnalysis(code string, at time.Time) (interface{}, error) {
+	m.mutex.RLock()
+	analyzer, exists := m.analyzers[code]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	// 历史回放同样调用AI，与手动触发一样以最高优先级排队
+	if m.semaphore != nil {
+		m.acquireSemaphore(stock.AnalysisPriorityManual)
+		defer m.semaphore.Release()
+	}
+
+	return analyzer.AnalyzeReplay(at)
+}
+
+// saveAnalysisResult 保存分析结果到历史记录
+func (m *AnalyzerManager) saveAnalysisResult(code string, result *stock.AnalysisResult) {
+	// 按内存上限淘汰会访问所有组合的历史，需在释放本组合的锁之后执行（defer按后进先出，先解锁再淘汰）
+	defer m.historyMemory.Enforce()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.analysisHistory == nil {
+		m.analysisHistory = make(map[string][]*stock.AnalysisResult)
+	}
+
+	history := m.analysisHistory[code]
+	if history == nil {
+		history = []*stock.AnalysisResult{}
+	}
+
+	// 与上一条信号比较，翻转时回调Webhook（异步发送，不阻塞分析）
+	if m.signalChangeWebhook != nil && len(history) > 0 && history[0].Signal != result.Signal {
+		event := buildSignalChangeEvent(history[0], result)
+		go func() {
+			if err := m.signalChangeWebhook.SendSignalChange(event); err != nil {
+				log.Printf("⚠️  [%s] 信号翻转Webhook发送失败: %v", event.StockName, err)
+			} else {
+				log.Printf("🔁 [%s] 信号翻转 %s → %s，已回调Webhook", event.StockName, event.OldSignal, event.NewSignal)
+			}
+		}()
+	}
+
+	// 归档为JSON文件（异步写入）
+	if m.archiver != nil {
+		m.archiver.Archive(result)
+	}
+
+	// 添加到列表开头（最新的在前面）
+	history = append([]*stock.AnalysisResult{result}, history...)
+
+	// 限制历史记录数量
+	if len(history) > m.maxHistorySize {
+		history = history[:m.maxHistorySize]
 	}
 
 	m.analysisHistory[code] = history
 }
 
+// ExportHistory 导出全部分析历史（股票代码 -> 记录，最新的在前），返回副本
+func (m *AnalyzerManager) ExportHistory() map[string][]*stock.AnalysisResult {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	exported := make(map[string][]*stock.AnalysisResult, len(m.analysisHistory))
+	for code, history := range m.analysisHistory {
+		exported[code] = append([]*stock.AnalysisResult(nil), history...)
+	}
+	return exported
+}
+
+// EvictHistory 按内存上限淘汰指定的历史记录（由stock.HistoryMemory调用），返回删除的记录数
+func (m *AnalyzerManager) EvictHistory(code string, victims map[*stock.AnalysisResult]bool) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	history := m.analysisHistory[code]
+	remaining := make([]*stock.AnalysisResult, 0, len(history))
+	for _, result := range history {
+		if !victims[result] {
+			remaining = append(remaining, result)
+		}
+	}
+	if len(remaining) > 0 {
+		m.analysisHistory[code] = remaining
+	}
+	return len(history) - len(remaining)
+}
+
+// ImportHistory 导入分析历史：与现有记录按时间戳合并去重、按时间倒序排列，并按条数上限保留最新的记录
+// 返回实际新增的记录数和跳过的代码（当前未监控的股票不导入）
+// 导入的记录只保存在内存中（与分析历史本身一致），不触发信号翻转回调，也不归档，重启后丢失
+func (m *AnalyzerManager) ImportHistory(history map[string][]*stock.AnalysisResult) (int, []string) {
+	defer m.historyMemory.Enforce()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.analysisHistory == nil {
+		m.analysisHistory = make(map[string][]*stock.AnalysisResult)
+	}
+
+	added := 0
+	var skipped []string
+	for code, results := range history {
+		// 旧快照中的代码可能带市场前缀/后缀，统一写法后与现有历史合并
+		code = config.NormalizeStockCode(code)
+		if _, ok := m.analyzers[code]; !ok {
+			skipped = append(skipped, code)
+			continue
+		}
+		merged := append([]*stock.AnalysisResult(nil), m.analysisHistory[code]...)
+		seen := make(map[int64]bool, len(merged))
+		for _, result := range merged {
+			seen[result.Timestamp.UnixNano()] = true
+		}
+		newResults := make(map[*stock.AnalysisResult]bool)
+		for _, result := range results {
+			if result == nil || seen[result.Timestamp.UnixNano()] {
+				continue
+			}
+			seen[result.Timestamp.UnixNano()] = true
+			newResults[result] = true
+			merged = append(merged, result)
+		}
+
+		sort.SliceStable(merged, func(i, j int) bool {
+			return merged[i].Timestamp.After(merged[j].Timestamp)
+		})
+		if len(merged) > m.maxHistorySize {
+			merged = merged[:m.maxHistorySize]
+		}
+
+		// 统计最终保留下来的导入记录数
+		for _, result := range merged {
+			if newResults[result] {
+				added++
+			}
+		}
+		if len(merged) > 0 {
+			m.analysisHistory[code] = merged
+		}
+	}
+
+	sort.Strings(skipped)
+	if len(skipped) > 0 {
+		log.Printf("⚠️  [%s] 快照中的股票未在监控列表中，已跳过: %v", m.portfolioID, skipped)
+	}
+	log.Printf("📥 [%s] 已导入 %d 条分析记录", m.portfolioID, added)
+	return added, skipped
+}
+
+// buildSignalChangeEvent 构建信号翻转事件，diff只包含前后两次结果有变化的字段
+func buildSignalChangeEvent(oldResult, newResult *stock.AnalysisResult) *notifier.SignalChangeEvent {
+	return &notifier.SignalChangeEvent{
+		StockCode: newResult.StockCode,
+		StockName: newResult.StockName,
+		OldSignal: oldResult.Signal,
+		NewSignal: newResult.Signal,
+		Timestamp: newResult.Timestamp,
+		Diff:      stock.DiffResults(oldResult, newResult),
+		OldResult: oldResult,
+		NewResult: newResult,
+	}
+}
+
+// GetAnalysisAt 按时间戳获取一条分析历史记录
+func (m *AnalyzerManager) GetAnalysisAt(code string, timestamp time.Time) (*stock.AnalysisResult, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, result := range m.analysisHistory[code] {
+		if result.Timestamp.Equal(timestamp) {
+			return result, true
+		}
+	}
+	return nil, false
+}
+
+// GetAnalysisDiff 对比两条分析历史记录的字段级差异
+// from、to都为nil时对比最近两条记录
+func (m *AnalyzerManager) GetAnalysisDiff(code string, from, to *time.Time) (map[string]interface{}, error) {
+	var oldResult, newResult *stock.AnalysisResult
+	if from == nil && to == nil {
+		m.mutex.RLock()
+		history := m.analysisHistory[code]
+		if len(history) >= 2 {
+			oldResult, newResult = history[1], history[0]
+		}
+		m.mutex.RUnlock()
+		if newResult == nil {
+			return nil, fmt.Errorf("股票 %s 的分析记录不足两条", code)
+		}
+	} else {
+		var ok bool
+		if oldResult, ok = m.GetAnalysisAt(code, *from); !ok {
+			return nil, fmt.Errorf("股票 %s 没有时间为 %s 的分析记录", code, from.Format(time.RFC3339Nano))
+		}
+		if newResult, ok = m.GetAnalysisAt(code, *to); !ok {
+			return nil, fmt.Errorf("股票 %s 没有时间为 %s 的分析记录", code, to.Format(time.RFC3339Nano))
+		}
+	}
+
+	return map[string]interface{}{
+		"stock_code":        code,
+		"stock_name":        newResult.StockName,
+		"from":              oldResult.Timestamp,
+		"to":                newResult.Timestamp,
+		"changes":           stock.DiffResults(oldResult, newResult),
+		"indicator_changes": stock.DiffIndicators(oldResult, newResult),
+	}, nil
+}
+
 // GetAnalysisHistory 获取分析历史记录
 func (m *AnalyzerManager) GetAnalysisHistory(code string, limit int) interface{} {
 	m.mutex.RLock()
@@ -403,8 +1657,89 @@ func (m *AnalyzerManager) GetAnalysisHistory(code string, limit int) interface{}
 	return history
 }
 
-// GetAllRecentAnalysis 获取所有股票的最远分析记录（最近N条）
-func (m *AnalyzerManager) GetAllRecentAnalysis(limit int) interface{} {
+// GetAnalysisHistoryPage 分页获取分析历史记录（offset从0开始），同时返回历史记录总数
+func (m *AnalyzerManager) GetAnalysisHistoryPage(code string, offset, limit int) (interface{}, int) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if limit <= 0 {
+		limit = 20 // 默认20条
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	history := m.analysisHistory[code]
+	total := len(history)
+	if offset >= total {
+		return []*stock.AnalysisResult{}, total
+	}
+
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return history[offset:end], total
+}
+
+// SubscribeAIStream 订阅某股票分析时的AI实时输出
+func (m *AnalyzerManager) SubscribeAIStream(code string) (<-chan stock.AIStreamEvent, func(), error) {
+	m.mutex.RLock()
+	analyzer, exists := m.analyzers[code]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	ch, cancel := analyzer.SubscribeAIStream()
+	return ch, cancel, nil
+}
+
+// GetIndicatorSeries 获取某股票指定指标的时间序列（按时间升序）
+// 优先从历史分析结果的TechnicalData中抽取，历史中没有该指标时用日K线重算最近一段
+func (m *AnalyzerManager) GetIndicatorSeries(code, name string, limit int) (map[string]interface{}, error) {
+	m.mutex.RLock()
+	analyzer, exists := m.analyzers[code]
+	history := m.analysisHistory[code]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("股票代码 %s 的分析器不存在", code)
+	}
+
+	// 历史记录按最新在前存储，倒序遍历得到时间升序
+	points := []stock.IndicatorPoint{}
+	for i := len(history) - 1; i >= 0; i-- {
+		if value, ok := stock.IndicatorValue(history[i].TechnicalData, name); ok {
+			points = append(points, stock.IndicatorPoint{Time: history[i].Timestamp, Value: value})
+		}
+	}
+	if len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+
+	source := "history"
+	if len(points) == 0 {
+		recalculated, err := analyzer.CalculateIndicatorSeries(name, limit)
+		if err != nil {
+			return nil, err
+		}
+		points = recalculated
+		source = "recalculated"
+	}
+
+	return map[string]interface{}{
+		"code":   code,
+		"name":   name,
+		"source": source, // history: 来自分析历史；recalculated: 用日K线重算
+		"points": points,
+	}, nil
+}
+
+// GetAllRecentAnalysis 获取所有股票的最远分析记录（最近N条），只保留最新记录满足筛选条件的股票
+func (m *AnalyzerManager) GetAllRecentAnalysis(limit int, filter stock.ResultFilter) interface{} {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
@@ -416,20 +1751,16 @@ func (m *AnalyzerManager) GetAllRecentAnalysis(limit int) interface{} {
 
 	// 收集所有股票的最新分析结果
 	for _, history := range m.analysisHistory {
-		if len(history) > 0 {
+		if len(history) > 0 && filter.Match(history[0]) {
 			// 只取每个股票的最新一条
 			allResults = append(allResults, history[0])
 		}
 	}
 
-	// 按时间排序（最新的在前）
-	for i := 0; i < len(allResults)-1; i++ {
-		for j := i + 1; j < len(allResults); j++ {
-			if allResults[i].Timestamp.Before(allResults[j].Timestamp) {
-				allResults[i], allResults[j] = allResults[j], allResults[i]
-			}
-		}
-	}
+	// 按时间（最新的在前）或涨跌停可能性排序
+	sort.SliceStable(allResults, func(i, j int) bool {
+		return filter.Less(allResults[i], allResults[j])
+	})
 
 	// 限制返回数量
 	if len(allResults) > limit {
@@ -439,6 +1770,101 @@ func (m *AnalyzerManager) GetAllRecentAnalysis(limit int) interface{} {
 	return allResults
 }
 
+// TriggerAllAnalysis 异步批量触发所有股票的分析（受并发信号量限制），返回批次ID
+// 如果已有批次在运行，则拒绝本次请求
+func (m *AnalyzerManager) TriggerAllAnalysis() (string, error) {
+	m.mutex.Lock()
+	if m.runningBatchID != "" {
+		runningID := m.runningBatchID
+		m.mutex.Unlock()
+		return runningID, fmt.Errorf("已有批量分析正在运行（批次 %s），请等待完成后再试", runningID)
+	}
+
+	batch := &AnalysisBatch{
+		ID:        fmt.Sprintf("batch-%s", time.Now().Format("20060102150405.000")),
+		Status:    "running",
+		StartedAt: time.Now(),
+		Total:     len(m.analyzers),
+		Results:   make(map[string]string),
+	}
+	analyzers := make(map[string]*stock.StockAnalyzer, len(m.analyzers))
+	for code, analyzer := range m.analyzers {
+		analyzers[code] = analyzer
+		batch.Results[code] = "pending"
+	}
+
+	if m.batches == nil {
+		m.batches = make(map[string]*AnalysisBatch)
+	}
+	m.batches[batch.ID] = batch
+	m.batchOrder = append(m.batchOrder, batch.ID)
+	if len(m.batchOrder) > maxBatchRecords {
+		delete(m.batches, m.batchOrder[0])
+		m.batchOrder = m.batchOrder[1:]
+	}
+	m.runningBatchID = batch.ID
+	m.mutex.Unlock()
+
+	log.Printf("🚀 开始批量分析 %d 只股票（批次 %s）", batch.Total, batch.ID)
+
+	go func() {
+		runOne := func(code string, analyzer *stock.StockAnalyzer) {
+			batch.markRunning(code)
+			_, err := m.runAnalysisWithSemaphore(code, analyzer, stock.AnalysisPriorityScheduled)
+			batch.markDone(code, err)
+		}
+
+		if m.actualMode == "polling" {
+			// 轮询模式：顺序执行
+			for code, analyzer := range analyzers {
+				runOne(code, analyzer)
+			}
+		} else {
+			// 并发模式：并发执行，由信号量控制并发数
+			var wg sync.WaitGroup
+			for code, analyzer := range analyzers {
+				wg.Add(1)
+				go func(code string, analyzer *stock.StockAnalyzer) {
+					defer wg.Done()
+					runOne(code, analyzer)
+				}(code, analyzer)
+			}
+			wg.Wait()
+		}
+
+		// 计数在同一把锁下读取，与markDone的更新保持同步
+		batch.mutex.Lock()
+		finishedAt := time.Now()
+		batch.FinishedAt = &finishedAt
+		batch.Status = "completed"
+		succeeded, failed, skipped := batch.Succeeded, batch.Failed, batch.Skipped
+		batch.mutex.Unlock()
+
+		m.mutex.Lock()
+		m.runningBatchID = ""
+		m.mutex.Unlock()
+
+		log.Printf("✅ 批量分析完成（批次 %s）: 成功 %d，失败 %d，跳过（已暂停） %d", batch.ID, succeeded, failed, skipped)
+	}()
+
+	return batch.ID, nil
+}
+
+// GetBatchStatus 获取批量分析批次的进度（batchID为空时返回最近一个批次）
+func (m *AnalyzerManager) GetBatchStatus(batchID string) (map[string]interface{}, bool) {
+	m.mutex.RLock()
+	if batchID == "" && len(m.batchOrder) > 0 {
+		batchID = m.batchOrder[len(m.batchOrder)-1]
+	}
+	batch, exists := m.batches[batchID]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+	return batch.snapshot(), true
+}
+
 // StartAll 启动所有分析器
 func (m *AnalyzerManager) StartAll() {
 	m.mutex.RLock()
@@ -448,12 +1874,18 @@ func (m *AnalyzerManager) StartAll() {
 	actualMode, actualMaxConcurrent := m.determineAnalysisMode()
 
 	log.Printf("📊 分析模式: %s，最大并发数: %d，股票总数: %d", actualMode, actualMaxConcurrent, m.stockCount)
-
-	// 初始化并发控制信号量
-	if actualMode == "concurrent" || actualMode == "smart" {
-		m.semaphore = make(chan struct{}, actualMaxConcurrent)
+	m.actualMode = actualMode
+	if m.semaphore != nil {
+		_, capacity, _ := m.semaphore.Status()
+		if capacity < actualMaxConcurrent {
+			log.Printf("ℹ️  AI并发信号量由所有组合共享，实际并发不超过 %d", capacity)
+		}
 	}
 
+	// 配置了定时计划的股票交给cron调度器
+	m.startCronSchedules()
+	m.startSessionSummaries()
+
 	// 如果是轮询模式，使用轮询方式启动
 	if actualMode == "polling" {
 		m.startPollingMode()
@@ -462,32 +1894,132 @@ func (m *AnalyzerManager) StartAll() {
 
 	// 并发模式或智能模式，使用并发方式启动
 	for code, analyzer := range m.analyzers {
+		if len(analyzer.AnalysisConfig.CronSchedules) > 0 {
+			continue
+		}
 		stopChan := m.stopChans[code]
-		go func(code string, analyzer *stock.StockAnalyzer, stopChan chan struct{}) {
-			// 包装监控函数，在分析完成后保存结果
-			ticker := time.NewTicker(analyzer.AnalysisConfig.ScanInterval)
-			defer ticker.Stop()
+		intervalChan := m.intervalChans[code]
+		go func(code string, analyzer *stock.StockAnalyzer, stopChan, intervalChan chan struct{}) {
+			// 包装监控函数，在分析完成后保存结果（每轮按失败退避后的间隔重新计时）
+			timer := time.NewTimer(analyzer.AnalysisConfig.ScanInterval)
+			defer timer.Stop()
 
 			log.Printf("🚀 开始监控股票 %s，扫描间隔: %v",
 				code,
 				analyzer.AnalysisConfig.ScanInterval)
 
 			// 立即执行一次分析（带并发控制）
-			m.runAnalysisWithSemaphore(code, analyzer)
+			m.runAnalysisWithSemaphore(code, analyzer, stock.AnalysisPriorityScheduled)
+			timer.Reset(m.scanInterval(code, m.baseInterval(analyzer)))
 
 			for {
 				select {
-				case <-ticker.C:
-					m.runAnalysisWithSemaphore(code, analyzer)
+				case <-timer.C:
+					m.runAnalysisWithSemaphore(code, analyzer, m.schedulePriority(code))
+					timer.Reset(m.scanInterval(code, m.baseInterval(analyzer)))
+				case <-intervalChan:
+					// 扫描间隔已调整，按新间隔重新计时
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					timer.Reset(m.scanInterval(code, m.baseInterval(analyzer)))
 				case <-stopChan:
 					log.Printf("⏹️  停止监控股票 %s", code)
 					return
 				}
 			}
-		}(code, analyzer, stopChan)
+		}(code, analyzer, stopChan, intervalChan)
 	}
 }
 
+// startCronSchedules 为配置了cron表达式的股票注册定时分析任务（调用方需持有读锁）
+func (m *AnalyzerManager) startCronSchedules() {
+	location := m.cronLocation
+	if location == nil {
+		location = time.Local
+	}
+
+	scheduler := cron.New(cron.WithLocation(location))
+	jobCount := 0
+	for code, analyzer := range m.analyzers {
+		for _, spec := range analyzer.AnalysisConfig.CronSchedules {
+			code, analyzer, spec := code, analyzer, spec
+			_, err := scheduler.AddFunc(spec, func() {
+				log.Printf("⏰ [定时] 开始分析股票 %s（计划: %s）", code, spec)
+				if _, err := m.runAnalysisWithSemaphore(code, analyzer, m.schedulePriority(code)); err != nil && !errors.Is(err, ErrStockPaused) {
+					log.Printf("⚠️  [定时] 分析股票 %s 失败: %v", code, err)
+				}
+			})
+			if err != nil {
+				log.Printf("⚠️  股票 %s 的cron表达式 '%s' 无效: %v", code, spec, err)
+				continue
+			}
+			jobCount++
+		}
+		if len(analyzer.AnalysisConfig.CronSchedules) > 0 {
+			log.Printf("🚀 开始监控股票 %s，定时计划: %s", code, strings.Join(analyzer.AnalysisConfig.CronSchedules, " | "))
+		}
+	}
+
+	if jobCount == 0 {
+		return
+	}
+	scheduler.Start()
+	m.cron = scheduler
+	log.Printf("⏰ 定时分析调度器已启动，共 %d 个计划任务（时区: %s）", jobCount, location)
+}
+
+// startSessionSummaries 启动交易时段汇总：按交易时间配置的时段定义，在每个时段结束时（如A股11:30、15:00）推送该时段内的信号汇总
+func (m *AnalyzerManager) startSessionSummaries() {
+	if m.summaryStop == nil {
+		return
+	}
+	log.Printf("📋 [%s] 交易时段汇总已启用，时段: %s", m.portfolioID, strings.Join(m.tradingTimeChecker.Config.TradingHours, ", "))
+
+	go func() {
+		for {
+			start, end, ok := m.tradingTimeChecker.NextSessionEnd(m.tradingTimeChecker.Now())
+			if !ok {
+				log.Printf("⚠️  [%s] 未配置有效的交易时段，停止时段汇总", m.portfolioID)
+				return
+			}
+			timer := time.NewTimer(time.Until(end.Add(sessionSummaryDelay)))
+			select {
+			case <-timer.C:
+				m.sendSessionSummary(start, end)
+			case <-m.summaryStop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// sendSessionSummary 汇总[start, end]时段内的分析结果并推送（时段内没有分析结果时不推送）
+func (m *AnalyzerManager) sendSessionSummary(start, end time.Time) {
+	m.mutex.RLock()
+	history := make(map[string][]*stock.AnalysisResult, len(m.analysisHistory))
+	for code, results := range m.analysisHistory {
+		history[code] = append([]*stock.AnalysisResult(nil), results...)
+	}
+	m.mutex.RUnlock()
+
+	title := fmt.Sprintf("%s %s-%s 时段汇总", m.portfolioName, start.Format("15:04"), end.Format("15:04"))
+	report := stock.BuildDailyReport(strings.TrimSpace(title), start, end.Add(sessionSummaryDelay), history)
+	if report.Total() == 0 {
+		log.Printf("📋 [%s] %s-%s 时段内没有分析结果，跳过时段汇总", m.portfolioID, start.Format("15:04"), end.Format("15:04"))
+		return
+	}
+	if err := m.summaryNotifier.SendMessage(report.Markdown()); err != nil {
+		log.Printf("⚠️  [%s] 发送时段汇总失败: %v", m.portfolioID, err)
+		return
+	}
+	log.Printf("📋 [%s] 已推送 %s-%s 时段汇总（%d只股票，%d次分析）", m.portfolioID, start.Format("15:04"), end.Format("15:04"), len(report.Stocks), report.Total())
+}
+
 // determineAnalysisMode 确定实际使用的分析模式和并发数
 func (m *AnalyzerManager) determineAnalysisMode() (string, int) {
 	if m.analysisMode == "polling" {
@@ -512,23 +2044,336 @@ func (m *AnalyzerManager) determineAnalysisMode() (string, int) {
 	return "polling", 1
 }
 
-// runAnalysisWithSemaphore 带并发控制的分析执行
-func (m *AnalyzerManager) runAnalysisWithSemaphore(code string, analyzer *stock.StockAnalyzer) {
-	if m.semaphore == nil {
-		// 如果没有信号量（轮询模式），直接执行
-		if result, err := analyzer.Analyze(); err == nil && result != nil {
-			m.saveAnalysisResult(code, result)
-		}
+// runAnalysisWithSemaphore 带并发控制的分析执行，priority为排队优先级（stock.AnalysisPriority*）
+// 单次分析计时（不含排队等待），超过slow_threshold时记录慢分析告警
+func (m *AnalyzerManager) runAnalysisWithSemaphore(code string, analyzer *stock.StockAnalyzer, priority int) (*stock.AnalysisResult, error) {
+	var queued time.Duration
+	if m.semaphore != nil {
+		waitStart := time.Now()
+		m.acquireSemaphore(priority)
+		defer m.semaphore.Release()
+		queued = time.Since(waitStart)
+	}
+
+	start := time.Now()
+	result, err := m.runAnalysis(code, analyzer)
+	m.checkSlowAnalysis(code, analyzer, time.Since(start), queued, result, err)
+	return result, err
+}
+
+// slowAlertInterval 同一股票慢分析告警通知的最短间隔（日志每次都记录），避免AI持续变慢时刷屏
+const slowAlertInterval = 30 * time.Minute
+
+// checkSlowAnalysis 单次分析耗时超过slow_threshold时记录慢分析告警（含trace_id与各阶段耗时），用于发现AI/TDX性能退化
+func (m *AnalyzerManager) checkSlowAnalysis(code string, analyzer *stock.StockAnalyzer, elapsed, queued time.Duration, result *stock.AnalysisResult, err error) {
+	if m.slowThreshold <= 0 || elapsed < m.slowThreshold {
 		return
 	}
+	atomic.AddInt64(&m.slowAnalysis, 1)
 
-	// 获取信号量（控制并发数）
-	m.semaphore <- struct{}{}
-	defer func() { <-m.semaphore }()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🐌 慢分析告警: %s(%s) 单次分析耗时 %.1fs，超过阈值 %v", analyzer.AnalysisConfig.StockName, code, elapsed.Seconds(), m.slowThreshold))
+	if result != nil {
+		sb.WriteString(fmt.Sprintf("\n链路追踪ID: %s", result.TraceID))
+		if len(result.StageDurations) > 0 {
+			sb.WriteString(fmt.Sprintf("\n各阶段耗时: %s", stock.FormatStageDurations(result.StageDurations)))
+		}
+	} else if err != nil {
+		// 失败时错误信息中已带 trace=<ID>
+		sb.WriteString(fmt.Sprintf("\n分析失败: %v", err))
+	}
+	if queued >= time.Second {
+		sb.WriteString(fmt.Sprintf("\n排队等待: %.1fs（不计入分析耗时）", queued.Seconds()))
+	}
+	message := sb.String()
+	log.Printf("%s", message)
+
+	m.slowMutex.Lock()
+	now := time.Now()
+	notify := now.Sub(m.slowAlertedAt[code]) >= slowAlertInterval
+	if notify {
+		m.slowAlertedAt[code] = now
+	}
+	m.slowMutex.Unlock()
 
-	if result, err := analyzer.Analyze(); err == nil && result != nil {
+	if notify && m.alertNotifier != nil {
+		if sendErr := m.alertNotifier.SendMessage(message); sendErr != nil {
+			log.Printf("⚠️  [%s] 发送慢分析告警失败: %v", code, sendErr)
+		}
+	}
+}
+
+// acquireSemaphore 按优先级获取信号量（控制并发数），排队期间计入等待数
+func (m *AnalyzerManager) acquireSemaphore(priority int) {
+	atomic.AddInt64(&m.waitingCount, 1)
+	m.semaphore.Acquire(priority)
+	atomic.AddInt64(&m.waitingCount, -1)
+}
+
+// schedulePriority 定时分析的排队优先级：上一轮信号翻转待确认时本轮作为复查优先执行，否则为常规定时
+func (m *AnalyzerManager) schedulePriority(code string) int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if history := m.analysisHistory[code]; len(history) > 0 && history[0].PendingConfirmation {
+		return stock.AnalysisPriorityRecheck
+	}
+	return stock.AnalysisPriorityScheduled
+}
+
+// ErrStockPaused 股票已暂停，跳过本轮定时/批量分析（不属于分析失败）
+var ErrStockPaused = errors.New("股票已暂停")
+
+// runAnalysis 执行单次分析并保存结果，同时维护运行时统计；股票已暂停时返回ErrStockPaused
+func (m *AnalyzerManager) runAnalysis(code string, analyzer *stock.StockAnalyzer) (result *stock.AnalysisResult, err error) {
+	if m.isPaused(code) {
+		log.Printf("⏸️  股票 %s 已暂停，跳过本轮分析", code)
+		return nil, ErrStockPaused
+	}
+
+	atomic.AddInt64(&m.runningCount, 1)
+	defer atomic.AddInt64(&m.runningCount, -1)
+	atomic.AddInt64(&m.totalAnalysis, 1)
+
+	// 分析过程中的panic转为本轮失败（上报Sentry），避免整个进程退出
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			stack := debug.Stack()
+			log.Printf("💥 [%s] 分析过程发生panic: %v\n%s", code, recovered, stack)
+			m.errorReporter.CapturePanic(recovered, stack, m.errorTags(code, analyzer))
+			atomic.AddInt64(&m.failedAnalysis, 1)
+			result, err = nil, fmt.Errorf("分析过程发生panic: %v", recovered)
+		}
+	}()
+
+	result, err = analyzer.Analyze()
+	m.recordAnalysisOutcome(code, m.baseInterval(analyzer), err)
+	if err != nil {
+		atomic.AddInt64(&m.failedAnalysis, 1)
+		if !errors.Is(err, stock.ErrNotTradingTime) {
+			m.errorReporter.CaptureError(err, m.errorTags(code, analyzer))
+		}
+		return nil, err
+	}
+	if result != nil {
 		m.saveAnalysisResult(code, result)
 	}
+	return result, nil
+}
+
+// backoffInterval 按连续失败次数计算退避后的扫描间隔：达到阈值后每多失败一次间隔翻倍，不超过上限
+func backoffInterval(base time.Duration, failures, threshold int, maxInterval time.Duration) time.Duration {
+	if threshold <= 0 || failures < threshold || maxInterval <= base {
+		return base
+	}
+	interval := base
+	for i := threshold; i <= failures && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+	return interval
+}
+
+// errorTags 上报Sentry时的上下文标签
+func (m *AnalyzerManager) errorTags(code string, analyzer *stock.StockAnalyzer) map[string]string {
+	return map[string]string{
+		"stock_code": code,
+		"stock_name": analyzer.AnalysisConfig.StockName,
+		"portfolio":  m.portfolioID,
+		"stage":      "analysis",
+	}
+}
+
+// adaptiveIntervalState 股票最近一次按活跃度计算的扫描间隔
+type adaptiveIntervalState struct {
+	activity float64
+	interval time.Duration
+}
+
+// activityInterval 按市场活跃度调整扫描间隔（未启用或今天还没有分析结果时为配置间隔）
+// 活跃度取个股活跃度和市场活跃度的平均值，只有一方有数据时取该方
+func (m *AnalyzerManager) activityInterval(code string, base time.Duration) time.Duration {
+	if m.adaptiveInterval == nil {
+		return base
+	}
+
+	m.mutex.RLock()
+	var latest, previous *stock.AnalysisResult
+	if history := m.analysisHistory[code]; len(history) > 0 {
+		latest = history[0]
+		if len(history) > 1 {
+			previous = history[1]
+		}
+	}
+	all := make([]*stock.AnalysisResult, 0, len(m.analysisHistory))
+	for _, history := range m.analysisHistory {
+		if len(history) > 0 {
+			all = append(all, history[0])
+		}
+	}
+	m.mutex.RUnlock()
+
+	now := time.Now()
+	stockActivity, hasStock := stock.StockActivity(latest, previous, now)
+	marketActivity, hasMarket := stock.MarketActivity(all, now)
+	var activity float64
+	switch {
+	case hasStock && hasMarket:
+		activity = (stockActivity + marketActivity) / 2
+	case hasStock:
+		activity = stockActivity
+	case hasMarket:
+		activity = marketActivity
+	default:
+		return base
+	}
+	interval := m.adaptiveInterval.Interval(base, activity)
+
+	m.adaptiveMutex.Lock()
+	previousState, exists := m.adaptiveIntervals[code]
+	m.adaptiveIntervals[code] = adaptiveIntervalState{activity: activity, interval: interval}
+	m.adaptiveMutex.Unlock()
+	if !exists || previousState.interval != interval {
+		log.Printf("🌊 股票 %s 活跃度 %.2f（个股 %.2f，市场 %.2f），扫描间隔 %v → %v", code, activity, stockActivity, marketActivity, base, interval)
+	}
+	return interval
+}
+
+// scanInterval 股票当前实际使用的扫描间隔（按市场活跃度自适应，连续失败时按退避延长）
+func (m *AnalyzerManager) scanInterval(code string, base time.Duration) time.Duration {
+	base = m.activityInterval(code, base)
+	if m.backoffThreshold <= 0 {
+		return base
+	}
+	m.backoffMutex.Lock()
+	failures := m.failureCounts[code]
+	m.backoffMutex.Unlock()
+	return backoffInterval(base, failures, m.backoffThreshold, m.backoffMaxInterval)
+}
+
+// recordAnalysisOutcome 记录分析结果用于失败退避：成功时恢复原频率，失败时累计次数并在达到间隔上限时告警
+// 非交易时段跳过不计为失败
+func (m *AnalyzerManager) recordAnalysisOutcome(code string, base time.Duration, err error) {
+	if m.backoffThreshold <= 0 || errors.Is(err, stock.ErrNotTradingTime) {
+		return
+	}
+
+	m.backoffMutex.Lock()
+	if err == nil {
+		if m.failureCounts[code] >= m.backoffThreshold {
+			log.Printf("✅ [%s] 分析恢复成功，扫描间隔恢复为 %v", code, base)
+		}
+		delete(m.failureCounts, code)
+		delete(m.backoffAlerted, code)
+		m.backoffMutex.Unlock()
+		return
+	}
+
+	m.failureCounts[code]++
+	failures := m.failureCounts[code]
+	interval := backoffInterval(base, failures, m.backoffThreshold, m.backoffMaxInterval)
+	alert := interval >= m.backoffMaxInterval && !m.backoffAlerted[code]
+	if alert {
+		m.backoffAlerted[code] = true
+	}
+	m.backoffMutex.Unlock()
+
+	if failures < m.backoffThreshold {
+		return
+	}
+	log.Printf("🐢 [%s] 连续分析失败 %d 次，扫描间隔延长至 %v", code, failures, interval)
+	if !alert {
+		return
+	}
+
+	message := fmt.Sprintf("⚠️ 股票 %s 连续分析失败 %d 次，扫描间隔已延长至上限 %v。\n最近错误: %v\n可能已停牌、退市或代码错误，建议检查配置或移除该股票。", code, failures, interval, err)
+	log.Printf("%s", message)
+	if m.alertNotifier != nil {
+		if sendErr := m.alertNotifier.SendMessage(message); sendErr != nil {
+			log.Printf("⚠️  [%s] 发送失败退避告警失败: %v", code, sendErr)
+		}
+	}
+}
+
+// GetPortfolioInfo 获取所属组合信息
+func (m *AnalyzerManager) GetPortfolioInfo() map[string]interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return map[string]interface{}{
+		"id":          m.portfolioID,
+		"name":        m.portfolioName,
+		"stock_count": len(m.analyzers),
+	}
+}
+
+// GetRuntimeStatus 获取运行时状态（信号量占用、排队数、累计分析次数），用于诊断AI限流导致的积压
+func (m *AnalyzerManager) GetRuntimeStatus() map[string]interface{} {
+	m.mutex.RLock()
+	mode := m.actualMode
+	analyzerCount := len(m.analyzers)
+	m.mutex.RUnlock()
+
+	semaphoreStatus := map[string]interface{}{
+		"enabled": false,
+	}
+	if m.semaphore != nil {
+		inUse, capacity, waiting := m.semaphore.Status()
+		semaphoreStatus = map[string]interface{}{
+			"enabled":             true,
+			"in_use":              inUse,
+			"capacity":            capacity,
+			"waiting_by_priority": waiting,
+		}
+	}
+
+	status := map[string]interface{}{
+		"analysis_mode":   mode,
+		"analyzer_count":  analyzerCount,
+		"semaphore":       semaphoreStatus,
+		"waiting":         atomic.LoadInt64(&m.waitingCount),
+		"running":         atomic.LoadInt64(&m.runningCount),
+		"total_analysis":  atomic.LoadInt64(&m.totalAnalysis),
+		"failed_analysis": atomic.LoadInt64(&m.failedAnalysis),
+	}
+	if m.slowThreshold > 0 {
+		status["slow_threshold"] = m.slowThreshold.String()
+		status["slow_analysis"] = atomic.LoadInt64(&m.slowAnalysis)
+	}
+
+	// 连续失败的股票及退避后的扫描间隔
+	if m.backoffThreshold > 0 {
+		backoff := map[string]interface{}{}
+		m.mutex.RLock()
+		m.backoffMutex.Lock()
+		for code, failures := range m.failureCounts {
+			if analyzer, ok := m.analyzers[code]; ok {
+				base := analyzer.AnalysisConfig.ScanInterval
+				backoff[code] = map[string]interface{}{
+					"consecutive_failures": failures,
+					"scan_interval":        backoffInterval(base, failures, m.backoffThreshold, m.backoffMaxInterval).String(),
+				}
+			}
+		}
+		m.backoffMutex.Unlock()
+		m.mutex.RUnlock()
+		status["failure_backoff"] = backoff
+	}
+
+	// 按市场活跃度自适应后的扫描间隔
+	if m.adaptiveInterval != nil {
+		adaptive := map[string]interface{}{}
+		m.adaptiveMutex.Lock()
+		for code, state := range m.adaptiveIntervals {
+			adaptive[code] = map[string]interface{}{
+				"activity":      math.Round(state.activity*100) / 100,
+				"scan_interval": state.interval.String(),
+			}
+		}
+		m.adaptiveMutex.Unlock()
+		status["adaptive_interval"] = adaptive
+	}
+	return status
 }
 
 // startPollingMode 启动轮询模式（顺序分析）
@@ -543,6 +2388,9 @@ func (m *AnalyzerManager) startPollingMode() {
 
 	var analyzers []analyzerInfo
 	for code, analyzer := range m.analyzers {
+		if len(analyzer.AnalysisConfig.CronSchedules) > 0 {
+			continue
+		}
 		analyzers = append(analyzers, analyzerInfo{
 			code:     code,
 			analyzer: analyzer,
@@ -552,6 +2400,10 @@ func (m *AnalyzerManager) startPollingMode() {
 		log.Printf("🚀 准备监控股票 %s，扫描间隔: %v", code, analyzer.AnalysisConfig.ScanInterval)
 	}
 
+	if len(analyzers) == 0 {
+		return
+	}
+
 	// 启动轮询协程（顺序分析）
 	go func() {
 		log.Printf("🔄 启动轮询模式，顺序分析 %d 只股票", len(analyzers))
@@ -564,9 +2416,7 @@ func (m *AnalyzerManager) startPollingMode() {
 				return
 			default:
 				log.Printf("📊 [轮询] 开始分析股票 %s", info.code)
-				if result, err := info.analyzer.Analyze(); err == nil && result != nil {
-					m.saveAnalysisResult(info.code, result)
-				}
+				m.runAnalysisWithSemaphore(info.code, info.analyzer, stock.AnalysisPriorityScheduled)
 				log.Printf("✅ [轮询] 完成分析股票 %s", info.code)
 			}
 		}
@@ -584,6 +2434,9 @@ func (m *AnalyzerManager) startPollingMode() {
 				minInterval = info.interval
 			}
 		}
+		if m.adaptiveInterval != nil && m.adaptiveInterval.Min < minInterval {
+			minInterval = m.adaptiveInterval.Min // 活跃时间隔可缩短到最短间隔
+		}
 
 		// 主轮询循环
 		ticker := time.NewTicker(minInterval / 4) // 每1/4间隔检查一次
@@ -609,11 +2462,10 @@ func (m *AnalyzerManager) startPollingMode() {
 						goto nextCheck // 重新开始检查
 					default:
 						// 检查是否到了该股票的分析时间
-						if time.Since(lastAnalysis[info.code]) >= info.interval {
+						// 轮询模式下调整后的间隔在下一次检查时生效（检查周期为最短初始间隔的1/4）
+						if time.Since(lastAnalysis[info.code]) >= m.scanInterval(info.code, m.baseInterval(info.analyzer)) {
 							log.Printf("📊 [轮询] 开始分析股票 %s（第 %d/%d 只）", info.code, i+1, len(analyzers))
-							if result, err := info.analyzer.Analyze(); err == nil && result != nil {
-								m.saveAnalysisResult(info.code, result)
-							}
+							m.runAnalysisWithSemaphore(info.code, info.analyzer, stock.AnalysisPriorityScheduled)
 							lastAnalysis[info.code] = time.Now()
 							log.Printf("✅ [轮询] 完成分析股票 %s", info.code)
 						}
@@ -626,10 +2478,18 @@ func (m *AnalyzerManager) startPollingMode() {
 	}()
 }
 
-// StopAll 停止所有分析器
+// StopAll 停止所有分析器，并推送合并通知窗口内尚未发出的信号（停止、重启和退出时都会调用）
 func (m *AnalyzerManager) StopAll() {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
+	defer notifier.FlushPending(m.notifier)
+
+	if m.cron != nil {
+		m.cron.Stop()
+	}
+	if m.summaryStop != nil {
+		close(m.summaryStop)
+	}
 
 	for _, stopChan := range m.stopChans {
 		close(stopChan)
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nofx/notifier"
+	"nofx/stock"
+)
+
+// recordingNotifier 记录发送的文本消息
+type recordingNotifier struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (n *recordingNotifier) SendSignal(signal *notifier.TradingSignal) error { return nil }
+
+func (n *recordingNotifier) SendMessage(message string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func (n *recordingNotifier) sent() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return append([]string(nil), n.messages...)
+}
+
+// waitFor 在超时前轮询条件
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 轮询模式也经过共享信号量排队（常规定时优先级），并对慢分析告警
+func TestPollingModeUsesSemaphoreAndSlowAlert(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond) // 模拟TDX变慢
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	analyzer := stock.NewStockAnalyzer(stock.NewTDXClient(server.URL), nil, nil, &stock.AnalysisConfig{
+		StockCode:    "000001",
+		StockName:    "平安银行",
+		ScanInterval: time.Hour,
+	}, nil)
+	alerts := &recordingNotifier{}
+	stopChan := make(chan struct{})
+	m := &AnalyzerManager{
+		analyzers:     map[string]*stock.StockAnalyzer{"000001": analyzer},
+		stopChans:     map[string]chan struct{}{"000001": stopChan},
+		semaphore:     stock.NewPrioritySemaphore(1),
+		slowThreshold: 20 * time.Millisecond,
+		slowAlertedAt: make(map[string]time.Time),
+		alertNotifier: alerts,
+	}
+	defer close(stopChan)
+
+	// 名额被占用时轮询分析排队等待，不直接执行
+	m.semaphore.Acquire(stock.AnalysisPriorityManual)
+	m.startPollingMode()
+	waitFor(t, "轮询分析进入信号量排队", func() bool {
+		_, _, waiting := m.semaphore.Status()
+		return waiting["scheduled"] == 1
+	})
+	if atomic.LoadInt32(&requests) != 0 {
+		t.Fatal("未获得信号量名额前不应开始分析")
+	}
+
+	m.semaphore.Release()
+	waitFor(t, "慢分析告警", func() bool { return len(alerts.sent()) > 0 })
+	if atomic.LoadInt64(&m.slowAnalysis) != 1 {
+		t.Fatalf("慢分析次数应为1，实际 %d", atomic.LoadInt64(&m.slowAnalysis))
+	}
+	if message := alerts.sent()[0]; !strings.Contains(message, "慢分析告警") || !strings.Contains(message, "平安银行") {
+		t.Fatalf("告警内容不符: %s", message)
+	}
+}
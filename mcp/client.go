@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -28,6 +29,17 @@ type Client struct {
 	Model      string
 	Timeout    time.Duration
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
+	Stream     bool // 是否使用流式输出（SSE）
+
+	// ResponsePath 响应内容字段路径（如 data.text、choices.0.message.content），为空时自动探测常见格式
+	ResponsePath string
+}
+
+// Usage 单次调用消耗的token数（来自API响应的usage字段，未返回时为nil）
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 func New() *Client {
@@ -84,10 +96,37 @@ func (cfg *Client) SetClient(Client Client) {
 	cfg = &Client
 }
 
-// CallWithMessages 使用 system + user prompt 调用AI API（推荐）
+// CallWithMessages 使用 system + user prompt 调用AI API（推荐）；不返回token用量，需要统计费用时使用CallWithMessagesUsage
 func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	content, _, err := cfg.CallWithMessagesUsage(systemPrompt, userPrompt)
+	return content, err
+}
+
+// CallWithMessagesUsage 同CallWithMessages，同时返回本次调用消耗的token数（API未返回usage时为nil，响应内容提取失败时也返回）
+func (cfg *Client) CallWithMessagesUsage(systemPrompt, userPrompt string) (string, *Usage, error) {
+	return cfg.callWithRetry(func() (string, *Usage, error) {
+		return cfg.callOnce(systemPrompt, userPrompt)
+	})
+}
+
+// CallWithMessagesStream 流式调用AI API，每收到一段内容就回调onDelta，返回完整内容
+// 重试时会重新开始输出，onDelta可能收到重复片段
+func (cfg *Client) CallWithMessagesStream(systemPrompt, userPrompt string, onDelta func(delta string)) (string, error) {
+	content, _, err := cfg.CallWithMessagesStreamUsage(systemPrompt, userPrompt, onDelta)
+	return content, err
+}
+
+// CallWithMessagesStreamUsage 同CallWithMessagesStream，同时返回本次调用消耗的token数（API未返回usage时为nil）
+func (cfg *Client) CallWithMessagesStreamUsage(systemPrompt, userPrompt string, onDelta func(delta string)) (string, *Usage, error) {
+	return cfg.callWithRetry(func() (string, *Usage, error) {
+		return cfg.callOnceStream(systemPrompt, userPrompt, onDelta)
+	})
+}
+
+// callWithRetry 带重试地执行一次AI调用（网络类错误才重试）
+func (cfg *Client) callWithRetry(call func() (string, *Usage, error)) (string, *Usage, error) {
 	if cfg.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+		return "", nil, fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
 
 	// 重试配置
@@ -99,18 +138,18 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
 		}
 
-		result, err := cfg.callOnce(systemPrompt, userPrompt)
+		result, usage, err := call()
 		if err == nil {
 			if attempt > 1 {
 				fmt.Printf("✓ AI API重试成功\n")
 			}
-			return result, nil
+			return result, usage, nil
 		}
 
 		lastErr = err
-		// 如果不是网络错误，不重试
+		// 如果不是网络错误，不重试（返回失败调用已消耗的用量）
 		if !isRetryableError(err) {
-			return "", err
+			return "", usage, err
 		}
 
 		// 重试前等待
@@ -121,11 +160,11 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 		}
 	}
 
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	return "", nil, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
-// callOnce 单次调用AI API（内部使用）
-func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
+// newChatRequest 构建chat/completions请求（stream为true时请求流式响应）
+func (cfg *Client) newChatRequest(systemPrompt, userPrompt string, stream bool) (*http.Request, error) {
 	// 构建 messages 数组
 	messages := []map[string]string{}
 
@@ -150,13 +189,20 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
 		"max_tokens":  2000,
 	}
+	if stream {
+		requestBody["stream"] = true
+		// 要求在最后一个数据块中返回usage（DeepSeek/Qwen兼容模式支持；自定义API不一定支持，不发送以免报错）
+		if cfg.Provider != ProviderCustom {
+			requestBody["stream_options"] = map[string]bool{"include_usage": true}
+		}
+	}
 
 	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
 	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -170,7 +216,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	}
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -187,42 +233,135 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
 	}
 
+	return req, nil
+}
+
+// callOnce 单次调用AI API（内部使用）
+func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, *Usage, error) {
+	req, err := cfg.newChatRequest(systemPrompt, userPrompt, false)
+	if err != nil {
+		return "", nil, err
+	}
+
 	// 发送请求
 	client := &http.Client{Timeout: cfg.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return "", nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return "", nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	// 解析响应
+	// usage字段只有OpenAI兼容格式才有，解析失败时不统计；提取内容失败时同样返回用量（已计费）
 	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+		Usage *Usage `json:"usage"`
 	}
+	_ = json.Unmarshal(body, &result)
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+	// 解析响应（第三方网关格式各异，按配置的字段路径或自动探测提取内容）
+	content, err := extractContent(body, cfg.ResponsePath)
+	if err != nil {
+		return "", result.Usage, err
 	}
+	return content, result.Usage, nil
+}
 
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+// callOnceStream 单次流式调用AI API（内部使用）
+// 响应为SSE格式：每行 "data: {...}"，以 "data: [DONE]" 结束
+func (cfg *Client) callOnceStream(systemPrompt, userPrompt string, onDelta func(delta string)) (string, *Usage, error) {
+	req, err := cfg.newChatRequest(systemPrompt, userPrompt, true)
+	if err != nil {
+		return "", nil, err
 	}
+	req.Header.Set("Accept", "text/event-stream")
 
-	return result.Choices[0].Message.Content, nil
+	// 发送请求
+	client := &http.Client{Timeout: cfg.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var content strings.Builder
+	var usage *Usage
+	// 不支持流式的网关会忽略stream参数直接返回完整JSON，没有data行时按非流式响应解析
+	var plain bytes.Buffer
+	sawData := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			if !sawData {
+				plain.WriteString(line)
+			}
+			continue // 空行、注释行（": keep-alive"）等
+		}
+		sawData = true
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *Usage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			logRawResponse([]byte(data))
+			return "", nil, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage // usage在最后一个数据块中（choices为空）
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if onDelta != nil {
+			onDelta(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	if !sawData && plain.Len() > 0 {
+		full, err := extractContent(plain.Bytes(), cfg.ResponsePath)
+		if err != nil {
+			return "", nil, err
+		}
+		if onDelta != nil {
+			onDelta(full)
+		}
+		return full, nil, nil
+	}
+
+	if content.Len() == 0 {
+		return "", nil, fmt.Errorf("API返回空响应")
+	}
+	return content.String(), usage, nil
 }
 
 // isRetryableError 判断错误是否可重试
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// knownResponsePaths 自动探测时依次尝试的常见响应内容字段路径
+var knownResponsePaths = []string{
+	"choices.0.message.content",         // OpenAI兼容格式
+	"choices.0.text",                    // OpenAI Completions格式
+	"data.choices.0.message.content",    // 网关包裹一层data的OpenAI格式
+	"output.choices.0.message.content",  // 阿里云DashScope原生格式（message模式）
+	"output.text",                       // 阿里云DashScope原生格式（text模式）
+	"message.content",                   // Ollama /api/chat
+	"response",                          // Ollama /api/generate
+	"content.0.text",                    // Anthropic Messages格式
+	"candidates.0.content.parts.0.text", // Gemini格式
+	"result",                            // 百度千帆等
+	"data.content",                      // 常见网关包裹格式
+	"data.text",                         // 常见网关包裹格式
+	"data.answer",                       // 常见网关包裹格式
+	"answer",                            // Dify等应用网关
+	"text",                              // 直接返回text
+	"content",                           // 直接返回content
+	"data",                              // data本身为字符串
+}
+
+// rawResponseLogLimit 解析失败时打印的原始响应最大字节数
+const rawResponseLogLimit = 2000
+
+// extractContent 从非流式响应中提取AI回复内容：path非空时按路径读取，否则依次尝试常见格式
+func extractContent(body []byte, path string) (string, error) {
+	var root interface{}
+	if err := json.Unmarshal(body, &root); err != nil {
+		logRawResponse(body)
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if path != "" {
+		value, ok := lookupPath(root, path)
+		if !ok {
+			logRawResponse(body)
+			return "", fmt.Errorf("响应中不存在字段 %s（请检查 custom_response_path）", path)
+		}
+		content, ok := value.(string)
+		if !ok {
+			logRawResponse(body)
+			return "", fmt.Errorf("响应字段 %s 不是字符串", path)
+		}
+		return content, nil
+	}
+
+	for _, candidate := range knownResponsePaths {
+		if value, ok := lookupPath(root, candidate); ok {
+			if content, ok := value.(string); ok && content != "" {
+				return content, nil
+			}
+		}
+	}
+
+	logRawResponse(body)
+	if message := responseErrorMessage(root); message != "" {
+		return "", fmt.Errorf("API返回错误: %s", message)
+	}
+	return "", fmt.Errorf("API返回空响应或无法识别的响应格式（可配置 custom_response_path 指定内容字段）")
+}
+
+// lookupPath 按路径读取JSON字段，路径以"."分隔，数组下标写成数字（如 choices.0.message.content），
+// 也兼容 $.choices[0].message.content 写法
+func lookupPath(root interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	current := root
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// responseErrorMessage 读取响应中的错误信息（如 {"error":{"message":"..."}}），HTTP 200但业务失败的网关常见
+func responseErrorMessage(root interface{}) string {
+	for _, path := range []string{"error.message", "error", "msg", "message"} {
+		if value, ok := lookupPath(root, path); ok {
+			if message, ok := value.(string); ok && message != "" {
+				return message
+			}
+		}
+	}
+	return ""
+}
+
+// logRawResponse 打印原始响应（超长时截断），便于适配第三方网关的响应格式
+func logRawResponse(body []byte) {
+	raw := string(body)
+	if len(raw) > rawResponseLogLimit {
+		raw = raw[:rawResponseLogLimit] + "...(已截断)"
+	}
+	log.Printf("⚠️  AI响应解析失败，原始响应: %s", raw)
+}
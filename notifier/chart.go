@@ -0,0 +1,30 @@
+package notifier
+
+import (
+	"fmt"
+	"nofx/config"
+)
+
+// 看图链接提供方
+const (
+	ChartProviderTradingView = "tradingview"
+	ChartProviderXueqiu      = "xueqiu"
+)
+
+// ChartURL 生成股票的K线看图链接
+// TradingView 使用 SSE:/SZSE: 前缀的symbol；TradingView不覆盖的北交所股票使用雪球页面
+func ChartURL(code string, provider string) string {
+	market, pureCode := config.StockMarket(code)
+	if market == "" {
+		return ""
+	}
+
+	if provider != ChartProviderXueqiu && market != "BJ" {
+		exchange := "SSE"
+		if market == "SZ" {
+			exchange = "SZSE"
+		}
+		return fmt.Sprintf("https://cn.tradingview.com/chart/?symbol=%s:%s", exchange, pureCode)
+	}
+	return fmt.Sprintf("https://xueqiu.com/S/%s%s", market, pureCode)
+}
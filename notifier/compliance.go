@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 合规过滤命中敏感词后的处理方式
+const (
+	ComplianceActionReplace = "replace" // 敏感词替换为等长的*，并在末尾追加合规提示
+	ComplianceActionAppend  = "append"  // 保留原文，只在末尾追加合规提示（列出命中的敏感词）
+)
+
+// DefaultComplianceWords 内置的投资合规常见违禁措辞（承诺收益、夸大确定性、诱导交易等）
+// 只收录完整的宣传话术，不收录"无风险""内幕"这类单独出现时多为正常表述的词（如"并非没有风险""不存在内幕交易""无风险利率"）
+var DefaultComplianceWords = []string{
+	"保证盈利", "保证收益", "保本", "稳赚", "稳赚不赔", "稳赢", "包赚", "必赚", "必涨", "必涨无疑",
+	"只赚不赔", "零风险", "绝对安全", "百分之百盈利", "100%盈利", "翻倍无忧",
+	"内幕消息", "庄家消息", "坐等收钱", "闭眼买", "满仓干", "梭哈", "抄底必赚",
+}
+
+// complianceNotice 命中敏感词时追加的合规提示
+const complianceNotice = "⚠️ 合规提示：以上内容由系统自动生成，仅供参考，不构成投资建议，不承诺任何收益。市场有风险，投资需谨慎。"
+
+// ComplianceFilter 推送前对分析理由做敏感词/合规过滤
+type ComplianceFilter struct {
+	Words  []string // 敏感词（已按长度从长到短排序，优先匹配较长的词）
+	Action string   // 命中后的处理方式：replace/append
+}
+
+// NewComplianceFilter 创建合规过滤器：builtin为true时在自定义词表基础上合并内置词表，action为空时默认replace
+func NewComplianceFilter(words []string, builtin bool, action string) *ComplianceFilter {
+	seen := make(map[string]bool)
+	var merged []string
+	candidates := words
+	if builtin {
+		candidates = append(append([]string(nil), DefaultComplianceWords...), words...)
+	}
+	for _, word := range candidates {
+		word = strings.TrimSpace(word)
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		merged = append(merged, word)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return len([]rune(merged[i])) > len([]rune(merged[j]))
+	})
+	if action == "" {
+		action = ComplianceActionReplace
+	}
+	return &ComplianceFilter{Words: merged, Action: action}
+}
+
+// Apply 扫描文本中的敏感词，返回处理后的文本和命中的敏感词（未命中时原样返回）
+func (f *ComplianceFilter) Apply(text string) (string, []string) {
+	var hits []string
+	filtered := text
+	for _, word := range f.Words {
+		if !strings.Contains(filtered, word) {
+			continue
+		}
+		if f.Action != ComplianceActionReplace && containsPart(hits, word) {
+			continue // 保留原文时较短的词可能只是已命中长词的一部分（替换模式下长词已被替换，不存在该问题）
+		}
+		hits = append(hits, word)
+		if f.Action == ComplianceActionReplace {
+			filtered = strings.ReplaceAll(filtered, word, strings.Repeat("*", len([]rune(word))))
+		}
+	}
+	if len(hits) == 0 {
+		return text, nil
+	}
+
+	if f.Action == ComplianceActionReplace {
+		return filtered + "\n" + complianceNotice, hits
+	}
+	return fmt.Sprintf("%s\n%s（含不当措辞：%s）", text, complianceNotice, strings.Join(hits, "、")), hits
+}
+
+// containsPart word是否为已命中的较长敏感词的一部分（如已命中"稳赚不赔"时不再重复列出"稳赚"）
+func containsPart(hits []string, word string) bool {
+	for _, hit := range hits {
+		if strings.Contains(hit, word) {
+			return true
+		}
+	}
+	return false
+}
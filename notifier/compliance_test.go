@@ -0,0 +1,25 @@
+package notifier
+
+import "testing"
+
+func TestComplianceFilterIgnoresNegatedPhrases(t *testing.T) {
+	filter := NewComplianceFilter(nil, true, ComplianceActionReplace)
+	for _, text := range []string{
+		"短线并非没有风险，注意控制仓位",
+		"公司公告称不存在内幕交易",
+		"当前无风险利率下行，估值有支撑",
+		"不能百分之百确定突破有效",
+	} {
+		if got, hits := filter.Apply(text); len(hits) > 0 || got != text {
+			t.Errorf("正常表述不应命中: %q → %q（%v）", text, got, hits)
+		}
+	}
+}
+
+func TestComplianceFilterFlagsPromotionalPhrases(t *testing.T) {
+	filter := NewComplianceFilter(nil, true, ComplianceActionAppend)
+	_, hits := filter.Apply("据内幕消息，该股稳赚不赔")
+	if len(hits) != 2 || hits[0] != "稳赚不赔" || hits[1] != "内幕消息" {
+		t.Fatalf("命中结果不符: %v", hits)
+	}
+}
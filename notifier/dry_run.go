@@ -0,0 +1,24 @@
+package notifier
+
+import "log"
+
+// DryRunNotifier 试运行通知器：只把本应发送的通知打印到日志，不真正发送
+type DryRunNotifier struct{}
+
+// NewDryRunNotifier 创建试运行通知器
+func NewDryRunNotifier() *DryRunNotifier {
+	return &DryRunNotifier{}
+}
+
+// SendSignal 打印交易信号通知
+func (d *DryRunNotifier) SendSignal(signal *TradingSignal) error {
+	log.Printf("🧪 [试运行] 通知未发送: %s(%s) %s信号 | 价格%.2f | 信心度%d%% | 优先级%s",
+		signal.StockName, signal.StockCode, signal.Signal, signal.Price, signal.Confidence, getPriorityText(signal.Priority))
+	return nil
+}
+
+// SendMessage 打印文本通知
+func (d *DryRunNotifier) SendMessage(message string) error {
+	log.Printf("🧪 [试运行] 通知未发送: %s", message)
+	return nil
+}
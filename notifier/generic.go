@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// GenericWebhookNotifier 通用Webhook通知器
+// 以JSON格式POST到任意HTTP地址，便于下游系统（自建服务、自动化平台等）接入；payload均带schema_version（见SchemaVersion）
+type GenericWebhookNotifier struct {
+	URL              string
+	Headers          map[string]string // 自定义请求头（如鉴权Token）
+	OnlySignalChange bool              // 仅在信号翻转时回调（由AnalyzerManager比较前后两次信号后调用SendSignalChange）
+
+	client *http.Client
+}
+
+// SignalChangeEvent 信号翻转事件
+type SignalChangeEvent struct {
+	Event         string                 `json:"event"`          // 固定为 signal_change
+	SchemaVersion int                    `json:"schema_version"` // 结构版本号（见SchemaVersion）
+	StockCode     string                 `json:"stock_code"`
+	StockName     string                 `json:"stock_name"`
+	OldSignal     string                 `json:"old_signal"`
+	NewSignal     string                 `json:"new_signal"`
+	Timestamp     time.Time              `json:"timestamp"`
+	Diff          map[string]interface{} `json:"diff"`       // 前后两次结果有差异的字段：字段名 -> {"old": 旧值, "new": 新值}
+	OldResult     interface{}            `json:"old_result"` // 上一次分析结果
+	NewResult     interface{}            `json:"new_result"` // 本次分析结果
+}
+
+// NewGenericWebhookNotifier 创建通用Webhook通知器
+func NewGenericWebhookNotifier(url string, headers map[string]string, onlySignalChange bool) *GenericWebhookNotifier {
+	return &GenericWebhookNotifier{
+		URL:              url,
+		Headers:          headers,
+		OnlySignalChange: onlySignalChange,
+		client:           &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSignal 发送交易信号
+func (g *GenericWebhookNotifier) SendSignal(signal *TradingSignal) error {
+	data, err := MarshalSignal(signal)
+	if err != nil {
+		return fmt.Errorf("序列化交易信号失败: %w", err)
+	}
+	return g.post(map[string]interface{}{
+		"event":          "signal",
+		"schema_version": SchemaVersion,
+		"signal":         json.RawMessage(data),
+	})
+}
+
+// SendMessage 发送普通消息
+func (g *GenericWebhookNotifier) SendMessage(message string) error {
+	return g.post(map[string]interface{}{
+		"event":          "message",
+		"schema_version": SchemaVersion,
+		"message":        message,
+		"timestamp":      time.Now(),
+	})
+}
+
+// SendSignalChange 发送信号翻转事件
+func (g *GenericWebhookNotifier) SendSignalChange(event *SignalChangeEvent) error {
+	event.Event = "signal_change"
+	event.SchemaVersion = SchemaVersion
+	return g.post(event)
+}
+
+// post 以JSON格式POST到Webhook地址，非2xx响应视为失败
+func (g *GenericWebhookNotifier) post(payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range g.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Webhook返回异常状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MergingNotifier 合并通知：在合并窗口内收集各股票的信号，窗口结束时合并成一条汇总消息推送，减少多只股票同时出信号时的刷屏
+// 窗口内只有一条信号时按原格式单独推送；urgent信号不等待，立即单独推送；普通消息直接转发
+type MergingNotifier struct {
+	Inner  Notifier
+	Window time.Duration // 合并窗口（从窗口内第一条信号开始计时）
+
+	mutex   sync.Mutex
+	pending []*TradingSignal
+	timer   *time.Timer
+}
+
+// NewMergingNotifier 创建合并通知器
+func NewMergingNotifier(inner Notifier, window time.Duration) *MergingNotifier {
+	return &MergingNotifier{Inner: inner, Window: window}
+}
+
+// SendSignal 收集信号（同一股票在窗口内多次出信号时只保留最新的一条），urgent信号立即推送
+func (m *MergingNotifier) SendSignal(signal *TradingSignal) error {
+	if signal.Priority == PriorityUrgent {
+		return m.Inner.SendSignal(signal)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	replaced := false
+	for i, pending := range m.pending {
+		if pending.StockCode == signal.StockCode {
+			m.pending[i] = signal
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		m.pending = append(m.pending, signal)
+	}
+	if m.timer == nil {
+		m.timer = time.AfterFunc(m.Window, m.Flush)
+	}
+	return nil
+}
+
+// SendMessage 直接转发普通消息
+func (m *MergingNotifier) SendMessage(message string) error {
+	return m.Inner.SendMessage(message)
+}
+
+// Flush 立即推送窗口内收集的信号（窗口结束时自动调用）
+func (m *MergingNotifier) Flush() {
+	m.mutex.Lock()
+	signals := m.pending
+	m.pending = nil
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.mutex.Unlock()
+
+	switch len(signals) {
+	case 0:
+		return
+	case 1:
+		if err := m.Inner.SendSignal(signals[0]); err != nil {
+			log.Printf("❌ 发送通知失败: %v", err)
+		}
+	default:
+		if err := m.Inner.SendMessage(FormatSignalDigest(signals)); err != nil {
+			log.Printf("❌ 发送合并通知失败（%d只股票）: %v", len(signals), err)
+		} else {
+			log.Printf("✅ 已发送合并通知: %d只股票", len(signals))
+		}
+	}
+}
+
+// FlushPending 立即推送通知器中合并窗口内尚未推送的信号（递归处理MultiNotifier中的各通知器），在停止、重启和退出前调用，避免丢失
+func FlushPending(n Notifier) {
+	switch n := n.(type) {
+	case *MergingNotifier:
+		n.mutex.Lock()
+		pending := len(n.pending)
+		n.mutex.Unlock()
+		if pending > 0 {
+			log.Printf("📤 推送合并窗口内尚未发出的%d条信号", pending)
+		}
+		n.Flush()
+	case *MultiNotifier:
+		for _, inner := range n.Notifiers {
+			FlushPending(inner)
+		}
+	}
+}
+
+// FormatSignalDigest 把多条信号格式化为一条汇总消息（按优先级、信心度从高到低，每只股票一行）
+// 使用纯文本逐行对齐而非Markdown表格，钉钉/飞书/企业微信的文本消息都能正常显示
+func FormatSignalDigest(signals []*TradingSignal) string {
+	sorted := append([]*TradingSignal(nil), signals...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ri, rj := PriorityRank(sorted[i].Priority), PriorityRank(sorted[j].Priority); ri != rj {
+			return ri > rj
+		}
+		return sorted[i].Confidence > sorted[j].Confidence
+	})
+
+	counts := make(map[string]int)
+	for _, signal := range sorted {
+		counts[signal.Signal]++
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📋 信号汇总 - %d只股票【AI股票分析系统】\n", len(sorted)))
+	sb.WriteString(fmt.Sprintf("买入 %d / 卖出 %d / 持有 %d\n", counts["BUY"], counts["SELL"], counts["HOLD"]))
+	sb.WriteString("股票｜信号｜信心度｜现价｜目标/止损\n")
+	for _, signal := range sorted {
+		emoji := "⏸️"
+		switch signal.Signal {
+		case "BUY":
+			emoji = "🚀"
+		case "SELL":
+			emoji = "⚠️"
+		}
+		levels := "-"
+		if signal.TargetPrice > 0 || signal.StopLoss > 0 {
+			levels = fmt.Sprintf("%.2f/%.2f", signal.TargetPrice, signal.StopLoss)
+		}
+		line := fmt.Sprintf("%s %s(%s)｜%s｜%d%%｜%.2f元｜%s", emoji, signal.StockName, signal.StockCode,
+			getSignalText(signal.Signal), signal.Confidence, signal.Price, levels)
+		if PriorityRank(signal.Priority) >= PriorityRank(PriorityHigh) {
+			line += fmt.Sprintf("｜🔔%s", getPriorityText(signal.Priority))
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("时间: %s", time.Now().Format("2006-01-02 15:04:05")))
+	return sb.String()
+}
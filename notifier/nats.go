@@ -0,0 +1,261 @@
+package notifier
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsMessage 待发布的NATS消息
+type natsMessage struct {
+	subject string
+	payload []byte
+}
+
+// NATSNotifier NATS消息队列通知器
+// 将交易信号以JSON发布到 "<subject_prefix>.<股票代码>" 主题，普通消息发布到 "<subject_prefix>.message"
+// 发布是异步的：SendSignal只负责入队，后台协程负责发送和断线重连，不阻塞分析循环
+// 地址为 tls:// 、配置了TLS或服务端要求TLS（INFO中tls_required）时在握手前升级为TLS连接
+type NATSNotifier struct {
+	URL           string
+	SubjectPrefix string
+	TLS           bool // 强制使用TLS
+	TLSSkipVerify bool // 跳过服务端证书校验（仅用于自签名证书的测试环境）
+
+	queue      chan natsMessage
+	conn       net.Conn
+	mutex      sync.Mutex
+	retryDelay time.Duration // 发布失败后的重试间隔
+}
+
+const (
+	natsQueueSize      = 1000            // 待发布队列长度，队列满时丢弃新消息
+	natsDialTimeout    = 5 * time.Second // 连接超时
+	natsReconnectDelay = 5 * time.Second // 重连间隔
+	natsMaxAttempts    = 5               // 单条消息最多发布次数，仍失败时丢弃，避免一条消息堵住后续消息
+)
+
+// NewNATSNotifier 创建NATS通知器并启动后台发布协程
+func NewNATSNotifier(natsURL string, subjectPrefix string) *NATSNotifier {
+	if subjectPrefix == "" {
+		subjectPrefix = "stock.signal"
+	}
+	n := &NATSNotifier{
+		URL:           natsURL,
+		SubjectPrefix: strings.TrimSuffix(subjectPrefix, "."),
+		queue:         make(chan natsMessage, natsQueueSize),
+		retryDelay:    natsReconnectDelay,
+	}
+	go n.publishLoop()
+	return n
+}
+
+// SendSignal 发布交易信号到NATS（异步）
+func (n *NATSNotifier) SendSignal(signal *TradingSignal) error {
+	payload, err := json.Marshal(signal)
+	if err != nil {
+		return fmt.Errorf("序列化信号失败: %w", err)
+	}
+	return n.enqueue(fmt.Sprintf("%s.%s", n.SubjectPrefix, signal.StockCode), payload)
+}
+
+// SendMessage 发布普通消息到NATS（异步）
+func (n *NATSNotifier) SendMessage(message string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"message":   message,
+		"timestamp": time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+	return n.enqueue(n.SubjectPrefix+".message", payload)
+}
+
+// enqueue 将消息放入发布队列，队列满时返回错误而不是阻塞
+func (n *NATSNotifier) enqueue(subject string, payload []byte) error {
+	select {
+	case n.queue <- natsMessage{subject: subject, payload: payload}:
+		return nil
+	default:
+		return fmt.Errorf("NATS发布队列已满，丢弃消息: %s", subject)
+	}
+}
+
+// publishLoop 后台发布协程：逐条发布，失败时断开连接并在下次发布前重连，同一条消息失败natsMaxAttempts次后丢弃
+func (n *NATSNotifier) publishLoop() {
+	for msg := range n.queue {
+		for attempt := 1; ; attempt++ {
+			err := n.publish(msg)
+			if err == nil {
+				break
+			}
+			n.closeConn()
+			if attempt >= natsMaxAttempts {
+				log.Printf("❌ NATS发布失败%d次，丢弃消息: %s（%d字节）: %v", attempt, msg.subject, len(msg.payload), err)
+				break
+			}
+			log.Printf("⚠️  NATS发布失败（第%d次），%v后重试: %v", attempt, n.retryDelay, err)
+			time.Sleep(n.retryDelay)
+		}
+	}
+}
+
+// publish 发布单条消息（必要时建立连接）
+func (n *NATSNotifier) publish(msg natsMessage) error {
+	conn, err := n.getConn()
+	if err != nil {
+		return err
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if err := conn.SetWriteDeadline(time.Now().Add(natsDialTimeout)); err != nil {
+		return fmt.Errorf("设置NATS写超时失败: %w", err)
+	}
+	_, err = fmt.Fprintf(conn, "PUB %s %d\r\n%s\r\n", msg.subject, len(msg.payload), msg.payload)
+	return err
+}
+
+// getConn 获取当前连接，未连接时建立新连接
+func (n *NATSNotifier) getConn() (net.Conn, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.conn != nil {
+		return n.conn, nil
+	}
+
+	conn, err := n.connect()
+	if err != nil {
+		return nil, err
+	}
+	n.conn = conn
+	log.Printf("✓ NATS已连接: %s", n.URL)
+	return conn, nil
+}
+
+// connect 建立NATS连接：读取INFO，发送CONNECT和PING，等待PONG确认
+func (n *NATSNotifier) connect() (net.Conn, error) {
+	u, err := url.Parse(n.URL)
+	if err != nil {
+		return nil, fmt.Errorf("解析NATS地址失败: %w", err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	conn, err := net.DialTimeout("tcp", host, natsDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接NATS失败: %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(natsDialTimeout)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("设置NATS握手超时失败: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+
+	// 服务端首先发送 INFO {...}
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, fmt.Errorf("NATS握手失败: %v %s", err, strings.TrimSpace(line))
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "INFO"))), &info); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("解析NATS INFO失败: %w", err)
+	}
+
+	// 收到INFO后升级为TLS，之后的CONNECT（含账号密码）和消息都走加密连接
+	secure := n.TLS || u.Scheme == "tls" || info.TLSRequired
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: n.TLSSkipVerify,
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("NATS TLS握手失败: %w", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	options := map[string]interface{}{
+		"verbose":      false,
+		"pedantic":     false,
+		"tls_required": secure,
+		"name":         "ai-stock-notifier",
+		"lang":         "go",
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options["user"] = u.User.Username()
+			options["pass"] = password
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connectJSON, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connectJSON); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("发送CONNECT失败: %w", err)
+	}
+
+	line, err = reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "PONG") {
+		conn.Close()
+		return nil, fmt.Errorf("NATS认证失败: %v %s", err, strings.TrimSpace(line))
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("清除NATS超时失败: %w", err)
+	}
+
+	// 后台读取服务端消息：响应PING保活，读取失败时关闭连接触发重连
+	go n.readLoop(conn, reader)
+	return conn, nil
+}
+
+// readLoop 读取服务端消息，响应心跳PING
+func (n *NATSNotifier) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			n.mutex.Lock()
+			if n.conn == conn {
+				n.conn = nil
+				conn.Close()
+			}
+			n.mutex.Unlock()
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			n.mutex.Lock()
+			fmt.Fprint(conn, "PONG\r\n")
+			n.mutex.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Printf("⚠️  NATS服务端错误: %s", strings.TrimSpace(line))
+		}
+	}
+}
+
+// closeConn 关闭当前连接
+func (n *NATSNotifier) closeConn() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+}
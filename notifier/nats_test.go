@@ -0,0 +1,271 @@
+package notifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeNATS 假的NATS服务端：每个连接交给handle处理，index为连接序号（从1开始）
+func fakeNATS(t *testing.T, handle func(index int, conn net.Conn, reader *bufio.Reader)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var accepted int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			index := int(atomic.AddInt32(&accepted, 1))
+			go func() {
+				defer conn.Close()
+				handle(index, conn, bufio.NewReader(conn))
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// natsHandshake 服务端握手：发送INFO，读取CONNECT和PING，回复PONG，返回CONNECT选项
+func natsHandshake(conn net.Conn, reader *bufio.Reader) (map[string]interface{}, error) {
+	if _, err := fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n"); err != nil {
+		return nil, err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "CONNECT ") {
+		return nil, fmt.Errorf("期望CONNECT，实际 %q: %v", line, err)
+	}
+	var options map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "CONNECT "))), &options); err != nil {
+		return nil, err
+	}
+	if line, err = reader.ReadString('\n'); err != nil || line != "PING\r\n" {
+		return nil, fmt.Errorf("期望PING，实际 %q: %v", line, err)
+	}
+	_, err = fmt.Fprint(conn, "PONG\r\n")
+	return options, err
+}
+
+// readPub 读取一条PUB消息，返回主题和消息体（校验 "PUB <subject> <len>\r\n<payload>\r\n" 格式）
+func readPub(reader *bufio.Reader) (string, string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != "PUB" || !strings.HasSuffix(line, "\r\n") {
+		return "", "", fmt.Errorf("PUB行格式错误: %q", line)
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", "", err
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return "", "", err
+	}
+	if string(payload[size:]) != "\r\n" {
+		return "", "", fmt.Errorf("消息体后缺少CRLF: %q", payload)
+	}
+	return fields[1], string(payload[:size]), nil
+}
+
+// newTestNATS 创建重试间隔很短的NATS通知器
+func newTestNATS(url string) *NATSNotifier {
+	n := NewNATSNotifier(url, "stock.signal.")
+	n.retryDelay = 10 * time.Millisecond
+	return n
+}
+
+// natsEvent 服务端收到的一次握手或消息
+type natsEvent struct {
+	index   int
+	options map[string]interface{}
+	subject string
+	payload string
+	err     error
+}
+
+func receive(t *testing.T, events <-chan natsEvent) natsEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.err != nil {
+			t.Fatalf("服务端处理失败: %v", event.err)
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待NATS消息超时")
+		return natsEvent{}
+	}
+}
+
+func TestNATSConnectOptions(t *testing.T) {
+	cases := []struct {
+		name     string
+		userinfo string
+		want     map[string]string
+		absent   []string
+	}{
+		{"账号密码", "alice:secret@", map[string]string{"user": "alice", "pass": "secret"}, []string{"auth_token"}},
+		{"Token", "s3cr3t-token@", map[string]string{"auth_token": "s3cr3t-token"}, []string{"user", "pass"}},
+		{"无认证", "", nil, []string{"user", "pass", "auth_token"}},
+	}
+	for _, tc := range cases {
+		events := make(chan natsEvent, 1)
+		addr := fakeNATS(t, func(index int, conn net.Conn, reader *bufio.Reader) {
+			options, err := natsHandshake(conn, reader)
+			events <- natsEvent{options: options, err: err}
+		})
+		n := newTestNATS("nats://" + tc.userinfo + addr)
+		if err := n.SendMessage("hello"); err != nil {
+			t.Fatal(err)
+		}
+		options := receive(t, events).options
+		for key, value := range tc.want {
+			if options[key] != value {
+				t.Errorf("%s: CONNECT中 %s = %v，期望 %s", tc.name, key, options[key], value)
+			}
+		}
+		for _, key := range tc.absent {
+			if _, ok := options[key]; ok {
+				t.Errorf("%s: CONNECT中不应有 %s", tc.name, key)
+			}
+		}
+		if options["verbose"] != false || options["tls_required"] != false {
+			t.Errorf("%s: CONNECT选项不符: %v", tc.name, options)
+		}
+	}
+}
+
+func TestNATSPublishFraming(t *testing.T) {
+	events := make(chan natsEvent, 2)
+	addr := fakeNATS(t, func(index int, conn net.Conn, reader *bufio.Reader) {
+		if _, err := natsHandshake(conn, reader); err != nil {
+			events <- natsEvent{err: err}
+			return
+		}
+		for {
+			subject, payload, err := readPub(reader)
+			if err != nil {
+				return
+			}
+			events <- natsEvent{subject: subject, payload: payload}
+		}
+	})
+	n := newTestNATS("nats://" + addr)
+	if err := n.SendSignal(&TradingSignal{StockCode: "600519", StockName: "贵州茅台", Signal: "BUY"}); err != nil {
+		t.Fatal(err)
+	}
+	event := receive(t, events)
+	if event.subject != "stock.signal.600519" {
+		t.Fatalf("主题应为 stock.signal.600519，实际 %s", event.subject)
+	}
+	var signal TradingSignal
+	if err := json.Unmarshal([]byte(event.payload), &signal); err != nil || signal.StockName != "贵州茅台" {
+		t.Fatalf("消息体应为信号JSON（长度按字节计算），实际 %q: %v", event.payload, err)
+	}
+
+	if err := n.SendMessage("收盘汇总"); err != nil {
+		t.Fatal(err)
+	}
+	if event := receive(t, events); event.subject != "stock.signal.message" || !strings.Contains(event.payload, "收盘汇总") {
+		t.Fatalf("普通消息发布不符: %+v", event)
+	}
+}
+
+func TestNATSReconnectsAfterServerClose(t *testing.T) {
+	events := make(chan natsEvent, 2)
+	addr := fakeNATS(t, func(index int, conn net.Conn, reader *bufio.Reader) {
+		if _, err := natsHandshake(conn, reader); err != nil {
+			events <- natsEvent{err: err}
+			return
+		}
+		subject, payload, err := readPub(reader)
+		// 每个连接只收一条消息就由服务端关闭
+		events <- natsEvent{index: index, subject: subject, payload: payload, err: err}
+	})
+	n := newTestNATS("nats://" + addr)
+
+	n.SendMessage("第一条")
+	if event := receive(t, events); event.index != 1 {
+		t.Fatalf("第一条消息应走第1个连接，实际 %d", event.index)
+	}
+
+	// 等待读协程发现连接被关闭
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.mutex.Lock()
+		closed := n.conn == nil
+		n.mutex.Unlock()
+		if closed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("服务端关闭后应清除连接")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	n.SendMessage("第二条")
+	if event := receive(t, events); event.index != 2 || !strings.Contains(event.payload, "第二条") {
+		t.Fatalf("服务端关闭后应重连发布，实际 %+v", event)
+	}
+}
+
+func TestNATSDiscardsAfterMaxAttempts(t *testing.T) {
+	events := make(chan natsEvent, 2)
+	addr := fakeNATS(t, func(index int, conn net.Conn, reader *bufio.Reader) {
+		if index <= natsMaxAttempts {
+			fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+			return
+		}
+		if _, err := natsHandshake(conn, reader); err != nil {
+			events <- natsEvent{err: err}
+			return
+		}
+		for {
+			subject, payload, err := readPub(reader)
+			if err != nil {
+				return
+			}
+			events <- natsEvent{index: index, subject: subject, payload: payload}
+		}
+	})
+	n := newTestNATS("nats://" + addr)
+
+	// 第一条消息连续失败natsMaxAttempts次后丢弃，不堵住第二条
+	n.SendMessage("被丢弃")
+	n.SendMessage("正常发布")
+	event := receive(t, events)
+	if event.index != natsMaxAttempts+1 || !strings.Contains(event.payload, "正常发布") {
+		t.Fatalf("应在第%d次连接发布第二条消息，实际 %+v", natsMaxAttempts+1, event)
+	}
+	select {
+	case extra := <-events:
+		t.Fatalf("丢弃的消息不应再发布: %+v", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNATSConnectRejectsInvalidInfo(t *testing.T) {
+	addr := fakeNATS(t, func(index int, conn net.Conn, reader *bufio.Reader) {
+		fmt.Fprint(conn, "INFO {not json\r\n")
+	})
+	n := &NATSNotifier{URL: "nats://" + addr}
+	if _, err := n.connect(); err == nil || !strings.Contains(err.Error(), "解析NATS INFO失败") {
+		t.Fatalf("INFO无法解析时应返回错误，实际: %v", err)
+	}
+}
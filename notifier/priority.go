@@ -0,0 +1,87 @@
+package notifier
+
+// 通知优先级
+const (
+	PriorityLow    = "low"    // 低优先级（可静默）
+	PriorityNormal = "normal" // 普通
+	PriorityHigh   = "high"   // 高优先级
+	PriorityUrgent = "urgent" // 紧急（需要立即处理）
+)
+
+// priorityRank 优先级排序值，数值越大越紧急
+var priorityRank = map[string]int{
+	PriorityLow:    0,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+	PriorityUrgent: 3,
+}
+
+// PriorityRank 获取优先级排序值（未知优先级视为normal）
+func PriorityRank(priority string) int {
+	if rank, ok := priorityRank[priority]; ok {
+		return rank
+	}
+	return priorityRank[PriorityNormal]
+}
+
+// RaisePriority 优先级提升一级（urgent保持不变）
+func RaisePriority(priority string) string {
+	switch PriorityRank(priority) {
+	case priorityRank[PriorityLow]:
+		return PriorityNormal
+	case priorityRank[PriorityNormal]:
+		return PriorityHigh
+	default:
+		return PriorityUrgent
+	}
+}
+
+// DeterminePriority 根据信心度、信号类型和持仓盈亏告警决定通知优先级
+// 规则：
+//   - urgent: 持仓触及止损价，或持仓亏损超过10%，或BUY/SELL信心度≥90
+//   - high:   BUY/SELL信心度≥80，或持仓亏损超过5%
+//   - normal: 其余BUY/SELL信号，或信心度≥80的HOLD
+//   - low:    普通HOLD信号
+//
+// 除权除息日价格已调整而买入价未调整，持仓亏损和触及止损不参与判断，避免除权"跳水"被当作暴跌告警
+func DeterminePriority(signal *TradingSignal) string {
+	isAction := signal.Signal == "BUY" || signal.Signal == "SELL"
+
+	// 持仓盈亏告警
+	lossPercent := 0.0
+	if signal.PositionInfo != nil {
+		if percent, ok := positionFloat(signal.PositionInfo, "profit_loss_percent"); ok && percent < 0 {
+			lossPercent = -percent
+		}
+	}
+	hitStopLoss := signal.PositionStopLoss > 0 && signal.Price > 0 && signal.Price <= signal.PositionStopLoss
+	if signal.ExRightsDay {
+		lossPercent = 0
+		hitStopLoss = false
+	}
+
+	switch {
+	case hitStopLoss || lossPercent >= 10 || (isAction && signal.Confidence >= 90):
+		return PriorityUrgent
+	case lossPercent >= 5 || (isAction && signal.Confidence >= 80):
+		return PriorityHigh
+	case isAction || signal.Confidence >= 80:
+		return PriorityNormal
+	default:
+		return PriorityLow
+	}
+}
+
+// getPriorityText 获取优先级的中文显示文本
+func getPriorityText(priority string) string {
+	switch priority {
+	case PriorityUrgent:
+		return "紧急"
+	case PriorityHigh:
+		return "重要"
+	case PriorityLow:
+		return "低"
+	default:
+		return "普通"
+	}
+}
@@ -0,0 +1,233 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RetryEntry 待重投的通知
+type RetryEntry struct {
+	ID          string         `json:"id"`
+	Channel     string         `json:"channel"`           // 通知渠道名称（如 default/dingtalk）
+	Signal      *TradingSignal `json:"signal,omitempty"`  // 交易信号（与Message二选一）
+	Message     string         `json:"message,omitempty"` // 普通消息
+	Attempts    int            `json:"attempts"`          // 已尝试次数（含首次发送）
+	LastError   string         `json:"last_error"`
+	CreatedAt   time.Time      `json:"created_at"`
+	NextAttempt time.Time      `json:"next_attempt"`
+}
+
+// RetryQueue 持久化的通知重投队列
+// 发送失败的通知写入JSON文件，后台定期重投，成功后出队；进程重启后从文件恢复继续补发
+type RetryQueue struct {
+	File        string        // 队列文件路径
+	Interval    time.Duration // 重投检查间隔（重投失败时按次数线性退避）
+	MaxAttempts int           // 最大尝试次数，超过后丢弃
+	MaxAge      time.Duration // 最长保留时间，超过后丢弃（过期信号已无意义）
+
+	mutex    sync.Mutex
+	entries  []*RetryEntry
+	channels map[string]Notifier
+	seq      int
+}
+
+// NewRetryQueue 创建重投队列并加载文件中未发送成功的通知
+func NewRetryQueue(file string, interval time.Duration, maxAttempts int, maxAge time.Duration) (*RetryQueue, error) {
+	q := &RetryQueue{
+		File:        file,
+		Interval:    interval,
+		MaxAttempts: maxAttempts,
+		MaxAge:      maxAge,
+		channels:    make(map[string]Notifier),
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取重投队列文件失败: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &q.entries); err != nil {
+			return nil, fmt.Errorf("解析重投队列文件失败: %w", err)
+		}
+		if len(q.entries) > 0 {
+			log.Printf("📮 从重投队列恢复 %d 条未发送成功的通知", len(q.entries))
+		}
+	}
+	return q, nil
+}
+
+// Wrap 包装一个通知渠道：发送失败时自动入队重投
+// channel 用于进程重启后把队列中的通知交回同一渠道，需保持稳定
+func (q *RetryQueue) Wrap(channel string, n Notifier) Notifier {
+	q.mutex.Lock()
+	q.channels[channel] = n
+	q.mutex.Unlock()
+	return &retryNotifier{channel: channel, inner: n, queue: q}
+}
+
+// Start 启动后台重投协程
+func (q *RetryQueue) Start() {
+	go func() {
+		ticker := time.NewTicker(q.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			q.retryDue()
+		}
+	}()
+}
+
+// Len 返回队列中待重投的通知数量
+func (q *RetryQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.entries)
+}
+
+// enqueue 发送失败的通知入队
+func (q *RetryQueue) enqueue(channel string, signal *TradingSignal, message string, sendErr error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	q.seq++
+	q.entries = append(q.entries, &RetryEntry{
+		ID:          fmt.Sprintf("%d-%d", now.UnixNano(), q.seq),
+		Channel:     channel,
+		Signal:      signal,
+		Message:     message,
+		Attempts:    1,
+		LastError:   sendErr.Error(),
+		CreatedAt:   now,
+		NextAttempt: now.Add(q.Interval),
+	})
+	q.save()
+	log.Printf("📮 [%s] 通知发送失败，已加入重投队列（待重投 %d 条）", channel, len(q.entries))
+}
+
+// retryDue 重投到期的通知（发送在锁外进行，避免阻塞新的入队）
+func (q *RetryQueue) retryDue() {
+	now := time.Now()
+	q.mutex.Lock()
+	var due []*RetryEntry
+	for _, entry := range q.entries {
+		if !entry.NextAttempt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	q.mutex.Unlock()
+
+	done := make(map[string]bool)
+	for _, entry := range due {
+		if now.Sub(entry.CreatedAt) > q.MaxAge {
+			log.Printf("🗑️  [%s] 通知超过%v未发送成功，放弃重投: %s", entry.Channel, q.MaxAge, entry.describe())
+			done[entry.ID] = true
+			continue
+		}
+
+		q.mutex.Lock()
+		n, ok := q.channels[entry.Channel]
+		q.mutex.Unlock()
+		if !ok {
+			continue // 渠道未注册（如配置已变更），保留到过期
+		}
+
+		var err error
+		if entry.Signal != nil {
+			err = n.SendSignal(entry.Signal)
+		} else {
+			err = n.SendMessage(entry.Message)
+		}
+
+		q.mutex.Lock()
+		entry.Attempts++
+		if err == nil {
+			log.Printf("✅ [%s] 重投成功（第%d次尝试）: %s", entry.Channel, entry.Attempts, entry.describe())
+			done[entry.ID] = true
+		} else if entry.Attempts >= q.MaxAttempts {
+			log.Printf("🗑️  [%s] 重投%d次仍失败，放弃: %s | %v", entry.Channel, entry.Attempts, entry.describe(), err)
+			done[entry.ID] = true
+		} else {
+			entry.LastError = err.Error()
+			entry.NextAttempt = time.Now().Add(q.Interval * time.Duration(entry.Attempts))
+		}
+		q.mutex.Unlock()
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	remaining := q.entries[:0]
+	for _, entry := range q.entries {
+		if !done[entry.ID] {
+			remaining = append(remaining, entry)
+		}
+	}
+	q.entries = remaining
+	q.save()
+}
+
+// save 将队列写入文件（先写临时文件再重命名，避免写一半时进程退出导致文件损坏），调用方需持有锁
+func (q *RetryQueue) save() {
+	data, err := json.MarshalIndent(q.entries, "", "  ")
+	if err != nil {
+		log.Printf("⚠️  序列化重投队列失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(q.File), 0755); err != nil {
+		log.Printf("⚠️  创建重投队列目录失败: %v", err)
+		return
+	}
+	tmpFile := q.File + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		log.Printf("⚠️  写入重投队列文件失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmpFile, q.File); err != nil {
+		log.Printf("⚠️  保存重投队列文件失败: %v", err)
+	}
+}
+
+// describe 通知的简要描述（用于日志）
+func (e *RetryEntry) describe() string {
+	if e.Signal != nil {
+		return fmt.Sprintf("%s(%s) %s信号", e.Signal.StockName, e.Signal.StockCode, e.Signal.Signal)
+	}
+	runes := []rune(e.Message)
+	if len(runes) > 30 {
+		return string(runes[:30]) + "..."
+	}
+	return e.Message
+}
+
+// retryNotifier 发送失败时入队重投的通知器包装
+type retryNotifier struct {
+	channel string
+	inner   Notifier
+	queue   *RetryQueue
+}
+
+// SendSignal 发送交易信号，失败时入队
+func (r *retryNotifier) SendSignal(signal *TradingSignal) error {
+	err := r.inner.SendSignal(signal)
+	if err != nil {
+		r.queue.enqueue(r.channel, signal, "", err)
+	}
+	return err
+}
+
+// SendMessage 发送普通消息，失败时入队
+func (r *retryNotifier) SendMessage(message string) error {
+	err := r.inner.SendMessage(message)
+	if err != nil {
+		r.queue.enqueue(r.channel, nil, message, err)
+	}
+	return err
+}
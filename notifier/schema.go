@@ -0,0 +1,15 @@
+package notifier
+
+import "encoding/json"
+
+// SchemaVersion 对外JSON结构（TradingSignal、分析结果AnalysisResult及通用Webhook的payload）的版本号
+// 兼容约定：新增字段一律带omitempty且不改变已有字段的含义，旧消费者按原结构解析即可，版本号不变；
+// 只有删除、重命名字段或改变字段类型/含义时才递增版本号，消费方可据此切换解析逻辑。没有schema_version的旧数据按版本1处理
+const SchemaVersion = 1
+
+// MarshalSignal 按当前版本序列化交易信号（写入schema_version，不修改传入的信号）
+func MarshalSignal(signal *TradingSignal) ([]byte, error) {
+	versioned := *signal
+	versioned.SchemaVersion = SchemaVersion
+	return json.Marshal(&versioned)
+}
@@ -0,0 +1,152 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// SentryReporter 把panic和错误上报到Sentry（直接调用Sentry的envelope接口，不依赖SDK）
+// 方法对nil接收者安全：未配置DSN时传nil即可，调用方无需判断
+type SentryReporter struct {
+	dsn         string
+	endpoint    string // envelope接口地址
+	publicKey   string
+	environment string
+	serverName  string
+
+	client *http.Client
+}
+
+// sentryClientName 上报时的客户端标识
+const sentryClientName = "ai-ding-stock/1.0"
+
+// NewSentryReporter 解析DSN（格式 https://<公钥>@<主机>/<项目ID>）并创建上报器
+func NewSentryReporter(dsn, environment string) (*SentryReporter, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("Sentry DSN格式错误: %w", err)
+	}
+	if parsed.User == nil || parsed.User.Username() == "" {
+		return nil, fmt.Errorf("Sentry DSN缺少公钥")
+	}
+	path := strings.TrimSuffix(parsed.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("Sentry DSN缺少项目ID")
+	}
+
+	serverName, _ := os.Hostname()
+	return &SentryReporter{
+		dsn:         dsn,
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", parsed.Scheme, parsed.Host, path[:slash], projectID),
+		publicKey:   parsed.User.Username(),
+		environment: environment,
+		serverName:  serverName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// CaptureError 异步上报错误，tags为上下文标签（如股票代码、组合ID）
+func (r *SentryReporter) CaptureError(err error, tags map[string]string) {
+	if r == nil || err == nil {
+		return
+	}
+	r.capture("error", fmt.Sprintf("%T", err), err.Error(), "", tags)
+}
+
+// CapturePanic 异步上报recover捕获的panic及其堆栈
+func (r *SentryReporter) CapturePanic(value interface{}, stack []byte, tags map[string]string) {
+	if r == nil {
+		return
+	}
+	r.capture("fatal", "panic", fmt.Sprint(value), string(stack), tags)
+}
+
+// capture 组装事件并在后台发送（上报失败只记日志，不影响主流程）
+func (r *SentryReporter) capture(level, errType, message, stack string, tags map[string]string) {
+	eventID := newSentryEventID()
+	event := map[string]interface{}{
+		"event_id":    eventID,
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		"level":       level,
+		"platform":    "go",
+		"logger":      "ai-ding-stock",
+		"server_name": r.serverName,
+		"tags":        tags,
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{"type": errType, "value": message}},
+		},
+	}
+	if r.environment != "" {
+		event["environment"] = r.environment
+	}
+	if stack != "" {
+		event["extra"] = map[string]interface{}{"stack": stack}
+	}
+
+	go func() {
+		if err := r.send(eventID, event); err != nil {
+			log.Printf("⚠️  上报Sentry失败: %v", err)
+		}
+	}()
+}
+
+// send 以envelope格式发送事件：信封头、条目头、事件各占一行
+func (r *SentryReporter) send(eventID string, event map[string]interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %w", err)
+	}
+	header, _ := json.Marshal(map[string]interface{}{
+		"event_id": eventID,
+		"dsn":      r.dsn,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+	itemHeader, _ := json.Marshal(map[string]interface{}{"type": "event", "length": len(payload)})
+
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(itemHeader)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClientName, r.publicKey))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// newSentryEventID 生成32位十六进制的事件ID
+func newSentryEventID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
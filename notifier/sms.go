@@ -0,0 +1,321 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 短信服务商
+const (
+	SMSProviderAliyun  = "aliyun"
+	SMSProviderTencent = "tencent"
+)
+
+// smsParamMaxLen 短信模板变量的最大长度（服务商对单个变量有长度限制，超出会发送失败）
+const smsParamMaxLen = 20
+
+// SMSNotifier 短信通知器（阿里云/腾讯云模板短信）
+// 短信成本高、长度有限，只对urgent优先级的交易信号发送精简信息，普通消息不发送
+// 模板变量（按顺序）：name 股票名称、code 股票代码、signal 信号、price 现价、detail 止损/目标价或信心度
+type SMSNotifier struct {
+	Provider        string   // 服务商：aliyun/tencent
+	AccessKeyID     string   // 阿里云AccessKey ID / 腾讯云SecretId
+	AccessKeySecret string   // 阿里云AccessKey Secret / 腾讯云SecretKey
+	SignName        string   // 短信签名
+	TemplateCode    string   // 模板编号（阿里云TemplateCode / 腾讯云TemplateId）
+	PhoneNumbers    []string // 接收手机号
+	SDKAppID        string   // 腾讯云短信应用SdkAppId（仅腾讯云）
+	Region          string   // 地域（阿里云默认cn-hangzhou，腾讯云默认ap-guangzhou）
+
+	client *http.Client
+}
+
+// NewSMSNotifier 创建短信通知器
+func NewSMSNotifier(provider, accessKeyID, accessKeySecret, signName, templateCode string, phoneNumbers []string) *SMSNotifier {
+	return &SMSNotifier{
+		Provider:        provider,
+		AccessKeyID:     accessKeyID,
+		AccessKeySecret: accessKeySecret,
+		SignName:        signName,
+		TemplateCode:    templateCode,
+		PhoneNumbers:    phoneNumbers,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSignal 发送交易信号短信（仅urgent优先级）
+func (s *SMSNotifier) SendSignal(signal *TradingSignal) error {
+	if PriorityRank(signal.Priority) < PriorityRank(PriorityUrgent) {
+		return nil
+	}
+
+	params := smsTemplateParams(signal)
+	var err error
+	if s.Provider == SMSProviderTencent {
+		err = s.sendTencent(params)
+	} else {
+		err = s.sendAliyun(params)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("📱 已发送短信: %s(%s) %s信号 -> %d个号码", signal.StockName, signal.StockCode, signal.Signal, len(s.PhoneNumbers))
+	return nil
+}
+
+// SendMessage 模板短信不支持任意文本，普通消息不通过短信发送
+func (s *SMSNotifier) SendMessage(message string) error {
+	return nil
+}
+
+// smsTemplateParams 生成精简的模板变量（按name/code/signal/price/detail顺序）
+func smsTemplateParams(signal *TradingSignal) []smsParam {
+	detail := fmt.Sprintf("信心度%d%%", signal.Confidence)
+	switch {
+	case signal.PositionStopLoss > 0 && signal.Price > 0 && signal.Price <= signal.PositionStopLoss:
+		detail = fmt.Sprintf("跌破止损%.2f", signal.PositionStopLoss)
+	case signal.Signal == "BUY" && signal.TargetPrice > 0 && signal.StopLoss > 0:
+		detail = fmt.Sprintf("目标%.2f止损%.2f", signal.TargetPrice, signal.StopLoss)
+	case signal.Signal == "SELL" && signal.StopLoss > 0:
+		detail = fmt.Sprintf("止损%.2f", signal.StopLoss)
+	}
+
+	return []smsParam{
+		{"name", signal.StockName},
+		{"code", signal.StockCode},
+		{"signal", getSignalText(signal.Signal)},
+		{"price", fmt.Sprintf("%.2f", signal.Price)},
+		{"detail", detail},
+	}
+}
+
+// smsParam 模板变量
+type smsParam struct {
+	name  string
+	value string
+}
+
+// smsValue 截断超长的变量值
+func smsValue(value string) string {
+	runes := []rune(value)
+	if len(runes) > smsParamMaxLen {
+		return string(runes[:smsParamMaxLen])
+	}
+	return value
+}
+
+// sendAliyun 调用阿里云短信服务SendSms接口（RPC风格，HMAC-SHA1签名）
+func (s *SMSNotifier) sendAliyun(params []smsParam) error {
+	templateParam := make(map[string]string)
+	for _, p := range params {
+		templateParam[p.name] = smsValue(p.value)
+	}
+	templateJSON, err := json.Marshal(templateParam)
+	if err != nil {
+		return fmt.Errorf("序列化短信模板参数失败: %w", err)
+	}
+
+	region := s.Region
+	if region == "" {
+		region = "cn-hangzhou"
+	}
+	query := map[string]string{
+		"AccessKeyId":      s.AccessKeyID,
+		"Action":           "SendSms",
+		"Format":           "JSON",
+		"PhoneNumbers":     strings.Join(s.PhoneNumbers, ","),
+		"RegionId":         region,
+		"SignName":         s.SignName,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   strconv.FormatInt(time.Now().UnixNano(), 10),
+		"SignatureVersion": "1.0",
+		"TemplateCode":     s.TemplateCode,
+		"TemplateParam":    string(templateJSON),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Version":          "2017-05-25",
+	}
+
+	canonical, signature := aliyunSign(s.AccessKeySecret, query)
+	resp, err := s.client.Get("https://dysmsapi.aliyuncs.com/?Signature=" + aliyunEncode(signature) + "&" + canonical)
+	if err != nil {
+		return fmt.Errorf("发送短信请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取短信响应失败: %w", err)
+	}
+	var result struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析短信响应失败: %w", err)
+	}
+	if result.Code != "OK" {
+		return fmt.Errorf("阿里云短信错误: %s %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// aliyunSign 阿里云RPC签名：参数按名称排序编码后拼成规范化查询串，对 "GET&%2F&<编码后的查询串>" 做HMAC-SHA1（密钥为Secret加&）
+// 返回规范化查询串和Base64签名
+func aliyunSign(secret string, query map[string]string) (canonical, signature string) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, aliyunEncode(key)+"="+aliyunEncode(query[key]))
+	}
+	canonical = strings.Join(pairs, "&")
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte("GET&%2F&" + aliyunEncode(canonical)))
+	return canonical, base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunEncode 阿里云签名要求的URL编码（空格为%20，*为%2A，~不编码）
+func aliyunEncode(s string) string {
+	encoded := url.QueryEscape(s)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	return strings.ReplaceAll(encoded, "%7E", "~")
+}
+
+// sendTencent 调用腾讯云短信SendSms接口（API 3.0，TC3-HMAC-SHA256签名）
+func (s *SMSNotifier) sendTencent(params []smsParam) error {
+	const (
+		host    = "sms.tencentcloudapi.com"
+		service = "sms"
+	)
+
+	region := s.Region
+	if region == "" {
+		region = "ap-guangzhou"
+	}
+
+	var phones []string
+	for _, phone := range s.PhoneNumbers {
+		if !strings.HasPrefix(phone, "+") {
+			phone = "+86" + phone // 腾讯云要求E.164格式
+		}
+		phones = append(phones, phone)
+	}
+	var values []string
+	for _, p := range params {
+		values = append(values, smsValue(p.value))
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"PhoneNumberSet":   phones,
+		"SmsSdkAppId":      s.SDKAppID,
+		"SignName":         s.SignName,
+		"TemplateId":       s.TemplateCode,
+		"TemplateParamSet": values,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化短信请求失败: %w", err)
+	}
+
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	contentType := "application/json; charset=utf-8"
+
+	req, err := http.NewRequest(http.MethodPost, "https://"+host, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("创建短信请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Host", host)
+	req.Header.Set("X-TC-Action", "SendSms")
+	req.Header.Set("X-TC-Version", "2021-01-11")
+	req.Header.Set("X-TC-Timestamp", timestamp)
+	req.Header.Set("X-TC-Region", region)
+	req.Header.Set("Authorization", tencentAuthorization(s.AccessKeyID, s.AccessKeySecret, host, service, contentType, payload, now))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送短信请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取短信响应失败: %w", err)
+	}
+	var result struct {
+		Response struct {
+			Error *struct {
+				Code    string `json:"Code"`
+				Message string `json:"Message"`
+			} `json:"Error"`
+			SendStatusSet []struct {
+				PhoneNumber string `json:"PhoneNumber"`
+				Code        string `json:"Code"`
+				Message     string `json:"Message"`
+			} `json:"SendStatusSet"`
+		} `json:"Response"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析短信响应失败: %w", err)
+	}
+	if result.Response.Error != nil {
+		return fmt.Errorf("腾讯云短信错误: %s %s", result.Response.Error.Code, result.Response.Error.Message)
+	}
+	for _, status := range result.Response.SendStatusSet {
+		if status.Code != "Ok" {
+			return fmt.Errorf("腾讯云短信发送到%s失败: %s %s", status.PhoneNumber, status.Code, status.Message)
+		}
+	}
+	return nil
+}
+
+// tencentStringToSign 腾讯云API 3.0（TC3-HMAC-SHA256）的待签名字符串，签名content-type和host两个请求头
+func tencentStringToSign(host, service, contentType string, payload []byte, now time.Time) (stringToSign, credentialScope string) {
+	canonicalRequest := "POST\n/\n\ncontent-type:" + contentType + "\nhost:" + host + "\n\ncontent-type;host\n" + sha256Hex(payload)
+	credentialScope = now.UTC().Format("2006-01-02") + "/" + service + "/tc3_request"
+	stringToSign = "TC3-HMAC-SHA256\n" + strconv.FormatInt(now.Unix(), 10) + "\n" + credentialScope + "\n" + sha256Hex([]byte(canonicalRequest))
+	return stringToSign, credentialScope
+}
+
+// tencentAuthorization 腾讯云API 3.0的Authorization请求头：由SecretKey逐级派生日期、服务和签名密钥后对待签名字符串做HMAC-SHA256
+func tencentAuthorization(secretID, secretKey, host, service, contentType string, payload []byte, now time.Time) string {
+	stringToSign, credentialScope := tencentStringToSign(host, service, contentType, payload, now)
+
+	secretDate := hmacSHA256([]byte("TC3"+secretKey), now.UTC().Format("2006-01-02"))
+	secretService := hmacSHA256(secretDate, service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", secretID, credentialScope, signature)
+}
+
+// sha256Hex 计算SHA256并返回十六进制字符串
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
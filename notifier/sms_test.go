@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAliyunSign(t *testing.T) {
+	// 阿里云文档的签名示例
+	cases := []struct {
+		name   string
+		secret string
+		query  map[string]string
+		want   string
+	}{
+		{
+			name:   "RPC签名机制示例（DescribeRegions）",
+			secret: "testsecret",
+			query: map[string]string{
+				"AccessKeyId":      "testid",
+				"Action":           "DescribeRegions",
+				"Format":           "XML",
+				"SignatureMethod":  "HMAC-SHA1",
+				"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+				"SignatureVersion": "1.0",
+				"Timestamp":        "2016-02-23T12:46:24Z",
+				"Version":          "2014-05-26",
+			},
+			want: "OLeaidS1JvxuMvnyHOwuJ+uX5qY=",
+		},
+		{
+			name:   "短信服务签名示例（SendSms）",
+			secret: "testSecret",
+			query: map[string]string{
+				"AccessKeyId":      "testId",
+				"Action":           "SendSms",
+				"Format":           "XML",
+				"OutId":            "123",
+				"PhoneNumbers":     "15300000001",
+				"RegionId":         "cn-hangzhou",
+				"SignName":         "阿里云短信测试专用",
+				"SignatureMethod":  "HMAC-SHA1",
+				"SignatureNonce":   "45e25e9b-0a6f-4070-8c85-2956eda1b466",
+				"SignatureVersion": "1.0",
+				"TemplateCode":     "SMS_71390007",
+				"TemplateParam":    `{"customer":"test"}`,
+				"Timestamp":        "2017-07-12T02:42:19Z",
+				"Version":          "2017-05-25",
+			},
+			want: "zJDF+Lrzhj/ThnlvIToysFRq6t4=",
+		},
+	}
+	for _, tc := range cases {
+		canonical, signature := aliyunSign(tc.secret, tc.query)
+		if signature != tc.want {
+			t.Errorf("%s: 签名 = %s，期望 %s（规范化查询串: %s）", tc.name, signature, tc.want, canonical)
+		}
+	}
+}
+
+// 腾讯云API 3.0签名方法v3文档示例（CVM DescribeInstances，请求体中的中文为\u转义）
+const tencentExamplePayload = `{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`
+
+func TestTencentStringToSign(t *testing.T) {
+	got, scope := tencentStringToSign("cvm.tencentcloudapi.com", "cvm", "application/json; charset=utf-8", []byte(tencentExamplePayload), time.Unix(1551113065, 0))
+	// 文档给出的待签名字符串（最后一行为规范请求串的SHA256）
+	want := "TC3-HMAC-SHA256\n1551113065\n2019-02-25/cvm/tc3_request\n5ffe6a04c0664d6b969fab9a13bdab201d63ee709638e2749d62a09ca18d7031"
+	if got != want || scope != "2019-02-25/cvm/tc3_request" {
+		t.Fatalf("待签名字符串 =\n%s\n期望\n%s", got, want)
+	}
+	if hashed := sha256Hex([]byte(tencentExamplePayload)); hashed != "35e9c5b0e3ae67532d3c9f17ead6c90222632e5b1ff7f6e89887f1398934f064" {
+		t.Fatalf("请求体哈希 = %s", hashed)
+	}
+}
+
+func TestTencentAuthorization(t *testing.T) {
+	// 文档中的示例密钥已打码，签名值由独立实现（Python hmac）按相同的密钥派生步骤计算
+	got := tencentAuthorization("AKIDz8krbsJ5yKBZQpn74WFkmLPx3gnPhESA", "Gu5t9xGARNpq86cd98joQYCN3Cozk1qA",
+		"cvm.tencentcloudapi.com", "cvm", "application/json; charset=utf-8", []byte(tencentExamplePayload), time.Unix(1551113065, 0))
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3gnPhESA/2019-02-25/cvm/tc3_request, SignedHeaders=content-type;host, Signature=8571a3fd5c5a24cb2b8e10509e02add887e49e59370eed066496522e687e8f6b"
+	if got != want {
+		t.Fatalf("Authorization =\n%s\n期望\n%s", got, want)
+	}
+}
+
+func TestAliyunEncode(t *testing.T) {
+	cases := map[string]string{
+		"a b":     "a%20b",
+		"a*b":     "a%2Ab",
+		"a~b":     "a~b",
+		"1:2/3":   "1%3A2%2F3",
+		`{"k":1}`: "%7B%22k%22%3A1%7D",
+	}
+	for input, want := range cases {
+		if got := aliyunEncode(input); got != want {
+			t.Errorf("aliyunEncode(%q) = %s，期望 %s", input, got, want)
+		}
+	}
+	if !strings.Contains(aliyunEncode("阿里"), "%E9%98%BF") {
+		t.Error("中文应按UTF-8百分号编码")
+	}
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sparkBlocks 迷你走势图使用的块字符（从低到高）
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline 将价格序列归一化到块字符高度，生成迷你走势图（如 ▁▂▃▅▇）
+// 序列为空时返回空字符串；所有值相同时显示为中间高度的平线
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+
+	var builder strings.Builder
+	levels := len(sparkBlocks) - 1
+	for _, v := range values {
+		idx := levels / 2
+		if max > min {
+			idx = int((v-min)/(max-min)*float64(levels) + 0.5)
+		}
+		builder.WriteRune(sparkBlocks[idx])
+	}
+	return builder.String()
+}
+
+// formatPriceTrend 格式化近N日收盘价迷你走势（含区间涨跌幅），序列不足2个点时返回空字符串
+func formatPriceTrend(closes []float64) string {
+	if len(closes) < 2 {
+		return ""
+	}
+
+	first := closes[0]
+	last := closes[len(closes)-1]
+	change := 0.0
+	if first > 0 {
+		change = (last - first) / first * 100
+	}
+	sign := "+"
+	if change < 0 {
+		sign = ""
+	}
+	return fmt.Sprintf("%s  (%d日 %s%.2f%%)", Sparkline(closes), len(closes), sign, change)
+}
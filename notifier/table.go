@@ -0,0 +1,250 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 在线表格服务商
+const (
+	TableProviderFeishu   = "feishu"   // 飞书多维表格（Bitable）
+	TableProviderDingTalk = "dingtalk" // 钉钉智能表格（Notable）
+)
+
+// tableReasoningMaxLen 写入表格的分析理由最大长度
+const tableReasoningMaxLen = 500
+
+// TableNotifier 表格通知器：把每条交易信号写入飞书多维表格或钉钉智能表格的一行，便于团队协作跟踪
+// 使用企业自建应用的凭证换取访问令牌（自动缓存和续期）；表格需预先建好以下列（列名一致）：
+// 时间、股票代码、股票名称、信号、信心度、现价、目标价、止损价、风险回报比、优先级、分析理由
+// 其中信心度、现价、目标价、止损价为数字列，其余为文本列
+type TableNotifier struct {
+	Provider   string // 服务商：feishu/dingtalk
+	AppID      string // 飞书App ID / 钉钉AppKey
+	AppSecret  string // 飞书App Secret / 钉钉AppSecret
+	AppToken   string // 飞书多维表格app_token / 钉钉智能表格baseId
+	TableID    string // 飞书数据表table_id / 钉钉工作表ID或名称
+	OperatorID string // 钉钉操作人unionId（仅钉钉必填）
+
+	client      *http.Client
+	tokenMutex  sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewTableNotifier 创建表格通知器
+func NewTableNotifier(provider, appID, appSecret, appToken, tableID string) *TableNotifier {
+	return &TableNotifier{
+		Provider:  provider,
+		AppID:     appID,
+		AppSecret: appSecret,
+		AppToken:  appToken,
+		TableID:   tableID,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSignal 把交易信号写入表格的一行
+func (t *TableNotifier) SendSignal(signal *TradingSignal) error {
+	fields := tableFields(signal)
+	var err error
+	if t.Provider == TableProviderDingTalk {
+		err = t.insertDingTalk(fields)
+	} else {
+		err = t.insertFeishu(fields)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("📋 已写入表格: %s(%s) %s信号", signal.StockName, signal.StockCode, signal.Signal)
+	return nil
+}
+
+// SendMessage 表格只记录交易信号，普通消息不写入
+func (t *TableNotifier) SendMessage(message string) error {
+	return nil
+}
+
+// tableFields 生成表格一行的字段（列名 -> 值），价格为0的列不写入
+func tableFields(signal *TradingSignal) map[string]interface{} {
+	reasoning := []rune(signal.Reasoning)
+	if len(reasoning) > tableReasoningMaxLen {
+		reasoning = append(reasoning[:tableReasoningMaxLen], '…')
+	}
+
+	fields := map[string]interface{}{
+		"时间":   signal.Timestamp.Format("2006-01-02 15:04:05"),
+		"股票代码": signal.StockCode,
+		"股票名称": signal.StockName,
+		"信号":   getSignalText(signal.Signal),
+		"信心度":  signal.Confidence,
+		"现价":   signal.Price,
+		"优先级":  getPriorityText(signal.Priority),
+		"分析理由": string(reasoning),
+	}
+	if signal.TargetPrice > 0 {
+		fields["目标价"] = signal.TargetPrice
+	}
+	if signal.StopLoss > 0 {
+		fields["止损价"] = signal.StopLoss
+	}
+	if signal.RiskReward != "" {
+		fields["风险回报比"] = signal.RiskReward
+	}
+	return fields
+}
+
+// token 获取访问令牌（提前5分钟续期）
+func (t *TableNotifier) token() (string, error) {
+	t.tokenMutex.Lock()
+	defer t.tokenMutex.Unlock()
+
+	if t.accessToken != "" && time.Now().Before(t.tokenExpiry) {
+		return t.accessToken, nil
+	}
+
+	var (
+		token     string
+		expiresIn int
+		err       error
+	)
+	if t.Provider == TableProviderDingTalk {
+		token, expiresIn, err = t.fetchDingTalkToken()
+	} else {
+		token, expiresIn, err = t.fetchFeishuToken()
+	}
+	if err != nil {
+		return "", err
+	}
+	t.accessToken = token
+	t.tokenExpiry = time.Now().Add(time.Duration(expiresIn)*time.Second - 5*time.Minute)
+	return token, nil
+}
+
+// fetchFeishuToken 获取飞书tenant_access_token
+func (t *TableNotifier) fetchFeishuToken() (string, int, error) {
+	var result struct {
+		Code              int    `json:"code"`
+		Msg               string `json:"msg"`
+		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"`
+	}
+	err := t.postJSON("https://open.feishu.cn/open-apis/auth/v3/tenant_access_token/internal", nil,
+		map[string]string{"app_id": t.AppID, "app_secret": t.AppSecret}, &result)
+	if err != nil {
+		return "", 0, fmt.Errorf("获取飞书访问令牌失败: %w", err)
+	}
+	if result.Code != 0 {
+		return "", 0, fmt.Errorf("获取飞书访问令牌失败: %d %s", result.Code, result.Msg)
+	}
+	return result.TenantAccessToken, result.Expire, nil
+}
+
+// fetchDingTalkToken 获取钉钉企业内部应用accessToken
+func (t *TableNotifier) fetchDingTalkToken() (string, int, error) {
+	var result struct {
+		AccessToken string `json:"accessToken"`
+		ExpireIn    int    `json:"expireIn"`
+		Code        string `json:"code"`
+		Message     string `json:"message"`
+	}
+	err := t.postJSON("https://api.dingtalk.com/v1.0/oauth2/accessToken", nil,
+		map[string]string{"appKey": t.AppID, "appSecret": t.AppSecret}, &result)
+	if err != nil {
+		return "", 0, fmt.Errorf("获取钉钉访问令牌失败: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", 0, fmt.Errorf("获取钉钉访问令牌失败: %s %s", result.Code, result.Message)
+	}
+	return result.AccessToken, result.ExpireIn, nil
+}
+
+// insertFeishu 新增飞书多维表格记录
+func (t *TableNotifier) insertFeishu(fields map[string]interface{}) error {
+	token, err := t.token()
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	endpoint := fmt.Sprintf("https://open.feishu.cn/open-apis/bitable/v1/apps/%s/tables/%s/records",
+		url.PathEscape(t.AppToken), url.PathEscape(t.TableID))
+	err = t.postJSON(endpoint, map[string]string{"Authorization": "Bearer " + token},
+		map[string]interface{}{"fields": fields}, &result)
+	if err != nil {
+		return fmt.Errorf("写入飞书多维表格失败: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("写入飞书多维表格失败: %d %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// insertDingTalk 新增钉钉智能表格记录
+func (t *TableNotifier) insertDingTalk(fields map[string]interface{}) error {
+	token, err := t.token()
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Value []struct {
+			ID string `json:"id"`
+		} `json:"value"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	endpoint := fmt.Sprintf("https://api.dingtalk.com/v1.0/notable/bases/%s/sheets/%s/records?operatorId=%s",
+		url.PathEscape(t.AppToken), url.PathEscape(t.TableID), url.QueryEscape(t.OperatorID))
+	err = t.postJSON(endpoint, map[string]string{"x-acs-dingtalk-access-token": token},
+		map[string]interface{}{"records": []map[string]interface{}{{"fields": fields}}}, &result)
+	if err != nil {
+		return fmt.Errorf("写入钉钉智能表格失败: %w", err)
+	}
+	if len(result.Value) == 0 {
+		return fmt.Errorf("写入钉钉智能表格失败: %s %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// postJSON 发送JSON POST请求并解析JSON响应（HTTP状态码非2xx且响应无法解析时返回错误）
+func (t *TableNotifier) postJSON(endpoint string, headers map[string]string, payload interface{}, result interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("解析响应失败（HTTP %d）: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -2,10 +2,14 @@ package notifier
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -19,6 +23,7 @@ type Notifier interface {
 
 // TradingSignal 交易信号
 type TradingSignal struct {
+	SchemaVersion int                    `json:"schema_version,omitempty"` // 结构版本号（见SchemaVersion），由MarshalSignal写入
 	StockCode     string                 `json:"stock_code"`               // 股票代码
 	StockName     string                 `json:"stock_name"`               // 股票名称
 	Signal        string                 `json:"signal"`                   // 信号类型: BUY/SELL/HOLD
@@ -30,19 +35,51 @@ type TradingSignal struct {
 	RiskReward    string                 `json:"risk_reward"`              // 风险回报比
 	Timestamp     time.Time              `json:"timestamp"`                // 时间戳
 	TechnicalData map[string]interface{} `json:"technical_data,omitempty"` // 技术指标数据
-	
+
 	// 新增：持仓止盈止损价格（持仓模式下有效）
 	PositionProfitTarget float64                `json:"position_profit_target,omitempty"` // 持仓止盈价
 	PositionStopLoss     float64                `json:"position_stop_loss,omitempty"`     // 持仓止损价
 	PositionInfo         map[string]interface{} `json:"position_info,omitempty"`          // 持仓信息（可选）
+
+	// 今日为除权除息日（价格已调整，持仓亏损和止损告警不升级优先级）
+	ExRightsDay bool `json:"ex_rights_day,omitempty"`
+
+	// 通知优先级（low/normal/high/urgent），为空时按normal处理
+	Priority string `json:"priority,omitempty"`
+
+	// 上涨/震荡/下跌概率分布文本（如"上涨概率60%/震荡25%/下跌15%"），AI未给出时为空
+	Probabilities string `json:"probabilities,omitempty"`
+
+	// 短期触及涨跌停的可能性文本（如"涨停可能性高/跌停可能性低"），AI未给出时为空
+	LimitMove string `json:"limit_move,omitempty"`
+
+	// 近N日收盘价（按时间升序），用于绘制迷你走势图
+	RecentCloses []float64 `json:"recent_closes,omitempty"`
+
+	// K线看图链接（TradingView或雪球），为空时不展示
+	ChartURL string `json:"chart_url,omitempty"`
+
+	// 本系统的详情和重新分析链接（配置public_url时有效），用于卡片按钮
+	DetailURL  string `json:"detail_url,omitempty"`
+	AnalyzeURL string `json:"analyze_url,omitempty"`
+
+	// 数据质量（complete/degraded/stale），非complete时推理原因前已标注"数据受限"
+	DataQuality string `json:"data_quality,omitempty"`
 }
 
 // DingTalkNotifier 钉钉通知器
 type DingTalkNotifier struct {
-	WebhookURL string
-	Secret     string // 加签密钥（可选）
+	WebhookURL  string
+	Secret      string // 加签密钥（可选，机器人安全设置为"加签"时填写，以SEC开头）
+	MessageType string // 消息类型：markdown（默认）或 action_card
 }
 
+// 钉钉消息类型
+const (
+	DingTalkMessageMarkdown   = "markdown"
+	DingTalkMessageActionCard = "action_card"
+)
+
 // NewDingTalkNotifier 创建钉钉通知器
 func NewDingTalkNotifier(webhookURL string, secret string) *DingTalkNotifier {
 	return &DingTalkNotifier{
@@ -56,21 +93,61 @@ func (d *DingTalkNotifier) SendSignal(signal *TradingSignal) error {
 	// 构建Markdown格式的消息
 	markdown := d.formatSignalMarkdown(signal)
 
+	// 优先级映射：high/urgent在标题前加标记，urgent时@所有人
+	title := fmt.Sprintf("【%s】%s %s", signal.Signal, signal.StockName, signal.StockCode)
+	if PriorityRank(signal.Priority) >= PriorityRank(PriorityHigh) {
+		title = fmt.Sprintf("【%s】%s", getPriorityText(signal.Priority), title)
+	}
+	isAtAll := signal.Priority == PriorityUrgent
+	if isAtAll {
+		markdown += "\n\n@所有人"
+	}
+
+	// ActionCard不支持@所有人，紧急信号仍使用markdown；没有可用按钮时也使用markdown
+	if d.MessageType == DingTalkMessageActionCard && !isAtAll {
+		if buttons := dingTalkActionButtons(signal); len(buttons) > 0 {
+			return d.sendRequest(map[string]interface{}{
+				"msgtype": "actionCard",
+				"actionCard": map[string]interface{}{
+					"title":          title,
+					"text":           markdown,
+					"btnOrientation": "1", // 按钮横向排列
+					"btns":           buttons,
+				},
+			})
+		}
+	}
+
 	// 钉钉消息格式
 	message := map[string]interface{}{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
-			"title": fmt.Sprintf("【%s】%s %s", signal.Signal, signal.StockName, signal.StockCode),
+			"title": title,
 			"text":  markdown,
 		},
 		"at": map[string]interface{}{
-			"isAtAll": false,
+			"isAtAll": isAtAll,
 		},
 	}
 
 	return d.sendRequest(message)
 }
 
+// dingTalkActionButtons 构建ActionCard底部按钮（查看详情、重新分析、查看K线）
+func dingTalkActionButtons(signal *TradingSignal) []map[string]string {
+	buttons := []map[string]string{}
+	if signal.DetailURL != "" {
+		buttons = append(buttons, map[string]string{"title": "📋 查看详情", "actionURL": signal.DetailURL})
+	}
+	if signal.AnalyzeURL != "" {
+		buttons = append(buttons, map[string]string{"title": "🔄 重新分析", "actionURL": signal.AnalyzeURL})
+	}
+	if signal.ChartURL != "" {
+		buttons = append(buttons, map[string]string{"title": "📊 查看K线", "actionURL": signal.ChartURL})
+	}
+	return buttons
+}
+
 // SendMessage 发送普通消息到钉钉
 func (d *DingTalkNotifier) SendMessage(message string) error {
 	msg := map[string]interface{}{
@@ -96,6 +173,35 @@ func getSignalText(signal string) string {
 	}
 }
 
+// positionFloat 安全读取持仓信息中的数值字段
+// 键缺失或类型不符时返回false；兼容JSON反序列化得到的float64以及直接构造的int/int64
+func positionFloat(info map[string]interface{}, key string) (float64, bool) {
+	switch value := info[key].(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case json.Number:
+		f, err := value.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// positionInt 安全读取持仓信息中的整数字段（如持仓数量、持有天数）
+func positionInt(info map[string]interface{}, key string) (int, bool) {
+	value, ok := positionFloat(info, key)
+	if !ok {
+		return 0, false
+	}
+	return int(value), true
+}
+
 // formatSignalMarkdown 格式化信号为Markdown
 func (d *DingTalkNotifier) formatSignalMarkdown(signal *TradingSignal) string {
 	var emoji string
@@ -116,12 +222,24 @@ func (d *DingTalkNotifier) formatSignalMarkdown(signal *TradingSignal) string {
 	// 构建标题和系统标识
 	markdown := fmt.Sprintf("# %s %s信号 - %s(%s)\n\n", emoji, signalText, signal.StockName, signal.StockCode)
 	markdown += fmt.Sprintf("**【AI股票分析系统】**\n\n")
+	if PriorityRank(signal.Priority) >= PriorityRank(PriorityHigh) {
+		markdown += fmt.Sprintf("<font color=#FF0000>**🔔 %s通知**</font>\n\n", getPriorityText(signal.Priority))
+	}
 	markdown += fmt.Sprintf("---\n\n")
-	
+
 	// 1️⃣ 核心指标区域
 	markdown += fmt.Sprintf("**1️⃣  核心指标**\n\n")
 	markdown += fmt.Sprintf("💰 **当前价格**: %.2f元\n\n", signal.Price)
-	markdown += fmt.Sprintf("📈 **信心度**: %d%%\n\n", signal.Confidence)
+	markdown += fmt.Sprintf("📈 **信心度**: %d%% %s\n\n", signal.Confidence, confidenceLevelText(signal.Confidence))
+	if signal.Probabilities != "" {
+		markdown += fmt.Sprintf("🎲 **概率分布**: %s\n\n", signal.Probabilities)
+	}
+	if signal.LimitMove != "" {
+		markdown += fmt.Sprintf("🚦 **涨跌停**: %s\n\n", signal.LimitMove)
+	}
+	if trend := formatPriceTrend(signal.RecentCloses); trend != "" {
+		markdown += fmt.Sprintf("📉 **近期走势**: %s\n\n", trend)
+	}
 	markdown += fmt.Sprintf("---\n\n")
 
 	// 2️⃣ 交易建议区域
@@ -139,17 +257,17 @@ func (d *DingTalkNotifier) formatSignalMarkdown(signal *TradingSignal) string {
 
 		// 如果有持仓信息，添加到交易建议中
 		if signal.PositionInfo != nil {
-			if quantity, ok := signal.PositionInfo["quantity"].(int); ok && quantity > 0 {
+			if quantity, ok := positionInt(signal.PositionInfo, "quantity"); ok && quantity > 0 {
 				markdown += fmt.Sprintf("📦 **持仓数量**: %d股\n\n", quantity)
 			}
-			if buyPrice, ok := signal.PositionInfo["buy_price"].(float64); ok && buyPrice > 0 {
+			if buyPrice, ok := positionFloat(signal.PositionInfo, "buy_price"); ok && buyPrice > 0 {
 				markdown += fmt.Sprintf("💵 **购买价格**: %.2f元/股\n\n", buyPrice)
 			}
-			if currentPrice, ok := signal.PositionInfo["current_price"].(float64); ok && currentPrice > 0 {
+			if currentPrice, ok := positionFloat(signal.PositionInfo, "current_price"); ok && currentPrice > 0 {
 				markdown += fmt.Sprintf("💰 **持仓当前价格**: %.2f元/股\n\n", currentPrice)
 			}
-			if profitLoss, ok := signal.PositionInfo["profit_loss"].(float64); ok {
-				profitLossPercent := signal.PositionInfo["profit_loss_percent"].(float64)
+			if profitLoss, ok := positionFloat(signal.PositionInfo, "profit_loss"); ok {
+				profitLossPercent, _ := positionFloat(signal.PositionInfo, "profit_loss_percent")
 				profitEmoji := "📈"
 				sign := "+"
 				if profitLoss < 0 {
@@ -158,7 +276,25 @@ func (d *DingTalkNotifier) formatSignalMarkdown(signal *TradingSignal) string {
 				}
 				markdown += fmt.Sprintf("%s **浮动盈亏**: %s%.2f元 (%.2f%%)\n\n", profitEmoji, sign, profitLoss, profitLossPercent)
 			}
-			
+			if netProfitLoss, ok := positionFloat(signal.PositionInfo, "net_profit_loss"); ok {
+				netPercent, _ := positionFloat(signal.PositionInfo, "net_profit_loss_percent")
+				totalFees, _ := positionFloat(signal.PositionInfo, "total_fees")
+				markdown += fmt.Sprintf("🧾 **扣费后净盈亏**: %.2f元 (%.2f%%，费用%.2f元)\n\n", netProfitLoss, netPercent, totalFees)
+			}
+			if breakEven, ok := positionFloat(signal.PositionInfo, "break_even_price"); ok && breakEven > 0 {
+				markdown += fmt.Sprintf("⚖️ **回本价**: %.3f元\n\n", breakEven)
+			}
+			if holdingDays, ok := positionInt(signal.PositionInfo, "holding_days"); ok && holdingDays > 0 {
+				annualized, _ := positionFloat(signal.PositionInfo, "annualized_return")
+				markdown += fmt.Sprintf("📅 **持有%d天，年化收益率**: %.2f%%\n\n", holdingDays, annualized)
+				if note, ok := signal.PositionInfo["annualized_note"].(string); ok && note != "" {
+					markdown += fmt.Sprintf("> %s\n\n", note)
+				}
+			}
+			if realized, ok := positionFloat(signal.PositionInfo, "realized_profit_loss"); ok && realized != 0 {
+				markdown += fmt.Sprintf("💼 **已实现盈亏**: %.2f元\n\n", realized)
+			}
+
 			// 添加持仓止盈止损价格
 			if signal.PositionProfitTarget > 0 || signal.PositionStopLoss > 0 {
 				if signal.PositionProfitTarget > 0 {
@@ -179,6 +315,9 @@ func (d *DingTalkNotifier) formatSignalMarkdown(signal *TradingSignal) string {
 
 	// 4️⃣ 分析时间和风险提示
 	markdown += fmt.Sprintf("**4️⃣  分析时间**  %s\n\n", signal.Timestamp.Format("2006-01-02 15:04:05"))
+	if signal.ChartURL != "" {
+		markdown += fmt.Sprintf("[📊 查看K线](%s)\n\n", signal.ChartURL)
+	}
 	markdown += fmt.Sprintf("---\n\n")
 	markdown += fmt.Sprintf("‼️ **本分析仅供参考，投资有风险，决策需谨慎**")
 
@@ -194,16 +333,18 @@ func formatReasoning(reasoning string) string {
 	// 按句号换行：在每个中文句号（。！？）后添加换行
 	// 这样可以避免破坏括号内的内容，如 RSI(14)、MA5(5.88元) 等
 	result := regexp.MustCompile(`([。！？])`).ReplaceAllString(reasoning, "$1\n\n")
-	
+	// 结构化reasoning的【趋势】【量价】等小节标题加粗并另起一段
+	result = regexp.MustCompile(`\n*【([^】]+)】`).ReplaceAllString(result, "\n\n**【$1】**")
+
 	// 清理多余的空白行（3个或更多换行符替换为2个）
 	result = regexp.MustCompile(`\n{3,}`).ReplaceAllString(result, "\n\n")
-	
+
 	// 如果开头有多余的换行，移除
 	result = strings.TrimLeft(result, "\n")
-	
+
 	// 移除结尾多余的换行
 	result = strings.TrimRight(result, "\n")
-	
+
 	return result
 }
 
@@ -214,10 +355,7 @@ func (d *DingTalkNotifier) sendRequest(message map[string]interface{}) error {
 		return fmt.Errorf("序列化消息失败: %w", err)
 	}
 
-	// TODO: 如果有Secret，需要进行加签处理
-	// 钉钉加签文档: https://open.dingtalk.com/document/robots/custom-robot-access
-
-	resp, err := http.Post(d.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := http.Post(d.signedURL(time.Now().UnixMilli()), "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("发送请求失败: %w", err)
 	}
@@ -240,6 +378,27 @@ func (d *DingTalkNotifier) sendRequest(message map[string]interface{}) error {
 	return nil
 }
 
+// signedURL 配置了Secret时在Webhook地址上追加加签参数timestamp和sign，未配置时返回原地址
+// 钉钉加签文档: https://open.dingtalk.com/document/robots/custom-robot-access
+func (d *DingTalkNotifier) signedURL(timestamp int64) string {
+	if d.Secret == "" {
+		return d.WebhookURL
+	}
+	separator := "?"
+	if strings.Contains(d.WebhookURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%stimestamp=%d&sign=%s", d.WebhookURL, separator, timestamp, dingTalkSign(timestamp, d.Secret))
+}
+
+// dingTalkSign 钉钉加签：以Secret为密钥对"毫秒时间戳\nSecret"做HmacSHA256，再Base64编码、URL编码
+// 时间戳与钉钉服务器时间相差超过1小时的请求会被拒绝
+func dingTalkSign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s", timestamp, secret)
+	return url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
 // FeishuNotifier 飞书通知器
 type FeishuNotifier struct {
 	WebhookURL string
@@ -279,6 +438,58 @@ func (f *FeishuNotifier) SendMessage(message string) error {
 	return f.sendRequest(msg)
 }
 
+// 信心度分级阈值
+const (
+	highConfidence   = 80 // 高信心（≥80）
+	mediumConfidence = 60 // 中等信心（60-79），低于60为低信心
+)
+
+// confidenceLevelText 信心度分级文字
+func confidenceLevelText(confidence int) string {
+	switch {
+	case confidence >= highConfidence:
+		return "🔥高信心"
+	case confidence >= mediumConfidence:
+		return "✅中等信心"
+	default:
+		return "💤低信心"
+	}
+}
+
+// confidenceBadge 飞书卡片中的信心度徽章（带颜色）
+func confidenceBadge(confidence int) string {
+	color := "grey"
+	if confidence >= highConfidence {
+		color = "red"
+	} else if confidence >= mediumConfidence {
+		color = "green"
+	}
+	return fmt.Sprintf("<font color='%s'>%s</font>", color, confidenceLevelText(confidence))
+}
+
+// confidenceCardColor 按信号和信心度选择飞书卡片标题颜色
+// BUY：胭脂红/红/橙，SELL：绿/青绿/浅蓝，HOLD低信心时用灰色
+func confidenceCardColor(signal string, confidence int, defaultColor string) string {
+	level := 0 // 0=低 1=中 2=高
+	if confidence >= highConfidence {
+		level = 2
+	} else if confidence >= mediumConfidence {
+		level = 1
+	}
+
+	switch signal {
+	case "BUY":
+		return []string{"orange", "red", "carmine"}[level]
+	case "SELL":
+		return []string{"wathet", "turquoise", "green"}[level]
+	case "HOLD":
+		if level == 0 {
+			return "grey"
+		}
+	}
+	return defaultColor
+}
+
 // formatSignalRichText 格式化信号为飞书卡片
 func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]interface{} {
 	var emoji string
@@ -298,6 +509,18 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 		color = "grey"
 	}
 
+	// 按信心度细分颜色深浅：高信心用强烈颜色，低信心用较淡颜色
+	color = confidenceCardColor(signal.Signal, signal.Confidence, color)
+
+	// 优先级映射：urgent加红标题，high/urgent在标题前加标记
+	titlePrefix := ""
+	if PriorityRank(signal.Priority) >= PriorityRank(PriorityHigh) {
+		titlePrefix = fmt.Sprintf("【%s】", getPriorityText(signal.Priority))
+	}
+	if signal.Priority == PriorityUrgent {
+		color = "red"
+	}
+
 	// 飞书卡片消息
 	card := map[string]interface{}{
 		"config": map[string]bool{
@@ -306,7 +529,7 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 		"header": map[string]interface{}{
 			"title": map[string]interface{}{
 				"tag":     "plain_text",
-				"content": fmt.Sprintf("%s %s信号 - %s(%s)", emoji, getSignalText(signal.Signal), signal.StockName, signal.StockCode),
+				"content": fmt.Sprintf("%s%s %s信号 - %s(%s)", titlePrefix, emoji, getSignalText(signal.Signal), signal.StockName, signal.StockCode),
 			},
 			"template": color,
 		},
@@ -347,18 +570,60 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 						"is_short": true,
 						"text": map[string]string{
 							"tag":     "lark_md",
-							"content": fmt.Sprintf("📈 **信心度**\n%d%%", signal.Confidence),
+							"content": fmt.Sprintf("📈 **信心度**\n%d%% %s", signal.Confidence, confidenceBadge(signal.Confidence)),
 						},
 					},
 				},
 			},
-			// 分割线
-			{
-				"tag": "hr",
-			},
 		},
 	}
 
+	// 概率分布
+	if signal.Probabilities != "" {
+		card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
+			"tag": "div",
+			"text": map[string]string{
+				"tag":     "lark_md",
+				"content": fmt.Sprintf("🎲 **概率分布**  %s", signal.Probabilities),
+			},
+		})
+	}
+	if signal.LimitMove != "" {
+		card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
+			"tag": "div",
+			"text": map[string]string{
+				"tag":     "lark_md",
+				"content": fmt.Sprintf("🚦 **涨跌停**  %s", signal.LimitMove),
+			},
+		})
+	}
+
+	// 近期走势迷你图
+	if trend := formatPriceTrend(signal.RecentCloses); trend != "" {
+		card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
+			"tag": "div",
+			"text": map[string]string{
+				"tag":     "lark_md",
+				"content": fmt.Sprintf("📉 **近期走势**  %s", trend),
+			},
+		})
+	}
+	// 分割线
+	card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
+		"tag": "hr",
+	})
+
+	// 紧急通知@所有人
+	if signal.Priority == PriorityUrgent {
+		card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
+			"tag": "div",
+			"text": map[string]string{
+				"tag":     "lark_md",
+				"content": "<at id=all></at> **🔔 紧急通知，请及时处理**",
+			},
+		})
+	}
+
 	// 2️⃣ 添加目标价格和止损
 	if signal.TargetPrice > 0 || signal.StopLoss > 0 || signal.RiskReward != "" || signal.PositionInfo != nil {
 		// 添加标题
@@ -369,7 +634,7 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 				"content": "**2️⃣  交易建议**",
 			},
 		})
-		
+
 		fields := []map[string]interface{}{}
 		if signal.TargetPrice > 0 {
 			fields = append(fields, map[string]interface{}{
@@ -428,10 +693,10 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 				},
 			})
 		}
-		
+
 		positionFields := []map[string]interface{}{}
-		
-		if quantity, ok := signal.PositionInfo["quantity"].(int); ok && quantity > 0 {
+
+		if quantity, ok := positionInt(signal.PositionInfo, "quantity"); ok && quantity > 0 {
 			positionFields = append(positionFields, map[string]interface{}{
 				"is_short": true,
 				"text": map[string]string{
@@ -440,7 +705,7 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 				},
 			})
 		}
-		if buyPrice, ok := signal.PositionInfo["buy_price"].(float64); ok && buyPrice > 0 {
+		if buyPrice, ok := positionFloat(signal.PositionInfo, "buy_price"); ok && buyPrice > 0 {
 			positionFields = append(positionFields, map[string]interface{}{
 				"is_short": true,
 				"text": map[string]string{
@@ -449,7 +714,7 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 				},
 			})
 		}
-		if currentPrice, ok := signal.PositionInfo["current_price"].(float64); ok && currentPrice > 0 {
+		if currentPrice, ok := positionFloat(signal.PositionInfo, "current_price"); ok && currentPrice > 0 {
 			positionFields = append(positionFields, map[string]interface{}{
 				"is_short": true,
 				"text": map[string]string{
@@ -458,9 +723,9 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 				},
 			})
 		}
-		if profitLoss, ok := signal.PositionInfo["profit_loss"].(float64); ok {
+		if profitLoss, ok := positionFloat(signal.PositionInfo, "profit_loss"); ok {
 			profitLossPercent := 0.0
-			if percent, ok := signal.PositionInfo["profit_loss_percent"].(float64); ok {
+			if percent, ok := positionFloat(signal.PositionInfo, "profit_loss_percent"); ok {
 				profitLossPercent = percent
 			}
 			profitEmoji := "📈"
@@ -475,17 +740,60 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 				},
 			})
 		}
-		
+		if netProfitLoss, ok := positionFloat(signal.PositionInfo, "net_profit_loss"); ok {
+			netPercent, _ := positionFloat(signal.PositionInfo, "net_profit_loss_percent")
+			totalFees, _ := positionFloat(signal.PositionInfo, "total_fees")
+			positionFields = append(positionFields, map[string]interface{}{
+				"is_short": true,
+				"text": map[string]string{
+					"tag":     "lark_md",
+					"content": fmt.Sprintf("**扣费后净盈亏**\n%.2f元（%.2f%%）\n费用%.2f元", netProfitLoss, netPercent, totalFees),
+				},
+			})
+		}
+		if breakEven, ok := positionFloat(signal.PositionInfo, "break_even_price"); ok && breakEven > 0 {
+			positionFields = append(positionFields, map[string]interface{}{
+				"is_short": true,
+				"text": map[string]string{
+					"tag":     "lark_md",
+					"content": fmt.Sprintf("**回本价**\n%.3f元", breakEven),
+				},
+			})
+		}
+		if holdingDays, ok := positionInt(signal.PositionInfo, "holding_days"); ok && holdingDays > 0 {
+			annualized, _ := positionFloat(signal.PositionInfo, "annualized_return")
+			content := fmt.Sprintf("**年化收益率**\n%.2f%%（持有%d天）", annualized, holdingDays)
+			if note, ok := signal.PositionInfo["annualized_note"].(string); ok && note != "" {
+				content += "\n" + note
+			}
+			positionFields = append(positionFields, map[string]interface{}{
+				"is_short": true,
+				"text": map[string]string{
+					"tag":     "lark_md",
+					"content": content,
+				},
+			})
+		}
+		if realized, ok := positionFloat(signal.PositionInfo, "realized_profit_loss"); ok && realized != 0 {
+			positionFields = append(positionFields, map[string]interface{}{
+				"is_short": true,
+				"text": map[string]string{
+					"tag":     "lark_md",
+					"content": fmt.Sprintf("**已实现盈亏**\n%.2f元", realized),
+				},
+			})
+		}
+
 		if len(positionFields) > 0 {
 			card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
 				"tag":    "div",
 				"fields": positionFields,
 			})
 		}
-		
+
 		// 添加持仓止盈止损价格
 		if signal.PositionProfitTarget > 0 || signal.PositionStopLoss > 0 {
-			
+
 			profitStopFields := []map[string]interface{}{}
 			if signal.PositionProfitTarget > 0 {
 				profitStopFields = append(profitStopFields, map[string]interface{}{
@@ -549,6 +857,23 @@ func (f *FeishuNotifier) formatSignalRichText(signal *TradingSignal) map[string]
 			"content": fmt.Sprintf("**4️⃣  分析时间**  %s", signal.Timestamp.Format("2006-01-02 15:04:05")),
 		},
 	})
+	// 查看K线按钮
+	if signal.ChartURL != "" {
+		card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
+			"tag": "action",
+			"actions": []map[string]interface{}{
+				{
+					"tag": "button",
+					"text": map[string]string{
+						"tag":     "plain_text",
+						"content": "📊 查看K线",
+					},
+					"type": "default",
+					"url":  signal.ChartURL,
+				},
+			},
+		})
+	}
 	// 分割线
 	card["elements"] = append(card["elements"].([]map[string]interface{}), map[string]interface{}{
 		"tag": "hr",
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDingTalkSign(t *testing.T) {
+	const want = "aZLLrriXgn05YbwaGR7knYsLeJADjr9NwLaNNKpxh4g%3D"
+	if got := dingTalkSign(1700000000000, "SECtest"); got != want {
+		t.Fatalf("dingTalkSign = %s，期望 %s", got, want)
+	}
+}
+
+func TestDingTalkSignedURL(t *testing.T) {
+	cases := []struct {
+		webhook string
+		secret  string
+		want    string
+	}{
+		{"https://oapi.dingtalk.com/robot/send?access_token=abc", "SECtest",
+			"https://oapi.dingtalk.com/robot/send?access_token=abc&timestamp=1700000000000&sign=aZLLrriXgn05YbwaGR7knYsLeJADjr9NwLaNNKpxh4g%3D"},
+		{"https://example.com/hook", "SECtest",
+			"https://example.com/hook?timestamp=1700000000000&sign=aZLLrriXgn05YbwaGR7knYsLeJADjr9NwLaNNKpxh4g%3D"},
+		{"https://oapi.dingtalk.com/robot/send?access_token=abc", "",
+			"https://oapi.dingtalk.com/robot/send?access_token=abc"},
+	}
+	for _, tc := range cases {
+		notifier := &DingTalkNotifier{WebhookURL: tc.webhook, Secret: tc.secret}
+		if got := notifier.signedURL(1700000000000); got != tc.want {
+			t.Errorf("signedURL(%q, %q) = %s，期望 %s", tc.webhook, tc.secret, got, tc.want)
+		}
+	}
+}
+
+func TestPositionFloatSafeAccess(t *testing.T) {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"profit_loss_percent": -6.5, "quantity": 1000, "buy_price": "10.5"}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := positionFloat(decoded, "profit_loss_percent"); !ok || value != -6.5 {
+		t.Errorf("JSON反序列化的数值应可读取，实际 %v, %v", value, ok)
+	}
+	if value, ok := positionInt(decoded, "quantity"); !ok || value != 1000 {
+		t.Errorf("整数字段应可读取，实际 %v, %v", value, ok)
+	}
+	if _, ok := positionFloat(decoded, "buy_price"); ok {
+		t.Error("类型不符的字段应返回false")
+	}
+	if _, ok := positionFloat(decoded, "missing"); ok {
+		t.Error("缺失的字段应返回false")
+	}
+	if value, ok := positionFloat(map[string]interface{}{"holding_days": 12}, "holding_days"); !ok || value != 12 {
+		t.Errorf("直接构造的int字段应可读取，实际 %v, %v", value, ok)
+	}
+}
+
+func TestFormatSignalWithPartialPositionInfo(t *testing.T) {
+	// 通用Webhook或反序列化得到的信号可能只带部分持仓字段，格式化时不应panic
+	signal := &TradingSignal{
+		StockCode:    "000001",
+		StockName:    "平安银行",
+		Signal:       "SELL",
+		Confidence:   85,
+		Price:        10.2,
+		PositionInfo: map[string]interface{}{"quantity": "1000", "profit_loss": 120.0},
+	}
+	markdown := (&DingTalkNotifier{}).formatSignalMarkdown(signal)
+	if !strings.Contains(markdown, "交易建议") {
+		t.Errorf("钉钉消息应包含交易建议区域: %s", markdown)
+	}
+	(&FeishuNotifier{}).formatSignalRichText(signal)
+	if priority := DeterminePriority(signal); priority == "" {
+		t.Error("缺少盈亏比例时仍应给出优先级")
+	}
+}
@@ -0,0 +1,184 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// wecomAPIBase 企业微信服务端API地址
+const wecomAPIBase = "https://qyapi.weixin.qq.com/cgi-bin"
+
+// wecomMarkdownMaxBytes 企业微信markdown消息内容的最大字节数
+const wecomMarkdownMaxBytes = 2048
+
+// WeComAppNotifier 企业微信应用消息通知器：通过企业自建应用（corpid/corpsecret/agentid）推送markdown消息，
+// 可以发给指定成员、部门或标签，不受群机器人的频率限制；访问令牌自动缓存和续期
+type WeComAppNotifier struct {
+	CorpID     string
+	CorpSecret string
+	AgentID    int
+	ToUsers    []string // 接收成员的UserID，未指定任何接收方时发给应用可见范围内的全部成员（@all）
+	ToParties  []string // 接收部门ID
+	ToTags     []string // 接收标签ID
+
+	client      *http.Client
+	tokenMutex  sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewWeComAppNotifier 创建企业微信应用消息通知器
+func NewWeComAppNotifier(corpID, corpSecret string, agentID int, toUsers, toParties, toTags []string) *WeComAppNotifier {
+	return &WeComAppNotifier{
+		CorpID:     corpID,
+		CorpSecret: corpSecret,
+		AgentID:    agentID,
+		ToUsers:    toUsers,
+		ToParties:  toParties,
+		ToTags:     toTags,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSignal 发送交易信号（复用钉钉的markdown格式，颜色标记转换为企业微信支持的写法）
+func (w *WeComAppNotifier) SendSignal(signal *TradingSignal) error {
+	markdown := (&DingTalkNotifier{}).formatSignalMarkdown(signal)
+	markdown = strings.ReplaceAll(markdown, "<font color=#FF0000>", `<font color="warning">`)
+	return w.sendMarkdown(markdown)
+}
+
+// SendMessage 发送普通消息
+func (w *WeComAppNotifier) SendMessage(message string) error {
+	return w.sendMarkdown(message)
+}
+
+// sendMarkdown 发送markdown应用消息，令牌失效时刷新后重试一次
+func (w *WeComAppNotifier) sendMarkdown(content string) error {
+	content = truncateUTF8(content, wecomMarkdownMaxBytes)
+	message := map[string]interface{}{
+		"msgtype":  "markdown",
+		"agentid":  w.AgentID,
+		"markdown": map[string]string{"content": content},
+	}
+	if len(w.ToUsers) == 0 && len(w.ToParties) == 0 && len(w.ToTags) == 0 {
+		message["touser"] = "@all"
+	} else {
+		message["touser"] = strings.Join(w.ToUsers, "|")
+		message["toparty"] = strings.Join(w.ToParties, "|")
+		message["totag"] = strings.Join(w.ToTags, "|")
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		token, err := w.token()
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			ErrCode      int    `json:"errcode"`
+			ErrMsg       string `json:"errmsg"`
+			InvalidUser  string `json:"invaliduser"`
+			InvalidParty string `json:"invalidparty"`
+		}
+		if err := w.postJSON(wecomAPIBase+"/message/send?access_token="+url.QueryEscape(token), message, &result); err != nil {
+			return err
+		}
+		switch result.ErrCode {
+		case 0:
+			if result.InvalidUser != "" || result.InvalidParty != "" {
+				log.Printf("⚠️  企业微信部分接收方无效: 成员[%s] 部门[%s]", result.InvalidUser, result.InvalidParty)
+			}
+			return nil
+		case 40014, 42001: // 令牌无效或已过期
+			w.invalidateToken()
+			continue
+		default:
+			return fmt.Errorf("企业微信发送失败: %d %s", result.ErrCode, result.ErrMsg)
+		}
+	}
+	return fmt.Errorf("企业微信发送失败: 访问令牌刷新后仍无效")
+}
+
+// token 获取访问令牌（提前5分钟续期）
+func (w *WeComAppNotifier) token() (string, error) {
+	w.tokenMutex.Lock()
+	defer w.tokenMutex.Unlock()
+
+	if w.accessToken != "" && time.Now().Before(w.tokenExpiry) {
+		return w.accessToken, nil
+	}
+
+	resp, err := w.client.Get(fmt.Sprintf("%s/gettoken?corpid=%s&corpsecret=%s", wecomAPIBase, url.QueryEscape(w.CorpID), url.QueryEscape(w.CorpSecret)))
+	if err != nil {
+		return "", fmt.Errorf("获取企业微信access_token失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析企业微信access_token响应失败: %w", err)
+	}
+	if result.ErrCode != 0 || result.AccessToken == "" {
+		return "", fmt.Errorf("获取企业微信access_token失败: %d %s", result.ErrCode, result.ErrMsg)
+	}
+
+	w.accessToken = result.AccessToken
+	w.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 5*time.Minute)
+	return w.accessToken, nil
+}
+
+// invalidateToken 丢弃缓存的访问令牌（下次发送时重新获取）
+func (w *WeComAppNotifier) invalidateToken() {
+	w.tokenMutex.Lock()
+	defer w.tokenMutex.Unlock()
+	w.accessToken = ""
+}
+
+// postJSON 发送JSON请求并解析响应
+func (w *WeComAppNotifier) postJSON(apiURL string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+	resp, err := w.client.Post(apiURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("解析响应失败: %w, 响应: %s", err, string(respBody))
+	}
+	return nil
+}
+
+// truncateUTF8 按字节数截断文本（不截断多字节字符），超出时以省略号结尾
+func truncateUTF8(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	const ellipsis = "…"
+	cut := maxBytes - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + ellipsis
+}
@@ -0,0 +1,240 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// AccuracyWeighting 按历史命中率加权信心度的参数
+// 信号变为BUY/SELL时记录一次样本，HorizonDays个交易日后按日K线收盘价评估是否命中
+// （信号当日收盘价与HorizonDays个交易日后的收盘价比较，BUY后上涨、SELL后下跌为命中），
+// 命中率高于50%时上调信心度、低于50%时下调，命中率100%/0%时调整±MaxAdjust
+type AccuracyWeighting struct {
+	HorizonDays int            // 信号发出后多少个交易日评估
+	Window      int            // 统计最近多少个已评估的信号
+	MinSamples  int            // 已评估信号少于该数量时不调整
+	MaxAdjust   int            // 最大调整幅度（点）
+	Store       *AccuracyStore // 命中统计的持久化存储，nil时只保存在内存中
+}
+
+// AccuracyStats 历史信号命中率统计
+type AccuracyStats struct {
+	Samples int     `json:"samples"`  // 已评估的信号数（最近Window个）
+	Hits    int     `json:"hits"`     // 命中数
+	HitRate float64 `json:"hit_rate"` // 命中率（%）
+	Pending int     `json:"pending"`  // 尚未到评估时间的信号数
+}
+
+// pendingSignal 待评估的BUY/SELL信号
+type pendingSignal struct {
+	Date   string `json:"date"` // 信号日期（YYYY-MM-DD），以当日收盘价为评估基准
+	Signal string `json:"signal"`
+}
+
+// AccuracyRecord 单只股票的命中统计（持久化内容）
+type AccuracyRecord struct {
+	LastSignal string          `json:"last_signal,omitempty"` // 上一轮的信号，信号变化时才记录新样本
+	Pending    []pendingSignal `json:"pending,omitempty"`     // 待评估的信号
+	Outcomes   []bool          `json:"outcomes,omitempty"`    // 最近已评估信号的命中结果（按时间升序）
+}
+
+// AccuracyStore 命中统计的持久化存储（JSON文件：股票代码 -> 统计），重启后继续累计
+type AccuracyStore struct {
+	path    string
+	mutex   sync.Mutex
+	records map[string]AccuracyRecord
+}
+
+// NewAccuracyStore 创建命中统计存储并加载已有的文件（文件不存在时为空）
+func NewAccuracyStore(path string) (*AccuracyStore, error) {
+	store := &AccuracyStore{path: path, records: make(map[string]AccuracyRecord)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("读取命中统计文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.records); err != nil {
+		return store, fmt.Errorf("命中统计文件格式错误: %w", err)
+	}
+	return store, nil
+}
+
+// Get 获取股票的命中统计
+func (s *AccuracyStore) Get(code string) AccuracyRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.records[code]
+}
+
+// Set 更新股票的命中统计并写入文件（先写临时文件再重命名）
+func (s *AccuracyStore) Set(code string, record AccuracyRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[code] = record
+
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化命中统计失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建命中统计目录失败: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入命中统计文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入命中统计文件失败: %w", err)
+	}
+	return nil
+}
+
+// Adjust 根据命中统计计算信心度调整量（样本不足时为0）
+func (w *AccuracyWeighting) Adjust(stats AccuracyStats) int {
+	if stats.Samples < w.MinSamples || stats.Samples == 0 {
+		return 0
+	}
+	return int(math.Round((stats.HitRate - 50) / 50 * float64(w.MaxAdjust)))
+}
+
+// evaluatePendingSignals 按已收盘的日K线评估待评估信号：信号日之后已有horizon根日K线的评估命中，
+// 信号日不在K线中（已超出K线窗口）的丢弃，其余继续等待
+func evaluatePendingSignals(pending []pendingSignal, closedBars []KlineItem, horizon int) (remaining []pendingSignal, outcomes []bool) {
+	index := make(map[string]int, len(closedBars))
+	for i, item := range closedBars {
+		index[item.Time.In(chinaTZ).Format("2006-01-02")] = i
+	}
+	for _, p := range pending {
+		i, ok := index[p.Date]
+		if !ok {
+			if len(closedBars) > 0 && p.Date < closedBars[0].Time.In(chinaTZ).Format("2006-01-02") {
+				continue
+			}
+			remaining = append(remaining, p)
+			continue
+		}
+		if i+horizon >= len(closedBars) {
+			remaining = append(remaining, p)
+			continue
+		}
+		base, later := closedBars[i].Close, closedBars[i+horizon].Close
+		outcomes = append(outcomes, (p.Signal == "BUY" && later > base) || (p.Signal == "SELL" && later < base))
+	}
+	return remaining, outcomes
+}
+
+// closedDayBars 已收盘的日K线：当日15:00前不含当日K线（盘中的当日K线收盘价随时变化）
+func (a *StockAnalyzer) closedDayBars() ([]KlineItem, error) {
+	kline, err := a.getKline("day", a.dayKlineCount())
+	if err != nil {
+		return nil, err
+	}
+	now := a.now().In(chinaTZ)
+	today := now.Format("2006-01-02")
+	bars := kline.List
+	for len(bars) > 0 {
+		day := bars[len(bars)-1].Time.In(chinaTZ).Format("2006-01-02")
+		if day < today || (day == today && now.Hour() >= 15) {
+			break
+		}
+		bars = bars[:len(bars)-1]
+	}
+	return bars, nil
+}
+
+// applyAccuracyWeighting 评估到期的历史信号，按命中率计算本轮的加权信心度，并在信号变为BUY/SELL时记录样本待后续评估
+// 未启用时不做任何处理（AdjustedConfidence为0，通知决策使用原始信心度）；历史回放不计入统计
+func (a *StockAnalyzer) applyAccuracyWeighting(result *AnalysisResult) {
+	weighting := a.AnalysisConfig.AccuracyWeighting
+	if weighting == nil || result.InsufficientData || result.ReplayAt != nil {
+		return
+	}
+	bars, err := a.closedDayBars()
+	if err != nil {
+		tracef(result.TraceID, "⚠️  [%s] 获取日K线失败，本轮不评估历史信号: %v", a.AnalysisConfig.StockName, err)
+	}
+	code := a.AnalysisConfig.StockCode
+
+	a.mutex.Lock()
+	if !a.accuracyLoaded {
+		a.accuracyLoaded = true
+		if weighting.Store != nil {
+			a.accuracy = weighting.Store.Get(code)
+		}
+	}
+	record := &a.accuracy
+	if err == nil {
+		var outcomes []bool
+		record.Pending, outcomes = evaluatePendingSignals(record.Pending, bars, weighting.HorizonDays)
+		record.Outcomes = append(record.Outcomes, outcomes...)
+	}
+	if len(record.Outcomes) > weighting.Window {
+		record.Outcomes = record.Outcomes[len(record.Outcomes)-weighting.Window:]
+	}
+
+	stats := AccuracyStats{Samples: len(record.Outcomes), Pending: len(record.Pending)}
+	for _, hit := range record.Outcomes {
+		if hit {
+			stats.Hits++
+		}
+	}
+	if stats.Samples > 0 {
+		stats.HitRate = math.Round(float64(stats.Hits)/float64(stats.Samples)*1000) / 10
+	}
+
+	// 信号变为BUY/SELL时记录样本（同一信号持续多轮只记一次），同日同信号不重复记录
+	if (result.Signal == "BUY" || result.Signal == "SELL") && result.Signal != record.LastSignal {
+		date := a.now().In(chinaTZ).Format("2006-01-02")
+		duplicate := false
+		for _, p := range record.Pending {
+			if p.Date == date && p.Signal == result.Signal {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			record.Pending = append(record.Pending, pendingSignal{Date: date, Signal: result.Signal})
+		}
+	}
+	record.LastSignal = result.Signal
+	snapshot := AccuracyRecord{
+		LastSignal: record.LastSignal,
+		Pending:    append([]pendingSignal(nil), record.Pending...),
+		Outcomes:   append([]bool(nil), record.Outcomes...),
+	}
+	a.mutex.Unlock()
+
+	if weighting.Store != nil {
+		if err := weighting.Store.Set(code, snapshot); err != nil {
+			log.Printf("⚠️  [%s] 保存命中统计失败: %v", a.AnalysisConfig.StockName, err)
+		}
+	}
+
+	adjusted := result.Confidence + weighting.Adjust(stats)
+	if adjusted < 0 {
+		adjusted = 0
+	} else if adjusted > 100 {
+		adjusted = 100
+	}
+	result.AdjustedConfidence = adjusted
+	result.Accuracy = &stats
+	if adjusted != result.Confidence {
+		tracef(result.TraceID, "🎯 [%s] 历史命中率%.1f%%（%d/%d），信心度 %d → %d",
+			a.AnalysisConfig.StockName, stats.HitRate, stats.Hits, stats.Samples, result.Confidence, adjusted)
+	}
+}
+
+// decisionConfidence 通知决策使用的信心度：启用命中率加权时为加权信心度，否则为原始信心度
+func decisionConfidence(result *AnalysisResult) int {
+	if result.Accuracy != nil {
+		return result.AdjustedConfidence
+	}
+	return result.Confidence
+}
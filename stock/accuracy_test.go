@@ -0,0 +1,62 @@
+package stock
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func dayBars(closes ...int) []KlineItem {
+	var bars []KlineItem
+	for i, price := range closes {
+		bars = append(bars, KlineItem{Close: price, Time: time.Date(2025, 6, 9+i, 15, 0, 0, 0, chinaTZ)})
+	}
+	return bars
+}
+
+func TestEvaluatePendingSignalsUsesClosesOverHorizon(t *testing.T) {
+	bars := dayBars(1000, 900, 1100, 1200)
+	pending := []pendingSignal{
+		{Date: "2025-06-09", Signal: "BUY"},  // 06-09收盘1000，2个交易日后1100：命中
+		{Date: "2025-06-10", Signal: "SELL"}, // 06-10收盘900，2个交易日后1200：未命中
+		{Date: "2025-06-11", Signal: "BUY"},  // 之后只有1根K线，继续等待
+		{Date: "2025-06-01", Signal: "BUY"},  // 已超出K线窗口，丢弃
+	}
+	remaining, outcomes := evaluatePendingSignals(pending, bars, 2)
+	if len(outcomes) != 2 || !outcomes[0] || outcomes[1] {
+		t.Fatalf("评估结果不符: %v", outcomes)
+	}
+	if len(remaining) != 1 || remaining[0].Date != "2025-06-11" {
+		t.Fatalf("待评估信号不符: %+v", remaining)
+	}
+}
+
+func TestApplyAccuracyWeightingSamplesSignalChangesAndPersists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "accuracy.json")
+	store, err := NewAccuracyStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewTDXClient("http://127.0.0.1:0")
+	client.klineCache[klineCacheKey("000001", "day", 60)] = klineCacheEntry{data: &KlineData{List: dayBars(1000)}, expiresAt: time.Now().Add(time.Hour)}
+
+	weighting := &AccuracyWeighting{HorizonDays: 2, Window: 20, MinSamples: 1, MaxAdjust: 10, Store: store}
+	analyzer := NewStockAnalyzer(client, nil, nil, &AnalysisConfig{StockCode: "000001", StockName: "平安银行", AccuracyWeighting: weighting}, nil)
+	analyzer.Clock = FixedClock(time.Date(2025, 6, 10, 10, 0, 0, 0, chinaTZ))
+
+	for _, signal := range []string{"BUY", "BUY", "HOLD", "BUY"} {
+		analyzer.applyAccuracyWeighting(&AnalysisResult{Signal: signal, Confidence: 70})
+	}
+	// 持续的BUY只记一次，HOLD后再次变为同日BUY不重复记录
+	if got := store.Get("000001"); len(got.Pending) != 1 || got.LastSignal != "BUY" {
+		t.Fatalf("样本记录不符: %+v", got)
+	}
+
+	reloaded, err := NewAccuracyStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Get("000001"); len(got.Pending) != 1 || got.Pending[0].Date != "2025-06-10" {
+		t.Fatalf("重启后应恢复命中统计: %+v", got)
+	}
+}
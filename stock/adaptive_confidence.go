@@ -0,0 +1,50 @@
+package stock
+
+import (
+	"math"
+)
+
+// AdaptiveConfidence 自适应信心度阈值参数
+// 高波动时提高门槛减少噪声，低波动时降低门槛避免漏信号
+type AdaptiveConfidence struct {
+	BaseVolatility   float64 // 基准日波动率（%）
+	PointsPerPercent float64 // 波动率每偏离基准1个百分点调整的阈值点数
+	MaxAdjust        int     // 最大调整幅度（点）
+}
+
+// Adjust 根据日波动率（%）计算阈值调整量
+func (c *AdaptiveConfidence) Adjust(volatility float64) int {
+	adjust := int(math.Round((volatility - c.BaseVolatility) * c.PointsPerPercent))
+	if adjust > c.MaxAdjust {
+		adjust = c.MaxAdjust
+	} else if adjust < -c.MaxAdjust {
+		adjust = -c.MaxAdjust
+	}
+	return adjust
+}
+
+// effectiveMinConfidence 计算本轮生效的信心度阈值（未启用自适应或波动率缺失时为配置值）
+func (a *StockAnalyzer) effectiveMinConfidence(technical map[string]interface{}, traceID string) int {
+	base := a.AnalysisConfig.MinConfidence
+	adaptive := a.AnalysisConfig.AdaptiveConfidence
+	if adaptive == nil {
+		return base
+	}
+
+	volatility, ok := IndicatorValue(technical, "volatility_20d")
+	if !ok {
+		return base
+	}
+
+	threshold := base + adaptive.Adjust(volatility)
+	if threshold < 0 {
+		threshold = 0
+	} else if threshold > 100 {
+		threshold = 100
+	}
+	if threshold != base {
+		tracef(traceID, "🎚️  [%s] 近20日波动率%.2f%%（基准%.2f%%），信心度阈值 %d → %d",
+			a.AnalysisConfig.StockName, volatility, adaptive.BaseVolatility, base, threshold)
+	}
+	return threshold
+}
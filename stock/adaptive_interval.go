@@ -0,0 +1,108 @@
+package stock
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// A股连续竞价时段（自当天0点起的分钟数）：9:30-11:30、13:00-15:00，全天共240分钟
+var activitySessions = [][2]int{{9*60 + 30, 11*60 + 30}, {13 * 60, 15 * 60}}
+
+const activityDayMinutes = 240
+
+// minActivityElapsed 折算量比时已过交易时长的下限（分钟），避免开盘头几分钟量比被放大得过于夸张
+const minActivityElapsed = 15
+
+// AdaptiveInterval 扫描间隔按市场活跃度自适应的参数
+// 活跃度1表示正常：扫描间隔 = 配置间隔 / 活跃度，限制在[Min, Max]之间（开盘、尾盘放量时缩短，午盘平淡时拉长）
+type AdaptiveInterval struct {
+	Min time.Duration // 最短扫描间隔
+	Max time.Duration // 最长扫描间隔
+}
+
+// Interval 按活跃度计算扫描间隔
+func (c *AdaptiveInterval) Interval(base time.Duration, activity float64) time.Duration {
+	if activity <= 0 {
+		return base
+	}
+	interval := time.Duration(float64(base) / activity).Round(30 * time.Second) // 取整到30秒，避免活跃度微小波动导致间隔频繁变化
+	if interval < c.Min {
+		interval = c.Min
+	} else if interval > c.Max {
+		interval = c.Max
+	}
+	return interval
+}
+
+// tradingMinutesElapsed 当天截至t已过的连续竞价分钟数（0-240）
+func tradingMinutesElapsed(t time.Time) int {
+	minute := t.Hour()*60 + t.Minute()
+	elapsed := 0
+	for _, session := range activitySessions {
+		switch {
+		case minute >= session[1]:
+			elapsed += session[1] - session[0]
+		case minute > session[0]:
+			elapsed += minute - session[0]
+		}
+	}
+	return elapsed
+}
+
+// intradayVolumeRatio 分析结果中的量比（计算时已按已过交易时长折算），1表示与近5日同时段均量持平
+func intradayVolumeRatio(result *AnalysisResult) (float64, bool) {
+	ratio, ok := IndicatorValue(result.TechnicalData, "volume_ratio")
+	if !ok || ratio <= 0 {
+		return 0, false
+	}
+	return ratio, true
+}
+
+// StockActivity 个股活跃度（1表示正常）：折算量比，与自上次分析以来的价格变动相对近20日波动率的比值，取较大者
+// latest为最新一次分析结果，previous为上一次（可为nil）；最新结果不是今天的（如非交易日）时返回false
+func StockActivity(latest, previous *AnalysisResult, now time.Time) (float64, bool) {
+	if latest == nil || !sameDay(latest.Timestamp, now) {
+		return 0, false
+	}
+
+	activity, ok := intradayVolumeRatio(latest)
+
+	// 价格变动：日波动率按时间平方根折算到两次分析之间的交易时长
+	volatility, hasVolatility := IndicatorValue(latest.TechnicalData, "volatility_20d")
+	if previous != nil && hasVolatility && volatility > 0 && sameDay(previous.Timestamp, latest.Timestamp) &&
+		previous.CurrentPrice > 0 && latest.CurrentPrice > 0 {
+		minutes := tradingMinutesElapsed(latest.Timestamp) - tradingMinutesElapsed(previous.Timestamp)
+		if minutes > 0 {
+			move := math.Abs(latest.CurrentPrice-previous.CurrentPrice) / previous.CurrentPrice * 100
+			expected := volatility * math.Sqrt(float64(minutes)/activityDayMinutes)
+			if ratio := move / expected; !ok || ratio > activity {
+				activity, ok = ratio, true
+			}
+		}
+	}
+	return activity, ok
+}
+
+// MarketActivity 市场整体活跃度（1表示正常）：各股票今日最新结果的折算量比的中位数
+// 没有大盘指数数据，以所监控股票的整体成交量近似大盘成交量
+func MarketActivity(latest []*AnalysisResult, now time.Time) (float64, bool) {
+	var ratios []float64
+	for _, result := range latest {
+		if result == nil || !sameDay(result.Timestamp, now) {
+			continue
+		}
+		if ratio, ok := intradayVolumeRatio(result); ok {
+			ratios = append(ratios, ratio)
+		}
+	}
+	if len(ratios) == 0 {
+		return 0, false
+	}
+	sort.Float64s(ratios)
+	middle := len(ratios) / 2
+	if len(ratios)%2 == 0 {
+		return (ratios[middle-1] + ratios[middle]) / 2, true
+	}
+	return ratios[middle], true
+}
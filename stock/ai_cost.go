@@ -0,0 +1,171 @@
+package stock
+
+import (
+	"nofx/mcp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// aiCostRetentionDays AI费用统计在内存中保留的天数
+const aiCostRetentionDays = 90
+
+// AIPrice AI调用单价（元/百万token）
+type AIPrice struct {
+	Prompt     float64 `json:"prompt"`     // 输入token单价
+	Completion float64 `json:"completion"` // 输出token单价
+}
+
+// Cost 按单价估算费用（元）
+func (p AIPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// AICostItem 一段时间内的AI调用累计（按股票或合计）
+type AICostItem struct {
+	StockCode        string  `json:"stock_code,omitempty"`
+	StockName        string  `json:"stock_name,omitempty"`
+	Calls            int     `json:"calls"`             // 返回了usage的AI调用次数
+	PromptTokens     int     `json:"prompt_tokens"`     // 输入token数
+	CompletionTokens int     `json:"completion_tokens"` // 输出token数
+	Cost             float64 `json:"cost"`              // 估算费用（元）
+}
+
+func (item *AICostItem) add(other AICostItem) {
+	item.Calls += other.Calls
+	item.PromptTokens += other.PromptTokens
+	item.CompletionTokens += other.CompletionTokens
+	item.Cost += other.Cost
+}
+
+// AICostDay 某一天的AI调用累计
+type AICostDay struct {
+	Date string `json:"date"` // 北京时间日期（2006-01-02）
+	AICostItem
+	Stocks []AICostItem `json:"stocks"` // 按费用从高到低
+}
+
+// AICostReport AI费用统计
+type AICostReport struct {
+	Provider string       `json:"provider"`
+	Price    AIPrice      `json:"price"`  // 估算使用的单价（未配置时为0，只统计token数）
+	Total    AICostItem   `json:"total"`  // 统计范围内的合计
+	Days     []AICostDay  `json:"days"`   // 按天（最新的在前）
+	Stocks   []AICostItem `json:"stocks"` // 按股票（统计范围内合计，按费用从高到低）
+}
+
+// AICostTracker 按天、按股票累计AI调用的token数和估算费用（只在内存中保留最近90天，重启后重新统计）
+type AICostTracker struct {
+	Provider string
+	Price    AIPrice
+
+	mutex sync.Mutex
+	days  map[string]map[string]*AICostItem // 日期 -> 股票代码 -> 累计
+}
+
+// NewAICostTracker 创建AI费用统计
+func NewAICostTracker(provider string, price AIPrice) *AICostTracker {
+	return &AICostTracker{
+		Provider: provider,
+		Price:    price,
+		days:     make(map[string]map[string]*AICostItem),
+	}
+}
+
+// Record 记录一次AI调用的用量（API未返回usage时忽略），按调用时间的北京时间日期归档
+// 在AI调用处记录，分析、翻译、假设分析和历史回放都计入，AI响应解析失败的调用同样计费
+func (t *AICostTracker) Record(code, name string, at time.Time, usage *mcp.Usage) {
+	if usage == nil || usage.PromptTokens+usage.CompletionTokens == 0 {
+		return
+	}
+	date := at.In(chinaTZ).Format("2006-01-02")
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stocks := t.days[date]
+	if stocks == nil {
+		stocks = make(map[string]*AICostItem)
+		t.days[date] = stocks
+		t.pruneLocked()
+	}
+	item := stocks[code]
+	if item == nil {
+		item = &AICostItem{StockCode: code}
+		stocks[code] = item
+	}
+	item.StockName = name
+	item.add(AICostItem{
+		Calls:            1,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Cost:             t.Price.Cost(usage.PromptTokens, usage.CompletionTokens),
+	})
+}
+
+// pruneLocked 淘汰超过保留天数的记录（调用方持有锁）
+func (t *AICostTracker) pruneLocked() {
+	cutoff := time.Now().In(chinaTZ).AddDate(0, 0, -aiCostRetentionDays).Format("2006-01-02")
+	for date := range t.days {
+		if date < cutoff {
+			delete(t.days, date)
+		}
+	}
+}
+
+// Report 汇总最近days天（含今天）的费用，days<=0时汇总全部保留的记录
+func (t *AICostTracker) Report(days int) AICostReport {
+	report := AICostReport{Provider: t.Provider, Price: t.Price, Days: []AICostDay{}, Stocks: []AICostItem{}}
+	cutoff := ""
+	if days > 0 {
+		cutoff = time.Now().In(chinaTZ).AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	byStock := make(map[string]*AICostItem)
+	for date, stocks := range t.days {
+		if date < cutoff {
+			continue
+		}
+		day := AICostDay{Date: date}
+		for code, item := range stocks {
+			day.Stocks = append(day.Stocks, *item)
+			day.add(*item)
+
+			total := byStock[code]
+			if total == nil {
+				total = &AICostItem{StockCode: code}
+				byStock[code] = total
+			}
+			total.StockName = item.StockName
+			total.add(*item)
+		}
+		sortAICostItems(day.Stocks)
+		report.Days = append(report.Days, day)
+		report.Total.add(day.AICostItem)
+	}
+	for _, item := range byStock {
+		report.Stocks = append(report.Stocks, *item)
+	}
+
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date > report.Days[j].Date })
+	sortAICostItems(report.Stocks)
+	return report
+}
+
+// sortAICostItems 按费用从高到低排序（费用相同时按token总数、股票代码）
+func sortAICostItems(items []AICostItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Cost != items[j].Cost {
+			return items[i].Cost > items[j].Cost
+		}
+		ti := items[i].PromptTokens + items[i].CompletionTokens
+		tj := items[j].PromptTokens + items[j].CompletionTokens
+		if ti != tj {
+			return ti > tj
+		}
+		return items[i].StockCode < items[j].StockCode
+	})
+}
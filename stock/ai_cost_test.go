@@ -0,0 +1,43 @@
+package stock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nofx/mcp"
+)
+
+func TestAICostRecordedAtCallSite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"content":"不是JSON的回复"}}],"usage":{"prompt_tokens":1000,"completion_tokens":200}}`))
+	}))
+	defer server.Close()
+
+	client := mcp.New()
+	client.SetCustomAPI(server.URL, "test-key", "test-model")
+	tracker := NewAICostTracker("custom", AIPrice{Prompt: 2, Completion: 3})
+	analyzer := NewStockAnalyzer(nil, client, nil, &AnalysisConfig{StockCode: "000001", StockName: "平安银行", CostTracker: tracker}, nil)
+	analyzer.Clock = FixedClock(time.Now())
+
+	// AI回复无法解析为分析结果时同样计费（用量在调用处记录，不依赖分析结果）
+	if _, _, err := analyzer.callAI("system", "prompt", "trace"); err != nil {
+		t.Fatal(err)
+	}
+	// 翻译调用也计入该股票
+	translator := &ReasoningTranslator{Client: client, TargetLanguage: "English"}
+	_, usage, err := translator.Translate("放量突破")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer.recordAIUsage(usage)
+
+	report := tracker.Report(1)
+	if report.Total.Calls != 2 || report.Total.PromptTokens != 2000 || report.Total.CompletionTokens != 400 {
+		t.Fatalf("用量统计不符: %+v", report.Total)
+	}
+	if len(report.Stocks) != 1 || report.Stocks[0].StockCode != "000001" {
+		t.Fatalf("按股票统计不符: %+v", report.Stocks)
+	}
+}
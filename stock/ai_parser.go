@@ -3,6 +3,7 @@ package stock
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 	"time"
@@ -10,16 +11,309 @@ import (
 
 // AIDecisionResponse AI决策响应结构
 type AIDecisionResponse struct {
-	Signal      string  `json:"signal"`       // BUY/SELL/HOLD
-	Confidence  int     `json:"confidence"`   // 0-100
-	Reasoning   string  `json:"reasoning"`    // 分析理由
-	TargetPrice float64 `json:"target_price"` // 目标价格
-	StopLoss    float64 `json:"stop_loss"`    // 止损价格
-	RiskReward  string  `json:"risk_reward"`  // 风险回报比
-	
+	Signal            string             `json:"signal"`        // BUY/SELL/HOLD
+	Confidence        int                `json:"confidence"`    // 0-100
+	Reasoning         string             `json:"-"`             // 分析理由（纯文本，结构化输出时由各小节拼接而成）
+	RawReasoning      json.RawMessage    `json:"reasoning"`     // 原始reasoning（字符串或按小节输出的对象）
+	ReasoningSections *ReasoningSections `json:"-"`             // 结构化的分析理由（AI按小节输出或使用【小节】标记时有效）
+	TargetPrice       float64            `json:"target_price"`  // 目标价格
+	StopLoss          float64            `json:"stop_loss"`     // 止损价格
+	RiskReward        string             `json:"risk_reward"`   // 风险回报比
+	Probabilities     *Probabilities     `json:"probabilities"` // 上涨/震荡/下跌概率分布（可选）
+	LimitMove         *LimitMoveOdds     `json:"limit_move"`    // 短期触及涨停/跌停的可能性（可选）
+
 	// 新增：持仓止盈止损价格（持仓模式下有效）
 	PositionProfitTarget float64 `json:"position_profit_target"` // 持仓止盈价
 	PositionStopLoss     float64 `json:"position_stop_loss"`     // 持仓止损价
+
+	UnknownSignal string `json:"-"` // AI返回的无法识别的signal原值（已按HOLD处理）
+}
+
+// signalSynonyms signal的常见非标准写法（英文已转大写并去掉空格、下划线和连字符）
+var signalSynonyms = map[string]string{
+	"BUY": "BUY", "LONG": "BUY", "BULLISH": "BUY", "ACCUMULATE": "BUY", "OVERWEIGHT": "BUY",
+	"买入": "BUY", "买": "BUY", "买进": "BUY", "加仓": "BUY", "增持": "BUY", "做多": "BUY", "低吸": "BUY", "建仓": "BUY",
+	"SELL": "SELL", "SHORT": "SELL", "BEARISH": "SELL", "REDUCE": "SELL", "UNDERWEIGHT": "SELL",
+	"卖出": "SELL", "卖": "SELL", "减仓": "SELL", "减持": "SELL", "清仓": "SELL", "做空": "SELL", "止损": "SELL", "离场": "SELL", "止盈": "SELL",
+	"HOLD": "HOLD", "WAIT": "HOLD", "NEUTRAL": "HOLD", "WATCH": "HOLD",
+	"持有": "HOLD", "观望": "HOLD", "继续持有": "HOLD", "持仓": "HOLD", "等待": "HOLD", "中性": "HOLD", "不操作": "HOLD", "持股": "HOLD",
+}
+
+// signalModifiers signal前常见的程度修饰词（去掉后再查同义词，如"强烈买入"、"STRONG_BUY"、"逢低买入"）
+var signalModifiers = []string{"STRONG", "强烈", "建议", "谨慎", "适量", "逢低", "逢高", "坚决", "立即", "暂时", "继续"}
+
+// NormalizeSignal 把AI返回的signal标准化为BUY/SELL/HOLD：忽略大小写、空格和标点，识别常见中英文同义词及"强烈""建议"等修饰词
+// 无法识别时（含"不建议买入"这类否定说法）返回ok=false
+func NormalizeSignal(raw string) (signal string, ok bool) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-', '.', '。', '!', '！', '"', '\'', '“', '”':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(raw)))
+
+	for {
+		if signal, ok := signalSynonyms[cleaned]; ok {
+			return signal, true
+		}
+		stripped := cleaned
+		for _, modifier := range signalModifiers {
+			stripped = strings.TrimPrefix(stripped, modifier)
+		}
+		if stripped == cleaned || stripped == "" {
+			return "", false
+		}
+		cleaned = stripped
+	}
+}
+
+// ReasoningSections 按小节拆分的分析理由
+type ReasoningSections struct {
+	Trend       string `json:"trend,omitempty"`        // 趋势
+	VolumePrice string `json:"volume_price,omitempty"` // 量价
+	OrderBook   string `json:"order_book,omitempty"`   // 盘口
+	Risk        string `json:"risk,omitempty"`         // 风险
+	Summary     string `json:"summary,omitempty"`      // 结论
+}
+
+// Probabilities 上涨/震荡/下跌三种情形的概率分布（百分比，合计100）
+type Probabilities struct {
+	Up       int `json:"up"`       // 上涨概率
+	Sideways int `json:"sideways"` // 震荡概率
+	Down     int `json:"down"`     // 下跌概率
+}
+
+// Text 概率分布的展示文本，如"上涨概率60%/震荡25%/下跌15%"
+func (p *Probabilities) Text() string {
+	return fmt.Sprintf("上涨概率%d%%/震荡%d%%/下跌%d%%", p.Up, p.Sideways, p.Down)
+}
+
+// normalize 修正概率分布：负数按0处理，合计不为100时按比例缩放，舍入误差计入最大项
+// 全部为0时返回false（视为AI未给出概率）
+func (p *Probabilities) normalize() bool {
+	values := []*int{&p.Up, &p.Sideways, &p.Down}
+	total := 0
+	for _, v := range values {
+		if *v < 0 {
+			*v = 0
+		}
+		total += *v
+	}
+	if total == 0 {
+		return false
+	}
+	if total == 100 {
+		return true
+	}
+
+	sum := 0
+	largest := values[0]
+	for _, v := range values {
+		*v = int(math.Round(float64(*v) * 100 / float64(total)))
+		sum += *v
+		if *v > *largest {
+			largest = v
+		}
+	}
+	*largest += 100 - sum
+	return true
+}
+
+// 触及涨跌停的可能性等级
+const (
+	LimitMoveLow    = "low"
+	LimitMoveMedium = "medium"
+	LimitMoveHigh   = "high"
+)
+
+// LimitMoveOdds AI对短期（1-3个交易日）触及涨停/跌停可能性的定性判断（low/medium/high）
+type LimitMoveOdds struct {
+	Up   string `json:"up"`   // 触及涨停的可能性
+	Down string `json:"down"` // 触及跌停的可能性
+}
+
+// Text 展示文本，如"涨停可能性高/跌停可能性低"
+func (o *LimitMoveOdds) Text() string {
+	return fmt.Sprintf("涨停可能性%s/跌停可能性%s", limitMoveLevelText(o.Up), limitMoveLevelText(o.Down))
+}
+
+// normalize 统一等级写法（兼容大小写和"低/中/高"），无法识别的等级置空；两者都为空时返回false（视为AI未给出）
+func (o *LimitMoveOdds) normalize() bool {
+	o.Up = normalizeLimitMoveLevel(o.Up)
+	o.Down = normalizeLimitMoveLevel(o.Down)
+	return o.Up != "" || o.Down != ""
+}
+
+// normalizeLimitMoveLevel 把AI输出的等级转换为low/medium/high，无法识别时返回空
+func normalizeLimitMoveLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "low", "低":
+		return LimitMoveLow
+	case "medium", "mid", "中", "中等":
+		return LimitMoveMedium
+	case "high", "高":
+		return LimitMoveHigh
+	default:
+		return ""
+	}
+}
+
+// limitMoveLevelText 等级的中文文本
+func limitMoveLevelText(level string) string {
+	switch level {
+	case LimitMoveHigh:
+		return "高"
+	case LimitMoveMedium:
+		return "中"
+	case LimitMoveLow:
+		return "低"
+	default:
+		return "未知"
+	}
+}
+
+// LimitMoveRank 等级的排序值（high=3，medium=2，low=1，未给出为0）
+func LimitMoveRank(level string) int {
+	switch level {
+	case LimitMoveHigh:
+		return 3
+	case LimitMoveMedium:
+		return 2
+	case LimitMoveLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// reasoningSectionTitles 小节标题，顺序即拼接纯文本时的顺序
+var reasoningSectionTitles = []string{"趋势", "量价", "盘口", "风险", "结论"}
+
+// reasoningMarkerPattern 匹配纯文本reasoning中的【小节】标记
+var reasoningMarkerPattern = regexp.MustCompile(`【(趋势|量价|盘口|风险|结论)】`)
+
+// field 返回小节标题对应的字段
+func (r *ReasoningSections) field(title string) *string {
+	switch title {
+	case "趋势":
+		return &r.Trend
+	case "量价":
+		return &r.VolumePrice
+	case "盘口":
+		return &r.OrderBook
+	case "风险":
+		return &r.Risk
+	default:
+		return &r.Summary
+	}
+}
+
+// Text 将各小节拼接为带【小节】标记的纯文本，兼容只展示文本的通知和前端
+func (r *ReasoningSections) Text() string {
+	var parts []string
+	for _, title := range reasoningSectionTitles {
+		if content := strings.TrimSpace(*r.field(title)); content != "" {
+			parts = append(parts, fmt.Sprintf("【%s】%s", title, content))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// parseReasoningSections 从带【小节】标记的纯文本中拆分小节，没有标记时返回nil
+// 第一个标记之前的文字归入结论
+func parseReasoningSections(text string) *ReasoningSections {
+	locs := reasoningMarkerPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+
+	sections := &ReasoningSections{}
+	if preface := strings.TrimSpace(text[:locs[0][0]]); preface != "" {
+		sections.Summary = preface
+	}
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		field := sections.field(text[loc[2]:loc[3]])
+		content := strings.TrimSpace(text[loc[1]:end])
+		if *field != "" {
+			content = *field + "\n" + content
+		}
+		*field = content
+	}
+	return sections
+}
+
+// TruncateReasoning 将分析理由截断到limit字以内（按字符计，不含【小节】标记），返回是否发生截断
+// 按小节输出时各非空小节平分字数预算，超出部分以"…"结尾
+func (d *AIDecisionResponse) TruncateReasoning(limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+
+	if d.ReasoningSections == nil {
+		text, truncated := truncateRunes(d.Reasoning, limit)
+		d.Reasoning = text
+		return truncated
+	}
+
+	var fields []*string
+	total := 0
+	for _, title := range reasoningSectionTitles {
+		if field := d.ReasoningSections.field(title); *field != "" {
+			fields = append(fields, field)
+			total += len([]rune(*field))
+		}
+	}
+	if total <= limit {
+		return false
+	}
+
+	budget := limit / len(fields)
+	if budget < 1 {
+		budget = 1
+	}
+	for _, field := range fields {
+		*field, _ = truncateRunes(*field, budget)
+	}
+	d.Reasoning = d.ReasoningSections.Text()
+	return true
+}
+
+// truncateRunes 按字符截断文本，超出时以"…"结尾（"…"计入字数）
+func truncateRunes(text string, limit int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text, false
+	}
+	return string(runes[:limit-1]) + "…", true
+}
+
+// parseReasoning 解析reasoning字段：兼容纯文本（可带【小节】标记）和按小节输出的JSON对象
+func (d *AIDecisionResponse) parseReasoning() error {
+	raw := strings.TrimSpace(string(d.RawReasoning))
+	if raw == "" || raw == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(raw, "{") {
+		var sections ReasoningSections
+		if err := json.Unmarshal(d.RawReasoning, &sections); err != nil {
+			return fmt.Errorf("reasoning格式错误: %w", err)
+		}
+		d.ReasoningSections = &sections
+		d.Reasoning = sections.Text()
+		return nil
+	}
+
+	if err := json.Unmarshal(d.RawReasoning, &d.Reasoning); err != nil {
+		return fmt.Errorf("reasoning格式错误: %w", err)
+	}
+	d.ReasoningSections = parseReasoningSections(d.Reasoning)
+	return nil
 }
 
 // ParseAIResponse 解析AI响应，提取JSON决策
@@ -39,6 +333,9 @@ func ParseAIResponse(response string) (*AIDecisionResponse, error) {
 		matches = objectPattern.FindStringSubmatch(response)
 		if len(matches) >= 1 {
 			jsonStr = matches[0]
+		} else if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+			// 方式2.1: reasoning按小节输出时JSON有嵌套对象，取第一个{到最后一个}
+			jsonStr = response[start : end+1]
 		} else {
 			// 方式3: 尝试直接解析整个响应
 			jsonStr = response
@@ -53,16 +350,21 @@ func ParseAIResponse(response string) (*AIDecisionResponse, error) {
 	if err := json.Unmarshal([]byte(jsonStr), &decision); err != nil {
 		return nil, fmt.Errorf("JSON解析失败: %w\n原始响应:\n%s", err, response)
 	}
+	if err := decision.parseReasoning(); err != nil {
+		return nil, err
+	}
 
 	// 验证必填字段
 	if decision.Signal == "" {
 		return nil, fmt.Errorf("AI响应缺少signal字段")
 	}
 
-	// 规范化signal值
-	decision.Signal = strings.ToUpper(strings.TrimSpace(decision.Signal))
-	if decision.Signal != "BUY" && decision.Signal != "SELL" && decision.Signal != "HOLD" {
-		return nil, fmt.Errorf("无效的signal值: %s (必须是BUY/SELL/HOLD)", decision.Signal)
+	// 规范化signal值（"buy"、"强烈买入"等映射为标准值），无法识别时按HOLD处理并在验证警告中提示
+	if signal, ok := NormalizeSignal(decision.Signal); ok {
+		decision.Signal = signal
+	} else {
+		decision.UnknownSignal = decision.Signal
+		decision.Signal = "HOLD"
 	}
 
 	// 验证信心度范围
@@ -75,6 +377,14 @@ func ParseAIResponse(response string) (*AIDecisionResponse, error) {
 		}
 	}
 
+	// 修正概率分布（合计不为100时按比例缩放）
+	if decision.Probabilities != nil && !decision.Probabilities.normalize() {
+		decision.Probabilities = nil
+	}
+	if decision.LimitMove != nil && !decision.LimitMove.normalize() {
+		decision.LimitMove = nil
+	}
+
 	// 验证BUY信号必须有目标价和止损
 	if decision.Signal == "BUY" {
 		if decision.TargetPrice == 0 {
@@ -91,18 +401,21 @@ func ParseAIResponse(response string) (*AIDecisionResponse, error) {
 // ConvertToAnalysisResult 将AI决策转换为分析结果
 func ConvertToAnalysisResult(aiDecision *AIDecisionResponse, stockCode, stockName string, currentPrice float64, technical map[string]interface{}) *AnalysisResult {
 	return &AnalysisResult{
-		StockCode:          stockCode,
-		StockName:          stockName,
-		CurrentPrice:       currentPrice,
-		Signal:             aiDecision.Signal,
-		Confidence:         aiDecision.Confidence,
-		Reasoning:          aiDecision.Reasoning,
-		TargetPrice:        aiDecision.TargetPrice,
-		StopLoss:           aiDecision.StopLoss,
-		RiskReward:         aiDecision.RiskReward,
-		TechnicalData:      technical,
-		Timestamp:          time.Now(),
-		
+		StockCode:         stockCode,
+		StockName:         stockName,
+		CurrentPrice:      currentPrice,
+		Signal:            aiDecision.Signal,
+		Confidence:        aiDecision.Confidence,
+		Reasoning:         aiDecision.Reasoning,
+		ReasoningSections: aiDecision.ReasoningSections,
+		TargetPrice:       aiDecision.TargetPrice,
+		StopLoss:          aiDecision.StopLoss,
+		RiskReward:        aiDecision.RiskReward,
+		Probabilities:     aiDecision.Probabilities,
+		LimitMove:         aiDecision.LimitMove,
+		TechnicalData:     technical,
+		Timestamp:         time.Now(),
+
 		// 新增：持仓止盈止损价格
 		PositionProfitTarget: aiDecision.PositionProfitTarget,
 		PositionStopLoss:     aiDecision.PositionStopLoss,
@@ -113,6 +426,10 @@ func ConvertToAnalysisResult(aiDecision *AIDecisionResponse, stockCode, stockNam
 func ValidateDecision(decision *AIDecisionResponse, currentPrice float64) []string {
 	var warnings []string
 
+	if decision.UnknownSignal != "" {
+		warnings = append(warnings, fmt.Sprintf("AI返回的信号\"%s\"无法识别，已按HOLD处理", decision.UnknownSignal))
+	}
+
 	// 检查BUY信号
 	if decision.Signal == "BUY" {
 		// 目标价应该高于当前价
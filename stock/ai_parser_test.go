@@ -0,0 +1,58 @@
+package stock
+
+import "testing"
+
+func TestNormalizeSignal(t *testing.T) {
+	cases := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"BUY", "BUY", true},
+		{"buy", "BUY", true},
+		{" Buy ", "BUY", true},
+		{"STRONG_BUY", "BUY", true},
+		{"strong-buy", "BUY", true},
+		{"Bullish", "BUY", true},
+		{"强烈买入", "BUY", true},
+		{"建议逢低买入", "BUY", true},
+		{"加仓！", "BUY", true},
+		{"sell", "SELL", true},
+		{"Short", "SELL", true},
+		{"减持", "SELL", true},
+		{"坚决清仓", "SELL", true},
+		{"止损", "SELL", true},
+		{"hold", "HOLD", true},
+		{"Neutral", "HOLD", true},
+		{"观望", "HOLD", true},
+		{"继续持有", "HOLD", true},
+		{"“持有”", "HOLD", true},
+		{"不建议买入", "", false},
+		{"UNKNOWN", "", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := NormalizeSignal(tc.raw)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("NormalizeSignal(%q) = %q, %v，期望 %q, %v", tc.raw, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestParseAIResponseUnknownSignalFallsBackToHold(t *testing.T) {
+	decision, err := ParseAIResponse(`{"signal": "maybe", "confidence": 60, "reasoning": "信号不明确"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Signal != "HOLD" || decision.UnknownSignal != "maybe" {
+		t.Fatalf("无法识别的signal应按HOLD处理并保留原值，实际 %q（原值 %q）", decision.Signal, decision.UnknownSignal)
+	}
+
+	decision, err = ParseAIResponse(`{"signal": "强烈买入", "confidence": 80, "reasoning": "放量突破", "target_price": 12.5, "stop_loss": 10.8}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Signal != "BUY" || decision.UnknownSignal != "" {
+		t.Fatalf("中文同义词应映射为BUY，实际 %q", decision.Signal)
+	}
+}
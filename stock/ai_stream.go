@@ -0,0 +1,106 @@
+package stock
+
+import (
+	"nofx/mcp"
+	"strings"
+)
+
+// aiStreamBufferSize 每个订阅者的事件缓冲长度，订阅者消费过慢时丢弃新事件（不阻塞分析）
+const aiStreamBufferSize = 256
+
+// AI流式输出事件类型
+const (
+	AIStreamEventStart = "start" // 开始调用AI
+	AIStreamEventDelta = "delta" // 收到一段AI输出
+	AIStreamEventDone  = "done"  // AI输出结束
+	AIStreamEventError = "error" // AI调用失败
+)
+
+// AIStreamEvent AI流式输出事件
+type AIStreamEvent struct {
+	Type    string `json:"type"`
+	Content string `json:"content,omitempty"`
+}
+
+// SubscribeAIStream 订阅该股票分析时的AI实时输出，返回事件通道和取消订阅函数
+func (a *StockAnalyzer) SubscribeAIStream() (<-chan AIStreamEvent, func()) {
+	ch := make(chan AIStreamEvent, aiStreamBufferSize)
+
+	a.streamMutex.Lock()
+	if a.streamSubscribers == nil {
+		a.streamSubscribers = make(map[chan AIStreamEvent]struct{})
+	}
+	a.streamSubscribers[ch] = struct{}{}
+	a.streamMutex.Unlock()
+
+	cancel := func() {
+		a.streamMutex.Lock()
+		delete(a.streamSubscribers, ch)
+		a.streamMutex.Unlock()
+	}
+	return ch, cancel
+}
+
+// publishAIStream 向所有订阅者推送事件
+func (a *StockAnalyzer) publishAIStream(event AIStreamEvent) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+	for ch := range a.streamSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// callAI 调用AI：开启流式输出时边接收边按行打印日志并推送给订阅者，最终返回完整响应及token用量（API未返回时为nil）
+func (a *StockAnalyzer) callAI(systemPrompt, userPrompt, traceID string) (string, *mcp.Usage, error) {
+	if !a.MCPClient.Stream {
+		response, usage, err := a.MCPClient.CallWithMessagesUsage(systemPrompt, userPrompt)
+		a.recordAIUsage(usage)
+		return response, usage, err
+	}
+
+	name := a.AnalysisConfig.StockName
+	tracef(traceID, "💭 [%s] AI正在思考（流式输出）...", name)
+	a.publishAIStream(AIStreamEvent{Type: AIStreamEventStart})
+
+	// 按行缓冲输出，避免每个片段打一行日志
+	var line strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(line.String()); text != "" {
+			tracef(traceID, "💭 [%s] %s", name, text)
+		}
+		line.Reset()
+	}
+
+	response, usage, err := a.MCPClient.CallWithMessagesStreamUsage(systemPrompt, userPrompt, func(delta string) {
+		a.publishAIStream(AIStreamEvent{Type: AIStreamEventDelta, Content: delta})
+		for {
+			i := strings.IndexByte(delta, '\n')
+			if i < 0 {
+				line.WriteString(delta)
+				return
+			}
+			line.WriteString(delta[:i])
+			flush()
+			delta = delta[i+1:]
+		}
+	})
+	flush()
+	a.recordAIUsage(usage)
+
+	if err != nil {
+		a.publishAIStream(AIStreamEvent{Type: AIStreamEventError, Content: err.Error()})
+		return "", nil, err
+	}
+	a.publishAIStream(AIStreamEvent{Type: AIStreamEventDone})
+	return response, usage, nil
+}
+
+// recordAIUsage 把一次AI调用的token用量计入费用统计（未配置统计或API未返回usage时忽略）
+func (a *StockAnalyzer) recordAIUsage(usage *mcp.Usage) {
+	if tracker := a.AnalysisConfig.CostTracker; tracker != nil {
+		tracker.Record(a.AnalysisConfig.StockCode, a.AnalysisConfig.StockName, a.now(), usage)
+	}
+}
@@ -0,0 +1,138 @@
+package stock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 规则组合方式
+const (
+	AlertLogicAnd = "and" // 全部条件满足时命中（默认）
+	AlertLogicOr  = "or"  // 任一条件满足时命中
+)
+
+// AlertRule 指标预警规则（不依赖AI），每轮分析后对技术指标求值，命中时推送提醒
+type AlertRule struct {
+	Name       string           // 规则名称
+	Logic      string           // 条件组合方式：and（默认）或 or
+	Conditions []AlertCondition // 条件列表
+}
+
+// AlertCondition 单个条件：指标 运算符 阈值（如 rsi14 < 30）
+type AlertCondition struct {
+	Indicator string  // 技术指标名（如 rsi14、volume_ratio、change_percent）
+	Operator  string  // 运算符：< <= > >= == !=
+	Value     float64 // 阈值
+}
+
+// Match 判断条件是否满足，指标缺失（如K线不足未计算）时视为不满足
+func (c AlertCondition) Match(values map[string]float64) (float64, bool) {
+	value, ok := values[c.Indicator]
+	if !ok {
+		return 0, false
+	}
+	switch c.Operator {
+	case "<":
+		return value, value < c.Value
+	case "<=":
+		return value, value <= c.Value
+	case ">":
+		return value, value > c.Value
+	case ">=":
+		return value, value >= c.Value
+	case "==":
+		return value, value == c.Value
+	case "!=":
+		return value, value != c.Value
+	default:
+		return value, false
+	}
+}
+
+// Evaluate 对技术指标求值，命中时返回各条件的说明（如 "rsi14 < 30（当前 27.50）✓"）
+func (r AlertRule) Evaluate(values map[string]float64) (bool, []string) {
+	if len(r.Conditions) == 0 {
+		return false, nil
+	}
+	or := strings.EqualFold(r.Logic, AlertLogicOr)
+	matched := !or
+	details := make([]string, 0, len(r.Conditions))
+	for _, condition := range r.Conditions {
+		value, ok := condition.Match(values)
+		if or {
+			matched = matched || ok
+		} else {
+			matched = matched && ok
+		}
+
+		mark := "✗"
+		if ok {
+			mark = "✓"
+		}
+		current := "无数据"
+		if _, exists := values[condition.Indicator]; exists {
+			current = fmt.Sprintf("%.2f", value)
+		}
+		details = append(details, fmt.Sprintf("%s %s %g（当前 %s）%s", condition.Indicator, condition.Operator, condition.Value, current, mark))
+	}
+	return matched, details
+}
+
+// evaluateAlertRules 对本轮技术指标求值所有预警规则，返回命中的规则名称；sendAlert为true时推送提醒（同一规则每天只提醒一次）
+func (a *StockAnalyzer) evaluateAlertRules(technical map[string]interface{}, sendAlert bool, traceID string) []string {
+	if len(a.AnalysisConfig.AlertRules) == 0 {
+		return nil
+	}
+
+	values := TechnicalValues(technical)
+	var triggered []string
+	for _, rule := range a.AnalysisConfig.AlertRules {
+		matched, details := rule.Evaluate(values)
+		if !matched {
+			continue
+		}
+		triggered = append(triggered, rule.Name)
+		tracef(traceID, "🔔 [%s] 命中指标预警规则「%s」: %s", a.AnalysisConfig.StockName, rule.Name, strings.Join(details, "，"))
+		if sendAlert {
+			a.sendRuleAlert(rule, details, technical, traceID)
+		}
+	}
+	return triggered
+}
+
+// sendRuleAlert 推送指标预警提醒
+func (a *StockAnalyzer) sendRuleAlert(rule AlertRule, details []string, technical map[string]interface{}, traceID string) {
+	if a.Notifier == nil || !a.notificationEnabled() || a.inQuietPeriod(a.now()) {
+		return
+	}
+
+	today := a.now().Format("2006-01-02")
+	a.mutex.Lock()
+	if a.lastRuleAlertAt == nil {
+		a.lastRuleAlertAt = make(map[string]string)
+	}
+	if a.lastRuleAlertAt[rule.Name] == today {
+		a.mutex.Unlock()
+		return
+	}
+	a.lastRuleAlertAt[rule.Name] = today
+	a.mutex.Unlock()
+
+	joiner := " 且\n"
+	if strings.EqualFold(rule.Logic, AlertLogicOr) {
+		joiner = " 或\n"
+	}
+	message := fmt.Sprintf("🔔 指标预警 - %s(%s)\n规则: %s\n%s\n当前价格: %.2f元\n时间: %s",
+		a.AnalysisConfig.StockName,
+		a.AnalysisConfig.StockCode,
+		rule.Name,
+		strings.Join(details, joiner),
+		technical["current_price"],
+		a.now().Format("2006-01-02 15:04:05"))
+
+	if err := a.Notifier.SendMessage(message); err != nil {
+		tracef(traceID, "❌ 发送指标预警通知失败: %v", err)
+	} else {
+		tracef(traceID, "✅ 已发送指标预警通知: %s「%s」", a.AnalysisConfig.StockCode, rule.Name)
+	}
+}
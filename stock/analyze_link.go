@@ -0,0 +1,45 @@
+package stock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// AnalyzeLinkTTL 通知卡片"重新分析"签名链接的有效期
+const AnalyzeLinkTTL = 24 * time.Hour
+
+// SignAnalyzeLink "重新分析"链接的签名：以API Token为密钥对"股票代码|过期时间戳（秒）"做HmacSHA256（十六进制）
+func SignAnalyzeLink(secret, code string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s|%d", code, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAnalyzeLink 校验"重新分析"链接的签名和有效期（expires、sig为链接中的查询参数）
+func VerifyAnalyzeLink(secret, code, expires, sig string, now time.Time) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || sig == "" {
+		return fmt.Errorf("链接缺少有效的签名参数")
+	}
+	if !hmac.Equal([]byte(sig), []byte(SignAnalyzeLink(secret, code, expiresAt))) {
+		return fmt.Errorf("链接签名无效")
+	}
+	if now.Unix() > expiresAt {
+		return fmt.Errorf("链接已过期")
+	}
+	return nil
+}
+
+// analyzeLink 生成带签名和过期时间的"重新分析"链接，未配置签名密钥时返回空（通知中不展示该按钮）
+func (a *StockAnalyzer) analyzeLink(code string) string {
+	secret := a.AnalysisConfig.AnalyzeLinkSecret
+	if secret == "" {
+		return ""
+	}
+	expires := a.now().Add(AnalyzeLinkTTL).Unix()
+	return fmt.Sprintf("%s/stock/%s/analyze?expires=%d&sig=%s", a.AnalysisConfig.APIBaseURL, code, expires, SignAnalyzeLink(secret, code, expires))
+}
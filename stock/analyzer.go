@@ -1,13 +1,18 @@
 package stock
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"nofx/mcp"
 	"nofx/notifier"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // StockAnalyzer 股票分析器
@@ -17,20 +22,91 @@ type StockAnalyzer struct {
 	Notifier           notifier.Notifier
 	AnalysisConfig     *AnalysisConfig
 	TradingTimeChecker *TradingTimeChecker
+	Security           SecurityInfo // 标的类型及交易规则（股票/ETF/可转债）
+	Clock              Clock        // 时钟（时间戳、交易时段判断、冷静期等），测试时可注入固定时间
+
+	mutex            sync.Mutex
+	lastCrossAlertAt map[string]string // 均线交叉事件上次提醒的日期（事件类型 -> YYYY-MM-DD），避免同一天重复提醒
+	lastRuleAlertAt  map[string]string // 指标预警规则上次提醒的日期（规则名称 -> YYYY-MM-DD）
+	lastQualified    string            // 上一轮达到信心度阈值的信号（上一轮未达阈值时为空），用于信号确认
+	lastNotify       notifyState       // 最近一次通知的时间和价位（通知冷静期、价格事件）
+	floatSharesCache float64           // 从TDX获取的流通股本（股），0表示无法获取
+	floatSharesDate  string            // 流通股本的获取日期（YYYY-MM-DD），每天重新获取
+	accuracy         AccuracyRecord    // 命中率加权的样本和评估结果
+	accuracyLoaded   bool              // 是否已从持久化存储加载命中统计
+	muted            bool              // 静音：照常分析，但不推送任何通知（运行时通过API切换）
+	lastQuoteClose   int               // 上一轮实时行情的现价（厘），用于判断行情是否长时间未更新
+	lastQuoteVolume  int64             // 上一轮实时行情的总手数
+	quoteChangedAt   time.Time         // 实时行情最近一次发生变化的时间
+	quoteWarnedAt    time.Time         // 最近一次推送多数据源现价差异告警的时间
+	debugDir         string            // 调试追踪文件目录（为空表示未开启，运行时通过API切换）
+
+	// 虚拟组合（仅Basket非空时使用）
+	basketBase     []float64 // 各成分股的指数基准价（厘）
+	basketBaseDate string    // 基准日（YYYY-MM-DD），该日收盘记为1000点
+	basketSummary  string    // 最近一次合成行情时各成分股的权重和涨跌（用于提示词）
+
+	streamMutex       sync.Mutex
+	streamSubscribers map[chan AIStreamEvent]struct{} // AI流式输出的订阅者
 }
 
 // AnalysisConfig 分析配置
 type AnalysisConfig struct {
-	StockCode          string        // 股票代码
-	StockName          string        // 股票名称
-	ScanInterval       time.Duration // 扫描间隔
-	EnableNotification bool          // 是否启用通知
-	MinConfidence      int           // 最小信心度阈值（低于此值不发送通知）
+	StockCode           string                     // 股票代码
+	StockName           string                     // 股票名称
+	ScanInterval        time.Duration              // 扫描间隔
+	CronSchedules       []string                   // 定时分析计划（cron表达式），非空时由cron调度器触发分析，不再按扫描间隔执行
+	EnableNotification  bool                       // 是否启用通知
+	MinConfidence       int                        // 最小信心度阈值（低于此值不发送通知）
+	AdaptiveConfidence  *AdaptiveConfidence        // 自适应信心度阈值（按波动率浮动），nil表示使用固定阈值
+	AccuracyWeighting   *AccuracyWeighting         // 按历史命中率加权信心度（用于通知决策），nil表示不加权
+	MuteLowPriority     bool                       // 是否静默低优先级通知（low级别只记录不推送）
+	MinRiskReward       float64                    // BUY信号通知的最低风险回报比（回报/风险，如1.5表示1:1.5），0表示不过滤
+	ConsensusBoost      bool                       // AI信号与本地技术规则一致时通知优先级提升一级
+	ComplianceFilter    *notifier.ComplianceFilter // 推送前对分析理由做敏感词/合规过滤（可选）
+	Translator          *ReasoningTranslator       // 推送前把分析理由翻译为目标语言（可选，失败时使用原文）
+	CostTracker         *AICostTracker             // AI调用token数与费用统计（所有组合共享），nil时不统计
+	NotifyCooldown      time.Duration              // 通知冷静期：距上次通知不足该时长时不再推送（价格跌破止损/涨破目标价除外），0表示不限制
+	EnableMACrossAlert  bool                       // 是否启用均线金叉/死叉独立事件通知（不依赖AI）
+	AlertRules          []AlertRule                // 指标预警规则（不依赖AI，命中时推送提醒），为空时不求值
+	KlinePeriods        []string                   // 多周期共振分析的K线周期列表（如 minute5/minute15/minute30/hour），为空时不做多周期分析
+	PlainPrompt         bool                       // 是否使用纯文本提示词（去除emoji和markdown，适配纯文本模型）
+	IndicatorsInPrompt  []string                   // 提示词中展示的技术指标（如 ma5/rsi/macd/kdj），为空时使用默认列表
+	ChartProvider       string                     // 通知中K线看图链接的提供方（tradingview/xueqiu）
+	APIBaseURL          string                     // 本系统对外API地址（含组合前缀，如 http://host:9090/api），为空时通知中不带详情/重新分析链接
+	AnalyzeLinkSecret   string                     // "重新分析"链接的签名密钥（API Token），为空时通知中不带重新分析链接
+	RequireConfirmation bool                       // 是否需要信号确认：本轮与上一轮信号相同且都达到信心度阈值时才通知
+	SkipSuspensionGaps  bool                       // 均线/RSI/波动率窗口跨越停牌缺口时是否跳过计算（false时仅标注）
+	MinKlineDays        int                        // 分析所需的最少日K线数量，不足时跳过AI分析（0表示不限制）
+	DayKlineCount       int                        // 每轮分析拉取的日K线数量（0表示默认60根）
+	MinuteKlineCount    int                        // 每轮分析拉取的30分钟及多周期K线数量（0表示默认100根）
+	Basket              []BasketMember             // 虚拟组合成分股（非空时StockCode为组合ID，分析对象为按权重合成的组合指数）
+	BasketBaseDate      string                     // 虚拟组合指数的基准日（YYYY-MM-DD），为空时取首次锚定时近60个交易日的第一个共同交易日
+	BasketBaseFile      string                     // 虚拟组合指数锚定信息的持久化文件，为空时不持久化（每次启动重新锚定）
+	News                *NewsClient                // 新闻/公告摘要来源（注入提示词"消息面"小节），nil表示不使用
+	FloatShares         float64                    // 流通股本（股），0表示从TDX获取（获取不到时提示词不含市值信息）
+	MaxReasoningChars   int                        // 分析理由的字数上限（提示词中要求AI遵守，超长时截断），0表示不限制
+	QuoteVerifier       *QuoteVerifier             // 多数据源行情校验，nil表示不校验
+	RuleBasedAI         bool                       // 试运行：用本地规则代替AI调用（不消耗AI额度，仅用于演练流程）
+	BenchmarkIndex      string                     // 大盘指数代码（如 sh000300），用于计算近20日相对强弱，为空表示不计算
+	ExRightsDates       []string                   // 除权除息日（YYYY-MM-DD），当天价格已调整，抑制跌幅告警；另会按昨收价自动识别
+	QuietHours          []string                   // 通知静默时段（HH:MM-HH:MM，可跨午夜），期内的通知只记录不推送（紧急通知除外）
+	QuietDays           []string                   // 通知静默日（星期sun-sat或日期YYYY-MM-DD）
+	AutoTrade           *AutoTrade                 // 按信号自动交易（模拟盘），nil表示不自动交易
+	ThresholdBasis      string                     // 通知门槛的评分依据：confidence（默认，AI信心度）或 system_score（系统综合评分）
+	Scoring             *ScoringModel              // 技术指标健康度权重（运行时可调整），nil时使用默认权重
+	ErrorReporter       *notifier.SentryReporter   // Sentry错误上报（通知发送失败时上报），nil时不上报
 
 	// 新增：持仓信息（可选）
+	TrackPositions   bool      // 所在组合按持仓跟踪（有股票配置了持仓/成交记录），SELL信号与持仓状态联动
 	PositionQuantity int       // 持仓数量（股），0表示监控模式
 	BuyPrice         float64   // 购买价格（元/股），0表示监控模式
 	BuyDate          time.Time // 购买日期（可选）
+
+	RealizedProfitLoss float64 // 已实现盈亏（元，从成交记录计算，未导入成交记录时为0）
+
+	FeeRates    FeeRates // 交易费率（计算扣费后盈亏和回本价）
+	FeeTemplate string   // 费率模板名称（用于展示）
 }
 
 // IsPositionMode 判断是否为持仓模式
@@ -38,104 +114,499 @@ func (c *AnalysisConfig) IsPositionMode() bool {
 	return c.PositionQuantity > 0 && c.BuyPrice > 0
 }
 
+// positionSnapshot 持仓配置的快照：导入持仓时运行时更新（见SetPosition），每轮分析开始时取一次，本轮始终使用同一份持仓
+type positionSnapshot struct {
+	track    bool
+	quantity int
+	buyPrice float64
+	buyDate  time.Time
+}
+
+// held 是否为持仓模式
+func (p positionSnapshot) held() bool {
+	return p.quantity > 0 && p.buyPrice > 0
+}
+
 // NewStockAnalyzer 创建股票分析器
 func NewStockAnalyzer(tdxClient *TDXClient, mcpClient *mcp.Client, notif notifier.Notifier, config *AnalysisConfig, tradingTimeChecker *TradingTimeChecker) *StockAnalyzer {
-	return &StockAnalyzer{
+	analyzer := &StockAnalyzer{
 		TDXClient:          tdxClient,
 		MCPClient:          mcpClient,
 		Notifier:           notif,
 		AnalysisConfig:     config,
 		TradingTimeChecker: tradingTimeChecker,
+		Security:           DetectSecurity(config.StockCode, config.StockName),
+		Clock:              SystemClock{},
+	}
+	if len(config.Basket) > 0 {
+		analyzer.Security = basketSecurity()
 	}
+	return analyzer
 }
 
 // AnalysisResult 分析结果
 type AnalysisResult struct {
-	StockCode     string                 `json:"stock_code"`
-	StockName     string                 `json:"stock_name"`
-	CurrentPrice  float64                `json:"current_price"`
-	Signal        string                 `json:"signal"` // BUY/SELL/HOLD
-	Confidence    int                    `json:"confidence"`
-	Reasoning     string                 `json:"reasoning"`
-	TargetPrice   float64                `json:"target_price,omitempty"`
-	StopLoss      float64                `json:"stop_loss,omitempty"`
-	RiskReward    string                 `json:"risk_reward,omitempty"`
-	TechnicalData map[string]interface{} `json:"technical_data"`
-	Timestamp     time.Time              `json:"timestamp"`
+	SchemaVersion      int                    `json:"schema_version,omitempty"` // 结构版本号（见notifier.SchemaVersion），新增字段均为omitempty，不破坏旧消费者
+	StockCode          string                 `json:"stock_code"`
+	StockName          string                 `json:"stock_name"`
+	CurrentPrice       float64                `json:"current_price"`
+	Signal             string                 `json:"signal"` // BUY/SELL/HOLD
+	Confidence         int                    `json:"confidence"`
+	Reasoning          string                 `json:"reasoning"`
+	ReasoningSections  *ReasoningSections     `json:"reasoning_sections,omitempty"`  // 按趋势/量价/盘口/风险/结论拆分的分析理由
+	ReasoningTruncated bool                   `json:"reasoning_truncated,omitempty"` // 分析理由超过字数上限被截断
+	TargetPrice        float64                `json:"target_price,omitempty"`
+	StopLoss           float64                `json:"stop_loss,omitempty"`
+	RiskReward         string                 `json:"risk_reward,omitempty"`
+	RiskRewardRatio    float64                `json:"risk_reward_ratio,omitempty"` // 风险回报比解析出的回报/风险比率（如"1:2"为2），无法解析时为0
+	Probabilities      *Probabilities         `json:"probabilities,omitempty"`     // 上涨/震荡/下跌概率分布
+	LimitMove          *LimitMoveOdds         `json:"limit_move,omitempty"`        // 短期触及涨停/跌停的可能性（low/medium/high）
+	TechnicalData      map[string]interface{} `json:"technical_data"`
+	TechnicalValues    map[string]float64     `json:"technical_values,omitempty"` // technical_data中可转为数值的字段（百分比去掉%按百分数表示），字符串原值仍保留在technical_data中用于展示
+	Timestamp          time.Time              `json:"timestamp"`
 
 	// 新增：持仓止盈止损价格（持仓模式下有效）
 	PositionProfitTarget float64       `json:"position_profit_target,omitempty"` // 持仓止盈价
 	PositionStopLoss     float64       `json:"position_stop_loss,omitempty"`     // 持仓止损价
 	PositionInfo         *PositionInfo `json:"position_info,omitempty"`          // 持仓信息（可选）
+
+	PendingConfirmation    bool            `json:"pending_confirmation,omitempty"`     // 信号待确认（启用信号确认时，首次出现的信号不推送）
+	EffectiveMinConfidence int             `json:"effective_min_confidence,omitempty"` // 本轮实际生效的信心度阈值（启用自适应阈值时可能不同于配置值）
+	AdjustedConfidence     int             `json:"adjusted_confidence,omitempty"`      // 按该股历史命中率加权后的信心度（启用命中率加权时用于通知决策，confidence保留AI原始值）
+	Accuracy               *AccuracyStats  `json:"accuracy,omitempty"`                 // 该股历史BUY/SELL信号命中率统计（启用命中率加权时有效）
+	HealthScore            *int            `json:"health_score,omitempty"`             // 技术指标健康度（0-100，越高越偏多，按MA/RSI/MACD/量能加权），未计算时为nil
+	SystemScore            *int            `json:"system_score,omitempty"`             // 系统综合评分（0-100，健康度与信号的契合度+规则一致性+历史命中率，独立于AI信心度），未计算时为nil
+	WhatIf                 bool            `json:"what_if,omitempty"`                  // 假设分析结果（当前价为手动输入的假设价格）
+	InsufficientData       bool            `json:"insufficient_data,omitempty"`        // 日K线数量不足，未调用AI，结果为默认观望
+	CooldownSuppressed     bool            `json:"cooldown_suppressed,omitempty"`      // 处于通知冷静期，本轮未推送
+	RiskRewardFiltered     bool            `json:"risk_reward_filtered,omitempty"`     // BUY信号风险回报比低于min_risk_reward，本轮未推送
+	QuietSuppressed        bool            `json:"quiet_suppressed,omitempty"`         // 处于通知静默期，本轮通知只记录不推送
+	PriceEvent             string          `json:"price_event,omitempty"`              // 冷静期豁免的价格事件（如跌破止损价），有值时已立即推送
+	QuoteWarning           string          `json:"quote_warning,omitempty"`            // 多数据源现价差异过大的告警（已采用中位数）
+	TradeFill              *Fill           `json:"trade_fill,omitempty"`               // 本轮按信号自动交易的成交回报（启用模拟盘时）
+	PaperPosition          *TradeSummary   `json:"paper_position,omitempty"`           // 模拟盘虚拟持仓（启用模拟盘且有虚拟持仓时，与真实持仓互不影响）
+	NoPositionSell         bool            `json:"no_position_sell,omitempty"`         // 按持仓跟踪的组合中无持仓时的SELL信号（仅供参考，通知降为低优先级）
+	RuleSignal             string          `json:"rule_signal,omitempty"`              // 本地技术规则（MA/MACD/RSI综合）信号
+	RuleConfirmed          bool            `json:"confirmed,omitempty"`                // AI的BUY/SELL信号与本地技术规则一致
+	RuleConflict           bool            `json:"conflict,omitempty"`                 // AI的BUY/SELL信号与本地技术规则方向相反（推理原因中已提示分歧）
+	ReplayAt               *time.Time      `json:"replay_at,omitempty"`                // 历史回放时刻（历史回放结果才有）
+	PromptTokens           int             `json:"prompt_tokens,omitempty"`            // 本轮AI调用的输入token数（AI接口返回usage时才有）
+	CompletionTokens       int             `json:"completion_tokens,omitempty"`        // 本轮AI调用的输出token数
+	TraceID                string          `json:"trace_id,omitempty"`                 // 链路追踪ID（本次分析的日志行尾均带 trace=<ID>，可据此过滤完整链路）
+	StageDurations         []StageDuration `json:"stage_durations,omitempty"`          // 定时/手动分析各阶段耗时（行情、K线、指标、AI、解析、通知）
+	TriggeredRules         []string        `json:"triggered_rules,omitempty"`          // 本轮命中的指标预警规则名称
+	DataQuality            string          `json:"data_quality,omitempty"`             // 数据质量：complete/degraded（数据受限）/stale（数据过期）
+	QualityReasons         []string        `json:"quality_reasons,omitempty"`          // 数据受限或过期的原因（通知中提示"数据受限"）
 }
 
+// ErrNotTradingTime 非交易时段跳过分析（不属于分析失败）
+var ErrNotTradingTime = errors.New("非交易时段")
+
 // Analyze 执行单次分析
 func (a *StockAnalyzer) Analyze() (*AnalysisResult, error) {
+	return a.analyze(false)
+}
+
+// AnalyzeManual 手动触发的单次分析（配置多数据源校验时默认只在手动触发时校验现价）
+func (a *StockAnalyzer) AnalyzeManual() (*AnalysisResult, error) {
+	return a.analyze(true)
+}
+
+// analyze 执行单次分析，manual表示手动触发
+func (a *StockAnalyzer) analyze(manual bool) (*AnalysisResult, error) {
 	// 0. 检查是否在交易时间内
-	if a.TradingTimeChecker != nil && !a.TradingTimeChecker.IsTradingTime(time.Now()) {
-		status := a.TradingTimeChecker.GetTradingTimeStatus(time.Now())
+	if now := a.now(); a.TradingTimeChecker != nil && !a.TradingTimeChecker.IsTradingTime(now) {
+		status := a.TradingTimeChecker.GetTradingTimeStatus(now)
 		log.Printf("⏸️  非交易时段，跳过分析 | 下次交易时间: %v", status["next_trading_time"])
-		return nil, fmt.Errorf("非交易时段")
+		return nil, ErrNotTradingTime
 	}
 
-	log.Printf("📊 开始分析股票 %s(%s)...", a.AnalysisConfig.StockName, a.AnalysisConfig.StockCode)
+	// 每次分析生成链路追踪ID，贯穿行情/K线获取、AI调用、解析和通知的日志，并写入分析结果
+	traceID := newTraceID()
+	timer := newStageTimer()
+	tracef(traceID, "📊 开始分析股票 %s(%s)...", a.AnalysisConfig.StockName, a.AnalysisConfig.StockCode)
+
+	// 持仓可能在分析过程中被导入更新，本轮统一使用开始时的快照
+	position := a.position()
 
 	// 1. 获取实时行情
-	quote, err := a.TDXClient.GetQuote(a.AnalysisConfig.StockCode)
+	quote, err := a.getQuote()
+	if err != nil {
+		return nil, withTrace(traceID, fmt.Errorf("获取行情失败: %w", err))
+	}
+
+	// 1.1 多数据源校验现价（差异过大时采用中位数）
+	quoteWarning := ""
+	if verifier := a.AnalysisConfig.QuoteVerifier; verifier != nil && !a.IsBasket() && (manual || verifier.Always) {
+		quote, quoteWarning = verifier.Verify(a.AnalysisConfig.StockCode, quote)
+	}
+	timer.mark("quote")
+
+	debug := a.newDebugTrace(traceID)
+	result, err := a.analyzeQuote(quote, analyzeOptions{position: position, quoteWarning: quoteWarning, traceID: traceID, timer: timer, debug: debug})
+	debug.write(result, err)
+	if err != nil {
+		return nil, withTrace(traceID, err)
+	}
+	if quoteWarning != "" {
+		result.QuoteWarning = quoteWarning
+		a.sendQuoteWarning(quoteWarning, traceID)
+	}
+	a.checkQuoteFreshness(quote, result)
+
+	// 9. 发送通知（如果启用且信心度达到阈值）
+	// 通知条件：启用通知 + 信心度≥阈值 + 信号是BUY/SELL/HOLD中的任意一个
+	result.EffectiveMinConfidence = a.effectiveMinConfidence(result.TechnicalData, traceID)
+	a.applyAccuracyWeighting(result)
+	score := decisionConfidence(result)
+	if a.applySystemScore(result) && a.AnalysisConfig.ThresholdBasis == ThresholdBasisSystemScore {
+		score = *result.SystemScore
+	}
+	qualified := score >= result.EffectiveMinConfidence && !result.InsufficientData
+	confirmed := a.confirmSignal(result.Signal, qualified)
+
+	// 按信号自动交易（模拟盘）：与通知使用相同的信心度、信号确认和风险回报比条件，成交后的持仓随本轮通知推送
+	if qualified && confirmed && !a.riskRewardTooLow(result) {
+		a.autoTrade(result)
+	}
+	a.applyPositionLink(result, position.track)
+	if a.notificationEnabled() {
+		event := ""
+		if !isExRightsDay(result.TechnicalData) {
+			// 除权除息日价格整体下移，跌破上次通知的止损价是除权造成的，不作为价格事件
+			event = a.detectPriceEvent(result.CurrentPrice)
+		}
+		switch {
+		case event != "":
+			// 价格事件优先：现价跌破上次通知的止损价或涨破目标价时立即推送，不受冷静期、信心度和信号确认限制
+			result.PriceEvent = event
+			tracef(traceID, "🚨 [%s] %s，立即推送", a.AnalysisConfig.StockName, event)
+			a.sendNotification(result)
+		case !qualified:
+			// 信心度未达阈值，不推送
+		case a.riskRewardTooLow(result):
+			result.RiskRewardFiltered = true
+			tracef(traceID, "⚖️  [%s] BUY信号风险回报比%s低于要求的1:%.1f，本轮不推送", a.AnalysisConfig.StockName, result.RiskReward, a.AnalysisConfig.MinRiskReward)
+		case !confirmed:
+			// 新出现的信号先记录为待确认，下一轮同向时再推送
+			result.PendingConfirmation = true
+			tracef(traceID, "⏳ [%s] %s信号待确认（需连续两轮同向），本轮不推送", a.AnalysisConfig.StockName, result.Signal)
+		case a.inCooldown(a.now()):
+			result.CooldownSuppressed = true
+			tracef(traceID, "🧊 [%s] 处于通知冷静期（%v），本轮%s信号不推送", a.AnalysisConfig.StockName, a.AnalysisConfig.NotifyCooldown, result.Signal)
+		default:
+			// 所有信号（BUY/SELL/HOLD）都发送通知，只要信心度达到阈值
+			a.sendNotification(result)
+		}
+	}
+	timer.mark("notify")
+	result.StageDurations = timer.durations()
+
+	return result, nil
+}
+
+// AnalyzeWhatIf 假设分析：用手动输入的假设现价（元）替代实时最新价，其余K线使用真实历史数据
+// 用于情景推演，不受交易时段限制，不发送通知、不影响信号确认状态
+func (a *StockAnalyzer) AnalyzeWhatIf(price float64) (*AnalysisResult, error) {
+	traceID := newTraceID()
+	tracef(traceID, "🔮 假设分析 %s(%s)，假设现价 %.2f元...", a.AnalysisConfig.StockName, a.AnalysisConfig.StockCode, price)
+
+	quote, err := a.getQuote()
+	if err != nil {
+		return nil, withTrace(traceID, fmt.Errorf("获取行情失败: %w", err))
+	}
+
+	// 复制一份行情再修改，用假设价替换最新价，并扩展当日最高/最低价以包含假设价
+	whatIf := *quote
+	whatIf.K.Close = int(math.Round(price * 1000))
+	if whatIf.K.High < whatIf.K.Close {
+		whatIf.K.High = whatIf.K.Close
+	}
+	if whatIf.K.Low == 0 || whatIf.K.Low > whatIf.K.Close {
+		whatIf.K.Low = whatIf.K.Close
+	}
+	// 实时盘口与假设价不对应，不提供给AI
+	whatIf.BuyLevel = nil
+	whatIf.SellLevel = nil
+
+	result, err := a.analyzeQuote(&whatIf, analyzeOptions{whatIf: true, position: a.position(), traceID: traceID})
 	if err != nil {
-		return nil, fmt.Errorf("获取行情失败: %w", err)
+		return nil, withTrace(traceID, err)
+	}
+	result.WhatIf = true
+	return result, nil
+}
+
+// analyzeOptions 单次分析的选项
+type analyzeOptions struct {
+	whatIf   bool                  // 假设分析（当前价为手动输入的假设价格）
+	replayAt time.Time             // 历史回放时刻，零值表示实时分析
+	klines   map[string]*KlineData // 已拉取的K线（周期 -> K线），直接复用
+	position positionSnapshot      // 本轮分析使用的持仓快照
+
+	quoteWarning string      // 多数据源现价校验告警（现价已替换为中位数）
+	traceID      string      // 链路追踪ID（写入日志和分析结果）
+	timer        *stageTimer // 分阶段计时器（为nil时不计时）
+	debug        *debugTrace // 调试追踪记录（为nil时不记录）
+}
+
+// realtime 是否为实时分析（非假设分析、非历史回放），只有实时分析才使用分时/筹码数据和发送事件通知
+func (o analyzeOptions) realtime() bool {
+	return !o.whatIf && o.replayAt.IsZero()
+}
+
+// klineFor 按分析选项获取K线：优先复用已拉取的K线，历史回放时只取回放时刻之前的K线
+func (a *StockAnalyzer) klineFor(opts analyzeOptions, klineType string, limit int) (*KlineData, error) {
+	if kline, ok := opts.klines[klineType]; ok {
+		return kline, nil
+	}
+	if !opts.replayAt.IsZero() {
+		return a.replayKline(klineType, limit, opts.replayAt)
+	}
+	return a.getKline(klineType, limit)
+}
+
+// 每轮分析默认拉取的K线数量（未配置 day_kline_count / minute_kline_count 时）
+const (
+	defaultDayKlineCount    = 60
+	defaultMinuteKlineCount = 100
+)
+
+// klineCount 配置的K线数量，未配置时取默认值
+func klineCount(configured, fallback int) int {
+	if configured > 0 {
+		return configured
 	}
+	return fallback
+}
+
+// dayKlineCount 每轮分析拉取的日K线数量
+func (a *StockAnalyzer) dayKlineCount() int {
+	return klineCount(a.AnalysisConfig.DayKlineCount, defaultDayKlineCount)
+}
+
+// minuteKlineCount 每轮分析拉取的30分钟及多周期K线数量
+func (a *StockAnalyzer) minuteKlineCount() int {
+	return klineCount(a.AnalysisConfig.MinuteKlineCount, defaultMinuteKlineCount)
+}
 
-	// 2. 获取日K线数据（最近60天）
-	dayKline, err := a.TDXClient.GetKline(a.AnalysisConfig.StockCode, "day", 60)
+// analyzeQuote 基于给定行情拉取K线、计算指标并调用AI分析（假设分析、历史回放见analyzeOptions）
+func (a *StockAnalyzer) analyzeQuote(quote *QuoteData, opts analyzeOptions) (*AnalysisResult, error) {
+	// 2. 获取日K线数据（默认最近60天）
+	dayKline, err := a.klineFor(opts, "day", a.dayKlineCount())
 	if err != nil {
 		return nil, fmt.Errorf("获取日K线失败: %w", err)
 	}
 
-	// 3. 获取30分钟K线数据（最近100条）
-	min30Kline, err := a.TDXClient.GetKline(a.AnalysisConfig.StockCode, "minute30", 100)
+	// 3. 获取30分钟K线数据（默认最近100条）
+	min30Kline, err := a.klineFor(opts, "minute30", a.minuteKlineCount())
 	if err != nil {
 		return nil, fmt.Errorf("获取30分钟K线失败: %w", err)
 	}
 
-	// 4. 获取今日分时数据
-	minuteData, err := a.TDXClient.GetMinute(a.AnalysisConfig.StockCode, "")
-	if err != nil {
-		log.Printf("⚠️  获取分时数据失败（可能非交易时间）: %v", err)
-		minuteData = nil // 非交易时间可能获取不到，设为nil
+	// 4. 获取今日分时数据（假设分析、历史回放时与行情不对应，不使用；虚拟组合没有分时数据）
+	var minuteData *MinuteData
+	if opts.realtime() && !a.IsBasket() {
+		minuteData, err = a.TDXClient.GetMinute(a.AnalysisConfig.StockCode, "")
+		if err != nil {
+			tracef(opts.traceID, "⚠️  获取分时数据失败（可能非交易时间）: %v", err)
+			minuteData = nil // 非交易时间可能获取不到，设为nil
+		}
 	}
 
+	// 4.1 盘中用分时数据合成当前未收盘的30分钟K线，避免使用滞后的上一根K线
+	min30Kline = withRealtimeMin30Bar(min30Kline, minuteData, a.now())
+	opts.timer.mark("kline")
+
 	// 5. 计算技术指标
 	technicalData := a.calculateTechnicalIndicators(quote, dayKline, min30Kline)
 
+	// 5.0 日K线不足（如次新股）时指标不完整，跳过AI分析直接给出观望结果，避免无效调用
+	if days := len(dayKline.List); days < a.AnalysisConfig.MinKlineDays {
+		tracef(opts.traceID, "⏭️  [%s] 日K线仅%d根（要求至少%d根），数据不足，跳过AI分析", a.AnalysisConfig.StockName, days, a.AnalysisConfig.MinKlineDays)
+		result := a.insufficientDataResult(days, technicalData, opts.position)
+		result.TraceID = opts.traceID
+		return result, nil
+	}
+
+	// 5.0.1 多周期K线并行拉取，判断各周期趋势方向（多周期共振）
+	var missingPeriods []string
+	if len(a.AnalysisConfig.KlinePeriods) > 0 {
+		periodKlines := a.fetchMultiPeriodKlines(opts, a.AnalysisConfig.KlinePeriods, min30Kline)
+		a.calculateMultiPeriodTrends(periodKlines, technicalData)
+		for _, period := range a.AnalysisConfig.KlinePeriods {
+			if periodKlines[period] == nil {
+				missingPeriods = append(missingPeriods, period)
+			}
+		}
+	}
+
+	// 5.0.2 筹码分布（TDX代理提供时才有，获取失败不影响分析；虚拟组合、历史回放不适用）
+	if opts.replayAt.IsZero() {
+		if chip, err := a.getChipDistribution(); err == nil {
+			technicalData["chip_profit_ratio"] = chip.ProfitRatio
+			technicalData["chip_avg_cost"] = PriceToYuan(chip.AvgCost)
+			if chip.Cost70Low > 0 && chip.Cost70High > 0 {
+				technicalData["chip_main_cost_range"] = fmt.Sprintf("%.2f-%.2f元", PriceToYuan(chip.Cost70Low), PriceToYuan(chip.Cost70High))
+			}
+		} else if !errors.Is(err, ErrChipUnsupported) {
+			tracef(opts.traceID, "⚠️  获取筹码分布失败，跳过: %v", err)
+		}
+	}
+
+	// 5.0.3 流通股本（配置或TDX获取），计算流通市值和换手率，缺失时跳过
+	applyShareCapital(technicalData, a.floatShares())
+
+	// 5.0.3.1 近20日相对大盘强弱（配置了大盘指数时；历史回放时指数K线含回放时刻之后的数据，不适用）
+	if opts.replayAt.IsZero() {
+		a.applyRelativeStrength(dayKline, technicalData, opts.traceID)
+	}
+
+	// 5.0.4 近期新闻/公告摘要（历史回放时新闻源只能给出最新消息，不适用；获取失败不影响分析）
+	if a.AnalysisConfig.News != nil && opts.replayAt.IsZero() && !a.IsBasket() {
+		if news, err := a.AnalysisConfig.News.GetNews(a.AnalysisConfig.StockCode); err != nil {
+			tracef(opts.traceID, "⚠️  [%s] 获取新闻/公告失败，跳过消息面: %v", a.AnalysisConfig.StockName, err)
+		} else if len(news) > 0 {
+			technicalData["news"] = news
+		}
+	}
+
+	// 5.0.5 多数据源现价差异告警，提示AI现价经过校正
+	if opts.quoteWarning != "" {
+		technicalData["quote_warning"] = opts.quoteWarning
+	}
+
+	// 5.0.6 除权除息日识别（假设分析、历史回放时行情不对应当天，虚拟组合不适用）
+	if opts.realtime() && !a.IsBasket() {
+		a.detectExRights(quote, dayKline, technicalData)
+	}
+
+	// 5.1 均线交叉事件独立通知（不依赖AI）
+	if cross, ok := technicalData["ma_cross"].(string); ok && a.AnalysisConfig.EnableMACrossAlert && opts.realtime() {
+		a.sendMACrossAlert(cross, technicalData, opts.traceID)
+	}
+
+	// 5.2 指标预警规则求值（不依赖AI），只有实时分析才推送提醒
+	triggeredRules := a.evaluateAlertRules(technicalData, opts.realtime(), opts.traceID)
+
 	// 6. 构建AI分析提示词
-	prompt := a.buildAnalysisPrompt(quote, dayKline, min30Kline, minuteData, technicalData)
+	prompt := a.buildAnalysisPrompt(quote, dayKline, min30Kline, minuteData, technicalData, opts.position)
+	if !opts.replayAt.IsZero() {
+		prompt = fmt.Sprintf("⏪ 历史回放：以下数据截至 %s，请假设当前时间就是该时刻，只依据这些数据给出当时的操作建议，不要使用该时刻之后的任何信息。\n\n", opts.replayAt.Format("2006-01-02 15:04")) + prompt
+	}
+	if opts.whatIf {
+		prompt = fmt.Sprintf("⚠️ 情景推演：以下当前价 %.2f元 为用户假设的价格，并非实时行情，请假设价格已到达该位置给出操作建议。\n\n", PriceToYuan(quote.K.Close)) + prompt
+	}
+	if a.IsBasket() {
+		prompt = a.basketPromptHeader() + prompt
+	}
+
+	opts.timer.mark("indicators")
 
 	// 7. 调用AI进行分析
-	log.Printf("🤖 调用AI进行深度分析...")
+	tracef(opts.traceID, "🤖 调用AI进行深度分析...")
 	systemPrompt := "你是一位专业的A股分析师，精通技术分析和市场研判。"
-	aiResponse, err := a.MCPClient.CallWithMessages(systemPrompt, prompt)
+	if a.Security.Type != SecurityStock {
+		systemPrompt += fmt.Sprintf("你熟悉%s的交易规则和定价特点。", a.Security.TypeName)
+	}
+	if a.AnalysisConfig.PlainPrompt {
+		systemPrompt = toPlainTextPrompt(systemPrompt)
+		prompt = toPlainTextPrompt(prompt)
+	}
+	opts.debug.section("System Prompt", systemPrompt)
+	opts.debug.section("User Prompt", prompt)
+	var aiResponse string
+	var usage *mcp.Usage
+	if a.AnalysisConfig.RuleBasedAI {
+		tracef(opts.traceID, "🧪 [试运行] 使用本地规则代替AI调用（提示词%d字）", len([]rune(prompt)))
+		aiResponse, err = ruleBasedResponse(technicalData)
+	} else {
+		aiResponse, usage, err = a.callAI(systemPrompt, prompt, opts.traceID)
+	}
+	opts.timer.mark("ai")
 	if err != nil {
 		return nil, fmt.Errorf("AI分析失败: %w", err)
 	}
+	opts.debug.section("AI原始响应", aiResponse)
+	if usage != nil {
+		opts.debug.step("Token用量: 提示词%d，回复%d", usage.PromptTokens, usage.CompletionTokens)
+	}
 
 	// 8. 解析AI响应
-	result, err := a.parseAIResponse(aiResponse, quote, technicalData)
+	result, err := a.parseAIResponse(aiResponse, quote, technicalData, opts.traceID, opts.debug)
 	if err != nil {
 		return nil, fmt.Errorf("解析AI响应失败: %w", err)
 	}
+	result.SchemaVersion = notifier.SchemaVersion
+	result.TraceID = opts.traceID
+	a.attachPositionInfo(result, opts.position)
+	result.TechnicalValues = TechnicalValues(technicalData)
+	result.TriggeredRules = triggeredRules
+	a.assessDataQuality(result, technicalData, len(dayKline.List), missingPeriods)
+	if usage != nil {
+		result.PromptTokens = usage.PromptTokens
+		result.CompletionTokens = usage.CompletionTokens
+	}
 
-	// 9. 发送通知（如果启用且信心度达到阈值）
-	// 通知条件：启用通知 + 信心度≥阈值 + 信号是BUY/SELL/HOLD中的任意一个
-	if a.AnalysisConfig.EnableNotification &&
-		result.Confidence >= a.AnalysisConfig.MinConfidence {
-		// 所有信号（BUY/SELL/HOLD）都发送通知，只要信心度达到阈值
-		a.sendNotification(result)
+	// 8.1 AI信号与本地技术规则一致性校验
+	a.applyConsensus(result)
+	opts.timer.mark("parse")
+	if result.RuleSignal != "" {
+		opts.debug.step("本地技术规则信号: %s（一致=%v，矛盾=%v）", result.RuleSignal, result.RuleConfirmed, result.RuleConflict)
+	}
+	for _, reason := range result.QualityReasons {
+		opts.debug.step("数据质量降级: %s", reason)
 	}
 
 	return result, nil
 }
 
+// insufficientDataResult 日K线数量不足时的默认观望结果（不调用AI）
+func (a *StockAnalyzer) insufficientDataResult(days int, technical map[string]interface{}, position positionSnapshot) *AnalysisResult {
+	result := &AnalysisResult{
+		SchemaVersion: notifier.SchemaVersion,
+		StockCode:     a.AnalysisConfig.StockCode,
+		StockName:     a.AnalysisConfig.StockName,
+		CurrentPrice:  technical["current_price"].(float64),
+		Signal:        "HOLD",
+		Confidence:    0,
+		Reasoning:     fmt.Sprintf("数据不足，观望：日K线仅%d根，少于要求的%d根，均线等指标无法完整计算，本轮未进行AI分析", days, a.AnalysisConfig.MinKlineDays),
+		TechnicalData: technical,
+		Timestamp:     a.now(),
+
+		InsufficientData: true,
+	}
+	result.addQualityIssue(DataQualityDegraded, fmt.Sprintf("日K线仅%d根，未进行AI分析", days))
+	result.finishDataQuality()
+	result.TechnicalValues = TechnicalValues(technical)
+	a.attachPositionInfo(result, position)
+	return result
+}
+
+// feeRates 返回计算持仓费用所用的费率（ETF和可转债免收印花税）
+func (a *StockAnalyzer) feeRates() FeeRates {
+	return a.Security.feeRates(a.AnalysisConfig.FeeRates)
+}
+
+// confirmSignal 记录本轮信号并判断是否已确认
+// 未启用信号确认时总是返回true；启用时要求上一轮也是同一信号且都达到信心度阈值
+func (a *StockAnalyzer) confirmSignal(signal string, qualified bool) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	previous := a.lastQualified
+	if qualified {
+		a.lastQualified = signal
+	} else {
+		a.lastQualified = ""
+	}
+
+	if !a.AnalysisConfig.RequireConfirmation {
+		return true
+	}
+	return qualified && previous == signal
+}
+
 // calculateTechnicalIndicators 计算技术指标
 func (a *StockAnalyzer) calculateTechnicalIndicators(quote *QuoteData, dayKline *KlineData, min30Kline *KlineData) map[string]interface{} {
 	data := make(map[string]interface{})
@@ -148,6 +619,13 @@ func (a *StockAnalyzer) calculateTechnicalIndicators(quote *QuoteData, dayKline
 	data["low_price"] = PriceToYuan(quote.K.Low)
 	data["prev_close"] = PriceToYuan(quote.K.Last)
 
+	// 标的类型及涨跌停价（可转债无涨跌停）
+	data["security_type"] = string(a.Security.Type)
+	if up, down, ok := a.Security.LimitPrices(PriceToYuan(quote.K.Last)); ok {
+		data["limit_up_price"] = up
+		data["limit_down_price"] = down
+	}
+
 	// 涨跌幅
 	if quote.K.Last > 0 {
 		changePercent := (float64(quote.K.Close-quote.K.Last) / float64(quote.K.Last)) * 100
@@ -167,7 +645,8 @@ func (a *StockAnalyzer) calculateTechnicalIndicators(quote *QuoteData, dayKline
 
 	// 成交量和成交额
 	data["volume"] = VolumeToShares(quote.TotalHand)
-	data["amount"] = AmountToYuan(quote.Amount)
+	// 成交额统一换算为元（按成交量×现价校验原始单位，避免单位不一致导致数量级错误）
+	data["amount"] = NormalizeAmountToYuan(quote.Amount, quote.TotalHand, quote.K.Close)
 
 	// 内外盘比
 	if quote.InsideDish+quote.OuterDisc > 0 {
@@ -233,6 +712,17 @@ func (a *StockAnalyzer) calculateTechnicalIndicators(quote *QuoteData, dayKline
 		}
 	}
 
+	// 检测MA5/MA20金叉死叉（需要至少21根日K线，才能得到相邻两日的MA20）
+	if cross := DetectMACross(dayKline.List, 5, 20); cross != "" {
+		data["ma_cross"] = cross
+		data["ma_cross_text"] = getMACrossText(cross)
+	}
+
+	// 量比（当日成交量 / 前5个交易日平均成交量，盘中按已过交易时长折算），大于1.5通常视为放量
+	if ratio, ok := volumeRatio(dayKline.List, quote.TotalHand, a.now()); ok {
+		data["volume_ratio"] = fmt.Sprintf("%.2f", ratio)
+	}
+
 	// 计算简化RSI（相对强弱指标）
 	if len(dayKline.List) >= 14 {
 		rsi14 := a.calculateRSI(dayKline.List, 14)
@@ -245,9 +735,248 @@ func (a *StockAnalyzer) calculateTechnicalIndicators(quote *QuoteData, dayKline
 		data["volatility_20d"] = fmt.Sprintf("%.2f%%", volatility*100)
 	}
 
+	// MACD(12,26,9)
+	if dif, dea, hist, ok := CalculateMACD(dayKline.List); ok {
+		data["macd_dif"] = fmt.Sprintf("%.3f", dif)
+		data["macd_dea"] = fmt.Sprintf("%.3f", dea)
+		data["macd_hist"] = fmt.Sprintf("%.3f", hist)
+	}
+
+	// KDJ(9,3,3)
+	if k, d, j, ok := CalculateKDJ(dayKline.List); ok {
+		data["kdj_k"] = fmt.Sprintf("%.2f", k)
+		data["kdj_d"] = fmt.Sprintf("%.2f", d)
+		data["kdj_j"] = fmt.Sprintf("%.2f", j)
+	}
+
+	// 未回补的跳空缺口（支撑/压力参考）
+	if gaps := DetectPriceGaps(dayKline.List); len(gaps) > 0 {
+		data["price_gaps"] = gaps
+	}
+
+	// 停牌缺口处理：窗口跨越最近一次停牌缺口的指标会失真，按配置跳过或标注
+	a.applySuspensionGaps(dayKline, data)
+
+	// 近20日收盘价序列（按时间升序），用于通知中的迷你走势图
+	startIdx := len(dayKline.List) - recentClosesCount
+	if startIdx < 0 {
+		startIdx = 0
+	}
+	closes := make([]float64, 0, recentClosesCount)
+	for _, kline := range dayKline.List[startIdx:] {
+		closes = append(closes, PriceToYuan(kline.Close))
+	}
+	data["recent_closes"] = closes
+
 	return data
 }
 
+// recentClosesCount 迷你走势图使用的最近收盘价数量
+const recentClosesCount = 20
+
+// formatIndicator 格式化技术指标用于提示词展示，指标缺失（数据不足或被跳过）时显示"数据不足"
+func formatIndicator(technical map[string]interface{}, key string) string {
+	switch value := technical[key].(type) {
+	case float64:
+		return fmt.Sprintf("%.2f元", value)
+	case string:
+		return value
+	default:
+		return "数据不足"
+	}
+}
+
+// suspensionIndicatorWindows 受停牌缺口影响的指标及其所需的K线窗口长度
+var suspensionIndicatorWindows = []struct {
+	key    string
+	window int
+}{
+	{"ma5", 5},
+	{"ma10", 10},
+	{"ma20", 20},
+	{"ma60", 60},
+	{"rsi14", 15},
+	{"volatility_20d", 21},
+	{"macd_dif", 35},
+	{"macd_dea", 35},
+	{"macd_hist", 35},
+	{"kdj_k", 9},
+	{"kdj_d", 9},
+	{"kdj_j", 9},
+}
+
+// promptIndicator 提示词中可展示的技术指标
+type promptIndicator struct {
+	label string   // 展示名称
+	keys  []string // 对应technicalData中的键
+	names []string // 多值指标各分量的名称（单值指标为空）
+	note  string   // 附加说明
+}
+
+// promptIndicators 可在提示词中展示的技术指标（indicators_in_prompt 的可选值）
+var promptIndicators = map[string]promptIndicator{
+	"ma5":        {label: "MA5", keys: []string{"ma5"}},
+	"ma10":       {label: "MA10", keys: []string{"ma10"}},
+	"ma20":       {label: "MA20", keys: []string{"ma20"}},
+	"ma60":       {label: "MA60", keys: []string{"ma60"}, note: "（季线）"},
+	"rsi":        {label: "RSI(14)", keys: []string{"rsi14"}},
+	"volatility": {label: "近20日波动率", keys: []string{"volatility_20d"}},
+	"macd":       {label: "MACD(12,26,9)", keys: []string{"macd_dif", "macd_dea", "macd_hist"}, names: []string{"DIF", "DEA", "MACD柱"}},
+	"kdj":        {label: "KDJ(9,3,3)", keys: []string{"kdj_k", "kdj_d", "kdj_j"}, names: []string{"K", "D", "J"}},
+}
+
+// defaultPromptIndicators 未配置 indicators_in_prompt 时展示的指标
+var defaultPromptIndicators = []string{"ma5", "ma10", "ma20", "ma60", "rsi", "volatility"}
+
+// buildIndicatorSection 按配置的指标列表组织技术指标小节，未计算（数据不足或被跳过）的指标不展示
+func (a *StockAnalyzer) buildIndicatorSection(technical map[string]interface{}) string {
+	names := a.AnalysisConfig.IndicatorsInPrompt
+	if len(names) == 0 {
+		names = defaultPromptIndicators
+	}
+
+	section := "\n## 技术指标\n"
+	for _, name := range names {
+		indicator, ok := promptIndicators[name]
+		if !ok {
+			continue
+		}
+
+		values := make([]string, 0, len(indicator.keys))
+		for _, key := range indicator.keys {
+			if _, exists := technical[key]; !exists {
+				break
+			}
+			values = append(values, formatIndicator(technical, key))
+		}
+		if len(values) != len(indicator.keys) {
+			continue
+		}
+
+		if len(indicator.names) == 0 {
+			section += fmt.Sprintf("- **%s**: %s%s\n", indicator.label, values[0], indicator.note)
+			continue
+		}
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprintf("%s %s", indicator.names[i], value)
+		}
+		section += fmt.Sprintf("- **%s**: %s%s\n", indicator.label, strings.Join(parts, ", "), indicator.note)
+	}
+	section += relativeStrengthPromptLine(technical)
+	return section + "\n"
+}
+
+// applySuspensionGaps 检查指标计算窗口是否跨越停牌缺口（停牌前后价格直接相连会造成失真）
+// SkipSuspensionGaps为true时删除受影响的指标，否则记录受影响的指标列表用于标注
+func (a *StockAnalyzer) applySuspensionGaps(dayKline *KlineData, data map[string]interface{}) {
+	if len(dayKline.SuspensionGaps) == 0 {
+		return
+	}
+
+	latestGap := dayKline.SuspensionGaps[len(dayKline.SuspensionGaps)-1]
+	gaps := make([]map[string]interface{}, 0, len(dayKline.SuspensionGaps))
+	for _, gap := range dayKline.SuspensionGaps {
+		gaps = append(gaps, map[string]interface{}{
+			"from":         gap.From.Format("2006-01-02"),
+			"to":           gap.To.Format("2006-01-02"),
+			"missing_days": gap.MissingDays,
+		})
+	}
+	data["suspension_gaps"] = gaps
+
+	listLen := len(dayKline.List)
+	affected := []string{}
+	for _, indicator := range suspensionIndicatorWindows {
+		// 窗口起点在复牌K线之前，说明窗口跨越了停牌缺口
+		if listLen-indicator.window < latestGap.Index {
+			affected = append(affected, indicator.key)
+		}
+	}
+	if len(affected) == 0 {
+		return
+	}
+
+	data["suspension_affected"] = affected
+	if a.AnalysisConfig.SkipSuspensionGaps {
+		for _, key := range affected {
+			delete(data, key)
+		}
+		data["suspension_skipped"] = true
+	}
+}
+
+// multiPeriodFetchLimit 多周期K线同时请求的最大数量，避免周期较多时瞬间压垮TDX代理
+const multiPeriodFetchLimit = 4
+
+// fetchMultiPeriodKlines 并行拉取多个周期的K线数据（单个周期失败时跳过，不影响整体分析）
+// 已拉取的30分钟K线直接复用，避免重复请求
+func (a *StockAnalyzer) fetchMultiPeriodKlines(opts analyzeOptions, periods []string, min30Kline *KlineData) map[string]*KlineData {
+	results := make(map[string]*KlineData)
+	var mu sync.Mutex
+	var group errgroup.Group
+	group.SetLimit(multiPeriodFetchLimit)
+
+	for _, period := range periods {
+		if period == "minute30" && min30Kline != nil {
+			results[period] = min30Kline
+			continue
+		}
+
+		group.Go(func() error {
+			kline, err := a.klineFor(opts, period, a.minuteKlineCount())
+			if err != nil {
+				// 单个周期失败不中断其他周期，只跳过该周期
+				tracef(opts.traceID, "⚠️  获取%sK线失败，多周期分析跳过该周期: %v", getKlinePeriodText(period), err)
+				return nil
+			}
+			mu.Lock()
+			results[period] = kline
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	group.Wait()
+	return results
+}
+
+// calculateMultiPeriodTrends 计算各周期趋势方向和共振结论，写入technicalData
+func (a *StockAnalyzer) calculateMultiPeriodTrends(periodKlines map[string]*KlineData, data map[string]interface{}) {
+	trends := make(map[string]string)
+	counts := make(map[string]int)
+	for _, period := range a.AnalysisConfig.KlinePeriods {
+		kline, ok := periodKlines[period]
+		if !ok {
+			continue
+		}
+		if trend := DetectTrend(kline.List); trend != "" {
+			trends[period] = trend
+			counts[trend]++
+		}
+	}
+
+	if len(trends) == 0 {
+		return
+	}
+
+	// 共振判断：所有周期同向为强共振，超过半数同向为弱共振，否则为分歧
+	resonance := "分歧（各周期方向不一致）"
+	for _, trend := range []string{TrendUp, TrendDown} {
+		if counts[trend] == len(trends) && len(trends) >= 2 {
+			resonance = fmt.Sprintf("强共振（%d个周期均为%s）", len(trends), getTrendText(trend))
+			break
+		}
+		if counts[trend]*2 > len(trends) {
+			resonance = fmt.Sprintf("弱共振（%d/%d个周期为%s）", counts[trend], len(trends), getTrendText(trend))
+			break
+		}
+	}
+
+	data["multi_period_trends"] = trends
+	data["multi_period_resonance"] = resonance
+}
+
 // calculateRSI 计算RSI指标（简化版）
 func (a *StockAnalyzer) calculateRSI(klines []KlineItem, period int) float64 {
 	if len(klines) < period+1 {
@@ -323,14 +1052,16 @@ func (a *StockAnalyzer) calculateVolatility(klines []KlineItem, period int) floa
 }
 
 // buildAnalysisPrompt 构建AI分析提示词
-func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineData, min30Kline *KlineData, minuteData *MinuteData, technical map[string]interface{}) string {
-	prompt := fmt.Sprintf(`# 股票深度分析任务
+func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineData, min30Kline *KlineData, minuteData *MinuteData, technical map[string]interface{}, position positionSnapshot) string {
+	security := a.Security
+	prompt := fmt.Sprintf(`# %s深度分析任务
 
-你是一位专业的A股分析师，请对以下股票进行深度技术分析，并给出明确的操作建议。
+你是一位专业的A股分析师，请对以下%s进行深度技术分析，并给出明确的操作建议。
 
 ## 基本信息
-- **股票代码**: %s
-- **股票名称**: %s
+- **%s代码**: %s
+- **%s名称**: %s
+- **交易规则**: %s
 - **分析时间**: %s
 
 ## 实时行情数据
@@ -343,16 +1074,21 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 - **涨跌率**: %s
 - **现量**: %d手（当前成交的成交量）
 - **成交量**: %d股
-- **成交额**: %.2f万元
+- **成交额**: %s
 - **外盘占比**: %s（外盘越高说明买盘越强）
 - **买卖盘比**: %s（>1说明买盘强于卖盘）
 
 ## 五档盘口
 **买盘**:
 `,
+		security.TypeName,
+		security.TypeName,
+		security.TypeName,
 		a.AnalysisConfig.StockCode,
+		security.TypeName,
 		a.AnalysisConfig.StockName,
-		time.Now().Format("2006-01-02 15:04:05"),
+		security.RulesText(technical["prev_close"].(float64)),
+		a.now().Format("2006-01-02 15:04:05"),
 		technical["current_price"].(float64),
 		technical["open_price"].(float64),
 		technical["high_price"].(float64),
@@ -362,9 +1098,9 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 		technical["rate"].(string),
 		quote.Intuition,
 		technical["volume"].(int64),
-		AmountToYuan(quote.Amount)/10000,
-		technical["outer_ratio"].(string),
-		technical["buy_sell_ratio"].(string),
+		FormatAmount(technical["amount"].(float64)),
+		formatIndicator(technical, "outer_ratio"),
+		formatIndicator(technical, "buy_sell_ratio"),
 	)
 
 	// 添加买五档
@@ -378,54 +1114,153 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 		prompt += fmt.Sprintf("- 卖%d: %.2f元 x %d股\n", i+1, PriceToYuan(level.Price), level.Number)
 	}
 
-	// 添加技术指标
-	prompt += fmt.Sprintf(`
-## 技术指标
-- **MA5**: %.2f元
-- **MA10**: %.2f元
-- **MA20**: %.2f元
-- **MA60**: %.2f元（季线）
-- **RSI(14)**: %s
-- **近20日波动率**: %s
+	// 添加技术指标（按 indicators_in_prompt 配置组织）
+	prompt += a.buildIndicatorSection(technical)
 
-`,
-		technical["ma5"].(float64),
-		technical["ma10"].(float64),
-		technical["ma20"].(float64),
-		technical["ma60"].(float64),
-		technical["rsi14"].(string),
-		technical["volatility_20d"].(string),
-	)
+	// 筹码分布（数据可得时）
+	if profitRatio, ok := technical["chip_profit_ratio"].(float64); ok {
+		prompt += "## 筹码分布\n"
+		prompt += fmt.Sprintf("- **获利盘比例**: %.1f%%\n", profitRatio)
+		prompt += fmt.Sprintf("- **平均成本**: %s\n", formatIndicator(technical, "chip_avg_cost"))
+		if costRange, ok := technical["chip_main_cost_range"].(string); ok {
+			prompt += fmt.Sprintf("- **主力成本区（70%%筹码）**: %s\n", costRange)
+		}
+		prompt += "（获利盘比例低说明上方套牢盘多、反弹压力大；主力成本区附近通常有较强支撑或压力）\n\n"
+	}
+
+	// 除权除息提示
+	prompt += exRightsPromptSection(technical)
+
+	// 跳空缺口
+	prompt += priceGapPromptSection(technical)
+
+	// 行情数据源告警
+	if warning, ok := technical["quote_warning"].(string); ok {
+		prompt += fmt.Sprintf("## 数据源提示\n- %s，盘口和成交量等其他数据来自主数据源，可能存在误差，请谨慎判断\n\n", warning)
+	}
+
+	// 停牌提示
+	if gaps, ok := technical["suspension_gaps"].([]map[string]interface{}); ok && len(gaps) > 0 {
+		prompt += "## 停牌提示\n"
+		prompt += "- **该股近期有停牌**，停牌前后价格直接相连，相关指标可能失真：\n"
+		for _, gap := range gaps {
+			prompt += fmt.Sprintf("  - %s 至 %s 期间停牌（缺失%d个交易日）\n", gap["from"], gap["to"], gap["missing_days"])
+		}
+		if affected, ok := technical["suspension_affected"].([]string); ok {
+			if technical["suspension_skipped"] == true {
+				prompt += fmt.Sprintf("- 以下指标窗口跨越停牌缺口，已跳过计算: %s\n", strings.Join(affected, ", "))
+			} else {
+				prompt += fmt.Sprintf("- 以下指标窗口跨越停牌缺口，参考价值降低: %s\n", strings.Join(affected, ", "))
+			}
+		}
+		prompt += "\n"
+	}
+
+	// 均线交叉事件
+	if crossText, ok := technical["ma_cross_text"].(string); ok {
+		prompt += fmt.Sprintf("- **均线交叉事件**: 今日出现%s\n\n", crossText)
+	}
+
+	// 多周期共振
+	if trends, ok := technical["multi_period_trends"].(map[string]string); ok && len(trends) > 0 {
+		prompt += "## 多周期共振\n"
+		for _, period := range a.AnalysisConfig.KlinePeriods {
+			if trend, ok := trends[period]; ok {
+				prompt += fmt.Sprintf("- **%sK线趋势**: %s\n", getKlinePeriodText(period), getTrendText(trend))
+			}
+		}
+		prompt += fmt.Sprintf("- **共振结论**: %s\n", technical["multi_period_resonance"])
+		prompt += "（多个周期趋势同向时信号更可信，周期间分歧时请降低信心度）\n\n"
+	}
+
+	// 市值与流通盘（可获取流通股本时）
+	if marketCap, ok := technical["float_market_cap"].(float64); ok {
+		size, _ := technical["cap_size"].(string)
+		prompt += "## 市值与流通盘\n"
+		prompt += fmt.Sprintf("- **流通股本**: %.2f亿股\n", technical["float_shares"])
+		prompt += fmt.Sprintf("- **流通市值**: %.2f亿元（%s）\n", marketCap, getCapSizeText(size))
+		if turnover, ok := technical["turnover_rate"].(string); ok {
+			prompt += fmt.Sprintf("- **今日换手率**: %s\n", turnover)
+		}
+		switch size {
+		case "small":
+			prompt += "（小盘股筹码少、易被资金推动，请对量能异动更敏感：放量突破、异常换手往往意味着资金介入或出逃，同时注意追高风险）\n\n"
+		case "large":
+			prompt += "（大盘股走势相对稳健，单日量能波动的信号意义较弱，请更多关注趋势和持续性放量）\n\n"
+		default:
+			prompt += "\n"
+		}
+	}
+
+	// 消息面（配置了新闻源且有近期新闻时）
+	if news, ok := technical["news"].([]NewsItem); ok && len(news) > 0 {
+		prompt += "## 消息面\n"
+		for _, item := range news {
+			line := "- "
+			if item.Time != "" {
+				line += item.Time + " "
+			}
+			if item.Source != "" {
+				line += "[" + item.Source + "] "
+			}
+			prompt += line + item.Title + "\n"
+		}
+		prompt += "（以上为近期新闻/公告标题，请结合技术面判断其影响，如减持、业绩预告、监管问询等利空或利好；消息面与技术面矛盾时请降低信心度）\n\n"
+	}
 
 	// 检查是否为持仓模式，如果是则添加持仓信息
-	if a.AnalysisConfig.IsPositionMode() {
+	if position.held() {
 		currentPrice := technical["current_price"].(float64)
 		positionInfo := CalculatePositionInfo(
 			a.AnalysisConfig.StockCode,
 			a.AnalysisConfig.StockName,
-			a.AnalysisConfig.PositionQuantity,
-			a.AnalysisConfig.BuyPrice,
+			position.quantity,
+			position.buyPrice,
 			currentPrice,
-			a.AnalysisConfig.BuyDate,
+			position.buyDate,
+			a.now(),
+			a.feeRates(),
 		)
 
+		positionInfo.RealizedProfitLoss = a.AnalysisConfig.RealizedProfitLoss
+		positionInfo.FeeTemplate = a.AnalysisConfig.FeeTemplate
+
 		prompt += fmt.Sprintf(`
 ## 持仓信息
-- **持仓数量**: %d股
-- **购买价格**: %.2f元/股
+- **持仓数量**: %d%s
+- **购买价格**: %.2f元/%s
 - **持仓成本**: %.2f元
-- **当前价格**: %.2f元/股
+- **当前价格**: %.2f元/%s
 - **市值**: %.2f元
 - **浮动盈亏**: %s
-
+- **扣费后净盈亏**: %s
+- **回本价**: %.3f元/%s（已计入买卖费用，费率模板：%s）
 `,
 			positionInfo.Quantity,
+			security.UnitName,
 			positionInfo.BuyPrice,
+			security.UnitName,
 			positionInfo.TotalCost,
 			positionInfo.CurrentPrice,
+			security.UnitName,
 			positionInfo.MarketValue,
 			positionInfo.FormatProfitLoss(),
+			positionInfo.FormatNetProfitLoss(),
+			positionInfo.BreakEvenPrice,
+			security.UnitName,
+			positionInfo.FeeTemplate,
 		)
+		if positionInfo.HoldingDays > 0 {
+			prompt += fmt.Sprintf("- **持有天数**: %d天\n- **扣费后年化收益率**: %.2f%%", positionInfo.HoldingDays, positionInfo.AnnualizedReturn)
+			if positionInfo.AnnualizedNote != "" {
+				prompt += fmt.Sprintf("（%s）", positionInfo.AnnualizedNote)
+			}
+			prompt += "\n"
+		}
+		if positionInfo.RealizedProfitLoss != 0 {
+			prompt += fmt.Sprintf("- **已实现盈亏**: %.2f元（历史卖出部分，已扣费）\n", positionInfo.RealizedProfitLoss)
+		}
+		prompt += "\n"
 	}
 
 	// 添加K线概况
@@ -444,14 +1279,14 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 		// 从最新的一天开始倒序显示
 		for i := listLen - 1; i >= listLen-5 && i >= 0; i-- {
 			kline := dayKline.List[i]
-			prompt += fmt.Sprintf("- %s: 开%.2f 高%.2f 低%.2f 收%.2f元 | 成交量: %d手 | 成交额: %.2f万元\n",
+			prompt += fmt.Sprintf("- %s: 开%.2f 高%.2f 低%.2f 收%.2f元 | 成交量: %d手 | 成交额: %s\n",
 				kline.Time.Format("01-02"),
 				PriceToYuan(kline.Open),
 				PriceToYuan(kline.High),
 				PriceToYuan(kline.Low),
 				PriceToYuan(kline.Close),
 				kline.Volume,
-				AmountToYuan(kline.Amount)/10000)
+				FormatAmount(NormalizeAmountToYuan(kline.Amount, kline.Volume, kline.Close)))
 		}
 	}
 
@@ -465,13 +1300,14 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 		}
 		for i := startIdx; i < listLen; i++ {
 			kline := min30Kline.List[i]
-			prompt += fmt.Sprintf("- %s: 开%.2f 高%.2f 低%.2f 收%.2f元 | 成交量: %d手\n",
+			prompt += fmt.Sprintf("- %s: 开%.2f 高%.2f 低%.2f 收%.2f元 | 成交量: %d手%s\n",
 				kline.Time.Format("01-02 15:04"),
 				PriceToYuan(kline.Open),
 				PriceToYuan(kline.High),
 				PriceToYuan(kline.Low),
 				PriceToYuan(kline.Close),
-				kline.Volume)
+				kline.Volume,
+				partialBarText(kline))
 		}
 	}
 
@@ -503,7 +1339,7 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 	}
 
 	// 分析要求（根据是否为持仓模式调整）
-	if a.AnalysisConfig.IsPositionMode() {
+	if position.held() {
 		prompt += `
 ## 分析要求
 
@@ -557,10 +1393,18 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 {
   "signal": "BUY 或 SELL 或 HOLD",
   "confidence": 0-100的整数（信心度，越高越确定）,
-  "reasoning": "详细的分析理由，包含关键技术指标、持仓评估和逻辑",
+  "reasoning": {
+    "trend": "趋势分析：均线排列、K线形态、多周期方向",
+    "volume_price": "量价关系：成交量变化是否配合价格走势",
+    "order_book": "盘口分析：买卖盘力量、内外盘占比",
+    "risk": "风险与持仓评估：支撑压力位、持仓盈亏、风险收益比",
+    "summary": "综合结论和操作理由"
+  },
   "target_price": 目标价格（元，数字），如果是SELL或HOLD可以为0,
   "stop_loss": 止损价格（元，数字），如果是HOLD可以为0,
   "risk_reward": "风险回报比，例如 1:2 或 1:3",
+  "probabilities": {"up": 上涨概率, "sideways": 震荡概率, "down": 下跌概率},
+  "limit_move": {"up": "low 或 medium 或 high", "down": "low 或 medium 或 high"},
   "position_profit_target": 持仓止盈价格（元，数字），基于持仓成本价和技术分析给出,
   "position_stop_loss": 持仓止损价格（元，数字），基于持仓成本价和技术分析给出
 }
@@ -568,6 +1412,9 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 
 **注意事项**:
 - signal: BUY（建议买入/加仓）、SELL（建议卖出）、HOLD（建议持有）
+- reasoning 按 trend/volume_price/order_book/risk/summary 五个小节输出，每个小节写明分析逻辑和关键依据
+- probabilities: 未来几个交易日上涨/震荡/下跌三种情形的概率（0-100的整数，三者合计100）
+- limit_move: 未来1-3个交易日触及涨停（up）/跌停（down）的可能性，结合涨跌停价距离、封单情况、量能和题材热度给出定性判断（low/medium/high），无涨跌停限制的标的填 low
 - position_profit_target: 持仓止盈价，应该高于购买价格（如果盈利）或当前价格（如果亏损但看涨）
 - position_stop_loss: 持仓止损价，应该低于购买价格（如果盈利）或当前价格（如果亏损）
 - 如果是当前有持仓且盈利，应谨慎评估是否需要止盈
@@ -617,10 +1464,18 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 {
   "signal": "BUY 或 SELL 或 HOLD",
   "confidence": 0-100的整数（信心度，越高越确定）,
-  "reasoning": "详细的分析理由，包含关键技术指标和逻辑",
+  "reasoning": {
+    "trend": "趋势分析：均线排列、K线形态、多周期方向",
+    "volume_price": "量价关系：成交量变化是否配合价格走势",
+    "order_book": "盘口分析：买卖盘力量、内外盘占比",
+    "risk": "风险评估：支撑压力位、风险收益比",
+    "summary": "综合结论和操作理由"
+  },
   "target_price": 目标价格（元，数字），如果是SELL或HOLD可以为0,
   "stop_loss": 止损价格（元，数字），如果是HOLD可以为0,
   "risk_reward": "风险回报比，例如 1:2 或 1:3",
+  "probabilities": {"up": 上涨概率, "sideways": 震荡概率, "down": 下跌概率},
+  "limit_move": {"up": "low 或 medium 或 high", "down": "low 或 medium 或 high"},
   "position_profit_target": 0,
   "position_stop_loss": 0
 }
@@ -629,27 +1484,54 @@ func (a *StockAnalyzer) buildAnalysisPrompt(quote *QuoteData, dayKline *KlineDat
 **注意事项**:
 - signal只能是 "BUY"、"SELL" 或 "HOLD" 三个值之一
 - confidence是0-100的整数，代表你的信心程度
-- reasoning要详细说明你的分析逻辑和关键依据
+- reasoning 按 trend/volume_price/order_book/risk/summary 五个小节输出，每个小节详细说明分析逻辑和关键依据
 - 如果是BUY信号，必须给出target_price和stop_loss
+- probabilities 给出未来几个交易日上涨/震荡/下跌三种情形的概率（0-100的整数，三者合计100）
+- limit_move: 未来1-3个交易日触及涨停（up）/跌停（down）的可能性，结合涨跌停价距离、封单情况、量能和题材热度给出定性判断（low/medium/high），无涨跌停限制的标的填 low
 - 如果是SELL信号，应该给出止损建议
 - 如果是HOLD，说明原因（如趋势不明、等待突破等）
 - position_profit_target 和 position_stop_loss 在监控模式下为0
 `
 	}
 
+	// reasoning字数限制（控制token消耗，解析后超长的部分会被截断）
+	if limit := a.AnalysisConfig.MaxReasoningChars; limit > 0 {
+		prompt += fmt.Sprintf("- reasoning 各小节合计控制在%d字以内，只写关键依据和结论，不要复述上面的数据\n", limit)
+	}
+
 	return prompt
 }
 
+var (
+	// emojiPattern 匹配常见emoji及其修饰符（变体选择符、零宽连接符）
+	emojiPattern = regexp.MustCompile(`[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{2300}-\x{23FF}\x{2B00}-\x{2BFF}\x{FE0F}\x{200D}]`)
+	// markdownHeadingPattern 匹配markdown标题行（# 标题）
+	markdownHeadingPattern = regexp.MustCompile(`(?m)^#{1,6}\s*(.+?)\s*$`)
+)
+
+// toPlainTextPrompt 将提示词转换为纯文本：去除emoji，标题改为【标题】，去除加粗标记和代码块围栏
+// JSON输出格式示例本身保留，保证模型仍按约定格式输出
+func toPlainTextPrompt(prompt string) string {
+	result := emojiPattern.ReplaceAllString(prompt, "")
+	result = markdownHeadingPattern.ReplaceAllString(result, "【$1】")
+	result = strings.ReplaceAll(result, "**", "")
+	result = strings.ReplaceAll(result, "```json", "")
+	result = strings.ReplaceAll(result, "```", "")
+	return result
+}
+
 // parseAIResponse 解析AI响应
-func (a *StockAnalyzer) parseAIResponse(aiResponse string, quote *QuoteData, technical map[string]interface{}) (*AnalysisResult, error) {
+// debug为调试追踪记录（为nil时不记录解析过程）
+func (a *StockAnalyzer) parseAIResponse(aiResponse string, quote *QuoteData, technical map[string]interface{}, traceID string, debug *debugTrace) (*AnalysisResult, error) {
 	// 1. 解析AI响应中的JSON决策
 	aiDecision, err := ParseAIResponse(aiResponse)
 	if err != nil {
 		// 如果解析失败，记录完整响应并返回默认HOLD信号
-		log.Printf("⚠️  AI响应解析失败: %v", err)
-		log.Printf("AI原始响应:\n%s", aiResponse)
+		tracef(traceID, "⚠️  AI响应解析失败: %v", err)
+		tracef(traceID, "AI原始响应:\n%s", aiResponse)
+		debug.step("JSON解析失败，使用默认观望结果: %v", err)
 
-		return &AnalysisResult{
+		result := &AnalysisResult{
 			StockCode:     a.AnalysisConfig.StockCode,
 			StockName:     a.AnalysisConfig.StockName,
 			CurrentPrice:  technical["current_price"].(float64),
@@ -657,17 +1539,29 @@ func (a *StockAnalyzer) parseAIResponse(aiResponse string, quote *QuoteData, tec
 			Confidence:    30,
 			Reasoning:     fmt.Sprintf("AI响应解析失败，建议观望。原始响应: %s", aiResponse),
 			TechnicalData: technical,
-			Timestamp:     time.Now(),
-		}, nil
+			Timestamp:     a.now(),
+		}
+		result.addQualityIssue(DataQualityDegraded, "AI响应解析失败，结果为默认观望")
+		return result, nil
+	}
+
+	// 1.1 限制reasoning长度（AI未遵守字数要求时截断）
+	debug.step("JSON解析成功: 信号%s，信心度%d%%，目标价%.2f，止损价%.2f，风险回报比%s",
+		aiDecision.Signal, aiDecision.Confidence, aiDecision.TargetPrice, aiDecision.StopLoss, aiDecision.RiskReward)
+	truncated := aiDecision.TruncateReasoning(a.AnalysisConfig.MaxReasoningChars)
+	if truncated {
+		tracef(traceID, "✂️  AI分析理由超过%d字，已截断", a.AnalysisConfig.MaxReasoningChars)
+		debug.step("分析理由超过%d字，已截断", a.AnalysisConfig.MaxReasoningChars)
 	}
 
 	// 2. 验证决策合理性
 	currentPrice := technical["current_price"].(float64)
 	warnings := ValidateDecision(aiDecision, currentPrice)
 	if len(warnings) > 0 {
-		log.Printf("⚠️  决策验证警告:")
+		tracef(traceID, "⚠️  决策验证警告:")
 		for _, warning := range warnings {
-			log.Printf("   - %s", warning)
+			tracef(traceID, "   - %s", warning)
+			debug.step("决策验证警告: %s", warning)
 		}
 		// 将警告添加到reasoning中
 		aiDecision.Reasoning += "\n\n【系统提示】\n" + strings.Join(warnings, "\n")
@@ -682,27 +1576,58 @@ func (a *StockAnalyzer) parseAIResponse(aiResponse string, quote *QuoteData, tec
 		technical,
 	)
 
+	result.Timestamp = a.now()
+	result.ReasoningTruncated = truncated
+	if ratio, ok := ParseRiskReward(result.RiskReward); ok {
+		result.RiskRewardRatio = math.Round(ratio*100) / 100
+	}
+
 	// 4. 记录决策日志
-	log.Printf("✓ AI决策: %s | 信号: %s | 信心度: %d%%",
+	tracef(traceID, "✓ AI决策: %s | 信号: %s | 信心度: %d%%",
 		a.AnalysisConfig.StockName,
 		result.Signal,
 		result.Confidence)
 
 	if result.Signal == "BUY" {
-		log.Printf("  目标价: %.2f | 止损价: %.2f | 风险回报比: %s",
+		tracef(traceID, "  目标价: %.2f | 止损价: %.2f | 风险回报比: %s",
 			result.TargetPrice, result.StopLoss, result.RiskReward)
 	}
 
 	return result, nil
 }
 
+// attachPositionInfo 持仓模式下为结果附加持仓信息（含扣费后净盈亏和年化收益率）
+func (a *StockAnalyzer) attachPositionInfo(result *AnalysisResult, position positionSnapshot) {
+	if !position.held() {
+		return
+	}
+	result.PositionInfo = CalculatePositionInfo(
+		a.AnalysisConfig.StockCode,
+		a.AnalysisConfig.StockName,
+		position.quantity,
+		position.buyPrice,
+		result.CurrentPrice,
+		position.buyDate,
+		a.now(),
+		a.feeRates(),
+	)
+	result.PositionInfo.RealizedProfitLoss = a.AnalysisConfig.RealizedProfitLoss
+	result.PositionInfo.FeeTemplate = a.AnalysisConfig.FeeTemplate
+}
+
 // sendNotification 发送通知
 func (a *StockAnalyzer) sendNotification(result *AnalysisResult) {
 	if a.Notifier == nil {
 		return
 	}
+	a.deliverNotification(result, false)
+}
+
+// deliverNotification 组装并推送交易信号通知；resend为true时为补发历史结果（见ResendNotification）
+func (a *StockAnalyzer) deliverNotification(result *AnalysisResult, resend bool) error {
 
 	signal := &notifier.TradingSignal{
+		SchemaVersion: notifier.SchemaVersion,
 		StockCode:     result.StockCode,
 		StockName:     result.StockName,
 		Signal:        result.Signal,
@@ -718,6 +1643,22 @@ func (a *StockAnalyzer) sendNotification(result *AnalysisResult) {
 		// 新增：持仓止盈止损价格
 		PositionProfitTarget: result.PositionProfitTarget,
 		PositionStopLoss:     result.PositionStopLoss,
+		ExRightsDay:          isExRightsDay(result.TechnicalData),
+	}
+
+	if closes, ok := result.TechnicalData["recent_closes"].([]float64); ok {
+		signal.RecentCloses = closes
+	}
+	if result.Probabilities != nil {
+		signal.Probabilities = result.Probabilities.Text()
+	}
+	if result.LimitMove != nil {
+		signal.LimitMove = result.LimitMove.Text()
+	}
+	signal.ChartURL = notifier.ChartURL(result.StockCode, a.AnalysisConfig.ChartProvider)
+	if a.AnalysisConfig.APIBaseURL != "" {
+		signal.DetailURL = fmt.Sprintf("%s/stock/%s/latest", a.AnalysisConfig.APIBaseURL, result.StockCode)
+		signal.AnalyzeURL = a.analyzeLink(result.StockCode)
 	}
 
 	// 如果有持仓信息，转换为map格式传递
@@ -730,13 +1671,201 @@ func (a *StockAnalyzer) sendNotification(result *AnalysisResult) {
 			"market_value":        result.PositionInfo.MarketValue,
 			"profit_loss":         result.PositionInfo.ProfitLoss,
 			"profit_loss_percent": result.PositionInfo.ProfitLossPercent,
+
+			"net_profit_loss":         result.PositionInfo.NetProfitLoss,
+			"net_profit_loss_percent": result.PositionInfo.NetProfitLossPercent,
+			"total_fees":              result.PositionInfo.BuyFee + result.PositionInfo.SellFee,
+			"holding_days":            result.PositionInfo.HoldingDays,
+			"annualized_return":       result.PositionInfo.AnnualizedReturn,
+			"annualized_note":         result.PositionInfo.AnnualizedNote,
+			"realized_profit_loss":    result.PositionInfo.RealizedProfitLoss,
+			"break_even_price":        result.PositionInfo.BreakEvenPrice,
+		}
+	}
+
+	// 先翻译再做合规过滤，保证过滤的是最终推送的文本
+	if translator := a.AnalysisConfig.Translator; translator != nil {
+		translated, usage, err := translator.Translate(signal.Reasoning)
+		a.recordAIUsage(usage)
+		if err != nil {
+			tracef(result.TraceID, "⚠️  [%s] 翻译分析理由失败，使用原文: %v", a.AnalysisConfig.StockName, err)
+		} else {
+			signal.Reasoning = translated
+		}
+	}
+
+	if filter := a.AnalysisConfig.ComplianceFilter; filter != nil {
+		var hits []string
+		if signal.Reasoning, hits = filter.Apply(signal.Reasoning); len(hits) > 0 {
+			tracef(result.TraceID, "🛡️  [%s] 分析理由命中敏感词: %s", a.AnalysisConfig.StockName, strings.Join(hits, "、"))
 		}
 	}
 
+	if signal.ExRightsDay {
+		signal.Reasoning = "【除权除息】今日除权除息，价格已调整，跌幅告警已抑制\n" + signal.Reasoning
+	}
+
+	signal.DataQuality = result.DataQuality
+	if result.DataLimited() {
+		signal.Reasoning = fmt.Sprintf("【数据受限】%s，结论仅供参考\n", strings.Join(result.QualityReasons, "；")) + signal.Reasoning
+	}
+
+	if fill := result.TradeFill; fill != nil {
+		signal.Reasoning = fmt.Sprintf("【模拟成交】%s %d @ %.2f元（费用%.2f元）\n", getSideText(fill.Side), fill.Quantity, fill.Price, fill.Fee) + signal.Reasoning
+	}
+
+	// 价格事件写在推理原因最前面
+	if result.PriceEvent != "" {
+		signal.Reasoning = fmt.Sprintf("【价格事件】%s（冷静期内仍推送）\n", result.PriceEvent) + signal.Reasoning
+	}
+
+	if resend {
+		signal.Reasoning = fmt.Sprintf("【补发】原分析时间 %s\n", result.Timestamp.Format("2006-01-02 15:04")) + signal.Reasoning
+	}
+
+	// 根据信心度、信号类型和盈亏告警确定通知优先级（价格事件至少为高优先级）
+	signal.Priority = notifier.DeterminePriority(signal)
+	if result.PriceEvent != "" && notifier.PriorityRank(signal.Priority) < notifier.PriorityRank(notifier.PriorityHigh) {
+		signal.Priority = notifier.PriorityHigh
+	}
+	if result.RuleConfirmed && a.AnalysisConfig.ConsensusBoost {
+		signal.Priority = notifier.RaisePriority(signal.Priority)
+	}
+	a.positionLinkPriority(result, signal)
+	if a.AnalysisConfig.MuteLowPriority && signal.Priority == notifier.PriorityLow {
+		tracef(result.TraceID, "🔕 低优先级通知已静默: %s %s", result.StockCode, result.Signal)
+		return ErrNotificationMuted
+	}
+	if resend {
+		// 补发由用户手动发起，不受静默期限制，也不影响冷静期和价格事件的参考价
+		if err := a.Notifier.SendSignal(signal); err != nil {
+			tracef(result.TraceID, "❌ 补发通知失败: %v", err)
+			return err
+		}
+		tracef(result.TraceID, "✅ 已补发%s信号通知（原分析时间 %s）", result.Signal, result.Timestamp.Format("2006-01-02 15:04"))
+		return nil
+	}
+	if signal.Priority != notifier.PriorityUrgent && a.inQuietPeriod(a.now()) {
+		result.QuietSuppressed = true
+		tracef(result.TraceID, "🌙 [%s] 处于通知静默期，%s信号只记录不推送", a.AnalysisConfig.StockName, result.Signal)
+		return nil
+	}
+	a.recordNotify(result)
+
 	if err := a.Notifier.SendSignal(signal); err != nil {
-		log.Printf("❌ 发送通知失败: %v", err)
+		tracef(result.TraceID, "❌ 发送通知失败: %v", err)
+		a.AnalysisConfig.ErrorReporter.CaptureError(err, map[string]string{
+			"stock_code": result.StockCode,
+			"stock_name": result.StockName,
+			"signal":     result.Signal,
+			"stage":      "notification",
+		})
+		return err
+	}
+	tracef(result.TraceID, "✅ 已发送%s信号通知", result.Signal)
+	return nil
+}
+
+// sendMACrossAlert 发送均线交叉事件通知（同一事件每天只提醒一次）
+func (a *StockAnalyzer) sendMACrossAlert(cross string, technical map[string]interface{}, traceID string) {
+	if a.Notifier == nil || !a.notificationEnabled() || a.inQuietPeriod(a.now()) {
+		return
+	}
+
+	today := a.now().Format("2006-01-02")
+	a.mutex.Lock()
+	if a.lastCrossAlertAt == nil {
+		a.lastCrossAlertAt = make(map[string]string)
+	}
+	if a.lastCrossAlertAt[cross] == today {
+		a.mutex.Unlock()
+		return
+	}
+	a.lastCrossAlertAt[cross] = today
+	a.mutex.Unlock()
+
+	emoji := "📈"
+	if cross == MACrossDeath {
+		emoji = "📉"
+	}
+	message := fmt.Sprintf("%s 均线交叉提醒 - %s(%s)\n今日出现%s\n当前价格: %.2f元\nMA5: %.2f元 | MA20: %.2f元\n时间: %s",
+		emoji,
+		a.AnalysisConfig.StockName,
+		a.AnalysisConfig.StockCode,
+		getMACrossText(cross),
+		technical["current_price"],
+		technical["ma5"],
+		technical["ma20"],
+		a.now().Format("2006-01-02 15:04:05"))
+
+	if err := a.Notifier.SendMessage(message); err != nil {
+		tracef(traceID, "❌ 发送均线交叉通知失败: %v", err)
 	} else {
-		log.Printf("✅ 已发送%s信号通知", result.Signal)
+		tracef(traceID, "✅ 已发送均线交叉通知: %s %s", a.AnalysisConfig.StockCode, getMACrossText(cross))
+	}
+}
+
+// SetPosition 运行时更新持仓（导入持仓时调用，下一轮分析生效），导入过持仓的股票按持仓跟踪
+func (a *StockAnalyzer) SetPosition(quantity int, buyPrice float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.AnalysisConfig.PositionQuantity = quantity
+	a.AnalysisConfig.BuyPrice = buyPrice
+	a.AnalysisConfig.TrackPositions = true
+}
+
+// position 当前持仓配置的快照
+func (a *StockAnalyzer) position() positionSnapshot {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return positionSnapshot{
+		track:    a.AnalysisConfig.TrackPositions,
+		quantity: a.AnalysisConfig.PositionQuantity,
+		buyPrice: a.AnalysisConfig.BuyPrice,
+		buyDate:  a.AnalysisConfig.BuyDate,
+	}
+}
+
+// SetMuted 设置静音状态（静音时照常分析，但不推送任何通知）
+func (a *StockAnalyzer) SetMuted(muted bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.muted = muted
+}
+
+// IsMuted 是否处于静音状态
+func (a *StockAnalyzer) IsMuted() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.muted
+}
+
+// notificationEnabled 是否推送通知（启用通知且未静音）
+func (a *StockAnalyzer) notificationEnabled() bool {
+	return a.AnalysisConfig.EnableNotification && !a.IsMuted()
+}
+
+// sendQuoteWarning 发送多数据源现价差异告警（同一股票quoteWarningInterval内只推送一次，数据源持续异常时不刷屏）
+func (a *StockAnalyzer) sendQuoteWarning(warning, traceID string) {
+	if a.Notifier == nil || !a.notificationEnabled() {
+		return
+	}
+	now := a.now()
+	a.mutex.Lock()
+	if !a.quoteWarnedAt.IsZero() && now.Sub(a.quoteWarnedAt) < quoteWarningInterval {
+		a.mutex.Unlock()
+		tracef(traceID, "⏭️  [%s] 数据源告警%v内已推送过，本次只记录", a.AnalysisConfig.StockName, quoteWarningInterval)
+		return
+	}
+	a.quoteWarnedAt = now
+	a.mutex.Unlock()
+	message := fmt.Sprintf("🚨 行情数据源告警 - %s(%s)\n%s\n时间: %s",
+		a.AnalysisConfig.StockName,
+		a.AnalysisConfig.StockCode,
+		warning,
+		a.now().Format("2006-01-02 15:04:05"))
+	if err := a.Notifier.SendMessage(message); err != nil {
+		tracef(traceID, "❌ 发送数据源告警失败: %v", err)
 	}
 }
 
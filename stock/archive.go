@@ -0,0 +1,100 @@
+package stock
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// archiveQueueSize 待归档队列长度，队列满时丢弃（归档失败不影响分析）
+const archiveQueueSize = 100
+
+// ResultArchiver 分析结果JSON文件归档器
+// 每条结果写一个文件：<BaseDir>/<股票代码>/<日期>/<时间戳>.json，写入在后台协程中异步进行
+type ResultArchiver struct {
+	BaseDir string
+
+	queue chan *AnalysisResult
+}
+
+// NewResultArchiver 创建归档器并启动后台写入协程
+func NewResultArchiver(baseDir string) *ResultArchiver {
+	archiver := &ResultArchiver{
+		BaseDir: baseDir,
+		queue:   make(chan *AnalysisResult, archiveQueueSize),
+	}
+	go archiver.writeLoop()
+	return archiver
+}
+
+// Archive 异步归档一条分析结果
+func (r *ResultArchiver) Archive(result *AnalysisResult) {
+	select {
+	case r.queue <- result:
+	default:
+		log.Printf("⚠️  归档队列已满，丢弃分析结果: %s %s", result.StockCode, result.Timestamp.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// writeLoop 后台写入协程
+func (r *ResultArchiver) writeLoop() {
+	for result := range r.queue {
+		if err := r.write(result); err != nil {
+			log.Printf("⚠️  归档分析结果失败: %v", err)
+		}
+	}
+}
+
+// Remove 删除一条分析结果的归档文件（文件不存在时忽略）
+func (r *ResultArchiver) Remove(result *AnalysisResult) error {
+	if err := os.Remove(r.path(result)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除归档文件失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveAll 删除某只股票的全部归档文件（只允许删除归档根目录下的直接子目录，拒绝 ".." 等越界路径）
+func (r *ResultArchiver) RemoveAll(code string) error {
+	dir, err := r.stockDir(code)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("删除归档目录失败: %w", err)
+	}
+	return nil
+}
+
+// stockDir 股票的归档目录，清理后的路径必须是归档根目录下的直接子目录
+func (r *ResultArchiver) stockDir(code string) (string, error) {
+	base := filepath.Clean(r.BaseDir)
+	dir := filepath.Join(base, code)
+	if code == "" || filepath.Dir(dir) != base || filepath.Base(dir) != code {
+		return "", fmt.Errorf("无效的股票代码: %q", code)
+	}
+	return dir, nil
+}
+
+// path 分析结果的归档文件路径
+func (r *ResultArchiver) path(result *AnalysisResult) string {
+	return filepath.Join(r.BaseDir, result.StockCode, result.Timestamp.Format("2006-01-02"), result.Timestamp.Format("150405.000")+".json")
+}
+
+// write 将分析结果写入JSON文件
+func (r *ResultArchiver) write(result *AnalysisResult) error {
+	filename := r.path(result)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("创建归档目录失败: %w", err)
+	}
+
+	data, err := MarshalResult(result)
+	if err != nil {
+		return fmt.Errorf("序列化分析结果失败: %w", err)
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("写入归档文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package stock
+
+import (
+	"fmt"
+	"math"
+)
+
+// AutoTrade 按信号自动交易的参数（目前对接模拟盘）
+// BUY信号且无持仓时按OrderAmount买入（向下取整到最小交易单位），SELL信号且有持仓时全部卖出；HOLD不操作
+type AutoTrade struct {
+	Trader      Trader
+	OrderAmount float64 // 每次买入的金额（元）
+}
+
+// autoTrade 按本轮信号自动下单，结果中记录成交回报和下单后的虚拟持仓
+// 虚拟持仓只在交易接口内维护，不改动分析配置中的真实持仓（position_quantity/buy_price）
+func (a *StockAnalyzer) autoTrade(result *AnalysisResult) {
+	auto := a.AnalysisConfig.AutoTrade
+	if auto == nil || result.CurrentPrice <= 0 {
+		return
+	}
+
+	position, err := auto.Trader.Position(a.AnalysisConfig.StockCode)
+	if err != nil {
+		tracef(result.TraceID, "⚠️  [%s] 获取模拟持仓失败: %v", a.AnalysisConfig.StockName, err)
+		return
+	}
+	if position.Quantity > 0 {
+		result.PaperPosition = position
+	}
+
+	order := Order{
+		StockCode: a.AnalysisConfig.StockCode,
+		StockName: a.AnalysisConfig.StockName,
+		Side:      result.Signal,
+		Price:     result.CurrentPrice,
+		Reason:    fmt.Sprintf("%s信号，信心度%d%%", result.Signal, result.Confidence),
+	}
+	switch {
+	case result.Signal == "BUY" && position.Quantity == 0:
+		unit := a.Security.TradingUnit
+		if unit <= 0 {
+			unit = 100
+		}
+		order.Quantity = int(math.Floor(auto.OrderAmount/result.CurrentPrice/float64(unit))) * unit
+		if order.Quantity == 0 {
+			tracef(result.TraceID, "⚠️  [%s] 单笔买入金额%.2f元不足一手（现价%.2f元），跳过模拟买入", a.AnalysisConfig.StockName, auto.OrderAmount, result.CurrentPrice)
+			return
+		}
+	case result.Signal == "SELL" && position.Quantity > 0:
+		order.Quantity = position.Quantity
+	default:
+		return
+	}
+
+	fill, err := auto.Trader.PlaceOrder(order)
+	if err != nil {
+		tracef(result.TraceID, "❌ [%s] 模拟下单失败: %v", a.AnalysisConfig.StockName, err)
+		return
+	}
+	result.TradeFill = fill
+	tracef(result.TraceID, "🧾 [%s] 模拟成交: %s %d @ %.2f元，费用%.2f元", a.AnalysisConfig.StockName, fill.Side, fill.Quantity, fill.Price, fill.Fee)
+
+	if position, err = auto.Trader.Position(a.AnalysisConfig.StockCode); err != nil {
+		tracef(result.TraceID, "⚠️  [%s] 获取模拟持仓失败: %v", a.AnalysisConfig.StockName, err)
+		return
+	}
+	result.PaperPosition = nil
+	if position.Quantity > 0 {
+		result.PaperPosition = position
+	}
+}
+
+// getSideText 买卖方向的中文文本
+func getSideText(side string) string {
+	if side == "BUY" {
+		return "买入"
+	}
+	return "卖出"
+}
@@ -0,0 +1,366 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SecurityBasket 虚拟组合（多只股票按权重合成的板块指数）
+const SecurityBasket SecurityType = "basket"
+
+// basketIndexBase 组合指数基点：基准日（见basketBases）收盘记为1000点
+const basketIndexBase = 1000.0
+
+// BasketMember 虚拟组合成分股
+type BasketMember struct {
+	Code   string  // 股票代码
+	Name   string  // 股票名称
+	Weight float64 // 权重（按所有成分股权重之和归一化）
+}
+
+// basketSecurity 虚拟组合的交易规则（组合指数不可直接交易，无涨跌停限制）
+func basketSecurity() SecurityInfo {
+	return SecurityInfo{Type: SecurityBasket, TypeName: "板块组合", PriceDecimal: 2}
+}
+
+// IsBasket 判断是否为虚拟组合分析器
+func (a *StockAnalyzer) IsBasket() bool {
+	return len(a.AnalysisConfig.Basket) > 0
+}
+
+// getQuote 获取实时行情，虚拟组合时为按权重合成的组合行情
+func (a *StockAnalyzer) getQuote() (*QuoteData, error) {
+	if !a.IsBasket() {
+		return a.TDXClient.GetQuote(a.AnalysisConfig.StockCode)
+	}
+	return a.basketQuote()
+}
+
+// getKline 获取K线，虚拟组合时为按权重合成的组合指数K线
+func (a *StockAnalyzer) getKline(klineType string, limit int) (*KlineData, error) {
+	if !a.IsBasket() {
+		return a.TDXClient.GetKline(a.AnalysisConfig.StockCode, klineType, limit)
+	}
+	return a.basketKline(klineType, limit)
+}
+
+// getChipDistribution 获取筹码分布，虚拟组合不适用（返回ErrChipUnsupported）
+func (a *StockAnalyzer) getChipDistribution() (*ChipDistribution, error) {
+	if a.IsBasket() {
+		return nil, ErrChipUnsupported
+	}
+	return a.TDXClient.GetChipDistribution(a.AnalysisConfig.StockCode)
+}
+
+// basketWeights 归一化后的成分股权重
+func (a *StockAnalyzer) basketWeights() []float64 {
+	total := 0.0
+	for _, member := range a.AnalysisConfig.Basket {
+		total += member.Weight
+	}
+	weights := make([]float64, len(a.AnalysisConfig.Basket))
+	for i, member := range a.AnalysisConfig.Basket {
+		if total > 0 {
+			weights[i] = member.Weight / total
+		}
+	}
+	return weights
+}
+
+// basketAnchor 组合指数锚定信息（持久化到AnalysisConfig.BasketBaseFile，重启后沿用同一基准，保证指数跨日连续）
+type basketAnchor struct {
+	BaseDate string              `json:"base_date"` // 基准日（YYYY-MM-DD），该日收盘记为basketIndexBase点
+	Members  []basketAnchorPrice `json:"members"`   // 各成分股在基准日的收盘价
+}
+
+// basketAnchorPrice 成分股在基准日的收盘价
+type basketAnchorPrice struct {
+	Code string  `json:"code"`
+	Base float64 `json:"base"` // 基准日收盘价（厘）
+}
+
+// basketBases 各成分股的指数基准价（厘）
+// 基准日固定：配置了BasketBaseDate时取该日，否则取首次锚定时近60根日K线中第一个所有成分股都有数据的交易日；
+// 锚定结果持久化，之后只在成分股变化（或配置的基准日变化）时重新锚定，使组合指数的均线/RSI等跨日连续
+func (a *StockAnalyzer) basketBases() ([]float64, error) {
+	a.mutex.Lock()
+	if a.basketBase != nil {
+		bases := a.basketBase
+		a.mutex.Unlock()
+		return bases, nil
+	}
+	a.mutex.Unlock()
+
+	members := a.AnalysisConfig.Basket
+	anchor, reason := a.loadBasketAnchor()
+	if anchor == nil {
+		var err error
+		anchor, err = a.computeBasketAnchor()
+		if err != nil {
+			return nil, err
+		}
+		a.saveBasketAnchor(anchor)
+		log.Printf("📦 [%s] 组合指数基准日: %s（记为%.0f点，%s）", a.AnalysisConfig.StockName, anchor.BaseDate, basketIndexBase, reason)
+	}
+
+	bases := make([]float64, len(members))
+	for i := range members {
+		bases[i] = anchor.Members[i].Base
+	}
+
+	a.mutex.Lock()
+	a.basketBase = bases
+	a.basketBaseDate = anchor.BaseDate
+	a.mutex.Unlock()
+	return bases, nil
+}
+
+// loadBasketAnchor 读取已持久化的锚定信息，成分股或配置的基准日与之不一致时返回nil和重新锚定的原因
+func (a *StockAnalyzer) loadBasketAnchor() (*basketAnchor, string) {
+	path := a.AnalysisConfig.BasketBaseFile
+	if path == "" {
+		return nil, "首次锚定"
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, "首次锚定"
+	}
+	var anchor basketAnchor
+	if err == nil {
+		err = json.Unmarshal(data, &anchor)
+	}
+	if err != nil {
+		log.Printf("⚠️  [%s] 读取组合指数基准失败，重新锚定: %v", a.AnalysisConfig.StockName, err)
+		return nil, "基准文件无效"
+	}
+
+	members := a.AnalysisConfig.Basket
+	if len(anchor.Members) != len(members) {
+		return nil, "成分股变化，重新锚定"
+	}
+	for i, member := range members {
+		if anchor.Members[i].Code != member.Code || anchor.Members[i].Base <= 0 {
+			return nil, "成分股变化，重新锚定"
+		}
+	}
+	if baseDate := a.AnalysisConfig.BasketBaseDate; baseDate != "" && baseDate != anchor.BaseDate {
+		return nil, "配置的基准日变化，重新锚定"
+	}
+	return &anchor, ""
+}
+
+// computeBasketAnchor 计算锚定信息：配置了基准日时取各成分股该日收盘价，否则取近60根日K线中第一个共同交易日
+func (a *StockAnalyzer) computeBasketAnchor() (*basketAnchor, error) {
+	members := a.AnalysisConfig.Basket
+	baseDate := a.AnalysisConfig.BasketBaseDate
+	limit := 60
+	if baseDate != "" {
+		limit = MaxDayKlineLimit
+	}
+
+	klines := make([]*KlineData, len(members))
+	counts := make(map[string]int)
+	for i, member := range members {
+		kline, err := a.TDXClient.GetKline(member.Code, "day", limit)
+		if err != nil {
+			return nil, fmt.Errorf("获取成分股 %s(%s) 日K线失败: %w", member.Name, member.Code, err)
+		}
+		klines[i] = kline
+		for _, item := range kline.List {
+			counts[item.Time.Format("2006-01-02")]++
+		}
+	}
+
+	if baseDate != "" {
+		if counts[baseDate] != len(members) {
+			return nil, fmt.Errorf("基准日 %s 不是所有成分股的共同交易日（或超出近%d根日K线）", baseDate, limit)
+		}
+	} else {
+		for day, count := range counts {
+			if count == len(members) && (baseDate == "" || day < baseDate) {
+				baseDate = day
+			}
+		}
+		if baseDate == "" {
+			return nil, fmt.Errorf("成分股日K线没有共同的交易日")
+		}
+	}
+
+	anchor := &basketAnchor{BaseDate: baseDate}
+	for i, kline := range klines {
+		base := 0.0
+		for _, item := range kline.List {
+			if item.Time.Format("2006-01-02") == baseDate {
+				base = float64(item.Close)
+				break
+			}
+		}
+		if base <= 0 {
+			return nil, fmt.Errorf("成分股 %s(%s) 基准日收盘价无效", members[i].Name, members[i].Code)
+		}
+		anchor.Members = append(anchor.Members, basketAnchorPrice{Code: members[i].Code, Base: base})
+	}
+	return anchor, nil
+}
+
+// saveBasketAnchor 持久化锚定信息（先写临时文件再重命名），失败只记录日志（本次运行内仍使用该基准）
+func (a *StockAnalyzer) saveBasketAnchor(anchor *basketAnchor) {
+	path := a.AnalysisConfig.BasketBaseFile
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(anchor, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path+".tmp", data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		log.Printf("⚠️  [%s] 保存组合指数基准失败: %v", a.AnalysisConfig.StockName, err)
+	}
+}
+
+// basketPrice 按权重将各成分股价格（厘）合成组合指数点位（以厘为单位存储，与个股价格字段一致）
+func basketPrice(prices []int, weights, bases []float64) int {
+	sum := 0.0
+	for i, price := range prices {
+		sum += weights[i] * float64(price) / bases[i]
+	}
+	return int(math.Round(sum * basketIndexBase * 1000))
+}
+
+// basketQuote 合成组合实时行情：价格为组合指数点位，成交量、成交额、内外盘为成分股合计，不含五档盘口
+func (a *StockAnalyzer) basketQuote() (*QuoteData, error) {
+	bases, err := a.basketBases()
+	if err != nil {
+		return nil, err
+	}
+	weights := a.basketWeights()
+
+	members := a.AnalysisConfig.Basket
+	n := len(members)
+	last, open, high, low, closes := make([]int, n), make([]int, n), make([]int, n), make([]int, n), make([]int, n)
+	quote := &QuoteData{Code: a.AnalysisConfig.StockCode}
+	var summary []string
+	for i, member := range members {
+		q, err := a.TDXClient.GetQuote(member.Code)
+		if err != nil {
+			return nil, fmt.Errorf("获取成分股 %s(%s) 行情失败: %w", member.Name, member.Code, err)
+		}
+		last[i], open[i], high[i], low[i], closes[i] = q.K.Last, q.K.Open, q.K.High, q.K.Low, q.K.Close
+		quote.TotalHand += q.TotalHand
+		quote.Amount += q.Amount
+		quote.InsideDish += q.InsideDish
+		quote.OuterDisc += q.OuterDisc
+
+		change := "-"
+		if q.K.Last > 0 {
+			change = fmt.Sprintf("%+.2f%%", float64(q.K.Close-q.K.Last)/float64(q.K.Last)*100)
+		}
+		summary = append(summary, fmt.Sprintf("- %s(%s) 权重%.1f%%，现价%.2f元，涨跌幅%s",
+			member.Name, member.Code, weights[i]*100, PriceToYuan(q.K.Close), change))
+	}
+
+	quote.K = KData{
+		Last:  basketPrice(last, weights, bases),
+		Open:  basketPrice(open, weights, bases),
+		High:  basketPrice(high, weights, bases),
+		Low:   basketPrice(low, weights, bases),
+		Close: basketPrice(closes, weights, bases),
+	}
+
+	a.mutex.Lock()
+	a.basketSummary = strings.Join(summary, "\n")
+	a.mutex.Unlock()
+	return quote, nil
+}
+
+// basketKline 合成组合指数K线：只保留所有成分股都有数据的时间点（停牌的成分股会使该时间点缺失）
+// 开高低收分别按权重合成（高低点为近似值），成交量和成交额为成分股合计
+func (a *StockAnalyzer) basketKline(klineType string, limit int) (*KlineData, error) {
+	bases, err := a.basketBases()
+	if err != nil {
+		return nil, err
+	}
+	weights := a.basketWeights()
+
+	members := a.AnalysisConfig.Basket
+	n := len(members)
+	type basketBar struct {
+		open, high, low, close []int
+		volume                 int64
+		amount                 float64
+		count                  int
+	}
+	bars := make(map[time.Time]*basketBar)
+	for i, member := range members {
+		kline, err := a.TDXClient.GetKline(member.Code, klineType, limit)
+		if err != nil {
+			return nil, fmt.Errorf("获取成分股 %s(%s) K线失败: %w", member.Name, member.Code, err)
+		}
+		for _, item := range kline.List {
+			bar, ok := bars[item.Time]
+			if !ok {
+				bar = &basketBar{open: make([]int, n), high: make([]int, n), low: make([]int, n), close: make([]int, n)}
+				bars[item.Time] = bar
+			}
+			bar.open[i], bar.high[i], bar.low[i], bar.close[i] = item.Open, item.High, item.Low, item.Close
+			bar.volume += item.Volume
+			bar.amount += item.Amount
+			bar.count++
+		}
+	}
+
+	var times []time.Time
+	for t, bar := range bars {
+		if bar.count == n {
+			times = append(times, t)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	data := &KlineData{}
+	for _, t := range times {
+		bar := bars[t]
+		item := KlineItem{
+			Open:   basketPrice(bar.open, weights, bases),
+			High:   basketPrice(bar.high, weights, bases),
+			Low:    basketPrice(bar.low, weights, bases),
+			Close:  basketPrice(bar.close, weights, bases),
+			Volume: bar.volume,
+			Amount: bar.amount,
+			Time:   t,
+		}
+		if len(data.List) > 0 {
+			item.Last = data.List[len(data.List)-1].Close
+		}
+		data.List = append(data.List, item)
+	}
+	if len(data.List) > limit {
+		data.List = data.List[len(data.List)-limit:]
+	}
+	data.Count = len(data.List)
+	return data, nil
+}
+
+// basketPromptHeader 虚拟组合分析的提示词说明（组合构成及各成分股当前涨跌）
+func (a *StockAnalyzer) basketPromptHeader() string {
+	a.mutex.Lock()
+	summary := a.basketSummary
+	baseDate := a.basketBaseDate
+	a.mutex.Unlock()
+	return fmt.Sprintf("📦 板块组合分析：%s 是由以下%d只成分股按权重合成的虚拟组合，下文中的\"价格\"均为组合指数点位（%s收盘为基准%.0f点，单位按元显示），"+
+		"成交量和成交额为成分股合计，没有五档盘口。请对板块整体给出操作信号，目标价和止损价同样以组合指数点位给出。\n\n成分股：\n%s\n\n",
+		a.AnalysisConfig.StockName, len(a.AnalysisConfig.Basket), baseDate, basketIndexBase, summary)
+}
@@ -0,0 +1,88 @@
+package stock
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// seedDayKline 把日K线写入内存缓存，使GetKline不请求TDX
+func seedDayKline(client *TDXClient, code string, limit int, closes map[string]int) {
+	data := &KlineData{}
+	for _, day := range []string{"2025-06-09", "2025-06-10", "2025-06-11"} {
+		if price, ok := closes[day]; ok {
+			t, _ := time.ParseInLocation("2006-01-02", day, chinaTZ)
+			data.List = append(data.List, KlineItem{Close: price, Time: t.Add(15 * time.Hour)})
+		}
+	}
+	data.Count = len(data.List)
+	client.klineCache[klineCacheKey(code, "day", limit)] = klineCacheEntry{data: data, expiresAt: time.Now().Add(time.Hour)}
+}
+
+func newTestBasket(client *TDXClient, file string, members ...string) *StockAnalyzer {
+	config := &AnalysisConfig{StockCode: "bank", StockName: "银行板块", BasketBaseFile: file}
+	for _, code := range members {
+		config.Basket = append(config.Basket, BasketMember{Code: code, Name: code, Weight: 1})
+	}
+	return NewStockAnalyzer(client, nil, nil, config, nil)
+}
+
+func TestBasketBasesPersistAcrossRestarts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bank.json")
+	client := NewTDXClient("http://127.0.0.1:0")
+	seedDayKline(client, "600036", 60, map[string]int{"2025-06-10": 30000, "2025-06-11": 31000})
+	seedDayKline(client, "601166", 60, map[string]int{"2025-06-09": 20000, "2025-06-10": 21000, "2025-06-11": 22000})
+
+	bases, err := newTestBasket(client, file, "600036", "601166").basketBases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bases[0] != 30000 || bases[1] != 21000 {
+		t.Fatalf("应以第一个共同交易日2025-06-10为基准，实际 %v", bases)
+	}
+
+	// 次日K线窗口后移（2025-06-10不再是第一个共同交易日），重启后仍沿用持久化的基准
+	later := NewTDXClient("http://127.0.0.1:0")
+	seedDayKline(later, "600036", 60, map[string]int{"2025-06-11": 31000})
+	seedDayKline(later, "601166", 60, map[string]int{"2025-06-11": 22000})
+	analyzer := newTestBasket(later, file, "600036", "601166")
+	bases, err = analyzer.basketBases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bases[0] != 30000 || bases[1] != 21000 || analyzer.basketBaseDate != "2025-06-10" {
+		t.Fatalf("重启后应沿用原基准，实际 %v（%s）", bases, analyzer.basketBaseDate)
+	}
+
+	// 成分股变化时重新锚定
+	seedDayKline(later, "000001", 60, map[string]int{"2025-06-11": 12000})
+	bases, err = newTestBasket(later, file, "600036", "000001").basketBases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bases[0] != 31000 || bases[1] != 12000 {
+		t.Fatalf("成分股变化后应重新锚定，实际 %v", bases)
+	}
+}
+
+func TestBasketBasesConfiguredDate(t *testing.T) {
+	client := NewTDXClient("http://127.0.0.1:0")
+	seedDayKline(client, "600036", MaxDayKlineLimit, map[string]int{"2025-06-09": 29000, "2025-06-10": 30000})
+	seedDayKline(client, "601166", MaxDayKlineLimit, map[string]int{"2025-06-09": 20000, "2025-06-10": 21000})
+
+	analyzer := newTestBasket(client, "", "600036", "601166")
+	analyzer.AnalysisConfig.BasketBaseDate = "2025-06-10"
+	bases, err := analyzer.basketBases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bases[0] != 30000 || bases[1] != 21000 {
+		t.Fatalf("应以配置的基准日为准，实际 %v", bases)
+	}
+
+	missing := newTestBasket(client, "", "600036", "601166")
+	missing.AnalysisConfig.BasketBaseDate = "2025-06-11"
+	if _, err := missing.basketBases(); err == nil {
+		t.Fatal("基准日不是共同交易日时应报错")
+	}
+}
@@ -0,0 +1,41 @@
+package stock
+
+import "time"
+
+// Clock 时钟接口：分析器和交易时间判断通过它获取当前时间
+// 生产环境使用SystemClock，测试时可注入FixedClock验证盘后、节假日等行为
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 系统时钟
+type SystemClock struct{}
+
+// Now 返回系统当前时间
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock 固定时间的时钟
+type FixedClock time.Time
+
+// Now 返回固定的时间
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// now 返回分析器时钟的当前时间（未设置时钟时使用系统时间）
+func (a *StockAnalyzer) now() time.Time {
+	if a.Clock == nil {
+		return time.Now()
+	}
+	return a.Clock.Now()
+}
+
+// Now 返回交易时间判断所用时钟的当前时间（未设置时钟时使用系统时间）
+func (tc *TradingTimeChecker) Now() time.Time {
+	if tc.Clock == nil {
+		return time.Now()
+	}
+	return tc.Clock.Now()
+}
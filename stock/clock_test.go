@@ -0,0 +1,39 @@
+package stock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeSkipsOutsideTradingTimeWithInjectedClock(t *testing.T) {
+	checker, err := NewTradingTimeChecker(DefaultTradingTimeConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker.Config.EnableTradingTimeCheck = true
+	analyzer := NewStockAnalyzer(nil, nil, nil, &AnalysisConfig{StockCode: "000001", StockName: "平安银行"}, checker)
+
+	for _, now := range []time.Time{
+		time.Date(2025, 6, 10, 16, 0, 0, 0, chinaTZ), // 盘后
+		time.Date(2025, 6, 14, 10, 0, 0, 0, chinaTZ), // 周六
+		time.Date(2025, 10, 1, 10, 0, 0, 0, chinaTZ), // 国庆节
+	} {
+		analyzer.Clock = FixedClock(now)
+		if _, err := analyzer.Analyze(); !errors.Is(err, ErrNotTradingTime) {
+			t.Errorf("%s 应跳过分析，实际: %v", now.Format("2006-01-02 15:04"), err)
+		}
+	}
+}
+
+func TestCalculatePositionInfoUsesGivenTime(t *testing.T) {
+	buyDate := time.Date(2025, 6, 1, 0, 0, 0, 0, chinaTZ)
+	now := time.Date(2025, 6, 11, 10, 0, 0, 0, chinaTZ)
+	info := CalculatePositionInfo("000001", "平安银行", 1000, 10, 11, buyDate, now, FeeRates{})
+	if info.HoldingDays != 10 {
+		t.Fatalf("持有天数应按传入时间计算为10天，实际 %d", info.HoldingDays)
+	}
+	if info.ProfitLoss != 1000 {
+		t.Fatalf("浮动盈亏应为1000元，实际 %v", info.ProfitLoss)
+	}
+}
@@ -0,0 +1,97 @@
+package stock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// localRuleSignal 本地技术规则综合信号（MA/MACD/RSI三项投票，看多+1、看空-1，合计≥2为BUY、≤-2为SELL，其余HOLD）
+//   - MA：现价 > MA5 > MA20 看多，现价 < MA5 < MA20 看空
+//   - MACD：DIF > DEA 且MACD柱为正看多，DIF < DEA 且MACD柱为负看空
+//   - RSI：RSI14 < 30 超卖看多，RSI14 > 70 超买看空
+//
+// 返回信号和各项依据；MA和MACD都缺失时返回空信号（不做一致性校验）
+func localRuleSignal(technical map[string]interface{}) (string, []string) {
+	score := 0
+	var reasons []string
+	valid := false
+
+	price, _ := IndicatorValue(technical, "current_price")
+	ma5, hasMA5 := IndicatorValue(technical, "ma5")
+	ma20, hasMA20 := IndicatorValue(technical, "ma20")
+	if price > 0 && hasMA5 && hasMA20 {
+		valid = true
+		switch {
+		case price > ma5 && ma5 > ma20:
+			score++
+			reasons = append(reasons, "均线多头排列")
+		case price < ma5 && ma5 < ma20:
+			score--
+			reasons = append(reasons, "均线空头排列")
+		}
+	}
+
+	dif, hasDIF := IndicatorValue(technical, "macd_dif")
+	dea, hasDEA := IndicatorValue(technical, "macd_dea")
+	hist, _ := IndicatorValue(technical, "macd_hist")
+	if hasDIF && hasDEA {
+		valid = true
+		switch {
+		case dif > dea && hist > 0:
+			score++
+			reasons = append(reasons, "MACD金叉区间")
+		case dif < dea && hist < 0:
+			score--
+			reasons = append(reasons, "MACD死叉区间")
+		}
+	}
+
+	if rsi, ok := IndicatorValue(technical, "rsi14"); ok {
+		switch {
+		case rsi < 30:
+			score++
+			reasons = append(reasons, fmt.Sprintf("RSI %.1f超卖", rsi))
+		case rsi > 70:
+			score--
+			reasons = append(reasons, fmt.Sprintf("RSI %.1f超买", rsi))
+		}
+	}
+
+	if !valid {
+		return "", nil
+	}
+	switch {
+	case score >= 2:
+		return "BUY", reasons
+	case score <= -2:
+		return "SELL", reasons
+	default:
+		return "HOLD", reasons
+	}
+}
+
+// applyConsensus 对比AI信号与本地技术规则信号：AI的BUY/SELL与规则一致时标记confirmed，方向相反时标记conflict并在推理原因前提示分歧
+// AI给出HOLD、规则信号为HOLD或指标不足时不标记
+func (a *StockAnalyzer) applyConsensus(result *AnalysisResult) {
+	if result.InsufficientData {
+		return
+	}
+	ruleSignal, reasons := localRuleSignal(result.TechnicalData)
+	if ruleSignal == "" {
+		return
+	}
+	result.RuleSignal = ruleSignal
+	if (result.Signal != "BUY" && result.Signal != "SELL") || ruleSignal == "HOLD" {
+		return
+	}
+
+	basis := strings.Join(reasons, "，")
+	if ruleSignal == result.Signal {
+		result.RuleConfirmed = true
+		tracef(result.TraceID, "🤝 [%s] AI信号%s与技术指标一致（%s）", a.AnalysisConfig.StockName, result.Signal, basis)
+		return
+	}
+	result.RuleConflict = true
+	result.Reasoning = fmt.Sprintf("【信号分歧】AI给出%s，但技术指标综合为%s（%s），请谨慎参考\n", result.Signal, ruleSignal, basis) + result.Reasoning
+	tracef(result.TraceID, "⚔️  [%s] AI信号%s与技术指标%s矛盾（%s）", a.AnalysisConfig.StockName, result.Signal, ruleSignal, basis)
+}
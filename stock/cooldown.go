@@ -0,0 +1,93 @@
+package stock
+
+import (
+	"fmt"
+	"time"
+)
+
+// notifyState 最近一次通知的状态，用于通知冷静期和价格事件判断
+type notifyState struct {
+	at     time.Time // 最近一次通知时间
+	short  bool      // 价位方向：false为做多（BUY或持仓，止损在下、目标在上），true为做空（SELL，止损在上、目标在下）
+	stop   float64   // 待触发的止损价（元，持仓模式优先取持仓止损价），0表示未布防或已触发
+	target float64   // 待触发的目标价（元，持仓模式优先取持仓止盈价），0表示未布防或已触发
+}
+
+// inCooldown 判断是否处于通知冷静期（距上次通知不足NotifyCooldown）
+func (a *StockAnalyzer) inCooldown(now time.Time) bool {
+	if a.AnalysisConfig.NotifyCooldown <= 0 {
+		return false
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return !a.lastNotify.at.IsZero() && now.Sub(a.lastNotify.at) < a.AnalysisConfig.NotifyCooldown
+}
+
+// detectPriceEvent 检测现价是否触及上次通知给出的止损价/目标价（按信号方向判断），返回事件描述（无事件时为空）
+// 只在启用冷静期时检测；价位触发后即撤防，直到下一次常规信号通知重新布防，避免价格停留在价位之外时每轮重复推送
+func (a *StockAnalyzer) detectPriceEvent(price float64) string {
+	if a.AnalysisConfig.NotifyCooldown <= 0 || price <= 0 {
+		return ""
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	state := &a.lastNotify
+	if state.stop > 0 && (!state.short && price <= state.stop || state.short && price >= state.stop) {
+		verb := "跌破"
+		if state.short {
+			verb = "涨破"
+		}
+		event := fmt.Sprintf("现价%.2f元%s止损价%.2f元", price, verb, state.stop)
+		state.stop = 0
+		return event
+	}
+	if state.target > 0 && (!state.short && price >= state.target || state.short && price <= state.target) {
+		verb := "涨破"
+		if state.short {
+			verb = "跌破"
+		}
+		event := fmt.Sprintf("现价%.2f元%s目标价%.2f元", price, verb, state.target)
+		state.target = 0
+		return event
+	}
+	return ""
+}
+
+// recordNotify 记录本次通知的时间，常规信号通知时按信号方向重新布防止损价/目标价
+// 价格事件通知只更新时间（已触发的价位保持撤防）；HOLD信号（非持仓模式）不改变已布防的价位；
+// 布防时现价已越过的价位不布防，避免下一轮立即触发
+func (a *StockAnalyzer) recordNotify(result *AnalysisResult) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.lastNotify.at = a.now()
+	if result.PriceEvent != "" {
+		return
+	}
+
+	var state notifyState
+	switch {
+	case result.PositionStopLoss > 0 || result.PositionProfitTarget > 0:
+		// 持仓的止盈止损总是做多方向
+		state.stop, state.target = result.PositionStopLoss, result.PositionProfitTarget
+	case result.Signal == "BUY":
+		state.stop, state.target = result.StopLoss, result.TargetPrice
+	case result.Signal == "SELL":
+		state.short = true
+		state.stop, state.target = result.StopLoss, result.TargetPrice
+	default:
+		return
+	}
+
+	price := result.CurrentPrice
+	if price > 0 {
+		if state.stop > 0 && (!state.short && price <= state.stop || state.short && price >= state.stop) {
+			state.stop = 0
+		}
+		if state.target > 0 && (!state.short && price >= state.target || state.short && price <= state.target) {
+			state.target = 0
+		}
+	}
+	state.at = a.lastNotify.at
+	a.lastNotify = state
+}
@@ -0,0 +1,92 @@
+package stock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DailyReport 一段时间内各股票分析信号的汇总（每日报告和交易时段汇总共用，时段汇总按时段切分时间范围）
+type DailyReport struct {
+	Title  string            `json:"title"`
+	From   time.Time         `json:"from"`
+	To     time.Time         `json:"to"`
+	Counts map[string]int    `json:"counts"` // 信号 -> 次数（BUY/SELL/HOLD）
+	Stocks []DailyReportItem `json:"stocks"` // 有分析结果的股票（BUY/SELL次数多的在前）
+}
+
+// DailyReportItem 单只股票在报告期内的信号汇总
+type DailyReportItem struct {
+	StockCode      string         `json:"stock_code"`
+	StockName      string         `json:"stock_name"`
+	Counts         map[string]int `json:"counts"`          // 信号 -> 次数
+	LastSignal     string         `json:"last_signal"`     // 期内最后一次信号
+	LastConfidence int            `json:"last_confidence"` // 期内最后一次信心度
+	LastPrice      float64        `json:"last_price"`      // 期内最后一次分析时的价格（元）
+	LastTime       time.Time      `json:"last_time"`
+}
+
+// BuildDailyReport 汇总[from, to]内各股票的分析结果（history为股票代码 -> 按时间升序的分析结果）
+func BuildDailyReport(title string, from, to time.Time, history map[string][]*AnalysisResult) *DailyReport {
+	report := &DailyReport{Title: title, From: from, To: to, Counts: make(map[string]int)}
+	for code, results := range history {
+		item := DailyReportItem{StockCode: code, Counts: make(map[string]int)}
+		for _, result := range results {
+			if result == nil || result.Timestamp.Before(from) || result.Timestamp.After(to) {
+				continue
+			}
+			item.Counts[result.Signal]++
+			report.Counts[result.Signal]++
+			if !result.Timestamp.Before(item.LastTime) {
+				item.StockName = result.StockName
+				item.LastSignal = result.Signal
+				item.LastConfidence = result.Confidence
+				item.LastPrice = result.CurrentPrice
+				item.LastTime = result.Timestamp
+			}
+		}
+		if len(item.Counts) > 0 {
+			report.Stocks = append(report.Stocks, item)
+		}
+	}
+
+	sort.Slice(report.Stocks, func(i, j int) bool {
+		ai := report.Stocks[i].Counts["BUY"] + report.Stocks[i].Counts["SELL"]
+		aj := report.Stocks[j].Counts["BUY"] + report.Stocks[j].Counts["SELL"]
+		if ai != aj {
+			return ai > aj
+		}
+		return report.Stocks[i].StockCode < report.Stocks[j].StockCode
+	})
+	return report
+}
+
+// Total 报告期内的分析次数
+func (r *DailyReport) Total() int {
+	total := 0
+	for _, count := range r.Counts {
+		total += count
+	}
+	return total
+}
+
+// Markdown 生成通知用的Markdown文本
+func (r *DailyReport) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## 📋 %s\n\n", r.Title))
+	sb.WriteString(fmt.Sprintf("**时间范围**: %s - %s\n\n", r.From.Format("01-02 15:04"), r.To.Format("01-02 15:04")))
+	sb.WriteString(fmt.Sprintf("**分析次数**: %d（买入 %d / 卖出 %d / 持有 %d）\n\n",
+		r.Total(), r.Counts["BUY"], r.Counts["SELL"], r.Counts["HOLD"]))
+
+	if len(r.Stocks) == 0 {
+		sb.WriteString("期内没有分析结果\n")
+		return sb.String()
+	}
+	for _, item := range r.Stocks {
+		sb.WriteString(fmt.Sprintf("- **%s(%s)** 最新: %s 信心度%d%% @ %.2f元（%s）｜买%d 卖%d 持%d\n",
+			item.StockName, item.StockCode, item.LastSignal, item.LastConfidence, item.LastPrice,
+			item.LastTime.Format("15:04"), item.Counts["BUY"], item.Counts["SELL"], item.Counts["HOLD"]))
+	}
+	return sb.String()
+}
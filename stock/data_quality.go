@@ -0,0 +1,97 @@
+package stock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 分析结果的数据质量
+const (
+	DataQualityComplete = "complete" // 数据完整
+	DataQualityDegraded = "degraded" // 数据受限：使用了兜底规则、AI解析失败的默认结果、K线不足、停牌缺口等，可信度打折
+	DataQualityStale    = "stale"    // 数据过期：行情长时间未更新，可能为数据源缓存
+)
+
+// staleQuoteAfter 盘中行情（现价和成交量）超过该时长没有任何变化时视为数据过期
+const staleQuoteAfter = 5 * time.Minute
+
+// dataQualityRank 数据质量的严重程度，用于取较差者
+func dataQualityRank(quality string) int {
+	switch quality {
+	case DataQualityStale:
+		return 2
+	case DataQualityDegraded:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// addQualityIssue 记录一条降低结果可信度的原因，数据质量取较差者
+func (r *AnalysisResult) addQualityIssue(quality, reason string) {
+	r.QualityReasons = append(r.QualityReasons, reason)
+	if dataQualityRank(quality) > dataQualityRank(r.DataQuality) {
+		r.DataQuality = quality
+	}
+}
+
+// finishDataQuality 没有降级原因时标记为数据完整
+func (r *AnalysisResult) finishDataQuality() {
+	if r.DataQuality == "" {
+		r.DataQuality = DataQualityComplete
+	}
+}
+
+// DataLimited 结果是否数据受限或过期（通知中提示"数据受限"）
+func (r *AnalysisResult) DataLimited() bool {
+	return r.DataQuality == DataQualityDegraded || r.DataQuality == DataQualityStale
+}
+
+// assessDataQuality 检查本轮分析使用的数据和决策来源，记录降级原因
+// days为日K线根数，missingPeriods为多周期共振中获取失败的周期
+func (a *StockAnalyzer) assessDataQuality(result *AnalysisResult, technical map[string]interface{}, days int, missingPeriods []string) {
+	if a.AnalysisConfig.RuleBasedAI {
+		result.addQualityIssue(DataQualityDegraded, "试运行：以本地规则代替AI分析")
+	}
+	if _, ok := technical["quote_warning"]; ok {
+		result.addQualityIssue(DataQualityDegraded, "多数据源现价差异过大，已采用中位数")
+	}
+	if days < 60 {
+		result.addQualityIssue(DataQualityDegraded, fmt.Sprintf("日K线仅%d根，MA60等长周期指标不完整", days))
+	}
+	if affected, ok := technical["suspension_affected"].([]string); ok && len(affected) > 0 {
+		result.addQualityIssue(DataQualityDegraded, fmt.Sprintf("近期停牌，%s的计算窗口跨越停牌缺口", strings.Join(affected, "、")))
+	}
+	if len(missingPeriods) > 0 {
+		texts := make([]string, 0, len(missingPeriods))
+		for _, period := range missingPeriods {
+			texts = append(texts, getKlinePeriodText(period))
+		}
+		result.addQualityIssue(DataQualityDegraded, fmt.Sprintf("%sK线获取失败，多周期共振不完整", strings.Join(texts, "、")))
+	}
+	result.finishDataQuality()
+}
+
+// checkQuoteFreshness 盘中行情长时间没有变化（现价和成交量都不变）时标记数据过期
+// 只在配置了交易时段时检查（确定处于交易时段内，排除午休、收盘后行情本就不变的情况）
+func (a *StockAnalyzer) checkQuoteFreshness(quote *QuoteData, result *AnalysisResult) {
+	if a.TradingTimeChecker == nil || a.IsBasket() {
+		return
+	}
+
+	now := a.now()
+	a.mutex.Lock()
+	unchanged := !a.quoteChangedAt.IsZero() && quote.K.Close == a.lastQuoteClose && quote.TotalHand == a.lastQuoteVolume
+	if !unchanged {
+		a.lastQuoteClose = quote.K.Close
+		a.lastQuoteVolume = quote.TotalHand
+		a.quoteChangedAt = now
+	}
+	changedAt := a.quoteChangedAt
+	a.mutex.Unlock()
+
+	if unchanged && now.Sub(changedAt) >= staleQuoteAfter {
+		result.addQualityIssue(DataQualityStale, fmt.Sprintf("行情自%s起未更新（现价和成交量均无变化），可能为数据源缓存", changedAt.Format("15:04")))
+	}
+}
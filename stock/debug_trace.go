@@ -0,0 +1,94 @@
+package stock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// debugTrace 单轮分析的调试追踪记录：完整提示词、AI原始响应和解析过程，分析结束后写入独立的调试文件
+// 为nil时不记录（未对该股票开启调试追踪）
+type debugTrace struct {
+	dir       string
+	stock     string // 股票名称(代码)
+	traceID   string
+	startedAt time.Time
+	content   strings.Builder
+	steps     []string
+}
+
+// SetDebugTrace 开启/关闭调试追踪：dir为调试文件目录，为空表示关闭（运行时通过API切换，重启后恢复关闭）
+func (a *StockAnalyzer) SetDebugTrace(dir string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.debugDir = dir
+}
+
+// DebugTraceDir 调试文件目录，未开启调试追踪时为空
+func (a *StockAnalyzer) DebugTraceDir() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.debugDir
+}
+
+// newDebugTrace 开启了调试追踪时创建本轮的记录，否则返回nil
+func (a *StockAnalyzer) newDebugTrace(traceID string) *debugTrace {
+	dir := a.DebugTraceDir()
+	if dir == "" {
+		return nil
+	}
+	stock := fmt.Sprintf("%s(%s)", a.AnalysisConfig.StockName, a.AnalysisConfig.StockCode)
+	return &debugTrace{dir: dir, stock: stock, traceID: traceID, startedAt: a.now()}
+}
+
+// section 记录一段完整文本（提示词、原始响应等）
+func (d *debugTrace) section(title, text string) {
+	if d == nil {
+		return
+	}
+	fmt.Fprintf(&d.content, "## %s\n\n%s\n\n", title, text)
+}
+
+// step 记录一条解析过程
+func (d *debugTrace) step(format string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	d.steps = append(d.steps, fmt.Sprintf(format, args...))
+}
+
+// write 写入调试文件 <dir>/<日期>_<时间>_<追踪ID>.md，err为本轮分析失败的原因（成功时为nil）
+func (d *debugTrace) write(result *AnalysisResult, err error) {
+	if d == nil {
+		return
+	}
+
+	var file strings.Builder
+	fmt.Fprintf(&file, "# 调试追踪 %s\n\n- 开始时间: %s\n- 追踪ID: %s\n", d.stock, d.startedAt.Format("2006-01-02 15:04:05"), d.traceID)
+	if err != nil {
+		fmt.Fprintf(&file, "- 分析失败: %v\n", err)
+	} else if result != nil {
+		fmt.Fprintf(&file, "- 最终结果: %s，信心度%d%%，数据质量%s\n", result.Signal, result.Confidence, result.DataQuality)
+	}
+	file.WriteString("\n")
+	file.WriteString(d.content.String())
+	if len(d.steps) > 0 {
+		file.WriteString("## 解析过程\n\n")
+		for _, step := range d.steps {
+			fmt.Fprintf(&file, "- %s\n", step)
+		}
+	}
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		tracef(d.traceID, "⚠️  创建调试目录失败: %v", err)
+		return
+	}
+	name := filepath.Join(d.dir, fmt.Sprintf("%s_%s.md", d.startedAt.Format("20060102_150405"), d.traceID))
+	if err := os.WriteFile(name, []byte(file.String()), 0644); err != nil {
+		tracef(d.traceID, "⚠️  写入调试文件失败: %v", err)
+		return
+	}
+	tracef(d.traceID, "🐞 调试追踪已写入: %s", name)
+}
@@ -0,0 +1,65 @@
+package stock
+
+import "fmt"
+
+// 除权除息日识别：TDX行情的昨收价在除权除息日是调整后的价格，与上一交易日日K线的实际收盘价不一致
+// 也可在配置中手动填写除权除息日（自动识别依赖日K线为不复权数据）
+// 除权日当天价格"跳空下跌"是除权造成的，不应按暴跌处理：提示词告知AI价格已调整，并抑制跌幅相关的告警
+
+// exRightsMinGap 自动识别除权除息的最小价差（厘），低于1分的差异视为数据误差
+const exRightsMinGap = 10
+
+// detectExRights 判断今天是否为除权除息日，是则在technical中记录ex_rights_today及调整前后的昨收价
+func (a *StockAnalyzer) detectExRights(quote *QuoteData, dayKline *KlineData, technical map[string]interface{}) {
+	today := a.now().In(chinaTZ).Format("2006-01-02")
+
+	// 上一交易日的实际收盘价（日K线盘中可能已包含今天的K线）
+	prevClose := 0
+	for i := len(dayKline.List) - 1; i >= 0; i-- {
+		if dayKline.List[i].Time.In(chinaTZ).Format("2006-01-02") < today {
+			prevClose = dayKline.List[i].Close
+			break
+		}
+	}
+
+	source := ""
+	for _, date := range a.AnalysisConfig.ExRightsDates {
+		if date == today {
+			source = "配置"
+			break
+		}
+	}
+	if source == "" && prevClose > 0 && quote.K.Last > 0 && prevClose-quote.K.Last >= exRightsMinGap {
+		source = "自动识别"
+	}
+	if source == "" {
+		return
+	}
+
+	technical["ex_rights_today"] = true
+	technical["ex_rights_source"] = source
+	if prevClose > 0 && quote.K.Last > 0 && prevClose != quote.K.Last {
+		technical["ex_rights_prev_close"] = PriceToYuan(prevClose)
+		technical["ex_rights_adjusted_close"] = PriceToYuan(quote.K.Last)
+	}
+}
+
+// isExRightsDay 分析结果是否处于除权除息日
+func isExRightsDay(technical map[string]interface{}) bool {
+	return technical["ex_rights_today"] == true
+}
+
+// exRightsPromptSection 除权除息日的提示词小节，非除权日返回空字符串
+func exRightsPromptSection(technical map[string]interface{}) string {
+	if !isExRightsDay(technical) {
+		return ""
+	}
+	section := "## 除权除息提示\n"
+	section += "- **今日除权除息，价格已调整**"
+	if prev, ok := technical["ex_rights_prev_close"].(float64); ok {
+		adjusted, _ := technical["ex_rights_adjusted_close"].(float64)
+		section += fmt.Sprintf("：上一交易日收盘%.2f元，除权除息后昨收%.2f元", prev, adjusted)
+	}
+	section += "\n- 与历史K线相比的价格落差是除权除息造成的，不代表下跌，请以调整后的昨收价判断今日涨跌，均线等指标在除权前后可能不连续\n\n"
+	return section
+}
@@ -0,0 +1,235 @@
+package stock
+
+import (
+	"encoding/json"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 分析历史内存上限的淘汰水位：占用达到上限的90%时开始淘汰，淘汰到80%以下
+const (
+	historyEvictHighWater = 0.9
+	historyEvictLowWater  = 0.8
+)
+
+// historyKeepLatest 每只股票至少保留的最新记录数（信号确认、信号翻转判断依赖最近的记录，不参与淘汰）
+const historyKeepLatest = 3
+
+// HistoryStore 持有分析历史的一方（每个组合的分析器管理器）
+type HistoryStore interface {
+	ExportHistory() map[string][]*AnalysisResult                    // 全部分析历史的副本（股票代码 -> 记录，最新的在前）
+	EvictHistory(code string, victims map[*AnalysisResult]bool) int // 删除指定的记录，返回实际删除数
+}
+
+// HistoryMemoryUsage 分析历史的内存占用（按JSON序列化长度估算）
+type HistoryMemoryUsage struct {
+	Bytes   int64 `json:"bytes"`
+	Records int   `json:"records"`
+}
+
+// HistoryMemoryStatus 分析历史内存占用情况
+type HistoryMemoryStatus struct {
+	LimitBytes     int64                                    `json:"limit_bytes"`             // 内存上限（0表示不限制，只按条数保留）
+	UsedBytes      int64                                    `json:"used_bytes"`              // 估算占用
+	UsagePercent   float64                                  `json:"usage_percent,omitempty"` // 占上限的百分比
+	Records        int                                      `json:"records"`                 // 记录总数
+	EvictedRecords int64                                    `json:"evicted_records"`         // 启动以来因内存上限淘汰的记录数
+	LastEvictAt    *time.Time                               `json:"last_evict_at,omitempty"` // 最近一次淘汰时间
+	HeapAllocBytes uint64                                   `json:"heap_alloc_bytes"`        // 进程堆内存占用（runtime统计，含分析历史以外的数据）
+	SysBytes       uint64                                   `json:"sys_bytes"`               // 进程向系统申请的内存
+	Portfolios     map[string]map[string]HistoryMemoryUsage `json:"portfolios"`              // 组合ID -> 股票代码 -> 占用
+}
+
+// HistoryMemory 分析历史的全局内存上限（所有组合共享）
+// 条数上限之外再按估算内存淘汰：接近上限时优先淘汰最不重要（低信心HOLD）、最旧的记录，而不是所有股票一刀切按条数
+type HistoryMemory struct {
+	Limit int64 // 内存上限（字节），0表示不限制
+
+	mutex   sync.Mutex
+	ids     []string
+	stores  map[string]HistoryStore
+	sizes   map[*AnalysisResult]int64 // 记录的估算大小缓存（每次扫描时清理已不存在的记录）
+	evicted int64
+	lastAt  time.Time
+}
+
+// NewHistoryMemory 创建分析历史内存上限
+func NewHistoryMemory(limit int64) *HistoryMemory {
+	return &HistoryMemory{
+		Limit:  limit,
+		stores: make(map[string]HistoryStore),
+		sizes:  make(map[*AnalysisResult]int64),
+	}
+}
+
+// Register 登记一个组合的分析历史
+func (h *HistoryMemory) Register(id string, store HistoryStore) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.stores[id]; !ok {
+		h.ids = append(h.ids, id)
+	}
+	h.stores[id] = store
+}
+
+// historyEntry 扫描得到的一条记录
+type historyEntry struct {
+	store  string
+	code   string
+	result *AnalysisResult
+	size   int64
+	keep   bool // 每只股票最新的几条，不参与淘汰
+}
+
+// sizeOf 估算一条记录的内存占用（按JSON序列化长度，结果缓存），调用方持有锁
+func (h *HistoryMemory) sizeOf(result *AnalysisResult) int64 {
+	if size, ok := h.sizes[result]; ok {
+		return size
+	}
+	data, err := json.Marshal(result)
+	size := int64(len(data))
+	if err != nil {
+		size = int64(len(result.Reasoning)) + 1024
+	}
+	h.sizes[result] = size
+	return size
+}
+
+// scan 扫描所有组合的分析历史，调用方持有锁
+func (h *HistoryMemory) scan() ([]historyEntry, int64) {
+	var entries []historyEntry
+	var used int64
+	seen := make(map[*AnalysisResult]bool, len(h.sizes))
+	for _, id := range h.ids {
+		for code, history := range h.stores[id].ExportHistory() {
+			for i, result := range history {
+				if result == nil {
+					continue
+				}
+				size := h.sizeOf(result)
+				seen[result] = true
+				used += size
+				entries = append(entries, historyEntry{store: id, code: code, result: result, size: size, keep: i < historyKeepLatest})
+			}
+		}
+	}
+	for result := range h.sizes {
+		if !seen[result] {
+			delete(h.sizes, result)
+		}
+	}
+	return entries, used
+}
+
+// evictionRank 淘汰优先级：数值越小越先淘汰（低信心HOLD < 其他HOLD < BUY/SELL）
+func evictionRank(result *AnalysisResult) int {
+	switch {
+	case result.Signal != "BUY" && result.Signal != "SELL" && result.Confidence < 60:
+		return 0
+	case result.Signal != "BUY" && result.Signal != "SELL":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Enforce 占用接近上限时淘汰记录（在保存/导入分析结果后调用，调用方不能持有任何组合的锁）
+func (h *HistoryMemory) Enforce() {
+	if h == nil || h.Limit <= 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	entries, used := h.scan()
+	if float64(used) < float64(h.Limit)*historyEvictHighWater {
+		return
+	}
+	target := int64(float64(h.Limit) * historyEvictLowWater)
+
+	// 同一优先级内先淘汰最旧的
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, rj := evictionRank(entries[i].result), evictionRank(entries[j].result)
+		if ri != rj {
+			return ri < rj
+		}
+		return entries[i].result.Timestamp.Before(entries[j].result.Timestamp)
+	})
+
+	victims := make(map[string]map[string]map[*AnalysisResult]bool) // 组合ID -> 股票代码 -> 待删除记录
+	count := 0
+	for _, entry := range entries {
+		if used <= target {
+			break
+		}
+		if entry.keep {
+			continue
+		}
+		if victims[entry.store] == nil {
+			victims[entry.store] = make(map[string]map[*AnalysisResult]bool)
+		}
+		if victims[entry.store][entry.code] == nil {
+			victims[entry.store][entry.code] = make(map[*AnalysisResult]bool)
+		}
+		victims[entry.store][entry.code][entry.result] = true
+		used -= entry.size
+		count++
+	}
+	if count == 0 {
+		log.Printf("⚠️  分析历史内存占用 %.1fMB 接近上限 %.1fMB，但各股票只剩最新记录，无法继续淘汰", float64(used)/1024/1024, float64(h.Limit)/1024/1024)
+		return
+	}
+
+	removed := 0
+	for id, codes := range victims {
+		for code, results := range codes {
+			removed += h.stores[id].EvictHistory(code, results)
+			for result := range results {
+				delete(h.sizes, result)
+			}
+		}
+	}
+	h.evicted += int64(removed)
+	h.lastAt = time.Now()
+	log.Printf("🧹 分析历史内存占用接近上限 %.1fMB，已淘汰 %d 条记录（优先低信心HOLD、最旧的记录），当前约 %.1fMB", float64(h.Limit)/1024/1024, removed, float64(used)/1024/1024)
+}
+
+// Status 当前内存占用情况
+func (h *HistoryMemory) Status() HistoryMemoryStatus {
+	h.mutex.Lock()
+	entries, used := h.scan()
+	status := HistoryMemoryStatus{
+		LimitBytes:     h.Limit,
+		UsedBytes:      used,
+		Records:        len(entries),
+		EvictedRecords: h.evicted,
+		Portfolios:     make(map[string]map[string]HistoryMemoryUsage),
+	}
+	if !h.lastAt.IsZero() {
+		lastAt := h.lastAt
+		status.LastEvictAt = &lastAt
+	}
+	for _, id := range h.ids {
+		status.Portfolios[id] = make(map[string]HistoryMemoryUsage)
+	}
+	h.mutex.Unlock()
+
+	if status.LimitBytes > 0 {
+		status.UsagePercent = float64(used) / float64(status.LimitBytes) * 100
+	}
+	for _, entry := range entries {
+		usage := status.Portfolios[entry.store][entry.code]
+		usage.Bytes += entry.size
+		usage.Records++
+		status.Portfolios[entry.store][entry.code] = usage
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	status.HeapAllocBytes = mem.HeapAlloc
+	status.SysBytes = mem.Sys
+	return status
+}
@@ -0,0 +1,34 @@
+package stock
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// indexKlineTTL 指数K线的缓存时长：同一轮扫描中各股票共用一份指数K线，不再逐股请求
+const indexKlineTTL = time.Minute
+
+// GetIndexKline 获取指数K线（TDX代理的 /api/index 接口，code如 sh000300；/api/kline 只支持个股，
+// 同样的数字代码在个股接口中是另一只证券，如 000001 为平安银行），结果缓存indexKlineTTL
+func (c *TDXClient) GetIndexKline(code string, klineType string, limit int) (*KlineData, error) {
+	limit = capKlineLimit(code, klineType, limit)
+	key := "index|" + klineCacheKey(code, klineType, limit)
+	if cached, ok := c.getCachedKlineByKey(key); ok {
+		return cached, nil
+	}
+
+	data, err := c.requestKline(fmt.Sprintf("%s/api/index?code=%s&type=%s", c.BaseURL, url.QueryEscape(code), klineType))
+	if err != nil {
+		return nil, err
+	}
+	finishKline(data, klineType, limit)
+
+	c.cacheMutex.Lock()
+	c.klineCache[key] = klineCacheEntry{data: data, expiresAt: time.Now().Add(indexKlineTTL)}
+	c.cacheMutex.Unlock()
+
+	result := *data
+	result.List = append([]KlineItem(nil), data.List...)
+	return &result, nil
+}
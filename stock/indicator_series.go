@@ -0,0 +1,123 @@
+package stock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IndicatorPoint 指标时间序列中的一个点
+type IndicatorPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// seriesIndicatorWindows 支持时间序列查询的指标及计算所需的最少K线数
+var seriesIndicatorWindows = map[string]int{
+	"ma5":            5,
+	"ma10":           10,
+	"ma20":           20,
+	"ma60":           60,
+	"rsi14":          15,
+	"volatility_20d": 21,
+	"macd_dif":       35,
+	"macd_dea":       35,
+	"macd_hist":      35,
+	"kdj_k":          9,
+	"kdj_d":          9,
+	"kdj_j":          9,
+}
+
+// IsSeriesIndicator 判断指标是否支持时间序列查询
+func IsSeriesIndicator(name string) bool {
+	_, ok := seriesIndicatorWindows[name]
+	return ok
+}
+
+// IndicatorValue 从技术指标数据中读取数值（兼容 float64 以及 "12.34"、"1.23%" 这类格式化字符串）
+func IndicatorValue(technical map[string]interface{}, name string) (float64, bool) {
+	switch value := technical[name].(type) {
+	case float64:
+		return value, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(value, "%"), "元"), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// TechnicalValues 提取技术指标数据中所有可转为数值的字段（如 change_percent、rate、rsi14），便于前端画图和统计
+// 带%的字符串去掉%后按百分数返回（"1.23%" -> 1.23），无法解析的字段（如均线交叉类型）不返回
+func TechnicalValues(technical map[string]interface{}) map[string]float64 {
+	values := make(map[string]float64)
+	for name, raw := range technical {
+		switch value := raw.(type) {
+		case int64:
+			values[name] = float64(value)
+		case int:
+			values[name] = float64(value)
+		default:
+			if f, ok := IndicatorValue(technical, name); ok {
+				values[name] = f
+			}
+		}
+	}
+	return values
+}
+
+// CalculateIndicatorSeries 用最近的日K线重算指定指标最近limit个交易日的时间序列（按时间升序）
+func (a *StockAnalyzer) CalculateIndicatorSeries(name string, limit int) ([]IndicatorPoint, error) {
+	window, ok := seriesIndicatorWindows[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指标: %s", name)
+	}
+
+	dayKline, err := a.getKline("day", limit+window)
+	if err != nil {
+		return nil, fmt.Errorf("获取日K线失败: %w", err)
+	}
+
+	klines := dayKline.List
+	start := len(klines) - limit
+	if start < window {
+		start = window
+	}
+
+	points := []IndicatorPoint{}
+	for end := start; end <= len(klines); end++ {
+		value, ok := a.indicatorAt(name, klines[:end])
+		if !ok {
+			continue
+		}
+		points = append(points, IndicatorPoint{Time: klines[end-1].Time, Value: value})
+	}
+	return points, nil
+}
+
+// indicatorAt 计算以klines最后一根为当前K线的指标值
+func (a *StockAnalyzer) indicatorAt(name string, klines []KlineItem) (float64, bool) {
+	switch name {
+	case "ma5":
+		return movingAverageAt(klines, 5, len(klines))
+	case "ma10":
+		return movingAverageAt(klines, 10, len(klines))
+	case "ma20":
+		return movingAverageAt(klines, 20, len(klines))
+	case "ma60":
+		return movingAverageAt(klines, 60, len(klines))
+	case "rsi14":
+		return a.calculateRSI(klines, 14), len(klines) >= 15
+	case "volatility_20d":
+		return a.calculateVolatility(klines, 20) * 100, len(klines) >= 21
+	case "macd_dif", "macd_dea", "macd_hist":
+		dif, dea, hist, ok := CalculateMACD(klines)
+		return map[string]float64{"macd_dif": dif, "macd_dea": dea, "macd_hist": hist}[name], ok
+	case "kdj_k", "kdj_d", "kdj_j":
+		k, d, j, ok := CalculateKDJ(klines)
+		return map[string]float64{"kdj_k": k, "kdj_d": d, "kdj_j": j}[name], ok
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,228 @@
+package stock
+
+import "time"
+
+// 均线交叉事件类型
+const (
+	MACrossGolden = "golden_cross" // 金叉：短期均线上穿长期均线
+	MACrossDeath  = "death_cross"  // 死叉：短期均线下穿长期均线
+)
+
+// movingAverageAt 计算以end（不含）为结尾的period日收盘均价（元）
+// 数据不足时返回false
+func movingAverageAt(klines []KlineItem, period int, end int) (float64, bool) {
+	if period <= 0 || end > len(klines) || end-period < 0 {
+		return 0, false
+	}
+
+	sum := 0
+	for i := end - period; i < end; i++ {
+		sum += klines[i].Close
+	}
+	return PriceToYuan(sum) / float64(period), true
+}
+
+// DetectMACross 检测相邻两日短期/长期均线的交叉事件
+// 需要至少 longPeriod+1 根日K线（计算昨日和今日两组均线值），数据不足时返回空字符串
+func DetectMACross(klines []KlineItem, shortPeriod, longPeriod int) string {
+	n := len(klines)
+	if n < longPeriod+1 {
+		return ""
+	}
+
+	prevShort, ok1 := movingAverageAt(klines, shortPeriod, n-1)
+	prevLong, ok2 := movingAverageAt(klines, longPeriod, n-1)
+	currShort, ok3 := movingAverageAt(klines, shortPeriod, n)
+	currLong, ok4 := movingAverageAt(klines, longPeriod, n)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return ""
+	}
+
+	// 昨日短均线在长均线下方（或持平），今日上穿
+	if prevShort <= prevLong && currShort > currLong {
+		return MACrossGolden
+	}
+	// 昨日短均线在长均线上方（或持平），今日下穿
+	if prevShort >= prevLong && currShort < currLong {
+		return MACrossDeath
+	}
+	return ""
+}
+
+// getMACrossText 获取均线交叉事件的中文描述
+func getMACrossText(cross string) string {
+	switch cross {
+	case MACrossGolden:
+		return "MA5上穿MA20（金叉）"
+	case MACrossDeath:
+		return "MA5下穿MA20（死叉）"
+	default:
+		return "无交叉"
+	}
+}
+
+// 趋势方向
+const (
+	TrendUp       = "up"       // 上涨
+	TrendDown     = "down"     // 下跌
+	TrendSideways = "sideways" // 震荡
+)
+
+// DetectTrend 根据收盘价与MA5/MA10的排列判断K线趋势方向
+// 收盘价>MA5>MA10 为上涨，收盘价<MA5<MA10 为下跌，其余为震荡；数据不足10根返回空字符串
+func DetectTrend(klines []KlineItem) string {
+	n := len(klines)
+	ma5, ok1 := movingAverageAt(klines, 5, n)
+	ma10, ok2 := movingAverageAt(klines, 10, n)
+	if !ok1 || !ok2 {
+		return ""
+	}
+
+	lastClose := PriceToYuan(klines[n-1].Close)
+	switch {
+	case lastClose > ma5 && ma5 > ma10:
+		return TrendUp
+	case lastClose < ma5 && ma5 < ma10:
+		return TrendDown
+	default:
+		return TrendSideways
+	}
+}
+
+// getTrendText 获取趋势方向的中文描述
+func getTrendText(trend string) string {
+	switch trend {
+	case TrendUp:
+		return "上涨"
+	case TrendDown:
+		return "下跌"
+	case TrendSideways:
+		return "震荡"
+	default:
+		return "未知"
+	}
+}
+
+// getKlinePeriodText 获取K线周期的中文描述
+func getKlinePeriodText(klineType string) string {
+	switch klineType {
+	case "minute1":
+		return "1分钟"
+	case "minute5":
+		return "5分钟"
+	case "minute15":
+		return "15分钟"
+	case "minute30":
+		return "30分钟"
+	case "hour":
+		return "60分钟"
+	case "day":
+		return "日线"
+	case "week":
+		return "周线"
+	case "month":
+		return "月线"
+	default:
+		return klineType
+	}
+}
+
+// emaSeries 计算收盘价序列的指数移动平均（首值取第一个收盘价）
+func emaSeries(values []float64, period int) []float64 {
+	result := make([]float64, len(values))
+	if len(values) == 0 {
+		return result
+	}
+	alpha := 2.0 / float64(period+1)
+	result[0] = values[0]
+	for i := 1; i < len(values); i++ {
+		result[i] = alpha*values[i] + (1-alpha)*result[i-1]
+	}
+	return result
+}
+
+// CalculateMACD 计算MACD(12,26,9)，返回最新的DIF、DEA和MACD柱（2×(DIF-DEA)）
+// EMA需要一定长度才能收敛，K线少于35根时返回false
+func CalculateMACD(klines []KlineItem) (dif, dea, hist float64, ok bool) {
+	if len(klines) < 35 {
+		return 0, 0, 0, false
+	}
+
+	closes := make([]float64, len(klines))
+	for i, kline := range klines {
+		closes[i] = PriceToYuan(kline.Close)
+	}
+	ema12 := emaSeries(closes, 12)
+	ema26 := emaSeries(closes, 26)
+	difSeries := make([]float64, len(closes))
+	for i := range closes {
+		difSeries[i] = ema12[i] - ema26[i]
+	}
+	deaSeries := emaSeries(difSeries, 9)
+
+	n := len(closes) - 1
+	dif = difSeries[n]
+	dea = deaSeries[n]
+	return dif, dea, 2 * (dif - dea), true
+}
+
+// CalculateKDJ 计算KDJ(9,3,3)，返回最新的K、D、J值
+// RSV = (收盘价-9日最低价)/(9日最高价-9日最低价)×100，K、D初始值取50
+func CalculateKDJ(klines []KlineItem) (k, d, j float64, ok bool) {
+	const period = 9
+	if len(klines) < period {
+		return 0, 0, 0, false
+	}
+
+	k, d = 50, 50
+	for i := period - 1; i < len(klines); i++ {
+		low, high := klines[i].Low, klines[i].High
+		for _, kline := range klines[i-period+1 : i+1] {
+			if kline.Low < low {
+				low = kline.Low
+			}
+			if kline.High > high {
+				high = kline.High
+			}
+		}
+
+		rsv := 50.0
+		if high > low {
+			rsv = float64(klines[i].Close-low) / float64(high-low) * 100
+		}
+		k = 2.0/3.0*k + 1.0/3.0*rsv
+		d = 2.0/3.0*d + 1.0/3.0*k
+	}
+	return k, d, 3*k - 2*d, true
+}
+
+// volumeRatioDays 量比的参考天数
+const volumeRatioDays = 5
+
+// volumeRatio 计算量比：当日成交量（手）除以今日之前最近5个交易日的平均成交量，K线不足5日时返回false
+// 交易日盘中当日成交量只是截至当前的累计量，按已过的连续竞价时长折算到全天（标准量比：每分钟成交量之比），
+// 1表示与近5日同时段均量持平；非交易日拿到的是上一交易日的全天成交量，不折算
+func volumeRatio(klines []KlineItem, todayVolume int64, now time.Time) (float64, bool) {
+	today := now.Format("2006-01-02")
+	var sum int64
+	days := 0
+	for i := len(klines) - 1; i >= 0 && days < volumeRatioDays; i-- {
+		if klines[i].Partial || klines[i].Time.Format("2006-01-02") == today {
+			continue
+		}
+		sum += klines[i].Volume
+		days++
+	}
+	if days < volumeRatioDays || sum <= 0 || todayVolume <= 0 {
+		return 0, false
+	}
+	ratio := float64(todayVolume) / (float64(sum) / volumeRatioDays)
+	if now = now.In(chinaTZ); isWeekdayTradingDay(now) {
+		elapsed := tradingMinutesElapsed(now)
+		if elapsed < minActivityElapsed {
+			elapsed = minActivityElapsed
+		}
+		ratio = ratio * activityDayMinutes / float64(elapsed)
+	}
+	return ratio, true
+}
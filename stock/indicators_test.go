@@ -0,0 +1,34 @@
+package stock
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVolumeRatioScalesByElapsedMinutes(t *testing.T) {
+	// 2025-06-02(周一)之前5个交易日每日成交量10000手
+	var klines []KlineItem
+	for _, day := range []int{26, 27, 28, 29, 30} {
+		klines = append(klines, KlineItem{Volume: 10000, Time: time.Date(2025, 5, day, 15, 0, 0, 0, chinaTZ)})
+	}
+
+	cases := []struct {
+		name   string
+		now    time.Time
+		volume int64
+		want   float64
+	}{
+		{"上午10:30已过60分钟，成交量为全天均量的1/4，与同时段持平", time.Date(2025, 6, 3, 10, 30, 0, 0, chinaTZ), 2500, 1},
+		{"午休时已过120分钟，成交量与全天均量相同，同时段放量一倍", time.Date(2025, 6, 3, 12, 0, 0, 0, chinaTZ), 10000, 2},
+		{"收盘后不折算", time.Date(2025, 6, 3, 15, 30, 0, 0, chinaTZ), 15000, 1.5},
+		{"开盘头几分钟按15分钟折算", time.Date(2025, 6, 3, 9, 32, 0, 0, chinaTZ), 625, 1},
+		{"周末拿到的是上一交易日全天成交量，不折算", time.Date(2025, 6, 7, 10, 30, 0, 0, chinaTZ), 10000, 1},
+	}
+	for _, tc := range cases {
+		got, ok := volumeRatio(klines, tc.volume, tc.now)
+		if !ok || math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: volumeRatio = %.4f, %v，期望 %.4f", tc.name, got, ok, tc.want)
+		}
+	}
+}
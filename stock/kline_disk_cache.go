@@ -0,0 +1,183 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// K线磁盘缓存（L2）：获取K线后把结果按 代码+周期+数量 写入一个JSON文件（带保存时间和有效期），数据有变化时才重写
+// 内存缓存（L1）未命中时先查磁盘：有效期内的直接返回并载入内存缓存，不请求TDX（如收盘后重启，到下次开盘前都不必重拉）；
+// 已过有效期但不超过maxIncrementalAge的作为增量更新的基础，重启后只需增量拉取，避免早盘全量重拉
+
+// klineDiskEntry 磁盘缓存文件内容
+type klineDiskEntry struct {
+	Code      string     `json:"code"`
+	KlineType string     `json:"kline_type"`
+	Limit     int        `json:"limit"`
+	SavedAt   time.Time  `json:"saved_at"`   // 保存时间，超过maxIncrementalAge的缓存不再加载
+	ExpiresAt time.Time  `json:"expires_at"` // 有效期，之前可直接使用而不请求TDX（交易时段内保存的数据随时会变，有效期即保存时间）
+	Data      *KlineData `json:"data"`
+}
+
+// EnableDiskCache 启用K线磁盘缓存，清理过期的缓存文件，返回可用的缓存条数（首次获取对应K线时才读取）
+// validUntil 根据保存时间计算磁盘缓存的有效期（如收盘后保存的数据到下次开盘前有效），为nil时磁盘缓存只作为增量更新的基础
+func (c *TDXClient) EnableDiskCache(dir string, validUntil func(savedAt time.Time) time.Time) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("创建K线缓存目录失败: %w", err)
+	}
+	c.diskCacheDir = dir
+	c.diskValidUntil = validUntil
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	usable := 0
+	for _, file := range files {
+		entry, err := readKlineDiskEntry(file)
+		if err != nil {
+			log.Printf("⚠️  跳过无效的K线缓存文件: %s", filepath.Base(file))
+			continue
+		}
+		if time.Since(entry.SavedAt) > maxIncrementalAge {
+			os.Remove(file) // 过期缓存直接清理
+			continue
+		}
+		usable++
+	}
+	return usable, nil
+}
+
+// loadKlineFromDisk 内存缓存未命中时读取磁盘缓存（每个缓存键只读一次）：
+// 有效期内的载入内存缓存并返回；否则作为增量更新的基础（内存中已有基础数据时不覆盖）
+func (c *TDXClient) loadKlineFromDisk(code string, klineType string, limit int) (*KlineData, bool) {
+	if c.diskCacheDir == "" {
+		return nil, false
+	}
+
+	key := klineCacheKey(code, klineType, limit)
+	c.baseMutex.Lock()
+	if c.diskLoaded[key] {
+		c.baseMutex.Unlock()
+		return nil, false
+	}
+	c.diskLoaded[key] = true
+	c.baseMutex.Unlock()
+
+	entry, err := readKlineDiskEntry(filepath.Join(c.diskCacheDir, klineDiskFileName(code, klineType, limit)))
+	if err != nil || time.Since(entry.SavedAt) > maxIncrementalAge {
+		return nil, false
+	}
+
+	c.baseMutex.Lock()
+	if c.klineBase[key] == nil {
+		c.klineBase[key] = entry.Data
+		c.diskExpires[key] = entry.ExpiresAt
+	}
+	c.baseMutex.Unlock()
+
+	if !time.Now().Before(entry.ExpiresAt) {
+		return nil, false
+	}
+	c.cacheMutex.Lock()
+	c.klineCache[key] = klineCacheEntry{data: entry.Data, expiresAt: entry.ExpiresAt}
+	c.cacheMutex.Unlock()
+	return c.getCachedKlineByKey(key)
+}
+
+// readKlineDiskEntry 读取并校验一个磁盘缓存文件
+func readKlineDiskEntry(file string) (*klineDiskEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var entry klineDiskEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Data == nil || len(entry.Data.List) == 0 {
+		return nil, fmt.Errorf("缓存数据为空")
+	}
+	return &entry, nil
+}
+
+// saveKlineToDisk 把K线写入磁盘缓存（先写临时文件再重命名，避免写到一半时重启留下损坏文件），失败只记录日志
+// 与上次写入的数据相同且可直接使用的有效期没有延长时不重写（previous为本次获取前的增量基础数据）
+func (c *TDXClient) saveKlineToDisk(code string, klineType string, limit int, previous, data *KlineData) {
+	if c.diskCacheDir == "" {
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now
+	if c.diskValidUntil != nil {
+		expiresAt = c.diskValidUntil(now)
+	}
+
+	key := klineCacheKey(code, klineType, limit)
+	c.baseMutex.Lock()
+	extended := expiresAt.After(now) && expiresAt.After(c.diskExpires[key])
+	unchanged := sameKline(previous, data) && !extended
+	if !unchanged {
+		c.diskExpires[key] = expiresAt
+	}
+	c.baseMutex.Unlock()
+	if unchanged {
+		return
+	}
+
+	content, err := json.Marshal(klineDiskEntry{
+		Code:      code,
+		KlineType: klineType,
+		Limit:     limit,
+		SavedAt:   now,
+		ExpiresAt: expiresAt,
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("⚠️  序列化K线缓存失败: %v", err)
+		return
+	}
+
+	file := filepath.Join(c.diskCacheDir, klineDiskFileName(code, klineType, limit))
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		log.Printf("⚠️  写入K线缓存失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		log.Printf("⚠️  写入K线缓存失败: %v", err)
+	}
+}
+
+// sameKline 两份K线数据是否相同（根数相同、首尾K线一致；增量更新只会改动末尾的K线）
+func sameKline(a, b *KlineData) bool {
+	if a == nil || b == nil || len(a.List) != len(b.List) || len(a.List) == 0 {
+		return false
+	}
+	return sameKlineItem(a.List[0], b.List[0]) && sameKlineItem(a.List[len(a.List)-1], b.List[len(b.List)-1])
+}
+
+// sameKlineItem 两根K线是否相同（时间用Equal比较，从磁盘读回的时间时区指针不同）
+func sameKlineItem(a, b KlineItem) bool {
+	at, bt := a.Time, b.Time
+	a.Time, b.Time = time.Time{}, time.Time{}
+	return a == b && at.Equal(bt)
+}
+
+// klineDiskFileName 磁盘缓存文件名（代码中的特殊字符替换为下划线）
+func klineDiskFileName(code string, klineType string, limit int) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == '|' {
+			return '_'
+		}
+		return r
+	}, code)
+	return fmt.Sprintf("%s_%s_%d.json", safe, klineType, limit)
+}
@@ -0,0 +1,52 @@
+package stock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKlineDiskCacheServesUnexpiredEntry(t *testing.T) {
+	dir := t.TempDir()
+	data := &KlineData{Count: 2, List: []KlineItem{
+		{Close: 10000, Time: time.Date(2025, 6, 9, 15, 0, 0, 0, time.Local)},
+		{Close: 10100, Time: time.Date(2025, 6, 10, 15, 0, 0, 0, time.Local)},
+	}}
+
+	writer := NewTDXClient("http://127.0.0.1:0")
+	if _, err := writer.EnableDiskCache(dir, func(savedAt time.Time) time.Time { return savedAt.Add(time.Hour) }); err != nil {
+		t.Fatal(err)
+	}
+	writer.saveKlineToDisk("000001", "day", 60, nil, data)
+
+	// 新进程（冷启动）：TDX不可达，有效期内的磁盘缓存直接返回并载入内存缓存
+	reader := NewTDXClient("http://127.0.0.1:0")
+	if _, err := reader.EnableDiskCache(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := reader.GetKline("000001", "day", 60)
+	if err != nil {
+		t.Fatalf("磁盘缓存未命中: %v", err)
+	}
+	if len(got.List) != 2 || got.List[1].Close != 10100 {
+		t.Fatalf("磁盘缓存内容不符: %+v", got.List)
+	}
+	if _, ok := reader.getCachedKline("000001", "day", 60); !ok {
+		t.Fatal("磁盘缓存命中后应载入内存缓存")
+	}
+}
+
+func TestKlineDiskCacheSkipsUnchangedWrite(t *testing.T) {
+	client := NewTDXClient("http://127.0.0.1:0")
+	if _, err := client.EnableDiskCache(t.TempDir(), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := &KlineData{List: []KlineItem{{Close: 10000, Time: time.Date(2025, 6, 10, 15, 0, 0, 0, time.Local)}}}
+	client.saveKlineToDisk("000001", "day", 60, nil, data)
+	written := client.diskExpires[klineCacheKey("000001", "day", 60)]
+
+	same := &KlineData{List: append([]KlineItem(nil), data.List...)}
+	client.saveKlineToDisk("000001", "day", 60, data, same)
+	if !client.diskExpires[klineCacheKey("000001", "day", 60)].Equal(written) {
+		t.Fatal("数据未变化时不应重写磁盘缓存")
+	}
+}
@@ -0,0 +1,92 @@
+package stock
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// errKlineNotFound K线接口返回404（TDX代理不提供该接口）
+var errKlineNotFound = errors.New("K线接口不存在")
+
+// maxIncrementalAge 增量基础数据的最长间隔，超过后（如长时间停机）直接全量重拉
+const maxIncrementalAge = 7 * 24 * time.Hour
+
+// getKlineIncremental 获取K线：已有上次结果时只拉取上次最后一根K线所在日期以来的K线并合并，否则全量获取
+// 增量拉取失败时回退为全量获取
+func (c *TDXClient) getKlineIncremental(code string, klineType string, limit int) (*KlineData, error) {
+	key := klineCacheKey(code, klineType, limit)
+
+	c.baseMutex.Lock()
+	base := c.klineBase[key]
+	c.baseMutex.Unlock()
+
+	var data *KlineData
+	if base != nil && len(base.List) > 0 && atomic.LoadInt32(&c.klineHistoryUnsupported) == 0 &&
+		time.Since(base.List[len(base.List)-1].Time) < maxIncrementalAge {
+		merged, err := c.fetchKlineSince(code, klineType, limit, base)
+		if err == nil {
+			data = merged
+		} else if err != errKlineNotFound {
+			log.Printf("⚠️  增量更新 %s %s K线失败，改为全量获取: %v", code, klineType, err)
+		}
+	}
+
+	if data == nil {
+		fetched, err := c.fetchKline(code, klineType, limit)
+		if err != nil {
+			return nil, err
+		}
+		data = fetched
+	}
+
+	c.baseMutex.Lock()
+	c.klineBase[key] = data
+	c.baseMutex.Unlock()
+	c.saveKlineToDisk(code, klineType, limit, base, data)
+
+	// 返回副本，避免调用方修改增量基础数据
+	result := *data
+	result.List = append([]KlineItem(nil), data.List...)
+	return &result, nil
+}
+
+// fetchKlineSince 从历史K线接口拉取base最后一根K线所在日期以来的K线，合并到base
+// 最后一根K线可能未收盘，新数据中同一时间及之后的K线覆盖旧数据
+func (c *TDXClient) fetchKlineSince(code string, klineType string, limit int, base *KlineData) (*KlineData, error) {
+	last := base.List[len(base.List)-1].Time
+	url := fmt.Sprintf("%s/api/kline-history?code=%s&type=%s&start_date=%s&limit=800",
+		c.BaseURL, code, klineType, last.Format("20060102"))
+	recent, err := c.requestKline(url)
+	if err == errKlineNotFound {
+		atomic.StoreInt32(&c.klineHistoryUnsupported, 1)
+		log.Printf("ℹ️  TDX代理不提供历史K线接口，K线改为每次全量获取")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(recent.List) == 0 {
+		return nil, fmt.Errorf("历史K线接口返回空数据")
+	}
+	if recent.List[0].Time.After(last) {
+		// 新数据没有覆盖到上次最后一根K线，中间可能缺数据
+		return nil, fmt.Errorf("增量数据与缓存不连续（缓存截至 %s，增量起始 %s）",
+			last.Format("2006-01-02 15:04"), recent.List[0].Time.Format("2006-01-02 15:04"))
+	}
+
+	from := recent.List[0].Time
+	merged := &KlineData{}
+	for _, item := range base.List {
+		if item.Time.Before(from) {
+			merged.List = append(merged.List, item)
+		}
+	}
+	merged.List = append(merged.List, recent.List...)
+	merged.Count = len(merged.List)
+
+	finishKline(merged, klineType, limit)
+	return merged, nil
+}
@@ -0,0 +1,99 @@
+package stock
+
+import "time"
+
+// min30BarEnds A股30分钟K线的收盘时刻（K线时间为该根K线的结束时间）
+var min30BarEnds = []string{"10:00", "10:30", "11:00", "11:30", "13:30", "14:00", "14:30", "15:00"}
+
+// chinaTZ K线时间使用的时区（北京时间）
+var chinaTZ = time.FixedZone("CST", 8*3600)
+
+// min30BarEnd 返回分时时间点（HH:MM）所属30分钟K线的收盘时刻，不在交易时段内返回空串
+func min30BarEnd(minute string) string {
+	if minute < "09:30" || minute > "15:00" {
+		return ""
+	}
+	for _, end := range min30BarEnds {
+		if minute <= end {
+			return end
+		}
+	}
+	return ""
+}
+
+// withRealtimeMin30Bar 用今日分时数据合成当前未收盘的30分钟K线并拼到末尾
+// TDX的30分钟K线要等整点/半点收盘后才更新，盘中直接使用会滞后最多30分钟
+// 返回新的KlineData（不修改原数据，原数据可能来自K线缓存）；无需合成时原样返回
+func withRealtimeMin30Bar(min30Kline *KlineData, minuteData *MinuteData, now time.Time) *KlineData {
+	if min30Kline == nil || minuteData == nil || len(minuteData.List) == 0 {
+		return min30Kline
+	}
+	now = now.In(chinaTZ)
+	if !isWeekdayTradingDay(now) {
+		return min30Kline // 周末和节假日拿到的是上一交易日的分时数据
+	}
+
+	last := minuteData.List[len(minuteData.List)-1]
+	barEnd := min30BarEnd(last.Time)
+	if barEnd == "" || last.Time > now.Format("15:04") {
+		return min30Kline // 分时时间晚于当前时刻，说明是上一交易日的数据
+	}
+	endTime, err := time.ParseInLocation("2006-01-02 15:04", now.Format("2006-01-02")+" "+barEnd, chinaTZ)
+	if err != nil {
+		return min30Kline
+	}
+
+	// 已收盘K线的结束时间，其后的分时数据属于当前K线
+	lastClosed := ""
+	if n := len(min30Kline.List); n > 0 {
+		lastTime := min30Kline.List[n-1].Time.In(chinaTZ)
+		if !lastTime.Before(endTime) {
+			return min30Kline // 当前K线TDX已给出
+		}
+		if lastTime.Format("2006-01-02") == now.Format("2006-01-02") {
+			lastClosed = lastTime.Format("15:04")
+		}
+	}
+
+	var bar KlineItem
+	for _, item := range minuteData.List {
+		if item.Price <= 0 || item.Time <= lastClosed || min30BarEnd(item.Time) != barEnd {
+			continue
+		}
+		if bar.Open == 0 {
+			bar.Open, bar.High, bar.Low = item.Price, item.Price, item.Price
+		}
+		if item.Price > bar.High {
+			bar.High = item.Price
+		}
+		if item.Price < bar.Low {
+			bar.Low = item.Price
+		}
+		bar.Close = item.Price
+		bar.Volume += int64(item.Number)
+		bar.Amount += float64(item.Price) * float64(VolumeToShares(int64(item.Number)))
+	}
+	if bar.Open == 0 {
+		return min30Kline
+	}
+	if n := len(min30Kline.List); n > 0 {
+		bar.Last = min30Kline.List[n-1].Close
+	}
+	bar.Time = endTime
+	bar.Partial = now.Before(endTime)
+
+	list := make([]KlineItem, len(min30Kline.List), len(min30Kline.List)+1)
+	copy(list, min30Kline.List)
+	merged := *min30Kline
+	merged.List = append(list, bar)
+	merged.Count = len(merged.List)
+	return &merged
+}
+
+// partialBarText 未收盘K线在提示词中的标注
+func partialBarText(item KlineItem) string {
+	if !item.Partial {
+		return ""
+	}
+	return "（当前K线未收盘，由分时数据合成）"
+}
@@ -0,0 +1,24 @@
+package stock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithRealtimeMin30BarSkipsNonTradingDays(t *testing.T) {
+	min30 := &KlineData{List: []KlineItem{{Close: 10000, Time: time.Date(2025, 9, 30, 15, 0, 0, 0, chinaTZ)}}}
+	minute := &MinuteData{List: []MinuteItem{{Time: "09:31", Price: 10100, Number: 10}, {Time: "09:45", Price: 10200, Number: 20}}}
+
+	// 国庆节（周三）10:00拿到的是上一交易日的分时数据，不合成K线
+	holiday := time.Date(2025, 10, 1, 10, 0, 0, 0, chinaTZ)
+	if got := withRealtimeMin30Bar(min30, minute, holiday); len(got.List) != 1 {
+		t.Fatalf("节假日不应合成K线，实际 %d 根", len(got.List))
+	}
+
+	tradingDay := time.Date(2025, 9, 30, 9, 50, 0, 0, chinaTZ)
+	min30.List[0].Time = time.Date(2025, 9, 29, 15, 0, 0, 0, chinaTZ)
+	got := withRealtimeMin30Bar(min30, minute, tradingDay)
+	if len(got.List) != 2 || !got.List[1].Partial || got.List[1].Close != 10200 || got.List[1].Volume != 30 {
+		t.Fatalf("交易日盘中应合成未收盘K线: %+v", got.List)
+	}
+}
@@ -0,0 +1,16 @@
+package stock
+
+import "github.com/shopspring/decimal"
+
+// 金额与价格的十进制计算辅助
+// 成本、市值、盈亏和费用统一用decimal计算，避免float64累计多笔后产生分位误差；对外字段仍为float64
+
+// yuan 将以元为单位的浮点数转为decimal（按最短十进制表示转换，如12.35不会变成12.3499...）
+func yuan(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+// toCent 四舍五入到分后转为float64
+func toCent(d decimal.Decimal) float64 {
+	return d.Round(2).InexactFloat64()
+}
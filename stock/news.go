@@ -0,0 +1,154 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewsItem 一条新闻/公告
+type NewsItem struct {
+	Title  string `json:"title"`            // 标题
+	Time   string `json:"time,omitempty"`   // 发布时间（原样保留新闻源返回的格式）
+	Source string `json:"source,omitempty"` // 来源（如 公告/证券时报）
+}
+
+// NewsClient 新闻/公告摘要客户端
+// 按URL模板请求用户配置的新闻API，结果按股票缓存，避免每轮分析都请求
+type NewsClient struct {
+	URLTemplate string            // 请求地址模板，{code}替换为股票代码
+	Headers     map[string]string // 附加请求头（如API Key）
+	Limit       int               // 注入提示词的最大条数
+	MaxAge      time.Duration     // 只保留该时长内发布的新闻（无法解析发布时间的不过滤），0表示不过滤
+	CacheTTL    time.Duration     // 缓存有效期
+	HTTPClient  *http.Client
+
+	mutex sync.Mutex
+	cache map[string]newsCacheEntry
+}
+
+// newsCacheEntry 新闻缓存
+type newsCacheEntry struct {
+	items     []NewsItem
+	fetchedAt time.Time
+}
+
+// newsTimeLayouts 发布时间的可解析格式
+var newsTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "20060102"}
+
+// NewNewsClient 创建新闻客户端
+func NewNewsClient(urlTemplate string, headers map[string]string, limit int, maxAge, cacheTTL, timeout time.Duration) *NewsClient {
+	return &NewsClient{
+		URLTemplate: urlTemplate,
+		Headers:     headers,
+		Limit:       limit,
+		MaxAge:      maxAge,
+		CacheTTL:    cacheTTL,
+		HTTPClient:  &http.Client{Timeout: timeout},
+		cache:       make(map[string]newsCacheEntry),
+	}
+}
+
+// GetNews 获取股票近期的新闻/公告（优先使用缓存）
+func (c *NewsClient) GetNews(code string) ([]NewsItem, error) {
+	c.mutex.Lock()
+	entry, ok := c.cache[code]
+	c.mutex.Unlock()
+	if ok && time.Since(entry.fetchedAt) < c.CacheTTL {
+		return entry.items, nil
+	}
+
+	items, err := c.fetch(code)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.cache[code] = newsCacheEntry{items: items, fetchedAt: time.Now()}
+	c.mutex.Unlock()
+	return items, nil
+}
+
+// fetch 请求新闻API
+// 响应可以是新闻数组，也可以是 {"data": [...]} 包装；每条至少包含title，
+// 发布时间取 time/date/publish_time/pub_time 之一，来源取 source/type
+func (c *NewsClient) fetch(code string) ([]NewsItem, error) {
+	reqURL := strings.ReplaceAll(c.URLTemplate, "{code}", url.QueryEscape(code))
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建新闻请求失败: %w", err)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求新闻API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取新闻响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("新闻API返回状态码 %d", resp.StatusCode)
+	}
+
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		var wrapped struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("解析新闻响应失败: %w", err)
+		}
+		raw = wrapped.Data
+	}
+
+	var items []NewsItem
+	for _, m := range raw {
+		item := NewsItem{
+			Title:  strings.TrimSpace(firstString(m, "title")),
+			Time:   firstString(m, "time", "date", "publish_time", "pub_time"),
+			Source: firstString(m, "source", "type"),
+		}
+		if item.Title == "" || !c.isRecent(item.Time) {
+			continue
+		}
+		items = append(items, item)
+		if c.Limit > 0 && len(items) >= c.Limit {
+			break
+		}
+	}
+	return items, nil
+}
+
+// isRecent 判断发布时间是否在MaxAge内（无法解析时视为近期）
+func (c *NewsClient) isRecent(published string) bool {
+	if c.MaxAge <= 0 || published == "" {
+		return true
+	}
+	for _, layout := range newsTimeLayouts {
+		if t, err := time.ParseInLocation(layout, published, time.Local); err == nil {
+			return time.Since(t) <= c.MaxAge
+		}
+	}
+	return true
+}
+
+// firstString 返回map中第一个非空的字符串字段
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -2,45 +2,179 @@ package stock
 
 import (
 	"fmt"
+	"math"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
+// 持有天数少于该值时，年化收益率参考意义有限
+const minAnnualizeHoldingDays = 30
+
+// FeeRates 交易费率
+type FeeRates struct {
+	CommissionRate  float64 `json:"commission_rate"`   // 佣金费率（双向收取，如0.00025表示万2.5）
+	MinCommission   float64 `json:"min_commission"`    // 最低佣金（元/笔）
+	StampDutyRate   float64 `json:"stamp_duty_rate"`   // 印花税率（仅卖出收取）
+	TransferFeeRate float64 `json:"transfer_fee_rate"` // 过户费率（双向收取）
+}
+
+// DefaultFeeRates 默认交易费率（佣金万2.5最低5元，印花税0.05%，过户费0.001%）
+func DefaultFeeRates() FeeRates {
+	return FeeRates{
+		CommissionRate:  0.00025,
+		MinCommission:   5,
+		StampDutyRate:   0.0005,
+		TransferFeeRate: 0.00001,
+	}
+}
+
+// 券商费率模板
+const (
+	DefaultFeeTemplate = "万2.5"   // 默认模板，与DefaultFeeRates一致
+	FeeTemplateCustom  = "custom" // 自定义费率
+)
+
+// FeeTemplates 内置券商费率模板（印花税0.05%仅卖出收取，过户费0.001%双向收取）
+var FeeTemplates = map[string]FeeRates{
+	"万1.5":   {CommissionRate: 0.00015, MinCommission: 5, StampDutyRate: 0.0005, TransferFeeRate: 0.00001},
+	"万1.5免五": {CommissionRate: 0.00015, MinCommission: 0, StampDutyRate: 0.0005, TransferFeeRate: 0.00001},
+	"万2.5":   DefaultFeeRates(),
+	"万3":     {CommissionRate: 0.0003, MinCommission: 5, StampDutyRate: 0.0005, TransferFeeRate: 0.00001},
+}
+
+// CalculateFee 计算一笔交易的费用（元）
+func (r FeeRates) CalculateFee(amount float64, isSell bool) float64 {
+	return r.calculateFee(yuan(amount), isSell).InexactFloat64()
+}
+
+// calculateFee 按十进制计算一笔交易的费用，佣金、过户费、印花税各自四舍五入到分
+func (r FeeRates) calculateFee(amount decimal.Decimal, isSell bool) decimal.Decimal {
+	if !amount.IsPositive() {
+		return decimal.Zero
+	}
+
+	commission := amount.Mul(yuan(r.CommissionRate)).Round(2)
+	if minCommission := yuan(r.MinCommission); commission.LessThan(minCommission) {
+		commission = minCommission
+	}
+	fee := commission.Add(amount.Mul(yuan(r.TransferFeeRate)).Round(2))
+	if isSell {
+		fee = fee.Add(amount.Mul(yuan(r.StampDutyRate)).Round(2))
+	}
+	return fee
+}
+
+// BreakEvenPrice 计算回本价：按该价格全部卖出后，扣除卖出费用恰好收回投入（持仓成本+买入费用）
+// 佣金达不到最低佣金时按最低佣金计算；结果向上取整到厘（0.001元）
+func (r FeeRates) BreakEvenPrice(quantity int, invested float64) float64 {
+	if quantity <= 0 || invested <= 0 {
+		return 0
+	}
+
+	// 先假设佣金按比例收取：amount*(1-佣金率-过户费率-印花税率) = invested
+	one := decimal.NewFromInt(1)
+	otherRates := yuan(r.TransferFeeRate).Add(yuan(r.StampDutyRate))
+	amount := yuan(invested).Div(one.Sub(yuan(r.CommissionRate)).Sub(otherRates))
+	if minCommission := yuan(r.MinCommission); amount.Mul(yuan(r.CommissionRate)).LessThan(minCommission) {
+		// 佣金不足最低佣金，按最低佣金收取
+		amount = yuan(invested).Add(minCommission).Div(one.Sub(otherRates))
+	}
+	return amount.Div(decimal.NewFromInt(int64(quantity))).RoundCeil(3).InexactFloat64()
+}
+
 // PositionInfo 持仓信息
 type PositionInfo struct {
 	StockCode         string    `json:"stock_code"`
 	StockName         string    `json:"stock_name"`
-	Quantity          int       `json:"quantity"`        // 持仓数量（股）
-	BuyPrice          float64   `json:"buy_price"`       // 购买价格（元/股）
-	BuyDate           time.Time `json:"buy_date"`        // 购买日期
-	CurrentPrice      float64   `json:"current_price"`   // 当前价格（元/股）
-	TotalCost         float64   `json:"total_cost"`      // 持仓成本（元）
-	MarketValue       float64   `json:"market_value"`    // 市值（元）
-	ProfitLoss        float64   `json:"profit_loss"`     // 浮动盈亏（元）
+	Quantity          int       `json:"quantity"`            // 持仓数量（股）
+	BuyPrice          float64   `json:"buy_price"`           // 购买价格（元/股）
+	BuyDate           time.Time `json:"buy_date"`            // 购买日期
+	CurrentPrice      float64   `json:"current_price"`       // 当前价格（元/股）
+	TotalCost         float64   `json:"total_cost"`          // 持仓成本（元）
+	MarketValue       float64   `json:"market_value"`        // 市值（元）
+	ProfitLoss        float64   `json:"profit_loss"`         // 浮动盈亏（元）
 	ProfitLossPercent float64   `json:"profit_loss_percent"` // 盈亏比例（%）
+
+	// 扣费后的实际收益
+	BuyFee               float64 `json:"buy_fee"`                     // 买入费用（元）
+	SellFee              float64 `json:"sell_fee"`                    // 按当前价卖出的预估费用（元，含印花税）
+	NetProfitLoss        float64 `json:"net_profit_loss"`             // 扣费后净盈亏（元）
+	NetProfitLossPercent float64 `json:"net_profit_loss_percent"`     // 扣费后净收益率（%）
+	HoldingDays          int     `json:"holding_days,omitempty"`      // 持有天数（未填写购买日期时为0）
+	AnnualizedReturn     float64 `json:"annualized_return,omitempty"` // 扣费后年化收益率（%）
+	AnnualizedNote       string  `json:"annualized_note,omitempty"`   // 年化收益率说明（如持有时间过短）
+
+	BreakEvenPrice float64 `json:"break_even_price"`       // 回本价（元/股，扣除买卖费用后不亏的卖出价）
+	FeeTemplate    string  `json:"fee_template,omitempty"` // 计算所用的费率模板
+
+	RealizedProfitLoss float64 `json:"realized_profit_loss,omitempty"` // 已实现盈亏（元，来自成交记录）
 }
 
-// CalculatePositionInfo 计算持仓信息
-func CalculatePositionInfo(code, name string, quantity int, buyPrice, currentPrice float64, buyDate time.Time) *PositionInfo {
-	totalCost := buyPrice * float64(quantity)
-	marketValue := currentPrice * float64(quantity)
-	profitLoss := marketValue - totalCost
+// CalculatePositionInfo 计算持仓信息（按给定费率计算扣费后收益和回本价，now用于计算持有天数和年化）
+// 成本、市值和盈亏按十进制计算并精确到分
+func CalculatePositionInfo(code, name string, quantity int, buyPrice, currentPrice float64, buyDate, now time.Time, rates FeeRates) *PositionInfo {
+	shares := decimal.NewFromInt(int64(quantity))
+	totalCost := yuan(buyPrice).Mul(shares).Round(2)
+	marketValue := yuan(currentPrice).Mul(shares).Round(2)
+	profitLoss := marketValue.Sub(totalCost)
 	profitLossPercent := 0.0
 	if buyPrice > 0 {
 		profitLossPercent = ((currentPrice - buyPrice) / buyPrice) * 100.0
 	}
 
-	return &PositionInfo{
+	info := &PositionInfo{
 		StockCode:         code,
 		StockName:         name,
 		Quantity:          quantity,
 		BuyPrice:          buyPrice,
 		BuyDate:           buyDate,
 		CurrentPrice:      currentPrice,
-		TotalCost:         totalCost,
-		MarketValue:       marketValue,
-		ProfitLoss:        profitLoss,
+		TotalCost:         totalCost.InexactFloat64(),
+		MarketValue:       marketValue.InexactFloat64(),
+		ProfitLoss:        profitLoss.InexactFloat64(),
 		ProfitLossPercent: profitLossPercent,
 	}
+	info.calculateNetReturn(rates, now)
+
+	return info
+}
+
+// calculateNetReturn 计算扣除手续费、印花税后的净盈亏和年化收益率
+func (p *PositionInfo) calculateNetReturn(rates FeeRates, now time.Time) {
+	totalCost := yuan(p.TotalCost)
+	buyFee := rates.calculateFee(totalCost, false)
+	sellFee := rates.calculateFee(yuan(p.MarketValue), true)
+	netProfitLoss := yuan(p.ProfitLoss).Sub(buyFee).Sub(sellFee)
+	p.BuyFee = buyFee.InexactFloat64()
+	p.SellFee = sellFee.InexactFloat64()
+	p.NetProfitLoss = netProfitLoss.InexactFloat64()
+
+	invested := totalCost.Add(buyFee)
+	if invested.IsPositive() {
+		p.NetProfitLossPercent = netProfitLoss.Div(invested).Mul(decimal.NewFromInt(100)).InexactFloat64()
+	}
+	p.BreakEvenPrice = rates.BreakEvenPrice(p.Quantity, invested.InexactFloat64())
+
+	// 未填写购买日期时无法计算年化
+	if p.BuyDate.IsZero() {
+		return
+	}
+
+	days := int(now.Sub(p.BuyDate).Hours() / 24)
+	if days < 1 {
+		days = 1 // 当天买入按1天计算
+	}
+	p.HoldingDays = days
+
+	// 复利年化：(1+r)^(365/days) - 1，亏损超过100%时不计算
+	growth := 1 + p.NetProfitLossPercent/100.0
+	if growth > 0 {
+		p.AnnualizedReturn = (math.Pow(growth, 365.0/float64(days)) - 1) * 100.0
+	}
+	if days < minAnnualizeHoldingDays {
+		p.AnnualizedNote = fmt.Sprintf("持有不足%d天，年化参考意义有限", minAnnualizeHoldingDays)
+	}
 }
 
 // FormatProfitLoss 格式化盈亏显示
@@ -52,3 +186,11 @@ func (p *PositionInfo) FormatProfitLoss() string {
 	return fmt.Sprintf("%s%.2f元 (%.2f%%)", sign, p.ProfitLoss, p.ProfitLossPercent)
 }
 
+// FormatNetProfitLoss 格式化扣费后净盈亏显示
+func (p *PositionInfo) FormatNetProfitLoss() string {
+	sign := "+"
+	if p.NetProfitLoss < 0 {
+		sign = ""
+	}
+	return fmt.Sprintf("%s%.2f元 (%.2f%%，已扣除费用%.2f元)", sign, p.NetProfitLoss, p.NetProfitLossPercent, toCent(yuan(p.BuyFee).Add(yuan(p.SellFee))))
+}
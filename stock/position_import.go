@@ -0,0 +1,199 @@
+package stock
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 持仓导入的处理结果
+const (
+	PositionImportUpdated = "updated" // 已在监控的股票，持仓立即更新
+	PositionImportCreated = "created" // 未监控的股票，写入配置文件，重启后开始监控
+	PositionImportSkipped = "skipped" // 跳过（见Message）
+)
+
+// ImportedPosition 券商App导出的一条持仓
+type ImportedPosition struct {
+	Code         string  `json:"code"`                    // 股票代码
+	Name         string  `json:"name"`                    // 股票名称
+	Quantity     int     `json:"quantity"`                // 持仓数量（股）
+	CostPrice    float64 `json:"cost_price"`              // 成本价（元/股）
+	CurrentPrice float64 `json:"current_price,omitempty"` // 导出时的现价（元/股，可选）
+}
+
+// PositionImportResult 一条持仓的导入结果
+type PositionImportResult struct {
+	ImportedPosition
+	Action  string `json:"action"`            // updated/created/skipped
+	Message string `json:"message,omitempty"` // 说明（跳过原因、生效方式）
+}
+
+// 持仓CSV表头别名（各券商导出的列名不同，按去掉空格和括号单位后的列名匹配，同一字段取最先出现的列）
+var positionCSVColumns = map[string][]string{
+	"code":     {"code", "证券代码", "股票代码", "代码"},
+	"name":     {"name", "证券名称", "股票名称", "名称"},
+	"quantity": {"quantity", "持仓数量", "股票余额", "证券数量", "当前持仓", "持股数量", "实际数量", "参考持股", "股份余额", "持仓", "数量"},
+	"cost":     {"cost", "cost_price", "成本价", "参考成本价", "持仓成本价", "摊薄成本价", "买入成本价", "买入均价", "成本"},
+	"price":    {"price", "current_price", "现价", "最新价", "当前价", "市价", "参考市价", "最新价格"},
+}
+
+// normalizePositionHeader 规范化表头：去掉BOM、空格和括号中的单位（如"成本价(元)"）
+func normalizePositionHeader(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")
+	for _, pair := range [][2]string{{"(", ")"}, {"（", "）"}} {
+		if start := strings.Index(name, pair[0]); start >= 0 {
+			if end := strings.Index(name[start:], pair[1]); end >= 0 {
+				name = name[:start] + name[start+end+len(pair[1]):]
+			}
+		}
+	}
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+// positionColumns 识别表头行中各字段的列位置
+func positionColumns(header []string) map[string]int {
+	index := make(map[string]int)
+	for i, name := range header {
+		name = normalizePositionHeader(name)
+		for key, aliases := range positionCSVColumns {
+			if _, found := index[key]; found {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					index[key] = i
+					break
+				}
+			}
+		}
+	}
+	return index
+}
+
+// cleanPositionCode 清理代码单元格：去掉Excel的 ="000001" 写法和引号，纯数字代码补足6位（表格软件会吃掉前导0）
+func cleanPositionCode(value string) string {
+	value = strings.Trim(strings.TrimSpace(value), "=\"' \t")
+	if value == "" {
+		return ""
+	}
+	if _, err := strconv.Atoi(value); err == nil && len(value) < 6 {
+		value = strings.Repeat("0", 6-len(value)) + value
+	}
+	return value
+}
+
+// parsePositionNumber 解析数值单元格（兼容千分位逗号和"元"、"股"等单位）
+func parsePositionNumber(value string) (float64, error) {
+	value = strings.Trim(strings.TrimSpace(value), "=\"'")
+	value = strings.NewReplacer(",", "", "元", "", "股", "", " ", "").Replace(value)
+	if value == "" || value == "-" || value == "--" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// ParsePositionCSV 解析券商App（东方财富、同花顺等）导出的持仓CSV
+// 自动识别逗号/制表符分隔，表头可以不在第一行（跳过前面的标题、账户信息行），需包含代码、持仓数量、成本价列，名称和现价可选；
+// 代码不是有效证券代码的行（如"合计"）跳过。文件为UTF-8编码，不是有效UTF-8时按GBK解码（东方财富、同花顺默认导出GBK）
+func ParsePositionCSV(data []byte) ([]ImportedPosition, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("文件既不是UTF-8也不是GBK编码: %w", err)
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	// 前几行（可能有标题、账户信息行）中制表符多于逗号时按制表符分隔
+	lines := strings.SplitN(string(data), "\n", 11)
+	if len(lines) > 10 {
+		lines = lines[:10]
+	}
+	head := strings.Join(lines, "\n")
+	if strings.Count(head, "\t") > strings.Count(head, ",") {
+		reader.Comma = '\t'
+	}
+
+	var index map[string]int
+	var positions []ImportedPosition
+	line := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("第%d行解析失败: %w", line, err)
+		}
+
+		// 定位表头行
+		if index == nil {
+			columns := positionColumns(row)
+			_, hasCode := columns["code"]
+			_, hasQuantity := columns["quantity"]
+			_, hasCost := columns["cost"]
+			if hasCode && hasQuantity && hasCost {
+				index = columns
+			}
+			continue
+		}
+
+		field := func(key string) string {
+			i, ok := index[key]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		code := cleanPositionCode(field("code"))
+		if code == "" || !strings.ContainsAny(code, "0123456789") {
+			continue
+		}
+		quantity, err := parsePositionNumber(field("quantity"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: 持仓数量格式错误: %w", line, err)
+		}
+		cost, err := parsePositionNumber(field("cost"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: 成本价格式错误: %w", line, err)
+		}
+		price, err := parsePositionNumber(field("price"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: 现价格式错误: %w", line, err)
+		}
+		if quantity < 0 {
+			return nil, fmt.Errorf("第%d行: 持仓数量不能为负数", line)
+		}
+		if quantity != math.Trunc(quantity) {
+			return nil, fmt.Errorf("第%d行: 持仓数量必须为整数股（实际为 %s）", line, field("quantity"))
+		}
+
+		positions = append(positions, ImportedPosition{
+			Code:         code,
+			Name:         field("name"),
+			Quantity:     int(quantity),
+			CostPrice:    cost,
+			CurrentPrice: price,
+		})
+	}
+
+	if index == nil {
+		return nil, fmt.Errorf("未找到表头（需包含代码、持仓数量、成本价列，如 证券代码/股票余额/成本价）")
+	}
+	return positions, nil
+}
@@ -0,0 +1,70 @@
+package stock
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestParsePositionCSVHeaderAliases(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"东方财富", "资金账号: 12345\n证券代码,证券名称,股票余额,成本价(元),最新价\n=\"000001\",平安银行,\"1,000\",10.50,11.00\n合计,,1000,,\n"},
+		{"同花顺制表符", "股票代码\t股票名称\t实际数量\t摊薄成本价\t市价\n000001\t平安银行\t1000\t10.50\t11.00\n"},
+		{"英文表头", "code,name,quantity,cost_price,current_price\n1,平安银行,1000,10.5,11\n"},
+	}
+	for _, tc := range cases {
+		positions, err := ParsePositionCSV([]byte(tc.data))
+		if err != nil {
+			t.Errorf("%s: 解析失败: %v", tc.name, err)
+			continue
+		}
+		if len(positions) != 1 {
+			t.Errorf("%s: 期望1条持仓，实际 %d 条: %+v", tc.name, len(positions), positions)
+			continue
+		}
+		p := positions[0]
+		if p.Code != "000001" || p.Name != "平安银行" || p.Quantity != 1000 || p.CostPrice != 10.5 || p.CurrentPrice != 11 {
+			t.Errorf("%s: 解析结果不符: %+v", tc.name, p)
+		}
+	}
+}
+
+func TestCleanPositionCode(t *testing.T) {
+	cases := map[string]string{
+		`="000001"`: "000001",
+		`"600519"`:  "600519",
+		" 1 ":       "000001",
+		"sh600519":  "sh600519",
+		"":          "",
+	}
+	for input, want := range cases {
+		if got := cleanPositionCode(input); got != want {
+			t.Errorf("cleanPositionCode(%q) = %q，期望 %q", input, got, want)
+		}
+	}
+}
+
+func TestParsePositionCSVGBK(t *testing.T) {
+	data, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("证券代码,证券名称,股票余额,成本价\n600519,贵州茅台,100,1500.00\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, err := ParsePositionCSV(data)
+	if err != nil {
+		t.Fatalf("GBK编码文件解析失败: %v", err)
+	}
+	if len(positions) != 1 || positions[0].Name != "贵州茅台" || positions[0].Quantity != 100 {
+		t.Fatalf("GBK编码文件解析结果不符: %+v", positions)
+	}
+}
+
+func TestParsePositionCSVRejectsFractionalQuantity(t *testing.T) {
+	_, err := ParsePositionCSV([]byte("证券代码,股票余额,成本价\n000001,100.5,10\n"))
+	if err == nil || !strings.Contains(err.Error(), "整数股") {
+		t.Fatalf("带小数的持仓数量应报错，实际: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package stock
+
+import "nofx/notifier"
+
+// SELL信号与持仓联动：只在按持仓跟踪的组合中生效（AnalysisConfig.TrackPositions），纯监控的组合不变
+// 无持仓时SELL对用户没有操作意义，标注"仅供参考"并降为低优先级；有持仓时SELL意味着需要卖出，优先级提升一级
+
+// heldPosition 本轮分析时是否持有该股票（按真实持仓判断，模拟盘的虚拟持仓不算）
+func heldPosition(result *AnalysisResult) bool {
+	return result.PositionInfo != nil
+}
+
+// applyPositionLink 标注无持仓的SELL信号（在自动交易之后、通知之前调用），track为本轮持仓快照中的按持仓跟踪开关
+func (a *StockAnalyzer) applyPositionLink(result *AnalysisResult, track bool) {
+	if !track || result.Signal != "SELL" || heldPosition(result) {
+		return
+	}
+	result.NoPositionSell = true
+	tracef(result.TraceID, "📭 [%s] 当前无持仓，SELL信号仅供参考", a.AnalysisConfig.StockName)
+}
+
+// positionLinkPriority 按持仓状态调整SELL信号的通知优先级，并在无持仓时于推理原因前标注
+func (a *StockAnalyzer) positionLinkPriority(result *AnalysisResult, signal *notifier.TradingSignal) {
+	if !a.position().track || result.Signal != "SELL" {
+		return
+	}
+	if result.NoPositionSell {
+		signal.Reasoning = "【当前无持仓，SELL仅供参考】\n" + signal.Reasoning
+		signal.Priority = notifier.PriorityLow
+		return
+	}
+	signal.Priority = notifier.RaisePriority(signal.Priority)
+}
@@ -0,0 +1,63 @@
+package stock
+
+import (
+	"testing"
+	"time"
+)
+
+// seedAnalysisKlines 写入分析所需的日K线和30分钟K线缓存（价格在10元附近小幅波动）
+func seedAnalysisKlines(client *TDXClient, code string) {
+	for _, seed := range []struct {
+		klineType string
+		limit     int
+		step      time.Duration
+	}{
+		{"day", 60, 24 * time.Hour},
+		{"minute30", 100, 30 * time.Minute},
+	} {
+		data := &KlineData{}
+		start := time.Date(2025, 3, 1, 15, 0, 0, 0, chinaTZ)
+		for i := 0; i < seed.limit; i++ {
+			price := 10000 + (i%7)*50
+			data.List = append(data.List, KlineItem{Open: price, Close: price + 20, High: price + 80, Low: price - 60, Volume: 10000, Time: start.Add(time.Duration(i) * seed.step)})
+		}
+		data.Count = len(data.List)
+		client.klineCache[klineCacheKey(code, seed.klineType, seed.limit)] = klineCacheEntry{data: data, expiresAt: time.Now().Add(time.Hour)}
+	}
+}
+
+// 分析进行中导入持仓（go test -race 检查数据竞争），本轮使用开始时的持仓快照，下一轮使用导入后的持仓
+func TestSetPositionDuringAnalysis(t *testing.T) {
+	client := NewTDXClient(quoteServer(t, 10500))
+	seedAnalysisKlines(client, "000001")
+	analyzer := NewStockAnalyzer(client, nil, nil, &AnalysisConfig{StockCode: "000001", StockName: "平安银行", RuleBasedAI: true}, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			analyzer.SetPosition(100*i, 10)
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		result, err := analyzer.Analyze()
+		if err != nil {
+			t.Fatalf("分析失败: %v", err)
+		}
+		if info := result.PositionInfo; info != nil && (info.Quantity%100 != 0 || info.BuyPrice != 10) {
+			t.Fatalf("持仓信息应来自同一份快照，实际 %+v", info)
+		}
+	}
+	<-done
+
+	result, err := analyzer.Analyze()
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if result.PositionInfo == nil || result.PositionInfo.Quantity != 5000 {
+		t.Fatalf("导入后的下一轮应按新持仓分析，实际 %+v", result.PositionInfo)
+	}
+	if !analyzer.position().track {
+		t.Fatal("导入持仓的股票应按持仓跟踪")
+	}
+}
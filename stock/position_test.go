@@ -0,0 +1,50 @@
+package stock
+
+import "testing"
+
+func TestCalculateFee(t *testing.T) {
+	rates := DefaultFeeRates()
+	cases := []struct {
+		name   string
+		amount float64
+		isSell bool
+		want   float64
+	}{
+		{"买入不足最低佣金", 10000, false, 5.10},    // 佣金2.50按5元 + 过户费0.10
+		{"卖出不足最低佣金", 10000, true, 10.10},    // 再加印花税5.00
+		{"买入按比例佣金", 100000, false, 26.00},   // 佣金25.00 + 过户费1.00
+		{"卖出按比例佣金", 100000, true, 76.00},    // 再加印花税50.00
+		{"佣金.005进位", 34020, false, 8.85},    // 佣金8.505→8.51 + 过户费0.3402→0.34
+		{"卖出佣金.005进位", 34020, true, 25.86},  // 再加印花税17.01
+		{"过户费.005进位", 50500, false, 13.14},  // 佣金12.625→12.63 + 过户费0.505→0.51
+		{"卖出印花税.005进位", 50500, true, 38.39}, // 再加印花税25.25
+		{"零金额", 0, true, 0},
+	}
+	for _, tc := range cases {
+		if got := rates.CalculateFee(tc.amount, tc.isSell); got != tc.want {
+			t.Errorf("%s: 金额%.2f 费用应为%.2f，实际%.2f", tc.name, tc.amount, tc.want, got)
+		}
+	}
+}
+
+func TestBreakEvenPrice(t *testing.T) {
+	rates := DefaultFeeRates()
+	cases := []struct {
+		name     string
+		quantity int
+		invested float64
+		want     float64
+	}{
+		// 1000股×10元+买入费用5.10：卖出佣金不足最低佣金，(10005.10+5)/(1-0.00051)=10015.21，每股向上取整到厘
+		{"最低佣金", 1000, 10005.10, 10.016},
+		// 10000股×10元+买入费用26：100026/(1-0.00076)=100102.08；按10.010卖出到手100023.92不够，按10.011卖出到手100033.91
+		{"按比例佣金", 10000, 100026, 10.011},
+		{"无持仓", 0, 10000, 0},
+		{"无投入", 1000, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := rates.BreakEvenPrice(tc.quantity, tc.invested); got != tc.want {
+			t.Errorf("%s: 回本价应为%.3f，实际%.3f", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package stock
+
+import "fmt"
+
+// 跳空缺口方向
+const (
+	GapUp   = "up"   // 向上跳空：今日最低价高于昨日最高价
+	GapDown = "down" // 向下跳空：今日最高价低于昨日最低价
+)
+
+// maxPromptGaps 提示词中展示的未回补缺口数（取最近形成的）
+const maxPromptGaps = 3
+
+// PriceGap 未回补的跳空缺口（部分回补时Low/High为剩余未回补的区间）
+type PriceGap struct {
+	Direction string  `json:"direction"` // up/down
+	Date      string  `json:"date"`      // 形成缺口的交易日（YYYY-MM-DD）
+	Low       float64 `json:"low"`       // 缺口下沿（元）
+	High      float64 `json:"high"`      // 缺口上沿（元）
+}
+
+// DetectPriceGaps 检测日K线中相邻两日之间的跳空缺口，返回至今未完全回补的缺口（按形成时间升序）
+// 向上跳空缺口为 [昨日最高, 今日最低]，之后最低价跌到缺口内时下沿以上的部分视为已回补，跌破昨日最高即完全回补；向下跳空同理
+func DetectPriceGaps(klines []KlineItem) []PriceGap {
+	var gaps []PriceGap
+	for i := 1; i < len(klines); i++ {
+		prev, cur := klines[i-1], klines[i]
+		if prev.High <= 0 || cur.Low <= 0 {
+			continue
+		}
+
+		switch {
+		case cur.Low > prev.High:
+			// 之后的最低价决定回补程度
+			top := cur.Low
+			for _, later := range klines[i+1:] {
+				if later.Low > 0 && later.Low < top {
+					top = later.Low
+				}
+			}
+			if top > prev.High {
+				gaps = append(gaps, PriceGap{Direction: GapUp, Date: cur.Time.Format("2006-01-02"), Low: PriceToYuan(prev.High), High: PriceToYuan(top)})
+			}
+		case cur.High < prev.Low:
+			// 之后的最高价决定回补程度
+			bottom := cur.High
+			for _, later := range klines[i+1:] {
+				if later.High > bottom {
+					bottom = later.High
+				}
+			}
+			if bottom < prev.Low {
+				gaps = append(gaps, PriceGap{Direction: GapDown, Date: cur.Time.Format("2006-01-02"), Low: PriceToYuan(bottom), High: PriceToYuan(prev.Low)})
+			}
+		}
+	}
+	return gaps
+}
+
+// priceGapPromptSection 提示词中的跳空缺口小节（没有未回补缺口时为空）
+func priceGapPromptSection(technical map[string]interface{}) string {
+	gaps, ok := technical["price_gaps"].([]PriceGap)
+	if !ok || len(gaps) == 0 {
+		return ""
+	}
+	if len(gaps) > maxPromptGaps {
+		gaps = gaps[len(gaps)-maxPromptGaps:]
+	}
+
+	section := "## 跳空缺口\n"
+	for i := len(gaps) - 1; i >= 0; i-- {
+		gap := gaps[i]
+		direction := "向上"
+		if gap.Direction == GapDown {
+			direction = "向下"
+		}
+		section += fmt.Sprintf("- 存在未回补的%s跳空缺口在 %.2f-%.2f 元（%s形成）\n", direction, gap.Low, gap.High, gap.Date)
+	}
+	section += "（未回补的向上缺口通常构成下方支撑，向下缺口构成上方压力；价格回到缺口区间时留意回补后的支撑/压力转换，除权除息造成的缺口不具备技术意义）\n\n"
+	return section
+}
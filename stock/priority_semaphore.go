@@ -0,0 +1,115 @@
+package stock
+
+import (
+	"context"
+	"sync"
+)
+
+// 分析排队优先级（数值越小越优先）
+const (
+	AnalysisPriorityManual    = iota // 手动触发的单只股票分析
+	AnalysisPriorityRecheck          // 信号翻转复查（上一轮信号翻转待确认，本轮决定是否推送）
+	AnalysisPriorityScheduled        // 常规定时分析（含批量分析）
+	analysisPriorityCount
+)
+
+// AnalysisPriorityName 优先级名称（用于运行时状态展示）
+var AnalysisPriorityName = [analysisPriorityCount]string{"manual", "recheck", "scheduled"}
+
+// PrioritySemaphore 带优先级的并发信号量：名额释放时优先唤醒高优先级的等待者，同优先级先到先得
+// 用于限制同时进行的分析（AI调用）数量，避免手动触发被大量定时任务堵在后面
+type PrioritySemaphore struct {
+	mutex    sync.Mutex
+	capacity int
+	inUse    int
+	waiters  [analysisPriorityCount][]chan struct{}
+}
+
+// NewPrioritySemaphore 创建优先级信号量
+func NewPrioritySemaphore(capacity int) *PrioritySemaphore {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &PrioritySemaphore{capacity: capacity}
+}
+
+// Acquire 按优先级获取一个名额（无空闲名额时排队等待），未知优先级按常规定时处理
+func (s *PrioritySemaphore) Acquire(priority int) {
+	s.AcquireContext(context.Background(), priority)
+}
+
+// AcquireContext 同Acquire，但ctx取消时放弃排队并返回ctx.Err()（此时未占用名额，不需要Release）
+func (s *PrioritySemaphore) AcquireContext(ctx context.Context, priority int) error {
+	if priority < 0 || priority >= analysisPriorityCount {
+		priority = AnalysisPriorityScheduled
+	}
+
+	s.mutex.Lock()
+	if s.inUse < s.capacity && s.waitingLocked() == 0 {
+		s.inUse++
+		s.mutex.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	s.waiters[priority] = append(s.waiters[priority], ready)
+	s.mutex.Unlock()
+
+	select {
+	case <-ready: // 名额由Release直接转交，inUse不变
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	queue := s.waiters[priority]
+	for i, waiter := range queue {
+		if waiter == ready {
+			s.waiters[priority] = append(queue[:i:i], queue[i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// 取消的同时名额已转交过来，转交给下一个等待者，避免名额泄漏
+	s.releaseLocked()
+	return ctx.Err()
+}
+
+// Release 释放名额：有等待者时直接转交给优先级最高的等待者
+func (s *PrioritySemaphore) Release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked 释放名额（调用方需持有锁）
+func (s *PrioritySemaphore) releaseLocked() {
+	for priority := range s.waiters {
+		if len(s.waiters[priority]) > 0 {
+			ready := s.waiters[priority][0]
+			s.waiters[priority] = s.waiters[priority][1:]
+			close(ready)
+			return
+		}
+	}
+	s.inUse--
+}
+
+// Status 当前占用数、容量和各优先级的排队数
+func (s *PrioritySemaphore) Status() (inUse, capacity int, waiting map[string]int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	waiting = make(map[string]int, analysisPriorityCount)
+	for priority, queue := range s.waiters {
+		waiting[AnalysisPriorityName[priority]] = len(queue)
+	}
+	return s.inUse, s.capacity, waiting
+}
+
+// waitingLocked 排队总数（调用方需持有锁）
+func (s *PrioritySemaphore) waitingLocked() int {
+	total := 0
+	for _, queue := range s.waiters {
+		total += len(queue)
+	}
+	return total
+}
@@ -0,0 +1,106 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitQueued 等待指定优先级的排队数达到n
+func waitQueued(t *testing.T, s *PrioritySemaphore, priority, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, _, waiting := s.Status()
+		if waiting[AnalysisPriorityName[priority]] == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s排队数达到%d超时，实际 %v", AnalysisPriorityName[priority], n, waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrioritySemaphoreManualJumpsQueue(t *testing.T) {
+	s := NewPrioritySemaphore(1)
+	s.Acquire(AnalysisPriorityScheduled)
+
+	acquired := make(chan string, 3)
+	enqueue := func(name string, priority, queued int) {
+		go func() {
+			s.Acquire(priority)
+			acquired <- name
+		}()
+		waitQueued(t, s, priority, queued)
+	}
+	enqueue("scheduled-1", AnalysisPriorityScheduled, 1)
+	enqueue("scheduled-2", AnalysisPriorityScheduled, 2)
+	enqueue("manual", AnalysisPriorityManual, 1)
+
+	// 每次释放只转交一个名额：手动触发先于先到的定时任务，定时任务之间先到先得
+	for _, want := range []string{"manual", "scheduled-1", "scheduled-2"} {
+		s.Release()
+		select {
+		case got := <-acquired:
+			if got != want {
+				t.Fatalf("应由%s获得名额，实际%s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待%s获得名额超时", want)
+		}
+	}
+	s.Release()
+	if inUse, _, _ := s.Status(); inUse != 0 {
+		t.Fatalf("全部释放后占用数应为0，实际%d", inUse)
+	}
+}
+
+func TestPrioritySemaphoreCancelRemovesWaiter(t *testing.T) {
+	s := NewPrioritySemaphore(1)
+	s.Acquire(AnalysisPriorityScheduled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- s.AcquireContext(ctx, AnalysisPriorityManual) }()
+	waitQueued(t, s, AnalysisPriorityManual, 1)
+
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应返回context.Canceled，实际 %v", err)
+	}
+	if inUse, _, waiting := s.Status(); inUse != 1 || waiting["manual"] != 0 {
+		t.Fatalf("取消后应移出队列且不占用名额，实际占用%d、排队 %v", inUse, waiting)
+	}
+
+	// 释放后名额不会转交给已取消的等待者
+	s.Release()
+	if inUse, _, _ := s.Status(); inUse != 0 {
+		t.Fatalf("释放后占用数应为0，实际%d", inUse)
+	}
+}
+
+func TestPrioritySemaphoreCancelDuringHandOffDoesNotLeak(t *testing.T) {
+	s := NewPrioritySemaphore(1)
+	for i := 0; i < 20; i++ {
+		s.Acquire(AnalysisPriorityScheduled)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error, 1)
+		go func() { result <- s.AcquireContext(ctx, AnalysisPriorityScheduled) }()
+		waitQueued(t, s, AnalysisPriorityScheduled, 1)
+
+		// 等待者因取消被唤醒、还没拿到锁时名额转交给了它
+		s.mutex.Lock()
+		cancel()
+		s.releaseLocked()
+		s.mutex.Unlock()
+		if err := <-result; err == nil {
+			s.Release() // 成功获取的名额由调用方释放
+		}
+		if inUse, _, waiting := s.Status(); inUse != 0 || waiting["scheduled"] != 0 {
+			t.Fatalf("第%d轮: 名额泄漏，占用%d、排队 %v", i, inUse, waiting)
+		}
+	}
+}
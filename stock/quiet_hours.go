@@ -0,0 +1,46 @@
+package stock
+
+import (
+	"strings"
+	"time"
+)
+
+// quietWeekdays 静默日中可用的星期写法
+var quietWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// inQuietPeriod 是否处于通知静默期（静默时段或静默日，与交易时段独立配置）
+// 静默时段格式同交易时段（HH:MM-HH:MM），结束早于开始表示跨午夜（如 "22:00-08:00"），结束时刻不在静默期内
+func (a *StockAnalyzer) inQuietPeriod(t time.Time) bool {
+	location := chinaTZ
+	if a.TradingTimeChecker != nil && a.TradingTimeChecker.Location != nil {
+		location = a.TradingTimeChecker.Location
+	}
+	t = t.In(location)
+
+	for _, day := range a.AnalysisConfig.QuietDays {
+		if weekday, ok := quietWeekdays[strings.ToLower(day)]; ok && t.Weekday() == weekday {
+			return true
+		}
+		if day == t.Format("2006-01-02") {
+			return true
+		}
+	}
+
+	current := minuteOfDay(t)
+	for _, period := range a.AnalysisConfig.QuietHours {
+		start, end, ok := ParseTradingPeriod(period)
+		if !ok {
+			continue
+		}
+		if start <= end && current >= start && current < end {
+			return true
+		}
+		if start > end && (current >= start || current < end) {
+			return true
+		}
+	}
+	return false
+}
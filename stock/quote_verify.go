@@ -0,0 +1,122 @@
+package stock
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuoteVerifier 多数据源行情校验：从多个TDX地址获取同一股票的现价，差异过大时告警
+// 有3个及以上数据源的现价时改用中位数；只有2个时无法判断哪个出错，只告警、仍使用主数据源
+// 用于降低单一数据源偶发错价的风险；每次校验都会请求所有数据源，默认只在手动触发分析时启用
+type QuoteVerifier struct {
+	Clients        []*TDXClient // 其他TDX数据源（不含主数据源）
+	MaxDiffPercent float64      // 各数据源现价与中位数的最大允许偏差（%）
+	Always         bool         // 是否在每次定时分析时都校验（默认只在手动触发时校验）
+}
+
+// quoteWarningInterval 同一股票多数据源现价差异告警的最短推送间隔
+const quoteWarningInterval = 30 * time.Minute
+
+// NewQuoteVerifier 创建多数据源行情校验器
+func NewQuoteVerifier(urls []string, maxDiffPercent float64, always bool) *QuoteVerifier {
+	verifier := &QuoteVerifier{MaxDiffPercent: maxDiffPercent, Always: always}
+	for _, url := range urls {
+		verifier.Clients = append(verifier.Clients, NewTDXClient(url))
+	}
+	return verifier
+}
+
+// Verify 用其他数据源校验主数据源的行情，返回校验后的行情和告警描述（无异常时为空）
+// 3个及以上数据源且现价偏离中位数超过阈值时才替换（返回副本，其余字段仍使用主数据源）；其他数据源全部失败时原样返回
+func (v *QuoteVerifier) Verify(code string, quote *QuoteData) (*QuoteData, string) {
+	prices := make([]int, len(v.Clients))
+	var wg sync.WaitGroup
+	for i, client := range v.Clients {
+		wg.Add(1)
+		go func(i int, client *TDXClient) {
+			defer wg.Done()
+			other, err := client.GetQuote(code)
+			if err != nil {
+				log.Printf("⚠️  多源校验：数据源 %s 获取 %s 行情失败: %v", client.BaseURL, code, err)
+				return
+			}
+			prices[i] = other.K.Close
+		}(i, client)
+	}
+	wg.Wait()
+
+	sources := []string{fmt.Sprintf("主数据源%.2f", PriceToYuan(quote.K.Close))}
+	valid := []int{quote.K.Close}
+	for i, price := range prices {
+		if price > 0 {
+			sources = append(sources, fmt.Sprintf("%s %.2f", v.Clients[i].BaseURL, PriceToYuan(price)))
+			valid = append(valid, price)
+		}
+	}
+	if len(valid) < 2 {
+		return quote, ""
+	}
+	if len(valid) == 2 {
+		diff := diffPercent(valid[1], valid[0])
+		if diff <= v.MaxDiffPercent {
+			return quote, ""
+		}
+		warning := fmt.Sprintf("多数据源现价差异%.2f%%（%s），只有两个数据源无法判断哪个有误，仍采用主数据源", diff, strings.Join(sources, "，"))
+		log.Printf("🚨 [%s] %s", code, warning)
+		return quote, warning
+	}
+
+	median := medianPrice(valid)
+	maxDiff := 0.0
+	for _, price := range valid {
+		if diff := diffPercent(price, median); diff > maxDiff {
+			maxDiff = diff
+		}
+	}
+	if maxDiff <= v.MaxDiffPercent {
+		return quote, ""
+	}
+
+	warning := fmt.Sprintf("多数据源现价差异%.2f%%（%s），已采用中位数%.2f元", maxDiff, strings.Join(sources, "，"), PriceToYuan(median))
+	log.Printf("🚨 [%s] %s", code, warning)
+	if quote.K.Close == median {
+		return quote, warning
+	}
+
+	verified := *quote
+	verified.K.Close = median
+	if verified.K.High < median {
+		verified.K.High = median
+	}
+	if verified.K.Low > median || verified.K.Low == 0 {
+		verified.K.Low = median
+	}
+	return &verified, warning
+}
+
+// medianPrice 价格中位数（偶数个时取中间两个的平均值）
+func medianPrice(prices []int) int {
+	sorted := append([]int(nil), prices...)
+	sort.Ints(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// diffPercent price相对base的偏差（%，绝对值）
+func diffPercent(price, base int) float64 {
+	if base == 0 {
+		return 0
+	}
+	diff := float64(price-base) / float64(base) * 100
+	if diff < 0 {
+		return -diff
+	}
+	return diff
+}
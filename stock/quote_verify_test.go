@@ -0,0 +1,43 @@
+package stock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// quoteServer 返回固定现价（厘）的TDX行情接口
+func quoteServer(t *testing.T, price int) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal([]QuoteData{{Code: "000001", K: KData{Close: price, Last: price, Open: price, High: price, Low: price}}})
+		json.NewEncoder(w).Encode(APIResponse{Data: data})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestQuoteVerifierTwoSourcesKeepsPrimary(t *testing.T) {
+	verifier := NewQuoteVerifier([]string{quoteServer(t, 11000)}, 1, false)
+	quote := &QuoteData{Code: "000001", K: KData{Close: 10000}}
+	verified, warning := verifier.Verify("000001", quote)
+	if verified.K.Close != 10000 {
+		t.Fatalf("只有两个数据源时应保留主数据源现价，实际 %d", verified.K.Close)
+	}
+	if !strings.Contains(warning, "仍采用主数据源") {
+		t.Fatalf("差异超限应告警，实际: %q", warning)
+	}
+}
+
+func TestQuoteVerifierThreeSourcesUsesMedian(t *testing.T) {
+	verifier := NewQuoteVerifier([]string{quoteServer(t, 11000), quoteServer(t, 11010)}, 1, false)
+	quote := &QuoteData{Code: "000001", K: KData{Close: 10000, High: 10000, Low: 10000}}
+	verified, warning := verifier.Verify("000001", quote)
+	if verified.K.Close != 11000 || warning == "" {
+		t.Fatalf("三个数据源时应采用中位数，实际 %d（%q）", verified.K.Close, warning)
+	}
+	if quote.K.Close != 10000 {
+		t.Fatal("不应修改原行情")
+	}
+}
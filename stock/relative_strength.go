@@ -0,0 +1,67 @@
+package stock
+
+import "fmt"
+
+// alphaWindow 相对大盘强弱的计算窗口（交易日）
+const alphaWindow = 20
+
+// RelativeStrength 个股近n个交易日相对大盘指数的超额收益（百分点）：个股涨幅 - 指数同期涨幅
+// 按日期对齐：以两者最近的共同交易日为终点、个股往前第n根日K线为起点，指数在起止日期都有K线时才计算
+// （指数K线缓存比个股晚一天时终点相应前移；个股停牌期间指数照常计算涨跌）
+func RelativeStrength(stockKlines, indexKlines []KlineItem, n int) (alpha, stockChange, indexChange float64, ok bool) {
+	indexClose := make(map[string]int, len(indexKlines))
+	for _, item := range indexKlines {
+		if item.Close > 0 {
+			indexClose[item.Time.Format("2006-01-02")] = item.Close
+		}
+	}
+
+	for end := len(stockKlines) - 1; end >= n; end-- {
+		endIndex, found := indexClose[stockKlines[end].Time.Format("2006-01-02")]
+		if !found {
+			continue
+		}
+		start := stockKlines[end-n]
+		startIndex, found := indexClose[start.Time.Format("2006-01-02")]
+		if !found || start.Close <= 0 || stockKlines[end].Close <= 0 {
+			return 0, 0, 0, false
+		}
+		stockChange = (float64(stockKlines[end].Close)/float64(start.Close) - 1) * 100
+		indexChange = (float64(endIndex)/float64(startIndex) - 1) * 100
+		return stockChange - indexChange, stockChange, indexChange, true
+	}
+	return 0, 0, 0, false
+}
+
+// applyRelativeStrength 配置了大盘指数时计算近20日相对强弱写入技术数据（alpha_20d），指数数据获取失败或缺失时跳过
+func (a *StockAnalyzer) applyRelativeStrength(dayKline *KlineData, technical map[string]interface{}, traceID string) {
+	index := a.AnalysisConfig.BenchmarkIndex
+	if index == "" {
+		return
+	}
+	indexKline, err := a.TDXClient.GetIndexKline(index, "day", 60)
+	if err != nil {
+		tracef(traceID, "⚠️  [%s] 获取大盘指数%s日K线失败，跳过相对强弱: %v", a.AnalysisConfig.StockName, index, err)
+		return
+	}
+	alpha, stockChange, indexChange, ok := RelativeStrength(dayKline.List, indexKline.List, alphaWindow)
+	if !ok {
+		return
+	}
+	technical["alpha_20d"] = fmt.Sprintf("%.2f%%", alpha)
+	technical["benchmark_change_20d"] = fmt.Sprintf("%.2f%%", indexChange)
+	technical["stock_change_20d"] = fmt.Sprintf("%.2f%%", stockChange)
+}
+
+// relativeStrengthPromptLine 提示词中的相对大盘强弱（未计算时为空）
+func relativeStrengthPromptLine(technical map[string]interface{}) string {
+	alpha, ok := IndicatorValue(technical, "alpha_20d")
+	if !ok {
+		return ""
+	}
+	verdict := fmt.Sprintf("跑赢大盘%.2f%%", alpha)
+	if alpha < 0 {
+		verdict = fmt.Sprintf("跑输大盘%.2f%%", -alpha)
+	}
+	return fmt.Sprintf("- **相对大盘**: 近20日%s（个股%s，指数%s）\n", verdict, formatIndicator(technical, "stock_change_20d"), formatIndicator(technical, "benchmark_change_20d"))
+}
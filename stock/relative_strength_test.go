@@ -0,0 +1,101 @@
+package stock
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// dayKlines 按日期和收盘价（元）构造日K线
+func dayKlines(t *testing.T, bars map[string]float64, dates ...string) []KlineItem {
+	t.Helper()
+	list := make([]KlineItem, 0, len(dates))
+	for _, date := range dates {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			t.Fatalf("日期格式错误: %s", date)
+		}
+		list = append(list, KlineItem{Close: int(math.Round(bars[date] * 1000)), Time: day})
+	}
+	return list
+}
+
+func TestRelativeStrength(t *testing.T) {
+	stockCloses := map[string]float64{
+		"2026-03-02": 10, "2026-03-03": 10.5, "2026-03-04": 11, "2026-03-05": 11.5, "2026-03-06": 12,
+	}
+	indexCloses := map[string]float64{
+		"2026-03-02": 3000, "2026-03-03": 3030, "2026-03-04": 3060, "2026-03-05": 3090, "2026-03-06": 3150,
+	}
+	allDates := []string{"2026-03-02", "2026-03-03", "2026-03-04", "2026-03-05", "2026-03-06"}
+
+	tests := []struct {
+		name       string
+		stockDates []string
+		indexDates []string
+		wantOK     bool
+		wantStock  float64
+		wantIndex  float64
+	}{
+		{
+			name:       "日期完全对齐",
+			stockDates: allDates[1:],
+			indexDates: allDates[1:],
+			wantOK:     true,
+			wantStock:  (12/10.5 - 1) * 100,
+			wantIndex:  (3150.0/3030 - 1) * 100,
+		},
+		{
+			name:       "指数K线缓存晚一天，终点前移到共同的最近交易日",
+			stockDates: allDates,
+			indexDates: allDates[:4],
+			wantOK:     true,
+			wantStock:  (11.5/10 - 1) * 100,
+			wantIndex:  (3090.0/3000 - 1) * 100,
+		},
+		{
+			name:       "个股停牌一天，按个股K线往前数n根，指数取相同日期",
+			stockDates: []string{"2026-03-02", "2026-03-03", "2026-03-05", "2026-03-06"},
+			indexDates: allDates,
+			wantOK:     true,
+			wantStock:  (12.0/10 - 1) * 100,
+			wantIndex:  (3150.0/3000 - 1) * 100,
+		},
+		{
+			name:       "指数缺少起点日期时跳过",
+			stockDates: allDates[1:],
+			indexDates: []string{"2026-03-04", "2026-03-05", "2026-03-06"},
+			wantOK:     false,
+		},
+		{
+			name:       "没有共同日期时跳过",
+			stockDates: allDates[:2],
+			indexDates: allDates[3:],
+			wantOK:     false,
+		},
+		{
+			name:       "没有指数数据时跳过",
+			stockDates: allDates,
+			wantOK:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alpha, stockChange, indexChange, ok := RelativeStrength(
+				dayKlines(t, stockCloses, tt.stockDates...), dayKlines(t, indexCloses, tt.indexDates...), 3)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(stockChange-tt.wantStock) > 1e-9 || math.Abs(indexChange-tt.wantIndex) > 1e-9 {
+				t.Errorf("个股涨幅 %.4f%%、指数涨幅 %.4f%%，期望 %.4f%%、%.4f%%", stockChange, indexChange, tt.wantStock, tt.wantIndex)
+			}
+			if math.Abs(alpha-(tt.wantStock-tt.wantIndex)) > 1e-9 {
+				t.Errorf("alpha = %.4f，期望 %.4f", alpha, tt.wantStock-tt.wantIndex)
+			}
+		})
+	}
+}
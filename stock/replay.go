@@ -0,0 +1,164 @@
+package stock
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// klineBarsPerDay 各K线周期每个交易日的K线数量（用于估算历史查询的日期范围）
+var klineBarsPerDay = map[string]float64{
+	"minute1":  240,
+	"minute5":  48,
+	"minute15": 16,
+	"minute30": 8,
+	"hour":     4,
+	"day":      1,
+	"week":     0.2,
+	"month":    0.05,
+}
+
+// GetKlineRange 从历史K线接口获取 [start, end] 日期范围内的K线（需TDX代理提供 /api/kline-history）
+func (c *TDXClient) GetKlineRange(code string, klineType string, start, end time.Time, limit int) (*KlineData, error) {
+	url := fmt.Sprintf("%s/api/kline-history?code=%s&type=%s&start_date=%s&end_date=%s&limit=%d",
+		c.BaseURL, code, klineType, start.Format("20060102"), end.Format("20060102"), limit)
+	data, err := c.requestKline(url)
+	if err == errKlineNotFound {
+		return nil, fmt.Errorf("TDX代理不提供历史K线接口，无法获取历史数据")
+	}
+	return data, err
+}
+
+// AnalyzeReplay 历史回放：只用回放时刻at及之前可得的K线重新分析，查看当时AI会如何判断
+// 回放当日的日K线由截至at的30分钟K线重建；不使用分时、盘口和筹码数据，不发送通知、不影响信号确认状态
+func (a *StockAnalyzer) AnalyzeReplay(at time.Time) (*AnalysisResult, error) {
+	if a.IsBasket() {
+		return nil, fmt.Errorf("虚拟组合暂不支持历史回放")
+	}
+	if !at.Before(a.now()) {
+		return nil, fmt.Errorf("回放时刻必须早于当前时间")
+	}
+	traceID := newTraceID()
+	tracef(traceID, "⏪ 历史回放 %s(%s)，回放时刻 %s...", a.AnalysisConfig.StockName, a.AnalysisConfig.StockCode, at.Format("2006-01-02 15:04"))
+
+	min30Kline, err := a.replayKline("minute30", 100, at)
+	if err != nil {
+		return nil, withTrace(traceID, fmt.Errorf("获取30分钟K线失败: %w", err))
+	}
+	dayKline, err := a.replayKline("day", 60, at)
+	if err != nil {
+		return nil, withTrace(traceID, fmt.Errorf("获取日K线失败: %w", err))
+	}
+	if err := rebuildReplayDay(dayKline, min30Kline, at); err != nil {
+		return nil, withTrace(traceID, err)
+	}
+	if len(dayKline.List) == 0 {
+		return nil, fmt.Errorf("回放时刻 %s 之前没有日K线数据", at.Format("2006-01-02 15:04"))
+	}
+
+	result, err := a.analyzeQuote(replayQuote(a.AnalysisConfig.StockCode, dayKline), analyzeOptions{
+		replayAt: at,
+		klines:   map[string]*KlineData{"day": dayKline, "minute30": min30Kline},
+		position: a.position(),
+		traceID:  traceID,
+	})
+	if err != nil {
+		return nil, withTrace(traceID, err)
+	}
+	result.ReplayAt = &at
+	return result, nil
+}
+
+// replayKline 获取回放时刻at及之前的最近limit根K线
+func (a *StockAnalyzer) replayKline(klineType string, limit int, at time.Time) (*KlineData, error) {
+	barsPerDay, ok := klineBarsPerDay[klineType]
+	if !ok {
+		return nil, fmt.Errorf("不支持的K线周期: %s", klineType)
+	}
+
+	// 按每日K线数量估算所需的自然日范围（交易日约占自然日的5/7），另留出节假日余量
+	days := int(math.Ceil(float64(limit)/barsPerDay*7/5)) + 4
+	if barsPerDay <= 1 {
+		days += 15
+	}
+	start := at.AddDate(0, 0, -days)
+
+	data, err := a.TDXClient.GetKlineRange(a.AnalysisConfig.StockCode, klineType, start, at, 800)
+	if err != nil {
+		return nil, err
+	}
+
+	// 去掉回放时刻之后的K线（日K线按日期比较，当日K线由rebuildReplayDay处理）
+	list := data.List[:0]
+	for _, item := range data.List {
+		if barsPerDay <= 1 {
+			if item.Time.Format("2006-01-02") > at.Format("2006-01-02") {
+				continue
+			}
+		} else if item.Time.After(at) {
+			continue
+		}
+		list = append(list, item)
+	}
+	if len(list) > limit {
+		list = list[len(list)-limit:]
+	}
+	data.List = list
+	data.Count = len(list)
+	if klineType == "day" {
+		data.SuspensionGaps = detectSuspensionGaps(data.List)
+	}
+	return data, nil
+}
+
+// rebuildReplayDay 用截至回放时刻的30分钟K线重建回放当日的日K线（当日收盘后的日K线保持不变）
+func rebuildReplayDay(dayKline, min30Kline *KlineData, at time.Time) error {
+	if len(dayKline.List) == 0 {
+		return nil
+	}
+	date := at.Format("2006-01-02")
+	last := &dayKline.List[len(dayKline.List)-1]
+	if last.Time.Format("2006-01-02") != date {
+		return nil // 回放日期不是交易日或当日数据已完整
+	}
+
+	var bar *KlineItem
+	for _, item := range min30Kline.List {
+		if item.Time.Format("2006-01-02") != date {
+			continue
+		}
+		if bar == nil {
+			bar = &KlineItem{Last: last.Last, Open: item.Open, High: item.High, Low: item.Low, Time: last.Time}
+		}
+		if item.High > bar.High {
+			bar.High = item.High
+		}
+		if item.Low < bar.Low {
+			bar.Low = item.Low
+		}
+		bar.Close = item.Close
+		bar.Volume += item.Volume
+		bar.Amount += item.Amount
+	}
+	if bar == nil {
+		return fmt.Errorf("回放时刻 %s 当日还没有已完成的30分钟K线，请选择10:00之后的时刻", at.Format("2006-01-02 15:04"))
+	}
+	*last = *bar
+	return nil
+}
+
+// replayQuote 用回放时刻的日K线（最后一根）构造行情数据（不含盘口）
+func replayQuote(code string, dayKline *KlineData) *QuoteData {
+	list := dayKline.List
+	bar := list[len(list)-1]
+	prevClose := bar.Last
+	if prevClose == 0 && len(list) >= 2 {
+		prevClose = list[len(list)-2].Close
+	}
+	return &QuoteData{
+		Code:      code,
+		K:         KData{Last: prevClose, Open: bar.Open, High: bar.High, Low: bar.Low, Close: bar.Close},
+		TotalHand: bar.Volume,
+		Amount:    bar.Amount,
+	}
+}
@@ -0,0 +1,51 @@
+package stock
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxResendCount 单次补发的最大通知条数，超过时要求缩小时间范围或股票范围，避免误操作刷屏
+const MaxResendCount = 20
+
+// ResendInterval 补发时相邻两条通知的间隔（钉钉等机器人有每分钟20条的限流）
+const ResendInterval = time.Second
+
+// ErrNotificationMuted 通知按低优先级静默规则未推送
+var ErrNotificationMuted = errors.New("低优先级通知已静默")
+
+// ResendItem 一条补发通知的结果
+type ResendItem struct {
+	StockCode  string    `json:"stock_code"`
+	StockName  string    `json:"stock_name"`
+	Signal     string    `json:"signal"`
+	Confidence int       `json:"confidence"`
+	Timestamp  time.Time `json:"timestamp"`          // 原分析时间
+	TraceID    string    `json:"trace_id,omitempty"` // 原分析的追踪ID
+	Sent       bool      `json:"sent"`               // 是否已补发（dry_run时为false）
+	Error      string    `json:"error,omitempty"`    // 补发失败或跳过的原因
+}
+
+// ResendEligible 历史结果当时是否应推送通知：达到信心度阈值（或价格事件），且没有因信号确认、风险回报比、冷静期、静默期被有意拦下
+func (a *StockAnalyzer) ResendEligible(result *AnalysisResult) bool {
+	if result.PriceEvent != "" {
+		return true
+	}
+	if result.InsufficientData || result.PendingConfirmation || result.RiskRewardFiltered || result.CooldownSuppressed || result.QuietSuppressed {
+		return false
+	}
+	score := decisionConfidence(result)
+	if a.AnalysisConfig.ThresholdBasis == ThresholdBasisSystemScore && result.SystemScore != nil {
+		score = *result.SystemScore
+	}
+	return score >= result.EffectiveMinConfidence
+}
+
+// ResendNotification 补发历史结果的通知：走完整的通知流程，推理原因前标注"补发"和原分析时间；
+// 不受当前静默期限制，也不更新冷静期和价格事件的参考价
+func (a *StockAnalyzer) ResendNotification(result *AnalysisResult) error {
+	if a.Notifier == nil || !a.notificationEnabled() {
+		return errors.New("该股票未启用通知或已静音")
+	}
+	return a.deliverNotification(result, true)
+}
@@ -0,0 +1,74 @@
+package stock
+
+import "math"
+
+// DiffResults 对比两条分析结果的关键字段，只返回有变化的字段：字段名 -> {"old": 旧值, "new": 新值}
+func DiffResults(oldResult, newResult *AnalysisResult) map[string]interface{} {
+	diff := make(map[string]interface{})
+	addDiff := func(field string, oldValue, newValue interface{}) {
+		if oldValue != newValue {
+			diff[field] = map[string]interface{}{"old": oldValue, "new": newValue}
+		}
+	}
+	addDiff("signal", oldResult.Signal, newResult.Signal)
+	addDiff("confidence", oldResult.Confidence, newResult.Confidence)
+	addDiff("current_price", oldResult.CurrentPrice, newResult.CurrentPrice)
+	addDiff("target_price", oldResult.TargetPrice, newResult.TargetPrice)
+	addDiff("stop_loss", oldResult.StopLoss, newResult.StopLoss)
+	addDiff("risk_reward", oldResult.RiskReward, newResult.RiskReward)
+	addDiff("position_profit_target", oldResult.PositionProfitTarget, newResult.PositionProfitTarget)
+	addDiff("position_stop_loss", oldResult.PositionStopLoss, newResult.PositionStopLoss)
+	return diff
+}
+
+// DiffIndicators 对比两条分析结果的技术指标，只返回有变化的指标
+// 数值型指标返回 {"old", "new", "change"}（change为新值减旧值），文本型指标（如均线交叉、趋势）返回 {"old", "new"}；
+// 只在一侧存在的指标另一侧为nil
+func DiffIndicators(oldResult, newResult *AnalysisResult) map[string]interface{} {
+	oldValues := resultTechnicalValues(oldResult)
+	newValues := resultTechnicalValues(newResult)
+	diff := make(map[string]interface{})
+
+	for name, newValue := range newValues {
+		oldValue, ok := oldValues[name]
+		switch {
+		case !ok:
+			diff[name] = map[string]interface{}{"old": nil, "new": newValue}
+		case math.Abs(newValue-oldValue) > 1e-9:
+			diff[name] = map[string]interface{}{
+				"old":    oldValue,
+				"new":    newValue,
+				"change": math.Round((newValue-oldValue)*10000) / 10000,
+			}
+		}
+	}
+	for name, oldValue := range oldValues {
+		if _, ok := newValues[name]; !ok {
+			diff[name] = map[string]interface{}{"old": oldValue, "new": nil}
+		}
+	}
+
+	// 文本型指标
+	for name, raw := range newResult.TechnicalData {
+		if _, numeric := newValues[name]; numeric {
+			continue
+		}
+		newText, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		oldText, _ := oldResult.TechnicalData[name].(string)
+		if oldText != newText {
+			diff[name] = map[string]interface{}{"old": oldText, "new": newText}
+		}
+	}
+	return diff
+}
+
+// resultTechnicalValues 返回分析结果的数值型指标（早期记录没有technical_values时从technical_data提取）
+func resultTechnicalValues(result *AnalysisResult) map[string]float64 {
+	if len(result.TechnicalValues) > 0 {
+		return result.TechnicalValues
+	}
+	return TechnicalValues(result.TechnicalData)
+}
@@ -0,0 +1,75 @@
+package stock
+
+import "strings"
+
+// 分析结果排序方式
+const (
+	ResultSortTime      = "time"       // 按时间（最新的在前，默认）
+	ResultSortLimitUp   = "limit_up"   // 按触及涨停的可能性从高到低
+	ResultSortLimitDown = "limit_down" // 按触及跌停的可能性从高到低
+)
+
+// ResultFilter 分析结果筛选条件（零值表示不筛选）及排序方式
+type ResultFilter struct {
+	MinConfidence int      // 最低信心度，0表示不限制
+	Signals       []string // 信号类型（BUY/SELL/HOLD），为空表示不限制
+	SortBy        string   // 排序方式（ResultSort*），为空按时间
+}
+
+// ValidResultSort 排序方式是否有效
+func ValidResultSort(sortBy string) bool {
+	return sortBy == "" || sortBy == ResultSortTime || sortBy == ResultSortLimitUp || sortBy == ResultSortLimitDown
+}
+
+// Less 按排序方式比较两条结果（a排在b前面时返回true），涨跌停可能性相同或未给出时按时间
+func (f ResultFilter) Less(a, b *AnalysisResult) bool {
+	if f.SortBy == ResultSortLimitUp || f.SortBy == ResultSortLimitDown {
+		if rankA, rankB := limitMoveRankOf(a, f.SortBy), limitMoveRankOf(b, f.SortBy); rankA != rankB {
+			return rankA > rankB
+		}
+	}
+	return a.Timestamp.After(b.Timestamp)
+}
+
+// limitMoveRankOf 结果中触及涨停或跌停可能性的排序值
+func limitMoveRankOf(result *AnalysisResult, sortBy string) int {
+	if result.LimitMove == nil {
+		return 0
+	}
+	if sortBy == ResultSortLimitUp {
+		return LimitMoveRank(result.LimitMove.Up)
+	}
+	return LimitMoveRank(result.LimitMove.Down)
+}
+
+// ParseSignals 解析逗号分隔的信号列表（如"BUY,SELL"），统一为大写并校验取值
+func ParseSignals(text string) ([]string, bool) {
+	var signals []string
+	for _, part := range strings.Split(text, ",") {
+		signal := strings.ToUpper(strings.TrimSpace(part))
+		if signal == "" {
+			continue
+		}
+		if signal != "BUY" && signal != "SELL" && signal != "HOLD" {
+			return nil, false
+		}
+		signals = append(signals, signal)
+	}
+	return signals, true
+}
+
+// Match 判断分析结果是否满足筛选条件
+func (f ResultFilter) Match(result *AnalysisResult) bool {
+	if result.Confidence < f.MinConfidence {
+		return false
+	}
+	if len(f.Signals) == 0 {
+		return true
+	}
+	for _, signal := range f.Signals {
+		if result.Signal == signal {
+			return true
+		}
+	}
+	return false
+}
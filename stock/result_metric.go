@@ -0,0 +1,57 @@
+package stock
+
+// ResultMetrics 可作为时间序列查询的分析结果字段（供Grafana等外部看板出图）
+var ResultMetrics = []string{
+	"confidence",          // AI信心度
+	"adjusted_confidence", // 用于通知决策的信心度（启用命中率加权时为加权后的值）
+	"price",               // 分析时的现价（元）
+	"target_price",        // 目标价
+	"stop_loss",           // 止损价
+	"health_score",        // 技术指标健康度
+	"system_score",        // 系统综合评分
+	"signal",              // 信号（BUY=1，HOLD=0，SELL=-1）
+}
+
+// signalValues 信号对应的数值
+var signalValues = map[string]float64{"BUY": 1, "HOLD": 0, "SELL": -1}
+
+// ResultMetricValue 读取分析结果中指标的数值：ResultMetrics中的字段取结果字段（价格为0表示未给出、评分未计算时不返回），
+// 其余名称按技术指标读取（如 rsi14、change_percent）
+func ResultMetricValue(result *AnalysisResult, name string) (float64, bool) {
+	nonZero := func(value float64) (float64, bool) {
+		return value, value != 0
+	}
+
+	switch name {
+	case "confidence":
+		return float64(result.Confidence), true
+	case "adjusted_confidence":
+		return float64(decisionConfidence(result)), true
+	case "price":
+		return nonZero(result.CurrentPrice)
+	case "target_price":
+		return nonZero(result.TargetPrice)
+	case "stop_loss":
+		return nonZero(result.StopLoss)
+	case "health_score":
+		return scoreValue(result.HealthScore)
+	case "system_score":
+		return scoreValue(result.SystemScore)
+	case "signal":
+		value, ok := signalValues[result.Signal]
+		return value, ok
+	}
+
+	if value, ok := result.TechnicalValues[name]; ok {
+		return value, true
+	}
+	return IndicatorValue(result.TechnicalData, name)
+}
+
+// scoreValue 评分的数值（0分是有效评分，未计算时不返回）
+func scoreValue(score *int) (float64, bool) {
+	if score == nil {
+		return 0, false
+	}
+	return float64(*score), true
+}
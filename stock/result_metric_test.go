@@ -0,0 +1,16 @@
+package stock
+
+import "testing"
+
+func TestResultMetricValueZeroScoreIsValid(t *testing.T) {
+	zero := 0
+	scored := &AnalysisResult{HealthScore: &zero, SystemScore: &zero}
+	for _, name := range []string{"health_score", "system_score"} {
+		if value, ok := ResultMetricValue(scored, name); !ok || value != 0 {
+			t.Errorf("%s: 已计算的0分应返回，实际 %v, %v", name, value, ok)
+		}
+		if _, ok := ResultMetricValue(&AnalysisResult{}, name); ok {
+			t.Errorf("%s: 未计算时不应返回", name)
+		}
+	}
+}
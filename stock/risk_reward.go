@@ -0,0 +1,45 @@
+package stock
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// riskRewardPattern 匹配"风险:回报"两个数（如 1:2、1：2.5、1 比 3）
+var riskRewardPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:[:：/]|比)\s*(\d+(?:\.\d+)?)`)
+
+// riskRewardNumber 匹配单个数值（如 2.0、2倍）
+var riskRewardNumber = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// ParseRiskReward 把AI给出的风险回报比文本解析为回报/风险的比率（如"1:2"为2，"1：1.5"为1.5，"2.0"为2）
+// 无法解析时返回false
+func ParseRiskReward(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, false
+	}
+
+	if match := riskRewardPattern.FindStringSubmatch(text); match != nil {
+		risk, err1 := strconv.ParseFloat(match[1], 64)
+		reward, err2 := strconv.ParseFloat(match[2], 64)
+		if err1 != nil || err2 != nil || risk <= 0 {
+			return 0, false
+		}
+		return reward / risk, true
+	}
+
+	if match := riskRewardNumber.FindString(text); match != "" {
+		ratio, err := strconv.ParseFloat(match, 64)
+		if err == nil && ratio > 0 {
+			return ratio, true
+		}
+	}
+	return 0, false
+}
+
+// riskRewardTooLow BUY信号的风险回报比是否低于配置的下限（无法解析时不过滤）
+func (a *StockAnalyzer) riskRewardTooLow(result *AnalysisResult) bool {
+	return a.AnalysisConfig.MinRiskReward > 0 && result.Signal == "BUY" &&
+		result.RiskRewardRatio > 0 && result.RiskRewardRatio < a.AnalysisConfig.MinRiskReward
+}
@@ -0,0 +1,59 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ruleBasedResponse 试运行时用本地规则代替AI生成决策（JSON格式与AI响应一致，走同样的解析流程）
+// 规则只看均线排列和RSI，仅用于演练流程，不作为交易依据：
+//   - 现价 > MA5 > MA20 且 RSI < 70：BUY
+//   - 现价 < MA5 < MA20 且 RSI > 30：SELL
+//   - 其余：HOLD
+func ruleBasedResponse(technical map[string]interface{}) (string, error) {
+	price, _ := IndicatorValue(technical, "current_price")
+	ma5, hasMA5 := IndicatorValue(technical, "ma5")
+	ma20, hasMA20 := IndicatorValue(technical, "ma20")
+	rsi, hasRSI := IndicatorValue(technical, "rsi14")
+	if !hasRSI {
+		rsi = 50
+	}
+
+	decision := map[string]interface{}{
+		"signal":     "HOLD",
+		"confidence": 50,
+	}
+	var reasons []string
+	switch {
+	case price <= 0 || !hasMA5 || !hasMA20:
+		reasons = append(reasons, "均线数据不足，观望")
+	case price > ma5 && ma5 > ma20 && rsi < 70:
+		decision["signal"] = "BUY"
+		decision["confidence"] = 60
+		decision["target_price"] = roundPrice(price * 1.05)
+		decision["stop_loss"] = roundPrice(ma20)
+		decision["risk_reward"] = fmt.Sprintf("1:%.1f", price*0.05/(price-ma20))
+		reasons = append(reasons, fmt.Sprintf("现价%.2f > MA5 %.2f > MA20 %.2f，多头排列，RSI %.1f未超买", price, ma5, ma20, rsi))
+	case price < ma5 && ma5 < ma20 && rsi > 30:
+		decision["signal"] = "SELL"
+		decision["confidence"] = 60
+		decision["stop_loss"] = roundPrice(ma5)
+		reasons = append(reasons, fmt.Sprintf("现价%.2f < MA5 %.2f < MA20 %.2f，空头排列，RSI %.1f未超卖", price, ma5, ma20, rsi))
+	default:
+		reasons = append(reasons, fmt.Sprintf("现价%.2f，MA5 %.2f，MA20 %.2f，RSI %.1f，均线未形成明确排列，观望", price, ma5, ma20, rsi))
+	}
+	decision["reasoning"] = "【试运行】本地规则生成，未调用AI：" + strings.Join(reasons, "；")
+
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// roundPrice 价格保留两位小数
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,19 @@
+package stock
+
+import (
+	"encoding/json"
+
+	"nofx/notifier"
+)
+
+// MarshalResult 按当前版本序列化分析结果（写入schema_version，不修改传入的结果），兼容约定见notifier.SchemaVersion
+func MarshalResult(result *AnalysisResult) ([]byte, error) {
+	return json.Marshal(VersionedResult(result))
+}
+
+// VersionedResult 返回写入了当前schema_version的结果副本（恢复自旧版本状态文件的历史记录可能没有版本号）
+func VersionedResult(result *AnalysisResult) *AnalysisResult {
+	versioned := *result
+	versioned.SchemaVersion = notifier.SchemaVersion
+	return &versioned
+}
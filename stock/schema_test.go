@@ -0,0 +1,27 @@
+package stock
+
+import (
+	"encoding/json"
+	"testing"
+
+	"nofx/notifier"
+)
+
+func TestMarshalResultWritesSchemaVersion(t *testing.T) {
+	// 从旧版本状态文件恢复的历史记录没有版本号
+	result := &AnalysisResult{StockCode: "000001", Signal: "HOLD"}
+	data, err := MarshalResult(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AnalysisResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SchemaVersion != notifier.SchemaVersion || decoded.StockCode != "000001" {
+		t.Fatalf("序列化结果不符: %+v", decoded)
+	}
+	if result.SchemaVersion != 0 {
+		t.Fatalf("不应修改传入的结果，实际 schema_version=%d", result.SchemaVersion)
+	}
+}
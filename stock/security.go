@@ -0,0 +1,97 @@
+package stock
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"nofx/config"
+)
+
+// SecurityType 标的类型
+type SecurityType string
+
+const (
+	SecurityStock       SecurityType = "stock"       // 股票
+	SecurityETF         SecurityType = "etf"         // ETF基金
+	SecurityConvertible SecurityType = "convertible" // 可转债
+)
+
+// SecurityInfo 标的交易规则
+type SecurityInfo struct {
+	Type         SecurityType
+	TypeName     string  // 类型名称（用于提示词）
+	PriceLimit   float64 // 涨跌幅限制（如0.1表示±10%），0表示无涨跌停限制
+	TradingUnit  int     // 最小交易单位数量（股票/ETF 100，可转债 10）
+	UnitName     string  // 数量单位（股/份/张）
+	PriceDecimal int     // 报价小数位（股票2位，ETF和可转债3位）
+}
+
+// DetectSecurity 按代码前缀（及名称中的ST标记）识别标的类型和交易规则
+// 代码先经config.StockMarket去掉市场前缀/后缀，600000、sh600000、600000.SH按同一只股票识别
+// 11/12开头为可转债，其余1/5开头为ETF，其他按股票处理（科创板/创业板±20%，北交所±30%，ST±5%，主板±10%）
+func DetectSecurity(code, name string) SecurityInfo {
+	_, pureCode := config.StockMarket(code)
+
+	switch {
+	case strings.HasPrefix(pureCode, "11") || strings.HasPrefix(pureCode, "12"):
+		return SecurityInfo{Type: SecurityConvertible, TypeName: "可转债", TradingUnit: 10, UnitName: "张", PriceDecimal: 3}
+	case strings.HasPrefix(pureCode, "1") || strings.HasPrefix(pureCode, "5"):
+		return SecurityInfo{Type: SecurityETF, TypeName: "ETF", PriceLimit: 0.1, TradingUnit: 100, UnitName: "份", PriceDecimal: 3}
+	}
+
+	info := SecurityInfo{Type: SecurityStock, TypeName: "股票", PriceLimit: 0.1, TradingUnit: 100, UnitName: "股", PriceDecimal: 2}
+	switch {
+	case strings.HasPrefix(pureCode, "688") || strings.HasPrefix(pureCode, "689") ||
+		strings.HasPrefix(pureCode, "300") || strings.HasPrefix(pureCode, "301"):
+		info.PriceLimit = 0.2 // 科创板、创业板
+	case strings.HasPrefix(pureCode, "8") || strings.HasPrefix(pureCode, "4") || strings.HasPrefix(pureCode, "92"):
+		info.PriceLimit = 0.3 // 北交所
+	case strings.Contains(strings.ToUpper(name), "ST"):
+		info.PriceLimit = 0.05 // 主板ST股票
+	}
+	return info
+}
+
+// TPlusZero 是否T+0交易（可转债当日买入当日可卖，股票和ETF为T+1）
+func (s SecurityInfo) TPlusZero() bool {
+	return s.Type == SecurityConvertible
+}
+
+// feeRates 按标的类型调整费率：只有股票征收印花税，ETF和可转债免征
+func (s SecurityInfo) feeRates(base FeeRates) FeeRates {
+	if s.Type != SecurityStock {
+		base.StampDutyRate = 0
+	}
+	return base
+}
+
+// LimitPrices 根据昨收价（元）计算涨停价和跌停价，无涨跌停限制时返回ok=false
+func (s SecurityInfo) LimitPrices(prevClose float64) (up, down float64, ok bool) {
+	if s.PriceLimit <= 0 || prevClose <= 0 {
+		return 0, 0, false
+	}
+	scale := math.Pow(10, float64(s.PriceDecimal))
+	up = math.Round(prevClose*(1+s.PriceLimit)*scale) / scale
+	down = math.Round(prevClose*(1-s.PriceLimit)*scale) / scale
+	return up, down, true
+}
+
+// RulesText 生成交易规则说明（用于提示词）
+func (s SecurityInfo) RulesText(prevClose float64) string {
+	if s.Type == SecurityBasket {
+		return "多只成分股按权重合成的组合指数（单位为点），不可直接交易，信号用于指导成分股的整体仓位"
+	}
+	if s.TPlusZero() {
+		// 可转债T+0交易，无涨跌停但盘中大幅波动会临时停牌
+		return fmt.Sprintf("%s，最小交易单位%d%s，T+0交易；无涨跌停限制，但盘中涨跌幅达±20%%、±30%%时触发临时停牌（熔断）",
+			s.TypeName, s.TradingUnit, s.UnitName)
+	}
+
+	text := fmt.Sprintf("%s，最小交易单位%d%s，T+1交易", s.TypeName, s.TradingUnit, s.UnitName)
+	if up, down, ok := s.LimitPrices(prevClose); ok {
+		text += fmt.Sprintf("；涨跌幅限制±%.0f%%（涨停价%.*f元，跌停价%.*f元）",
+			s.PriceLimit*100, s.PriceDecimal, up, s.PriceDecimal, down)
+	}
+	return text
+}
@@ -0,0 +1,27 @@
+package stock
+
+import "testing"
+
+func TestDetectSecurityNormalizesCode(t *testing.T) {
+	cases := []struct {
+		codes      []string
+		name       string
+		typ        SecurityType
+		priceLimit float64
+	}{
+		{[]string{"600000", "sh600000", "SH600000", "600000.SH", "600000.sh", " 600000 "}, "浦发银行", SecurityStock, 0.1},
+		{[]string{"300750", "sz300750", "300750.SZ"}, "宁德时代", SecurityStock, 0.2},
+		{[]string{"510300", "sh510300", "510300.SH"}, "沪深300ETF", SecurityETF, 0.1},
+		{[]string{"113050", "sh113050", "113050.SH"}, "南银转债", SecurityConvertible, 0},
+		{[]string{"123107", "sz123107", "123107.SZ"}, "温氏转债", SecurityConvertible, 0},
+		{[]string{"830799", "bj830799", "830799.BJ"}, "艾融软件", SecurityStock, 0.3},
+	}
+	for _, tc := range cases {
+		for _, code := range tc.codes {
+			info := DetectSecurity(code, tc.name)
+			if info.Type != tc.typ || info.PriceLimit != tc.priceLimit {
+				t.Errorf("%s: 应识别为%s（涨跌幅%.2f），实际%s（涨跌幅%.2f）", code, tc.typ, tc.priceLimit, info.Type, info.PriceLimit)
+			}
+		}
+	}
+}
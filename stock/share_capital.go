@@ -0,0 +1,140 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+// ShareCapital 股本数据
+type ShareCapital struct {
+	Code        string `json:"Code"`
+	TotalShares int64  `json:"TotalShares"` // 总股本（股）
+	FloatShares int64  `json:"FloatShares"` // 流通股本（股）
+}
+
+// ErrShareCapitalUnsupported TDX代理不提供股本数据
+var ErrShareCapitalUnsupported = fmt.Errorf("TDX代理不支持股本接口")
+
+// 流通市值分档（亿元）：低于smallCapLimit为小盘，高于largeCapLimit为大盘
+const (
+	smallCapLimit = 50.0
+	largeCapLimit = 200.0
+)
+
+// GetShareCapital 获取股本数据
+// 并非所有TDX代理都提供该接口：返回404时记录为不支持，后续直接返回ErrShareCapitalUnsupported
+func (c *TDXClient) GetShareCapital(code string) (*ShareCapital, error) {
+	if atomic.LoadInt32(&c.shareCapitalUnsupported) == 1 {
+		return nil, ErrShareCapitalUnsupported
+	}
+
+	urlStr := fmt.Sprintf("%s/api/finance?code=%s", c.BaseURL, code)
+	resp, err := c.HTTPClient.Get(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		atomic.StoreInt32(&c.shareCapitalUnsupported, 1)
+		return nil, ErrShareCapitalUnsupported
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var apiResp APIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if apiResp.Code != 0 {
+		return nil, fmt.Errorf("API错误: %s", apiResp.Message)
+	}
+
+	var capital ShareCapital
+	if err := json.Unmarshal(apiResp.Data, &capital); err != nil {
+		return nil, fmt.Errorf("解析股本数据失败: %w", err)
+	}
+	if capital.FloatShares <= 0 {
+		return nil, fmt.Errorf("流通股本数据为空")
+	}
+
+	return &capital, nil
+}
+
+// floatShares 返回流通股本（股）：优先使用配置值，否则从TDX获取（每天获取一次）
+// 无法获取时返回0
+func (a *StockAnalyzer) floatShares() float64 {
+	if a.AnalysisConfig.FloatShares > 0 {
+		return a.AnalysisConfig.FloatShares
+	}
+	if a.IsBasket() {
+		return 0
+	}
+
+	today := a.now().Format("2006-01-02")
+	a.mutex.Lock()
+	if a.floatSharesDate == today {
+		shares := a.floatSharesCache
+		a.mutex.Unlock()
+		return shares
+	}
+	a.mutex.Unlock()
+
+	shares := 0.0
+	if capital, err := a.TDXClient.GetShareCapital(a.AnalysisConfig.StockCode); err == nil {
+		shares = float64(capital.FloatShares)
+	} else if err != ErrShareCapitalUnsupported {
+		log.Printf("⚠️  获取股本数据失败，跳过市值信息: %v", err)
+		return 0 // 临时失败不缓存，下一轮重试
+	}
+
+	a.mutex.Lock()
+	a.floatSharesCache = shares
+	a.floatSharesDate = today
+	a.mutex.Unlock()
+	return shares
+}
+
+// applyShareCapital 计算流通市值、换手率和盘子大小并写入技术指标数据
+func applyShareCapital(technical map[string]interface{}, floatShares float64) {
+	price, _ := technical["current_price"].(float64)
+	if floatShares <= 0 || price <= 0 {
+		return
+	}
+
+	marketCap := price * floatShares / 1e8
+	technical["float_shares"] = floatShares / 1e8
+	technical["float_market_cap"] = marketCap
+	if volume, ok := technical["volume"].(int64); ok {
+		technical["turnover_rate"] = fmt.Sprintf("%.2f%%", float64(volume)/floatShares*100)
+	}
+
+	switch {
+	case marketCap < smallCapLimit:
+		technical["cap_size"] = "small"
+	case marketCap > largeCapLimit:
+		technical["cap_size"] = "large"
+	default:
+		technical["cap_size"] = "mid"
+	}
+}
+
+// getCapSizeText 盘子大小的中文描述
+func getCapSizeText(size string) string {
+	switch size {
+	case "small":
+		return fmt.Sprintf("小盘股（流通市值低于%.0f亿）", smallCapLimit)
+	case "large":
+		return fmt.Sprintf("大盘股（流通市值高于%.0f亿）", largeCapLimit)
+	default:
+		return "中盘股"
+	}
+}
@@ -0,0 +1,63 @@
+package stock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StageDuration 单次分析中一个阶段的耗时
+type StageDuration struct {
+	Stage  string `json:"stage"` // 阶段：quote（行情）、kline（K线）、indicators（指标/筹码/新闻）、ai（AI调用）、parse（解析）、notify（通知）
+	Millis int64  `json:"ms"`    // 耗时（毫秒）
+}
+
+// stageTimer 单次分析的分阶段计时器（按阶段顺序记录，同名阶段累加），为nil时不计时
+type stageTimer struct {
+	last   time.Time
+	stages []StageDuration
+}
+
+// newStageTimer 创建计时器，从当前时刻开始计时
+func newStageTimer() *stageTimer {
+	return &stageTimer{last: time.Now()}
+}
+
+// mark 结束一个阶段：记录上次mark（或开始计时）至今的耗时
+func (t *stageTimer) mark(stage string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	elapsed := now.Sub(t.last).Milliseconds()
+	t.last = now
+	for i := range t.stages {
+		if t.stages[i].Stage == stage {
+			t.stages[i].Millis += elapsed
+			return
+		}
+	}
+	t.stages = append(t.stages, StageDuration{Stage: stage, Millis: elapsed})
+}
+
+// durations 已记录的各阶段耗时
+func (t *stageTimer) durations() []StageDuration {
+	if t == nil {
+		return nil
+	}
+	return append([]StageDuration(nil), t.stages...)
+}
+
+// FormatStageDurations 把各阶段耗时格式化为一行文本（如 "quote 120ms / kline 850ms / ai 95.2s"）
+func FormatStageDurations(stages []StageDuration) string {
+	parts := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		d := time.Duration(stage.Millis) * time.Millisecond
+		if d >= time.Second {
+			parts = append(parts, fmt.Sprintf("%s %.1fs", stage.Stage, d.Seconds()))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %dms", stage.Stage, stage.Millis))
+		}
+	}
+	return strings.Join(parts, " / ")
+}
@@ -0,0 +1,73 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StockState 股票的运行时启停状态
+type StockState struct {
+	Paused bool `json:"paused,omitempty"` // 暂停定时/批量分析（手动触发仍可分析）
+	Muted  bool `json:"muted,omitempty"`  // 静音：照常分析和记录历史，但不推送任何通知
+}
+
+// StockStateStore 股票启停状态的持久化存储（JSON文件：股票代码 -> 状态），重启后恢复，只保存非默认状态
+type StockStateStore struct {
+	path   string
+	mutex  sync.Mutex
+	states map[string]StockState
+}
+
+// NewStockStateStore 创建状态存储并加载已有的状态文件（文件不存在时为空）
+func NewStockStateStore(path string) (*StockStateStore, error) {
+	store := &StockStateStore{path: path, states: make(map[string]StockState)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.states); err != nil {
+		return store, fmt.Errorf("状态文件格式错误: %w", err)
+	}
+	return store, nil
+}
+
+// Get 获取股票的状态（未保存时为默认状态）
+func (s *StockStateStore) Get(code string) StockState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.states[code]
+}
+
+// Set 更新股票的状态并写入文件（先写临时文件再重命名）
+func (s *StockStateStore) Set(code string, state StockState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if state == (StockState{}) {
+		delete(s.states, code)
+	} else {
+		s.states[code] = state
+	}
+
+	data, err := json.MarshalIndent(s.states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建状态目录失败: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	return nil
+}
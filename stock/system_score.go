@@ -0,0 +1,220 @@
+package stock
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// 通知门槛的评分依据
+const (
+	ThresholdBasisConfidence  = "confidence"   // AI信心度（启用命中率加权时为加权信心度）
+	ThresholdBasisSystemScore = "system_score" // 系统综合评分
+)
+
+// ScoringWeights 技术指标健康度（health_score）中各指标的权重，按有数据的指标加权平均（权重无需合计为100）
+type ScoringWeights struct {
+	MA     float64 `json:"ma"`     // 均线排列
+	RSI    float64 `json:"rsi"`    // RSI14强弱
+	MACD   float64 `json:"macd"`   // MACD多空
+	Volume float64 `json:"volume"` // 量能（外盘占比）
+}
+
+// DefaultScoringWeights 默认健康度权重
+func DefaultScoringWeights() ScoringWeights {
+	return ScoringWeights{MA: 30, RSI: 25, MACD: 30, Volume: 15}
+}
+
+// Validate 校验权重：不能为负数，且至少有一个指标的权重大于0
+func (w ScoringWeights) Validate() error {
+	if w.MA < 0 || w.RSI < 0 || w.MACD < 0 || w.Volume < 0 {
+		return fmt.Errorf("健康度权重不能为负数")
+	}
+	if w.MA+w.RSI+w.MACD+w.Volume == 0 {
+		return fmt.Errorf("健康度权重至少有一项大于0")
+	}
+	return nil
+}
+
+// ScoringModel 运行时可调整的健康度权重（所有分析器共享，调整后下一轮分析立即生效）
+type ScoringModel struct {
+	mutex   sync.RWMutex
+	weights ScoringWeights
+}
+
+// NewScoringModel 创建健康度评分模型
+func NewScoringModel(weights ScoringWeights) *ScoringModel {
+	return &ScoringModel{weights: weights}
+}
+
+// Weights 当前权重
+func (m *ScoringModel) Weights() ScoringWeights {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.weights
+}
+
+// ScoringWeightsPatch 部分调整权重：只修改非nil的字段，其余保持当前值
+type ScoringWeightsPatch struct {
+	MA     *float64 `json:"ma"`
+	RSI    *float64 `json:"rsi"`
+	MACD   *float64 `json:"macd"`
+	Volume *float64 `json:"volume"`
+}
+
+// Empty 是否没有设置任何权重
+func (p ScoringWeightsPatch) Empty() bool {
+	return p.MA == nil && p.RSI == nil && p.MACD == nil && p.Volume == nil
+}
+
+// UpdateWeights 把部分调整合并到当前权重（合并和替换在同一把锁内完成），返回调整前后的权重
+func (m *ScoringModel) UpdateWeights(patch ScoringWeightsPatch) (previous, weights ScoringWeights, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	weights = m.weights
+	if patch.MA != nil {
+		weights.MA = *patch.MA
+	}
+	if patch.RSI != nil {
+		weights.RSI = *patch.RSI
+	}
+	if patch.MACD != nil {
+		weights.MACD = *patch.MACD
+	}
+	if patch.Volume != nil {
+		weights.Volume = *patch.Volume
+	}
+	if err := weights.Validate(); err != nil {
+		return ScoringWeights{}, ScoringWeights{}, err
+	}
+	previous = m.weights
+	m.weights = weights
+	return previous, weights, nil
+}
+
+// 系统综合评分中各部分的占比
+const (
+	systemScoreHealthWeight    = 0.6 // 技术指标健康度与信号方向的契合度
+	systemScoreConsensusWeight = 0.2 // AI信号与本地技术规则的一致性
+	systemScoreAccuracyWeight  = 0.2 // 该股历史信号命中率
+)
+
+// HealthScore 技术指标健康度（0-100，越高越偏多），没有任何可用指标时返回false
+//   - MA：现价 > MA5 > MA20 为100，现价 > MA20 为65，现价 < MA5 < MA20 为0，其余35
+//   - RSI：50-70为80，70以上超买为40，30-50为45，30以下超卖为30
+//   - MACD：DIF > DEA且柱为正为100，DIF > DEA为70，DIF < DEA且柱为负为0，其余30
+//   - 量能：外盘占比（%）直接作为分数
+func HealthScore(technical map[string]interface{}, weights ScoringWeights) (int, bool) {
+	total, weightSum := 0.0, 0.0
+	add := func(score, weight float64) {
+		if weight > 0 {
+			total += score * weight
+			weightSum += weight
+		}
+	}
+
+	price, _ := IndicatorValue(technical, "current_price")
+	ma5, hasMA5 := IndicatorValue(technical, "ma5")
+	ma20, hasMA20 := IndicatorValue(technical, "ma20")
+	if price > 0 && hasMA5 && hasMA20 {
+		switch {
+		case price > ma5 && ma5 > ma20:
+			add(100, weights.MA)
+		case price < ma5 && ma5 < ma20:
+			add(0, weights.MA)
+		case price > ma20:
+			add(65, weights.MA)
+		default:
+			add(35, weights.MA)
+		}
+	}
+
+	if rsi, ok := IndicatorValue(technical, "rsi14"); ok {
+		switch {
+		case rsi > 70:
+			add(40, weights.RSI)
+		case rsi >= 50:
+			add(80, weights.RSI)
+		case rsi >= 30:
+			add(45, weights.RSI)
+		default:
+			add(30, weights.RSI)
+		}
+	}
+
+	dif, hasDIF := IndicatorValue(technical, "macd_dif")
+	dea, hasDEA := IndicatorValue(technical, "macd_dea")
+	hist, _ := IndicatorValue(technical, "macd_hist")
+	if hasDIF && hasDEA {
+		switch {
+		case dif > dea && hist > 0:
+			add(100, weights.MACD)
+		case dif > dea:
+			add(70, weights.MACD)
+		case hist < 0:
+			add(0, weights.MACD)
+		default:
+			add(30, weights.MACD)
+		}
+	}
+
+	if outer, ok := IndicatorValue(technical, "outer_ratio"); ok {
+		add(math.Max(0, math.Min(100, outer)), weights.Volume)
+	}
+
+	if weightSum == 0 {
+		return 0, false
+	}
+	return int(math.Round(total / weightSum)), true
+}
+
+// applySystemScore 计算技术指标健康度和系统综合评分（0-100），与AI信心度相互独立：
+// 健康度与信号方向的契合度占60%（BUY取健康度，SELL取100-健康度，HOLD越接近中性越高），
+// 与本地技术规则的一致性占20%（一致100、矛盾0、其余50），历史命中率占20%（样本不足时按50）
+// 没有可用的技术指标时不计算，返回false
+func (a *StockAnalyzer) applySystemScore(result *AnalysisResult) bool {
+	if result.InsufficientData {
+		return false
+	}
+	health, ok := HealthScore(result.TechnicalData, a.scoringWeights())
+	if !ok {
+		return false
+	}
+	result.HealthScore = &health
+
+	var fit float64
+	switch result.Signal {
+	case "BUY":
+		fit = float64(health)
+	case "SELL":
+		fit = float64(100 - health)
+	default:
+		fit = 100 - math.Abs(float64(health)-50)*2
+	}
+
+	consensus := 50.0
+	if result.RuleConfirmed {
+		consensus = 100
+	} else if result.RuleConflict {
+		consensus = 0
+	}
+
+	accuracy := 50.0
+	if weighting := a.AnalysisConfig.AccuracyWeighting; weighting != nil && result.Accuracy != nil &&
+		result.Accuracy.Samples >= weighting.MinSamples && result.Accuracy.Samples > 0 {
+		accuracy = result.Accuracy.HitRate
+	}
+
+	score := int(math.Round(fit*systemScoreHealthWeight + consensus*systemScoreConsensusWeight + accuracy*systemScoreAccuracyWeight))
+	result.SystemScore = &score
+	return true
+}
+
+// scoringWeights 当前使用的健康度权重
+func (a *StockAnalyzer) scoringWeights() ScoringWeights {
+	if a.AnalysisConfig.Scoring != nil {
+		return a.AnalysisConfig.Scoring.Weights()
+	}
+	return DefaultScoringWeights()
+}
@@ -0,0 +1,27 @@
+package stock
+
+import "testing"
+
+func TestScoringModelUpdateWeightsMergesPartialPatch(t *testing.T) {
+	model := NewScoringModel(DefaultScoringWeights())
+	rsi := 40.0
+	previous, weights, err := model.UpdateWeights(ScoringWeightsPatch{RSI: &rsi})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != DefaultScoringWeights() {
+		t.Errorf("调整前的权重应为默认权重，实际 %+v", previous)
+	}
+	want := ScoringWeights{MA: 30, RSI: 40, MACD: 30, Volume: 15}
+	if weights != want || model.Weights() != want {
+		t.Errorf("未填写的权重应保持当前值，期望 %+v，实际 %+v", want, weights)
+	}
+
+	negative := -1.0
+	if _, _, err := model.UpdateWeights(ScoringWeightsPatch{MA: &negative}); err == nil {
+		t.Error("负数权重应被拒绝")
+	}
+	if model.Weights() != want {
+		t.Errorf("校验失败时不应修改权重，实际 %+v", model.Weights())
+	}
+}
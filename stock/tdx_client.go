@@ -2,18 +2,49 @@ package stock
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"nofx/config"
 )
 
 // TDXClient TDX股票数据API客户端
 type TDXClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
+
+	chipUnsupported         int32 // TDX代理不提供筹码分布接口（返回404）时置1，之后不再请求
+	klineHistoryUnsupported int32 // TDX代理不提供历史K线接口（返回404）时置1，之后不再增量更新
+	shareCapitalUnsupported int32 // TDX代理不提供股本接口（返回404）时置1，之后不再请求
+
+	// K线缓存（由暖机预拉写入，过期后自动回源）
+	klineCache map[string]klineCacheEntry
+	cacheMutex sync.RWMutex
+
+	// 最近一次获取的K线（增量更新的基础），只追加新K线而不重拉全部
+	klineBase map[string]*KlineData
+	baseMutex sync.Mutex
+
+	// K线磁盘缓存（L2）目录（为空表示不落盘），跨重启保留K线数据
+	diskCacheDir   string
+	diskValidUntil func(savedAt time.Time) time.Time // 磁盘缓存的有效期
+	diskLoaded     map[string]bool                   // 已读取过磁盘缓存的缓存键（由baseMutex保护）
+	diskExpires    map[string]time.Time              // 磁盘中各缓存的有效期（由baseMutex保护）
+}
+
+// klineCacheEntry K线缓存条目
+type klineCacheEntry struct {
+	data      *KlineData
+	expiresAt time.Time
 }
 
 // NewTDXClient 创建新的TDX客户端
@@ -23,6 +54,10 @@ func NewTDXClient(baseURL string) *TDXClient {
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		klineCache:  make(map[string]klineCacheEntry),
+		klineBase:   make(map[string]*KlineData),
+		diskLoaded:  make(map[string]bool),
+		diskExpires: make(map[string]time.Time),
 	}
 }
 
@@ -71,6 +106,46 @@ type Level struct {
 type KlineData struct {
 	Count int         `json:"Count"`
 	List  []KlineItem `json:"List"`
+
+	// SuspensionGaps 停牌缺口（仅日K线，由客户端根据日期连续性计算，非TDX返回字段）
+	SuspensionGaps []SuspensionGap `json:"-"`
+}
+
+// SuspensionGap 日K线中的停牌缺口（相邻两根K线之间缺失了交易日）
+type SuspensionGap struct {
+	From        time.Time `json:"from"`         // 停牌前最后一个交易日
+	To          time.Time `json:"to"`           // 复牌后第一个交易日
+	MissingDays int       `json:"missing_days"` // 缺失的交易日数
+	Index       int       `json:"index"`        // 复牌后第一根K线在List中的下标
+}
+
+// detectSuspensionGaps 检测日K线中的停牌缺口：相邻两根K线之间存在未出现的交易日（非周末、非节假日）
+func detectSuspensionGaps(list []KlineItem) []SuspensionGap {
+	var gaps []SuspensionGap
+	for i := 1; i < len(list); i++ {
+		prev := list[i-1].Time
+		curr := list[i].Time
+		missing := 0
+		for d := prev.AddDate(0, 0, 1); d.Before(curr) && !sameDay(d, curr); d = d.AddDate(0, 0, 1) {
+			if isWeekdayTradingDay(d) {
+				missing++
+			}
+		}
+		if missing > 0 {
+			gaps = append(gaps, SuspensionGap{
+				From:        prev,
+				To:          curr,
+				MissingDays: missing,
+				Index:       i,
+			})
+		}
+	}
+	return gaps
+}
+
+// sameDay 判断两个时间是否为同一天
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
 }
 
 // KlineItem K线单条数据
@@ -83,8 +158,9 @@ type KlineItem struct {
 	Volume    int64     `json:"Volume"` // 成交量（手）
 	Amount    float64   `json:"Amount"` // 成交额（厘，可能是浮点数）
 	Time      time.Time `json:"Time"`
-	UpCount   int       `json:"UpCount"`   // 上涨数
-	DownCount int       `json:"DownCount"` // 下跌数
+	UpCount   int       `json:"UpCount"`           // 上涨数
+	DownCount int       `json:"DownCount"`         // 下跌数
+	Partial   bool      `json:"Partial,omitempty"` // 当前未收盘的K线（由分时数据实时合成）
 }
 
 // MinuteData 分时数据
@@ -100,6 +176,20 @@ type MinuteItem struct {
 	Number int    `json:"Number"` // 成交量（手）
 }
 
+// ChipDistribution 筹码分布（成本分布）数据
+type ChipDistribution struct {
+	Code        string  `json:"Code"`
+	ProfitRatio float64 `json:"ProfitRatio"` // 获利盘比例（%，当前价以下的筹码占比）
+	AvgCost     int     `json:"AvgCost"`     // 平均成本（厘）
+	Cost70Low   int     `json:"Cost70Low"`   // 70%筹码集中区间下沿（厘）
+	Cost70High  int     `json:"Cost70High"`  // 70%筹码集中区间上沿（厘）
+	Cost90Low   int     `json:"Cost90Low"`   // 90%筹码集中区间下沿（厘）
+	Cost90High  int     `json:"Cost90High"`  // 90%筹码集中区间上沿（厘）
+}
+
+// ErrChipUnsupported TDX代理不提供筹码分布数据
+var ErrChipUnsupported = errors.New("TDX代理不支持筹码分布接口")
+
 // SearchResult 搜索结果
 type SearchResult struct {
 	Code string `json:"code"`
@@ -129,6 +219,9 @@ func (c *TDXClient) GetQuote(code string) (*QuoteData, error) {
 		return nil, fmt.Errorf("API错误: %s", apiResp.Message)
 	}
 
+	if err := validateTDXData(apiResp.Data, tdxQuoteSchema); err != nil {
+		return nil, err
+	}
 	var quotes []QuoteData
 	if err := json.Unmarshal(apiResp.Data, &quotes); err != nil {
 		return nil, fmt.Errorf("解析行情数据失败: %w", err)
@@ -141,17 +234,113 @@ func (c *TDXClient) GetQuote(code string) (*QuoteData, error) {
 	return &quotes[0], nil
 }
 
-// GetKline 获取K线数据
+// 单次获取K线数量的上限，避免误传超大数量时一次性拉取和计算超大数组，保护内存和TDX代理
+const (
+	MaxDayKlineLimit    = config.MaxDayKlineCount    // 日/周/月K线最多500根
+	MaxMinuteKlineLimit = config.MaxMinuteKlineCount // 分钟/小时K线最多1000根
+)
+
+// KlineLimitCap K线周期对应的数量上限
+func KlineLimitCap(klineType string) int {
+	switch klineType {
+	case "day", "week", "month":
+		return MaxDayKlineLimit
+	default:
+		return MaxMinuteKlineLimit
+	}
+}
+
+// capKlineLimit 超出上限时截断为上限并告警
+func capKlineLimit(code string, klineType string, limit int) int {
+	if maxLimit := KlineLimitCap(klineType); limit > maxLimit {
+		log.Printf("⚠️  [%s] 请求%s K线%d根超过上限%d，已截断", code, klineType, limit, maxLimit)
+		return maxLimit
+	}
+	return limit
+}
+
+// GetKline 获取K线数据（数量超过上限时截断，见KlineLimitCap）
 // adjust参数: 0=不复权(默认), 1=前复权, 2=后复权
 // 为了与实时行情价格一致，默认使用不复权数据(adjust=0)
 func (c *TDXClient) GetKline(code string, klineType string, limit int) (*KlineData, error) {
+	limit = capKlineLimit(code, klineType, limit)
+	if cached, ok := c.getCachedKline(code, klineType, limit); ok {
+		return cached, nil
+	}
+	if cached, ok := c.loadKlineFromDisk(code, klineType, limit); ok {
+		return cached, nil
+	}
+	return c.getKlineIncremental(code, klineType, limit)
+}
+
+// WarmKline 预拉K线数据写入缓存，缓存在expiresAt之前有效
+func (c *TDXClient) WarmKline(code string, klineType string, limit int, expiresAt time.Time) error {
+	limit = capKlineLimit(code, klineType, limit)
+	data, err := c.fetchKline(code, klineType, limit)
+	if err != nil {
+		return err
+	}
+
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	c.klineCache[klineCacheKey(code, klineType, limit)] = klineCacheEntry{data: data, expiresAt: expiresAt}
+	return nil
+}
+
+// getCachedKline 读取未过期的K线缓存（返回副本，避免调用方修改缓存），过期条目顺便清理
+func (c *TDXClient) getCachedKline(code string, klineType string, limit int) (*KlineData, bool) {
+	return c.getCachedKlineByKey(klineCacheKey(code, klineType, limit))
+}
+
+// getCachedKlineByKey 按缓存键读取未过期的K线缓存
+func (c *TDXClient) getCachedKlineByKey(key string) (*KlineData, bool) {
+	c.cacheMutex.RLock()
+	entry, ok := c.klineCache[key]
+	c.cacheMutex.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		c.cacheMutex.Lock()
+		delete(c.klineCache, key)
+		c.cacheMutex.Unlock()
+		return nil, false
+	}
+
+	data := *entry.data
+	data.List = append([]KlineItem(nil), entry.data.List...)
+	return &data, true
+}
+
+// klineCacheKey K线缓存键
+func klineCacheKey(code string, klineType string, limit int) string {
+	return fmt.Sprintf("%s|%s|%d", code, klineType, limit)
+}
+
+// fetchKline 从TDX接口获取K线数据（不经过缓存）
+func (c *TDXClient) fetchKline(code string, klineType string, limit int) (*KlineData, error) {
 	url := fmt.Sprintf("%s/api/kline?code=%s&type=%s&adjust=0", c.BaseURL, code, klineType)
+	klineData, err := c.requestKline(url)
+	if err != nil {
+		return nil, err
+	}
+	finishKline(klineData, klineType, limit)
+	return klineData, nil
+}
+
+// requestKline 请求K线接口并解析响应
+func (c *TDXClient) requestKline(url string) (*KlineData, error) {
 	resp, err := c.HTTPClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errKlineNotFound
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("读取响应失败: %w", err)
@@ -166,18 +355,28 @@ func (c *TDXClient) GetKline(code string, klineType string, limit int) (*KlineDa
 		return nil, fmt.Errorf("API错误: %s", apiResp.Message)
 	}
 
+	if err := validateTDXData(apiResp.Data, tdxKlineSchema); err != nil {
+		return nil, err
+	}
 	var klineData KlineData
 	if err := json.Unmarshal(apiResp.Data, &klineData); err != nil {
 		return nil, fmt.Errorf("解析K线数据失败: %w", err)
 	}
+	return &klineData, nil
+}
 
+// finishKline 截取最近limit条K线，日K线标记停牌缺口
+func finishKline(klineData *KlineData, klineType string, limit int) {
 	// 限制返回数量（取最近的limit条，而不是最旧的limit条）
 	if limit > 0 && len(klineData.List) > limit {
 		klineData.List = klineData.List[len(klineData.List)-limit:]
 		klineData.Count = limit
 	}
 
-	return &klineData, nil
+	// 日K线标记停牌缺口（日期不连续）
+	if klineType == "day" {
+		klineData.SuspensionGaps = detectSuspensionGaps(klineData.List)
+	}
 }
 
 // GetMinute 获取分时数据
@@ -215,6 +414,50 @@ func (c *TDXClient) GetMinute(code string, date string) (*MinuteData, error) {
 	return &minuteData, nil
 }
 
+// GetChipDistribution 获取筹码分布
+// 并非所有TDX代理都提供该接口：返回404时记录为不支持，后续直接返回ErrChipUnsupported
+func (c *TDXClient) GetChipDistribution(code string) (*ChipDistribution, error) {
+	if atomic.LoadInt32(&c.chipUnsupported) == 1 {
+		return nil, ErrChipUnsupported
+	}
+
+	urlStr := fmt.Sprintf("%s/api/chip?code=%s", c.BaseURL, code)
+	resp, err := c.HTTPClient.Get(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		atomic.StoreInt32(&c.chipUnsupported, 1)
+		return nil, ErrChipUnsupported
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var apiResp APIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if apiResp.Code != 0 {
+		return nil, fmt.Errorf("API错误: %s", apiResp.Message)
+	}
+
+	var chip ChipDistribution
+	if err := json.Unmarshal(apiResp.Data, &chip); err != nil {
+		return nil, fmt.Errorf("解析筹码分布失败: %w", err)
+	}
+	if chip.AvgCost <= 0 {
+		return nil, fmt.Errorf("筹码分布数据为空")
+	}
+
+	return &chip, nil
+}
+
 // SearchStock 搜索股票
 func (c *TDXClient) SearchStock(keyword string) ([]SearchResult, error) {
 	urlStr := fmt.Sprintf("%s/api/search?keyword=%s", c.BaseURL, url.QueryEscape(keyword))
@@ -272,6 +515,9 @@ func (c *TDXClient) BatchGetQuote(codes []string) ([]QuoteData, error) {
 		return nil, fmt.Errorf("API错误: %s", apiResp.Message)
 	}
 
+	if err := validateTDXData(apiResp.Data, tdxQuoteSchema); err != nil {
+		return nil, err
+	}
 	var quotes []QuoteData
 	if err := json.Unmarshal(apiResp.Data, &quotes); err != nil {
 		return nil, fmt.Errorf("解析行情数据失败: %w", err)
@@ -294,3 +540,52 @@ func VolumeToShares(hands int64) int64 {
 func AmountToYuan(amount float64) float64 {
 	return amount / 1000.0
 }
+
+// amountUnits 成交额可能的原始单位（名称 -> 换算为元的除数）
+var amountUnits = []struct {
+	name    string
+	divisor float64
+}{
+	{"厘", 1000.0},
+	{"分", 100.0},
+	{"元", 1.0},
+}
+
+// NormalizeAmountToYuan 校验成交额单位并统一换算为元
+// TDX文档约定成交额单位为厘，但不同版本的代理可能返回分或元。
+// 这里用 成交量(手)×100×价格(元) 估算成交额，选择与估算值数量级最接近的单位；
+// 成交量或价格缺失无法校验时按厘换算。
+func NormalizeAmountToYuan(amount float64, volumeHands int64, priceLi int) float64 {
+	if amount <= 0 || volumeHands <= 0 || priceLi <= 0 {
+		return AmountToYuan(amount)
+	}
+
+	estimated := float64(VolumeToShares(volumeHands)) * PriceToYuan(priceLi)
+	best := amountUnits[0]
+	bestDistance := math.Inf(1)
+	for _, unit := range amountUnits {
+		distance := math.Abs(math.Log10(amount / unit.divisor / estimated))
+		if distance < bestDistance {
+			best = unit
+			bestDistance = distance
+		}
+	}
+	return amount / best.divisor
+}
+
+// AmountToWanYuan 将成交额（元）转换为万元
+func AmountToWanYuan(yuan float64) float64 {
+	return yuan / 10000.0
+}
+
+// FormatAmount 按数量级格式化成交额（元），小额显示元，万级显示万元，亿级显示亿元
+func FormatAmount(yuan float64) string {
+	switch {
+	case math.Abs(yuan) >= 1e8:
+		return fmt.Sprintf("%.2f亿元", yuan/1e8)
+	case math.Abs(yuan) >= 1e4:
+		return fmt.Sprintf("%.2f万元", AmountToWanYuan(yuan))
+	default:
+		return fmt.Sprintf("%.2f元", yuan)
+	}
+}
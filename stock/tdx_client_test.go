@@ -0,0 +1,46 @@
+package stock
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeAmountToYuan(t *testing.T) {
+	// 成交1000手、现价10.00元（10000厘），实际成交额约100万元
+	const hands, priceLi = 1000, 10000
+	cases := []struct {
+		name   string
+		amount float64
+		want   float64
+	}{
+		{"厘", 1.0005e9, 1000500},
+		{"分", 1.0005e8, 1000500},
+		{"元", 1000500, 1000500},
+	}
+	for _, tc := range cases {
+		if got := NormalizeAmountToYuan(tc.amount, hands, priceLi); math.Abs(got-tc.want) > 1e-6 {
+			t.Errorf("单位为%s时换算为 %.2f 元，期望 %.2f", tc.name, got, tc.want)
+		}
+	}
+
+	// 成交量或价格缺失无法校验时按厘换算
+	if got := NormalizeAmountToYuan(123456, 0, priceLi); got != 123.456 {
+		t.Errorf("无法校验时应按厘换算，实际 %v", got)
+	}
+}
+
+func TestFormatAmountKeepsPrecision(t *testing.T) {
+	cases := map[float64]string{
+		8500:       "8500.00元",
+		12345:      "1.23万元",
+		1000500:    "100.05万元",
+		2.5e8:      "2.50亿元",
+		-3.21e4:    "-3.21万元",
+		123.456e10: "12345.60亿元",
+	}
+	for yuan, want := range cases {
+		if got := FormatAmount(yuan); got != want {
+			t.Errorf("FormatAmount(%v) = %s，期望 %s", yuan, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package stock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// tdxKind TDX响应字段的JSON类型
+type tdxKind int
+
+const (
+	tdxNumber tdxKind = iota
+	tdxString
+	tdxBool
+	tdxObject
+	tdxArray
+)
+
+// tdxKindNames 类型的中文名称（用于错误提示）
+var tdxKindNames = map[tdxKind]string{
+	tdxNumber: "数字",
+	tdxString: "字符串",
+	tdxBool:   "布尔值",
+	tdxObject: "对象",
+	tdxArray:  "数组",
+}
+
+// tdxField TDX响应字段的结构约定：Required为true时必须存在，否则存在时才校验类型（数组允许为null）；
+// 对象校验Fields中的子字段，数组校验每个元素（元素为对象时按Items校验）
+type tdxField struct {
+	Name     string
+	Kind     tdxKind
+	Required bool
+	Fields   []tdxField
+	Items    []tdxField
+}
+
+// TDXSchemaError TDX响应不符合约定的结构（代理版本升级改了字段时便于定位）
+type TDXSchemaError struct {
+	Path     string // 字段路径，如 data[0].K.Close、data.List[3].Time
+	Missing  bool   // 缺少字段（否则为类型不符）
+	Expected string // 期望的类型
+	Actual   string // 实际的类型
+}
+
+func (e *TDXSchemaError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("TDX响应缺少字段 %s", e.Path)
+	}
+	return fmt.Sprintf("TDX响应字段 %s 类型错误: 期望%s，实际为%s", e.Path, e.Expected, e.Actual)
+}
+
+// tdxKFields 行情中K字段（价格单位为厘）
+var tdxKFields = []tdxField{
+	{Name: "Last", Kind: tdxNumber, Required: true},
+	{Name: "Open", Kind: tdxNumber, Required: true},
+	{Name: "High", Kind: tdxNumber, Required: true},
+	{Name: "Low", Kind: tdxNumber, Required: true},
+	{Name: "Close", Kind: tdxNumber, Required: true},
+}
+
+// tdxLevelFields 盘口档位
+var tdxLevelFields = []tdxField{
+	{Name: "Price", Kind: tdxNumber, Required: true},
+	{Name: "Number", Kind: tdxNumber, Required: true},
+}
+
+// tdxQuoteSchema 五档行情接口的data（行情数组）
+var tdxQuoteSchema = tdxField{Kind: tdxArray, Items: []tdxField{
+	{Name: "Code", Kind: tdxString, Required: true},
+	{Name: "K", Kind: tdxObject, Required: true, Fields: tdxKFields},
+	{Name: "TotalHand", Kind: tdxNumber, Required: true},
+	{Name: "Amount", Kind: tdxNumber, Required: true},
+	{Name: "InsideDish", Kind: tdxNumber},
+	{Name: "OuterDisc", Kind: tdxNumber},
+	{Name: "Intuition", Kind: tdxNumber},
+	{Name: "Rate", Kind: tdxNumber},
+	{Name: "ServerTime", Kind: tdxString},
+	{Name: "BuyLevel", Kind: tdxArray, Items: tdxLevelFields},
+	{Name: "SellLevel", Kind: tdxArray, Items: tdxLevelFields},
+}}
+
+// tdxKlineSchema K线接口的data
+var tdxKlineSchema = tdxField{Kind: tdxObject, Fields: []tdxField{
+	{Name: "Count", Kind: tdxNumber},
+	{Name: "List", Kind: tdxArray, Required: true, Items: []tdxField{
+		{Name: "Open", Kind: tdxNumber, Required: true},
+		{Name: "High", Kind: tdxNumber, Required: true},
+		{Name: "Low", Kind: tdxNumber, Required: true},
+		{Name: "Close", Kind: tdxNumber, Required: true},
+		{Name: "Time", Kind: tdxString, Required: true},
+		{Name: "Last", Kind: tdxNumber},
+		{Name: "Volume", Kind: tdxNumber},
+		{Name: "Amount", Kind: tdxNumber},
+	}},
+}}
+
+// validateTDXData 按结构约定校验TDX响应的data字段（在反序列化之前调用，错误信息带字段路径）
+func validateTDXData(data json.RawMessage, schema tdxField) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("TDX响应data不是有效的JSON: %w", err)
+	}
+	return validateTDXValue(value, "data", schema)
+}
+
+// validateTDXValue 校验单个值的类型及其子字段
+func validateTDXValue(value interface{}, path string, field tdxField) error {
+	if actual := tdxKindOf(value); actual != field.Kind {
+		// 数组字段允许为null（Go的nil切片序列化为null）
+		if value == nil && field.Kind == tdxArray {
+			return nil
+		}
+		return &TDXSchemaError{Path: path, Expected: tdxKindNames[field.Kind], Actual: tdxKindName(value)}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateTDXObject(v, path, field.Fields)
+	case []interface{}:
+		if len(field.Items) == 0 {
+			return nil
+		}
+		for i, item := range v {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				return &TDXSchemaError{Path: itemPath, Expected: tdxKindNames[tdxObject], Actual: tdxKindName(item)}
+			}
+			if err := validateTDXObject(object, itemPath, field.Items); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateTDXObject 校验对象的字段
+func validateTDXObject(object map[string]interface{}, path string, fields []tdxField) error {
+	for _, field := range fields {
+		fieldPath := path + "." + field.Name
+
+		value, ok := object[field.Name]
+		if !ok {
+			if field.Required {
+				return &TDXSchemaError{Path: fieldPath, Missing: true}
+			}
+			continue
+		}
+		if err := validateTDXValue(value, fieldPath, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tdxKindOf 值的JSON类型（null返回-1）
+func tdxKindOf(value interface{}) tdxKind {
+	switch value.(type) {
+	case json.Number:
+		return tdxNumber
+	case string:
+		return tdxString
+	case bool:
+		return tdxBool
+	case map[string]interface{}:
+		return tdxObject
+	case []interface{}:
+		return tdxArray
+	default:
+		return -1
+	}
+}
+
+// tdxKindName 值的类型名称
+func tdxKindName(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return tdxKindNames[tdxKindOf(value)]
+}
@@ -0,0 +1,34 @@
+package stock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+// newTraceID 生成单次分析的链路追踪ID（12位十六进制）
+func newTraceID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%012x", time.Now().UnixNano()&0xffffffffffff)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// tracef 打印带链路追踪ID的日志（行尾追加" | trace=<ID>"，可按ID过滤单次分析的完整链路），traceID为空时与log.Printf相同
+func tracef(traceID, format string, args ...interface{}) {
+	if traceID != "" {
+		format += " | trace=" + traceID
+	}
+	log.Printf(format, args...)
+}
+
+// withTrace 在错误信息中附加链路追踪ID（保留原错误，errors.Is/As仍可判断）
+func withTrace(traceID string, err error) error {
+	if err == nil || traceID == "" {
+		return err
+	}
+	return fmt.Errorf("%w | trace=%s", err, traceID)
+}
@@ -0,0 +1,234 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Order 下单请求（限价，数量需为最小交易单位的整数倍）
+type Order struct {
+	StockCode string  `json:"stock_code"`
+	StockName string  `json:"stock_name"`
+	Side      string  `json:"side"`     // 买卖方向：BUY/SELL
+	Price     float64 `json:"price"`    // 委托价格（元）
+	Quantity  int     `json:"quantity"` // 委托数量
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// Fill 成交回报
+type Fill struct {
+	OrderID   string    `json:"order_id"`
+	StockCode string    `json:"stock_code"`
+	StockName string    `json:"stock_name"`
+	Side      string    `json:"side"`
+	Price     float64   `json:"price"`    // 成交价格（元）
+	Quantity  int       `json:"quantity"` // 成交数量
+	Fee       float64   `json:"fee"`      // 费用合计（元）
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Trader 交易接口：目前只有模拟盘实现，真实券商接入后实现同一接口即可替换
+type Trader interface {
+	// PlaceOrder 下单，成交后返回成交回报
+	PlaceOrder(order Order) (*Fill, error)
+	// Position 股票当前持仓汇总（无持仓时数量为0）
+	Position(code string) (*TradeSummary, error)
+}
+
+// PaperTrader 模拟盘：委托按委托价全部成交，按费率扣费，维护虚拟资金和成交记录（持久化到JSON文件，重启后恢复）
+// 股票和ETF按T+1规则，当天买入的当天不能卖出（可转债T+0不受限）；ETF和可转债卖出不收印花税；虚拟持仓只在模拟盘内维护，与配置中的真实持仓互不影响
+type PaperTrader struct {
+	Clock Clock // 时钟（成交时间、T+1判断），测试时可注入固定时间
+
+	path     string
+	feeRates FeeRates
+	mutex    sync.Mutex
+	account  paperAccount
+}
+
+// paperAccount 模拟盘账户（持久化内容）
+type paperAccount struct {
+	InitialCash float64 `json:"initial_cash"` // 初始资金（元）
+	Cash        float64 `json:"cash"`         // 可用资金（元）
+	Fills       []Fill  `json:"fills"`        // 全部成交记录（按时间升序）
+}
+
+// PaperAccountSnapshot 模拟盘账户快照（用于API展示）
+type PaperAccountSnapshot struct {
+	InitialCash float64                  `json:"initial_cash"`
+	Cash        float64                  `json:"cash"`
+	Positions   map[string]*TradeSummary `json:"positions"` // 股票代码 -> 持仓汇总（不含已清仓的股票）
+	Fills       []Fill                   `json:"fills"`     // 最近的成交记录（最新的在前）
+}
+
+// NewPaperTrader 创建模拟盘，加载已有的账户文件（不存在时按初始资金新建）
+func NewPaperTrader(path string, initialCash float64, feeRates FeeRates) (*PaperTrader, error) {
+	trader := &PaperTrader{
+		Clock:    SystemClock{},
+		path:     path,
+		feeRates: feeRates,
+		account:  paperAccount{InitialCash: initialCash, Cash: initialCash},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return trader, nil
+	}
+	if err != nil {
+		return trader, fmt.Errorf("读取模拟盘账户失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &trader.account); err != nil {
+		return trader, fmt.Errorf("模拟盘账户文件格式错误: %w", err)
+	}
+	return trader, nil
+}
+
+// PlaceOrder 模拟成交：买入需资金足够（含费用），卖出不能超过持仓，T+1标的还不能卖出今天买入的部分
+func (t *PaperTrader) PlaceOrder(order Order) (*Fill, error) {
+	if order.Quantity <= 0 || order.Price <= 0 {
+		return nil, fmt.Errorf("委托价格和数量必须为正数")
+	}
+	if order.Side != "BUY" && order.Side != "SELL" {
+		return nil, fmt.Errorf("不支持的买卖方向: %s", order.Side)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	security := DetectSecurity(order.StockCode, order.StockName)
+	amount := yuan(order.Price).Mul(decimal.NewFromInt(int64(order.Quantity)))
+	fee := security.feeRates(t.feeRates).calculateFee(amount, order.Side == "SELL")
+	cash := yuan(t.account.Cash)
+
+	if order.Side == "BUY" {
+		if cost := amount.Add(fee); cost.GreaterThan(cash) {
+			return nil, fmt.Errorf("模拟盘可用资金不足: 需要%.2f元，可用%.2f元", cost.InexactFloat64(), t.account.Cash)
+		}
+		cash = cash.Sub(amount).Sub(fee)
+	} else {
+		summary, err := t.summaryLocked(order.StockCode)
+		if err != nil {
+			return nil, err
+		}
+		if order.Quantity > summary.Quantity {
+			return nil, fmt.Errorf("模拟盘持仓不足: 卖出%d，持有%d", order.Quantity, summary.Quantity)
+		}
+		if !security.TPlusZero() {
+			if sellable := t.sellableLocked(order.StockCode, summary.Quantity); order.Quantity > sellable {
+				return nil, fmt.Errorf("T+1限制: 今日买入的%s当日不可卖出，卖出%d，可卖%d", security.TypeName, order.Quantity, sellable)
+			}
+		}
+		cash = cash.Add(amount).Sub(fee)
+	}
+
+	now := t.Clock.Now()
+	fill := Fill{
+		OrderID:   fmt.Sprintf("paper-%d", now.UnixNano()),
+		StockCode: order.StockCode,
+		StockName: order.StockName,
+		Side:      order.Side,
+		Price:     order.Price,
+		Quantity:  order.Quantity,
+		Fee:       toCent(fee),
+		Time:      now,
+		Reason:    order.Reason,
+	}
+	previousCash := t.account.Cash
+	t.account.Cash = toCent(cash)
+	t.account.Fills = append(t.account.Fills, fill)
+	if err := t.saveLocked(); err != nil {
+		// 保存失败时回滚，避免内存与文件不一致
+		t.account.Cash = previousCash
+		t.account.Fills = t.account.Fills[:len(t.account.Fills)-1]
+		return nil, err
+	}
+	return &fill, nil
+}
+
+// sellableLocked 可卖数量：持仓减去今天买入的数量（调用方需持有锁）
+func (t *PaperTrader) sellableLocked(code string, quantity int) int {
+	today := t.Clock.Now().In(chinaTZ).Format("2006-01-02")
+	for _, fill := range t.account.Fills {
+		if fill.StockCode == code && fill.Side == "BUY" && fill.Time.In(chinaTZ).Format("2006-01-02") == today {
+			quantity -= fill.Quantity
+		}
+	}
+	if quantity < 0 {
+		return 0
+	}
+	return quantity
+}
+
+// Position 按成交记录计算股票的持仓汇总
+func (t *PaperTrader) Position(code string) (*TradeSummary, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.summaryLocked(code)
+}
+
+// Snapshot 账户快照，limit为返回的最近成交记录数（0表示全部）
+func (t *PaperTrader) Snapshot(limit int) PaperAccountSnapshot {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	snapshot := PaperAccountSnapshot{
+		InitialCash: t.account.InitialCash,
+		Cash:        t.account.Cash,
+		Positions:   make(map[string]*TradeSummary),
+	}
+	codes := make(map[string]bool)
+	for _, fill := range t.account.Fills {
+		codes[fill.StockCode] = true
+	}
+	for code := range codes {
+		if summary, err := t.summaryLocked(code); err == nil && summary.Quantity > 0 {
+			snapshot.Positions[code] = summary
+		}
+	}
+
+	for i := len(t.account.Fills) - 1; i >= 0; i-- {
+		if limit > 0 && len(snapshot.Fills) >= limit {
+			break
+		}
+		snapshot.Fills = append(snapshot.Fills, t.account.Fills[i])
+	}
+	return snapshot
+}
+
+// summaryLocked 把股票的成交转换为成交记录后计算持仓汇总（调用方需持有锁）
+func (t *PaperTrader) summaryLocked(code string) (*TradeSummary, error) {
+	var records []TradeRecord
+	for _, fill := range t.account.Fills {
+		if fill.StockCode == code {
+			records = append(records, TradeRecord{Date: fill.Time, Side: fill.Side, Price: fill.Price, Quantity: fill.Quantity, Fee: fill.Fee})
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Date.Before(records[j].Date) })
+	return CalculateTradeSummary(records)
+}
+
+// saveLocked 写入账户文件（先写临时文件再重命名，调用方需持有锁）
+func (t *PaperTrader) saveLocked() error {
+	data, err := json.MarshalIndent(t.account, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化模拟盘账户失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("创建模拟盘目录失败: %w", err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入模拟盘账户失败: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("写入模拟盘账户失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package stock
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPaperTraderEnforcesTPlusOne(t *testing.T) {
+	trader, err := NewPaperTrader(filepath.Join(t.TempDir(), "paper.json"), 100000, FeeRates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2025, 6, 9, 10, 0, 0, 0, chinaTZ)
+	trader.Clock = FixedClock(monday)
+
+	if _, err := trader.PlaceOrder(Order{StockCode: "000001", Side: "BUY", Price: 10, Quantity: 1000}); err != nil {
+		t.Fatalf("买入失败: %v", err)
+	}
+
+	trader.Clock = FixedClock(monday.Add(4 * time.Hour))
+	if _, err := trader.PlaceOrder(Order{StockCode: "000001", Side: "SELL", Price: 10.5, Quantity: 1000}); err == nil || !strings.Contains(err.Error(), "T+1") {
+		t.Fatalf("当日买入的股票当日卖出应被拒绝，实际: %v", err)
+	}
+
+	trader.Clock = FixedClock(monday.AddDate(0, 0, 1))
+	fill, err := trader.PlaceOrder(Order{StockCode: "000001", Side: "SELL", Price: 10.5, Quantity: 1000})
+	if err != nil {
+		t.Fatalf("次日卖出失败: %v", err)
+	}
+	if !fill.Time.Equal(monday.AddDate(0, 0, 1)) {
+		t.Fatalf("成交时间应使用注入的时钟，实际 %v", fill.Time)
+	}
+}
+
+func TestPaperTraderConvertibleIsTPlusZero(t *testing.T) {
+	trader, err := NewPaperTrader(filepath.Join(t.TempDir(), "paper.json"), 100000, FeeRates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2025, 6, 9, 10, 0, 0, 0, chinaTZ)
+	trader.Clock = FixedClock(monday)
+
+	if _, err := trader.PlaceOrder(Order{StockCode: "113050", Side: "BUY", Price: 120, Quantity: 100}); err != nil {
+		t.Fatalf("买入可转债失败: %v", err)
+	}
+	trader.Clock = FixedClock(monday.Add(time.Hour))
+	if _, err := trader.PlaceOrder(Order{StockCode: "113050", Side: "SELL", Price: 121, Quantity: 100}); err != nil {
+		t.Fatalf("可转债T+0，当日买入应可当日卖出: %v", err)
+	}
+	summary, err := trader.Position("113050")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Quantity != 0 {
+		t.Fatalf("卖出后应无持仓，实际 %d", summary.Quantity)
+	}
+}
+
+func TestPaperTraderFeesBySecurityType(t *testing.T) {
+	rates := FeeRates{CommissionRate: 0.00025, MinCommission: 5, StampDutyRate: 0.0005}
+	cases := []struct {
+		name string
+		code string
+		fee  float64
+	}{
+		// 卖出金额100000元：佣金25元，股票另收印花税50元
+		{"股票", "600000", 75},
+		{"ETF", "510300", 25},
+		{"可转债", "113050", 25},
+	}
+	for _, tc := range cases {
+		trader, err := NewPaperTrader(filepath.Join(t.TempDir(), "paper.json"), 200000, rates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		monday := time.Date(2025, 6, 9, 10, 0, 0, 0, chinaTZ)
+		trader.Clock = FixedClock(monday)
+		if _, err := trader.PlaceOrder(Order{StockCode: tc.code, Side: "BUY", Price: 100, Quantity: 1000}); err != nil {
+			t.Fatalf("%s: 买入失败: %v", tc.name, err)
+		}
+		trader.Clock = FixedClock(monday.AddDate(0, 0, 1))
+		fill, err := trader.PlaceOrder(Order{StockCode: tc.code, Side: "SELL", Price: 100, Quantity: 1000})
+		if err != nil {
+			t.Fatalf("%s: 卖出失败: %v", tc.name, err)
+		}
+		if fill.Fee != tc.fee {
+			t.Errorf("%s: 卖出费用应为%.2f元，实际%.2f元", tc.name, tc.fee, fill.Fee)
+		}
+	}
+}
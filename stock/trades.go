@@ -0,0 +1,222 @@
+package stock
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TradeRecord 一条成交记录
+type TradeRecord struct {
+	Date     time.Time `json:"date"`     // 成交日期
+	Side     string    `json:"side"`     // 买卖方向：BUY/SELL
+	Price    float64   `json:"price"`    // 成交价格（元/股）
+	Quantity int       `json:"quantity"` // 成交数量（股）
+	Fee      float64   `json:"fee"`      // 费用合计（元，含佣金、印花税、过户费）
+}
+
+// TradeSummary 根据成交记录计算出的持仓汇总
+type TradeSummary struct {
+	Quantity           int       `json:"quantity"`             // 当前净持仓（股）
+	AvgCost            float64   `json:"avg_cost"`             // 移动加权成本（元/股，不含费用）
+	RealizedProfitLoss float64   `json:"realized_profit_loss"` // 已实现盈亏（元，已扣除卖出部分对应的买入费用和卖出费用）
+	TotalFees          float64   `json:"total_fees"`           // 累计费用（元）
+	FirstBuyDate       time.Time `json:"first_buy_date"`       // 当前这轮持仓的首次买入日期（清仓后重新计算）
+	TradeCount         int       `json:"trade_count"`          // 成交笔数
+}
+
+// 成交记录CSV表头别名（兼容券商导出的中文表头）
+var tradeCSVColumns = map[string][]string{
+	"date":     {"date", "日期", "成交日期"},
+	"side":     {"side", "方向", "买卖方向", "买卖标志"},
+	"price":    {"price", "价格", "成交价格", "成交均价"},
+	"quantity": {"quantity", "数量", "成交数量"},
+	"fee":      {"fee", "费用", "手续费", "交易费用"},
+}
+
+// LoadTradeRecords 从CSV文件读取成交记录
+// 第一行为表头，需包含 date/side/price/quantity 列，fee 列可选
+func LoadTradeRecords(path string) ([]TradeRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开成交记录文件失败: %w", err)
+	}
+	defer file.Close()
+
+	return ParseTradeRecords(file)
+}
+
+// ParseTradeRecords 解析CSV格式的成交记录
+func ParseTradeRecords(r io.Reader) ([]TradeRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取成交记录表头失败: %w", err)
+	}
+
+	// 定位各列位置
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")) // 去掉Excel导出的BOM
+		for key, aliases := range tradeCSVColumns {
+			for _, alias := range aliases {
+				if strings.EqualFold(name, alias) {
+					index[key] = i
+				}
+			}
+		}
+	}
+	for _, key := range []string{"date", "side", "price", "quantity"} {
+		if _, ok := index[key]; !ok {
+			return nil, fmt.Errorf("成交记录缺少必需列: %s", key)
+		}
+	}
+
+	var records []TradeRecord
+	line := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("第%d行解析失败: %w", line, err)
+		}
+
+		field := func(key string) string {
+			i, ok := index[key]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		// 跳过空行
+		if field("date") == "" && field("side") == "" {
+			continue
+		}
+
+		record, err := parseTradeRow(field)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: %w", line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// parseTradeRow 解析单行成交记录
+func parseTradeRow(field func(key string) string) (TradeRecord, error) {
+	var record TradeRecord
+
+	date, err := parseTradeDate(field("date"))
+	if err != nil {
+		return record, err
+	}
+	record.Date = date
+
+	switch strings.ToUpper(field("side")) {
+	case "BUY", "B", "买入", "证券买入":
+		record.Side = "BUY"
+	case "SELL", "S", "卖出", "证券卖出":
+		record.Side = "SELL"
+	default:
+		return record, fmt.Errorf("无法识别的买卖方向: %s", field("side"))
+	}
+
+	record.Price, err = strconv.ParseFloat(field("price"), 64)
+	if err != nil || record.Price <= 0 {
+		return record, fmt.Errorf("成交价格无效: %s", field("price"))
+	}
+
+	record.Quantity, err = strconv.Atoi(field("quantity"))
+	if err != nil || record.Quantity <= 0 {
+		return record, fmt.Errorf("成交数量无效: %s", field("quantity"))
+	}
+
+	if fee := field("fee"); fee != "" {
+		record.Fee, err = strconv.ParseFloat(fee, 64)
+		if err != nil || record.Fee < 0 {
+			return record, fmt.Errorf("费用无效: %s", fee)
+		}
+	}
+
+	return record, nil
+}
+
+// parseTradeDate 解析成交日期，支持 2006-01-02、2006/01/02、20060102 三种格式
+func parseTradeDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析成交日期: %s", s)
+}
+
+// CalculateTradeSummary 按时间顺序回放成交记录，计算净持仓、移动加权成本和已实现盈亏
+// 买入：成本 = (原持仓×原成本 + 买入数量×买入价) / 新持仓，买入费用按股摊入
+// 卖出：已实现盈亏 = (卖出价 - 成本)×卖出数量 - 卖出费用 - 卖出部分摊到的买入费用，成本不变
+// 成本和盈亏按十进制累计，避免多笔成交后产生分位误差
+func CalculateTradeSummary(records []TradeRecord) (*TradeSummary, error) {
+	sorted := make([]TradeRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	summary := &TradeSummary{TradeCount: len(sorted)}
+	avgCost := decimal.Zero     // 移动加权成本
+	feePerShare := decimal.Zero // 当前持仓每股摊到的买入费用
+	realized := decimal.Zero
+	totalFees := decimal.Zero
+
+	for _, t := range sorted {
+		price, fee, quantity := yuan(t.Price), yuan(t.Fee), decimal.NewFromInt(int64(t.Quantity))
+		totalFees = totalFees.Add(fee)
+
+		switch t.Side {
+		case "BUY":
+			if summary.Quantity == 0 {
+				summary.FirstBuyDate = t.Date
+			}
+			held := decimal.NewFromInt(int64(summary.Quantity))
+			newQuantity := decimal.NewFromInt(int64(summary.Quantity + t.Quantity))
+			avgCost = avgCost.Mul(held).Add(price.Mul(quantity)).Div(newQuantity)
+			feePerShare = feePerShare.Mul(held).Add(fee).Div(newQuantity)
+			summary.Quantity += t.Quantity
+
+		case "SELL":
+			if t.Quantity > summary.Quantity {
+				return nil, fmt.Errorf("%s 卖出%d股超过当时持仓%d股，请检查成交记录是否完整",
+					t.Date.Format("2006-01-02"), t.Quantity, summary.Quantity)
+			}
+			realized = realized.Add(price.Sub(avgCost).Sub(feePerShare).Mul(quantity)).Sub(fee)
+			summary.Quantity -= t.Quantity
+
+			// 清仓后重置成本，下一轮持仓重新计算
+			if summary.Quantity == 0 {
+				avgCost = decimal.Zero
+				feePerShare = decimal.Zero
+				summary.FirstBuyDate = time.Time{}
+			}
+		}
+	}
+
+	summary.AvgCost = avgCost.Round(4).InexactFloat64()
+	summary.RealizedProfitLoss = toCent(realized)
+	summary.TotalFees = toCent(totalFees)
+	return summary, nil
+}
@@ -0,0 +1,80 @@
+package stock
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalculateTradeSummaryReplay(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, chinaTZ) }
+	rates := DefaultFeeRates()
+	records := []TradeRecord{
+		{Date: day(2), Side: "BUY", Price: 10.00, Quantity: 1000, Fee: 5.10},
+		{Date: day(3), Side: "BUY", Price: 10.60, Quantity: 500, Fee: 5.05},
+		{Date: day(5), Side: "SELL", Price: 11.00, Quantity: 800, Fee: 9.49},
+		{Date: day(6), Side: "SELL", Price: 10.50, Quantity: 700, Fee: 8.75},
+		{Date: day(9), Side: "BUY", Price: 9.80, Quantity: 200, Fee: 5.02},
+	}
+	// 用例中的费用与按默认费率计算的一致
+	for _, r := range records {
+		if fee := rates.CalculateFee(r.Price*float64(r.Quantity), r.Side == "SELL"); fee != r.Fee {
+			t.Fatalf("%s %d@%.2f 费用应为%.2f，实际%.2f", r.Side, r.Quantity, r.Price, r.Fee, fee)
+		}
+	}
+
+	summary, err := CalculateTradeSummary(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一轮清仓：卖出收入16150 - 买入成本15300 - 费用(5.10+5.05+9.49+8.75) = 821.61
+	if summary.RealizedProfitLoss != 821.61 {
+		t.Errorf("已实现盈亏应为821.61，实际%.2f", summary.RealizedProfitLoss)
+	}
+	// 清仓后成本重置，重新买入的持仓只按新成交计算
+	if summary.Quantity != 200 || summary.AvgCost != 9.8 {
+		t.Errorf("持仓应为200股、成本9.80，实际%d股、成本%.4f", summary.Quantity, summary.AvgCost)
+	}
+	if summary.TotalFees != 33.41 {
+		t.Errorf("累计费用应为33.41，实际%.2f", summary.TotalFees)
+	}
+	if !summary.FirstBuyDate.Equal(day(9)) || summary.TradeCount != 5 {
+		t.Errorf("首次买入日期应为重新买入日，成交5笔，实际 %v、%d笔", summary.FirstBuyDate, summary.TradeCount)
+	}
+}
+
+func TestCalculateTradeSummaryPartialSells(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, chinaTZ) }
+	summary, err := CalculateTradeSummary([]TradeRecord{
+		{Date: day(2), Side: "BUY", Price: 10.00, Quantity: 1000, Fee: 5.10},
+		{Date: day(3), Side: "SELL", Price: 12.00, Quantity: 300, Fee: 6.84},
+		{Date: day(4), Side: "SELL", Price: 9.00, Quantity: 200, Fee: 5.92},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每股摊到买入费用0.0051元：
+	// 第一笔 (12-10-0.0051)×300-6.84 = 591.63；第二笔 (9-10-0.0051)×200-5.92 = -206.94
+	if summary.RealizedProfitLoss != 384.69 {
+		t.Errorf("部分卖出的已实现盈亏应为384.69，实际%.2f", summary.RealizedProfitLoss)
+	}
+	// 部分卖出不改变剩余持仓的成本和首次买入日期
+	if summary.Quantity != 500 || summary.AvgCost != 10 || !summary.FirstBuyDate.Equal(day(2)) {
+		t.Errorf("剩余持仓应为500股、成本10.00、首次买入%v，实际%d股、成本%.4f、%v",
+			day(2), summary.Quantity, summary.AvgCost, summary.FirstBuyDate)
+	}
+	if summary.TotalFees != 17.86 {
+		t.Errorf("累计费用应为17.86，实际%.2f", summary.TotalFees)
+	}
+}
+
+func TestCalculateTradeSummaryRejectsOversell(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, chinaTZ) }
+	_, err := CalculateTradeSummary([]TradeRecord{
+		{Date: day(2), Side: "BUY", Price: 10, Quantity: 100, Fee: 5.01},
+		{Date: day(3), Side: "SELL", Price: 11, Quantity: 200, Fee: 6.13},
+	})
+	if err == nil || !strings.Contains(err.Error(), "卖出200股超过当时持仓100股") {
+		t.Fatalf("卖出超过持仓应返回错误，实际: %v", err)
+	}
+}
@@ -1,13 +1,18 @@
 package stock
 
 import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
 // TradingTimeConfig 交易时间配置
 type TradingTimeConfig struct {
 	EnableTradingTimeCheck bool     `json:"enable_trading_time_check"` // 是否启用交易时间检查
-	TradingHours           []string `json:"trading_hours"`             // 交易时段（如：["09:30-11:30", "13:00-15:00"]）
+	TradingHours           []string `json:"trading_hours"`             // 交易时段（如：["09:30-11:30", "13:00-15:00"]），结束早于开始表示跨午夜（如 "21:00-02:30"）
 	Timezone               string   `json:"timezone"`                  // 时区（如：Asia/Shanghai）
 }
 
@@ -27,6 +32,57 @@ func DefaultTradingTimeConfig() TradingTimeConfig {
 type TradingTimeChecker struct {
 	Config   TradingTimeConfig
 	Location *time.Location
+	Clock    Clock // 时钟，测试时可注入固定时间验证盘后、节假日等行为
+
+	periods []tradingPeriod // 解析后的交易时段（按开始时间排序）
+}
+
+// tradingPeriod 交易时段（自当天0点起的分钟数）
+// end < start 表示跨午夜的时段（如夜盘21:00-02:30），归属于开始的那个交易日，结束部分落在次日凌晨
+type tradingPeriod struct {
+	start int
+	end   int
+}
+
+// overnight 是否为跨午夜的时段
+func (p tradingPeriod) overnight() bool {
+	return p.end < p.start
+}
+
+// ParseTradingPeriod 解析交易时段（格式 HH:MM-HH:MM，允许空格），返回自0点起的开始、结束分钟数
+func ParseTradingPeriod(period string) (start, end int, ok bool) {
+	parts := strings.Split(period, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	startTime, err1 := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	endTime, err2 := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return startTime.Hour()*60 + startTime.Minute(), endTime.Hour()*60 + endTime.Minute(), true
+}
+
+// parseTradingPeriods 解析配置中的交易时段，格式错误的时段忽略，结果按开始时间排序
+func parseTradingPeriods(hours []string) []tradingPeriod {
+	var periods []tradingPeriod
+	for _, period := range hours {
+		if start, end, ok := ParseTradingPeriod(period); ok {
+			periods = append(periods, tradingPeriod{start: start, end: end})
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].start < periods[j].start })
+	return periods
+}
+
+// minuteOfDay 时间在当天的分钟数
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// atMinute 返回t所在日期第minute分钟的时间
+func atMinute(t time.Time, minute int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), minute/60, minute%60, 0, 0, t.Location())
 }
 
 // NewTradingTimeChecker 创建交易时间检查器
@@ -40,6 +96,8 @@ func NewTradingTimeChecker(config TradingTimeConfig) (*TradingTimeChecker, error
 	return &TradingTimeChecker{
 		Config:   config,
 		Location: loc,
+		Clock:    SystemClock{},
+		periods:  parseTradingPeriods(config.TradingHours),
 	}, nil
 }
 
@@ -54,8 +112,7 @@ func (tc *TradingTimeChecker) IsTradingDay(t time.Time) bool {
 		return false
 	}
 
-	// TODO: 可以添加节假日判断
-	// 这里可以集成节假日API或使用本地节假日数据
+	// 节假日（内置节假日表 + 配置 trading_time.holidays）
 	if tc.isHoliday(t) {
 		return false
 	}
@@ -70,20 +127,25 @@ func (tc *TradingTimeChecker) IsTradingTime(t time.Time) bool {
 		return true
 	}
 
-	// 首先判断是否是交易日
-	if !tc.IsTradingDay(t) {
-		return false
-	}
-
 	// 转换到配置的时区
 	t = t.In(tc.Location)
-
-	// 获取当前时间（只看时分）
-	currentTime := t.Format("15:04")
+	current := minuteOfDay(t)
+	tradingDay := tc.IsTradingDay(t)
 
 	// 检查是否在任一交易时段内
-	for _, period := range tc.Config.TradingHours {
-		if tc.isInTimePeriod(currentTime, period) {
+	for _, period := range tc.periods {
+		if !period.overnight() {
+			if tradingDay && current >= period.start && current <= period.end {
+				return true
+			}
+			continue
+		}
+
+		// 跨午夜时段：当天开始后的部分属于当天，次日凌晨的部分属于前一个交易日
+		if tradingDay && current >= period.start {
+			return true
+		}
+		if current <= period.end && tc.IsTradingDay(t.AddDate(0, 0, -1)) {
 			return true
 		}
 	}
@@ -91,53 +153,126 @@ func (tc *TradingTimeChecker) IsTradingTime(t time.Time) bool {
 	return false
 }
 
-// isInTimePeriod 判断时间是否在指定时段内
-func (tc *TradingTimeChecker) isInTimePeriod(current string, period string) bool {
-	// 解析时间段，格式：HH:MM-HH:MM
-	if len(period) < 11 {
-		return false
+// isHoliday 判断是否是节假日
+func (tc *TradingTimeChecker) isHoliday(t time.Time) bool {
+	return isMarketHoliday(t)
+}
+
+// marketHolidays 内置的A股休市日（周末以外的节假日，按国务院办公厅公布的节假日安排；逐年更新，
+// 新一年的安排公布前可通过配置 trading_time.holidays 补充，见RegisterMarketHolidays）
+var marketHolidays = map[string]bool{
+	"2025-01-01": true, // 元旦
+	"2025-01-28": true, // 春节
+	"2025-01-29": true, // 春节
+	"2025-01-30": true, // 春节
+	"2025-01-31": true, // 春节
+	"2025-02-01": true, // 春节
+	"2025-02-02": true, // 春节
+	"2025-02-03": true, // 春节
+	"2025-02-04": true, // 春节
+	"2025-04-04": true, // 清明节
+	"2025-04-05": true, // 清明节
+	"2025-04-06": true, // 清明节
+	"2025-05-01": true, // 劳动节
+	"2025-05-02": true, // 劳动节
+	"2025-05-03": true, // 劳动节
+	"2025-05-04": true, // 劳动节
+	"2025-05-05": true, // 劳动节
+	"2025-05-31": true, // 端午节
+	"2025-06-01": true, // 端午节
+	"2025-06-02": true, // 端午节
+	"2025-10-01": true, // 国庆节、中秋节
+	"2025-10-02": true, // 国庆节、中秋节
+	"2025-10-03": true, // 国庆节、中秋节
+	"2025-10-04": true, // 国庆节、中秋节
+	"2025-10-05": true, // 国庆节、中秋节
+	"2025-10-06": true, // 国庆节、中秋节
+	"2025-10-07": true, // 国庆节、中秋节
+	"2025-10-08": true, // 国庆节、中秋节
+	"2026-01-01": true, // 元旦
+	"2026-01-02": true, // 元旦
+	"2026-02-16": true, // 春节
+	"2026-02-17": true, // 春节
+	"2026-02-18": true, // 春节
+	"2026-02-19": true, // 春节
+	"2026-02-20": true, // 春节
+	"2026-02-23": true, // 春节
+	"2026-04-06": true, // 清明节
+	"2026-05-01": true, // 劳动节
+	"2026-05-04": true, // 劳动节
+	"2026-05-05": true, // 劳动节
+	"2026-06-19": true, // 端午节
+	"2026-09-25": true, // 中秋节
+	"2026-10-01": true, // 国庆节
+	"2026-10-02": true, // 国庆节
+	"2026-10-05": true, // 国庆节
+	"2026-10-06": true, // 国庆节
+	"2026-10-07": true, // 国庆节
+}
+
+var (
+	holidayMutex       sync.RWMutex
+	holidayYears       = holidayYearSet(marketHolidays) // 节假日表覆盖的年份
+	warnedHolidayYears = make(map[int]bool)             // 已告警缺少节假日数据的年份（每年只告警一次）
+)
+
+// holidayYearSet 节假日表覆盖的年份
+func holidayYearSet(holidays map[string]bool) map[int]bool {
+	years := make(map[int]bool)
+	for date := range holidays {
+		if t, err := time.Parse("2006-01-02", date); err == nil {
+			years[t.Year()] = true
+		}
 	}
+	return years
+}
 
-	// 简单的字符串比较（因为格式固定为HH:MM）
-	start := period[:5]
-	end := period[6:]
+// RegisterMarketHolidays 补充休市日期（YYYY-MM-DD，来自配置 trading_time.holidays），格式错误时返回错误且不做任何修改
+func RegisterMarketHolidays(dates []string) error {
+	parsed := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		t, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+		if err != nil {
+			return fmt.Errorf("休市日期格式错误（应为YYYY-MM-DD）: %s", date)
+		}
+		parsed = append(parsed, t)
+	}
 
-	return current >= start && current <= end
+	holidayMutex.Lock()
+	defer holidayMutex.Unlock()
+	for _, t := range parsed {
+		marketHolidays[t.Format("2006-01-02")] = true
+		holidayYears[t.Year()] = true
+	}
+	return nil
 }
 
-// isHoliday 判断是否是节假日
-func (tc *TradingTimeChecker) isHoliday(t time.Time) bool {
-	// 这里可以实现节假日判断逻辑
-	// 可以集成节假日API或使用本地数据
-
-	// 简单示例：2025年节假日（需要更新）
-	holidays := map[string]bool{
-		"2025-01-01": true, // 元旦
-		"2025-01-28": true, // 春节
-		"2025-01-29": true, // 春节
-		"2025-01-30": true, // 春节
-		"2025-01-31": true, // 春节
-		"2025-02-01": true, // 春节
-		"2025-02-02": true, // 春节
-		"2025-02-03": true, // 春节
-		"2025-04-04": true, // 清明节
-		"2025-04-05": true, // 清明节
-		"2025-04-06": true, // 清明节
-		"2025-05-01": true, // 劳动节
-		"2025-05-02": true, // 劳动节
-		"2025-05-03": true, // 劳动节
-		"2025-06-10": true, // 端午节
-		"2025-10-01": true, // 国庆节
-		"2025-10-02": true, // 国庆节
-		"2025-10-03": true, // 国庆节
-		"2025-10-04": true, // 国庆节
-		"2025-10-05": true, // 国庆节
-		"2025-10-06": true, // 国庆节
-		"2025-10-07": true, // 国庆节
-	}
-
-	dateStr := t.Format("2006-01-02")
-	return holidays[dateStr]
+// isMarketHoliday 判断某天是否是节假日（不含周末）
+// 节假日表没有该年份的数据时按非节假日处理，并醒目告警一次（节假日会被当作交易日，影响停牌缺口识别、交易时段判断等）
+func isMarketHoliday(t time.Time) bool {
+	holidayMutex.RLock()
+	holiday := marketHolidays[t.Format("2006-01-02")]
+	covered := holidayYears[t.Year()]
+	holidayMutex.RUnlock()
+
+	if !covered {
+		holidayMutex.Lock()
+		if !warnedHolidayYears[t.Year()] {
+			warnedHolidayYears[t.Year()] = true
+			log.Printf("🚨🚨 节假日表缺少%d年的休市安排，该年节假日将被视为交易日（停牌缺口误判、非交易日照常分析）！请在配置 trading_time.holidays 中补充当年休市日期，或升级程序", t.Year())
+		}
+		holidayMutex.Unlock()
+	}
+	return holiday
+}
+
+// isWeekdayTradingDay 判断某天是否为交易日（非周末且非节假日，不考虑时区）
+func isWeekdayTradingDay(t time.Time) bool {
+	weekday := t.Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	return !isMarketHoliday(t)
 }
 
 // GetNextTradingTime 获取下一个交易时间
@@ -154,40 +289,69 @@ func (tc *TradingTimeChecker) GetNextTradingTime(t time.Time) time.Time {
 		return t
 	}
 
-	// 尝试找到今天的下一个交易时段
-	today := t.Format("2006-01-02")
-	currentTime := t.Format("15:04")
-
-	if tc.IsTradingDay(t) {
-		for _, period := range tc.Config.TradingHours {
-			start := period[:5]
-			if currentTime < start {
-				// 找到今天的下一个交易时段
-				nextTime, _ := time.ParseInLocation("2006-01-02 15:04", today+" "+start, tc.Location)
-				return nextTime
+	// 从今天开始逐日查找第一个晚于当前时间的时段开始时间（时段已按开始时间排序），最多查找30天
+	for day := t; day.Sub(t) <= 30*24*time.Hour; day = day.AddDate(0, 0, 1) {
+		if !tc.IsTradingDay(day) {
+			continue
+		}
+		for _, period := range tc.periods {
+			if start := atMinute(day, period.start); start.After(t) {
+				return start
 			}
 		}
 	}
+	return t.Add(24 * time.Hour)
+}
 
-	// 如果今天没有下一个交易时段，找下一个交易日的第一个时段
-	nextDay := t.AddDate(0, 0, 1)
-	for {
-		if tc.IsTradingDay(nextDay) {
-			// 返回下一个交易日的第一个交易时段开始时间
-			if len(tc.Config.TradingHours) > 0 {
-				dateStr := nextDay.Format("2006-01-02")
-				start := tc.Config.TradingHours[0][:5]
-				nextTime, _ := time.ParseInLocation("2006-01-02 15:04", dateStr+" "+start, tc.Location)
-				return nextTime
-			}
+// NextMarketOpen 获取t之后最近一个交易日的开盘时间（第一个交易时段的开始时间）
+// 与GetNextTradingTime不同，不受交易时间检查开关影响，也不会因当前处于交易时段而返回当前时间
+func (tc *TradingTimeChecker) NextMarketOpen(t time.Time) time.Time {
+	t = t.In(tc.Location)
+
+	start := 9*60 + 30
+	if len(tc.periods) > 0 {
+		start = tc.periods[0].start
+	}
+
+	// 最多向后查找30天
+	for day := t; day.Sub(t) <= 30*24*time.Hour; day = day.AddDate(0, 0, 1) {
+		if !tc.IsTradingDay(day) {
+			continue
 		}
-		nextDay = nextDay.AddDate(0, 0, 1)
+		if open := atMinute(day, start); open.After(t) {
+			return open
+		}
+	}
+	return t.Add(24 * time.Hour)
+}
+
+// NextSessionEnd 获取t之后最近一个交易时段的开始和结束时间（按结束时间判断，用于时段收盘时的汇总）
+// 跨午夜时段的结束时间落在开始日的次日凌晨；没有配置交易时段时返回false
+func (tc *TradingTimeChecker) NextSessionEnd(t time.Time) (start, end time.Time, ok bool) {
+	t = t.In(tc.Location)
+	if len(tc.periods) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
 
-		// 防止无限循环，最多查找30天
-		if nextDay.Sub(t) > 30*24*time.Hour {
-			return t.Add(24 * time.Hour)
+	// 从前一天开始查找（前一天的夜盘可能在今天凌晨结束），最多向后查找30天
+	for day := t.AddDate(0, 0, -1); day.Sub(t) <= 30*24*time.Hour; day = day.AddDate(0, 0, 1) {
+		if !tc.IsTradingDay(day) {
+			continue
+		}
+		for _, period := range tc.periods {
+			periodEnd := atMinute(day, period.end)
+			if period.overnight() {
+				periodEnd = atMinute(day.AddDate(0, 0, 1), period.end)
+			}
+			if periodEnd.After(t) && (!ok || periodEnd.Before(end)) {
+				start, end, ok = atMinute(day, period.start), periodEnd, true
+			}
+		}
+		if ok {
+			return start, end, true
 		}
 	}
+	return time.Time{}, time.Time{}, false
 }
 
 // GetTradingTimeStatus 获取交易时间状态信息
@@ -0,0 +1,72 @@
+package stock
+
+import (
+	"testing"
+	"time"
+)
+
+func newNightSessionChecker(t *testing.T) *TradingTimeChecker {
+	checker, err := NewTradingTimeChecker(TradingTimeConfig{
+		EnableTradingTimeCheck: true,
+		TradingHours:           []string{"21:00-02:30", "09:00-11:30", "13:30-15:00"},
+		Timezone:               "Asia/Shanghai",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checker
+}
+
+func TestParseTradingPeriod(t *testing.T) {
+	start, end, ok := ParseTradingPeriod("21:00-02:30")
+	if !ok || start != 21*60 || end != 2*60+30 {
+		t.Fatalf("跨午夜时段解析错误: %d-%d, %v", start, end, ok)
+	}
+	for _, invalid := range []string{"21:00", "25:00-02:00", "09:30~11:30", ""} {
+		if _, _, ok := ParseTradingPeriod(invalid); ok {
+			t.Errorf("格式错误的时段 %q 应解析失败", invalid)
+		}
+	}
+}
+
+func TestIsTradingTimeOvernightSession(t *testing.T) {
+	checker := newNightSessionChecker(t)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, chinaTZ)
+	}
+	cases := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"周一夜盘开始", at(6, 9, 21, 0), true},
+		{"周一夜盘跨午夜到周二凌晨", at(6, 10, 1, 30), true},
+		{"夜盘结束后", at(6, 10, 2, 31), false},
+		{"日盘上午", at(6, 10, 10, 0), true},
+		{"午休", at(6, 10, 12, 0), false},
+		{"日盘下午", at(6, 10, 14, 0), true},
+		{"收盘后夜盘前", at(6, 10, 17, 0), false},
+		{"周五夜盘延续到周六凌晨", at(6, 14, 1, 0), true},
+		{"周六晚上没有夜盘", at(6, 14, 21, 30), false},
+		{"周一凌晨（周日无夜盘）", at(6, 16, 1, 0), false},
+		{"国庆节当晚没有夜盘", at(10, 1, 21, 30), false},
+	}
+	for _, tc := range cases {
+		if got := checker.IsTradingTime(tc.t); got != tc.want {
+			t.Errorf("%s（%s）: IsTradingTime = %v，期望 %v", tc.name, tc.t.Format("01-02 15:04 Mon"), got, tc.want)
+		}
+	}
+}
+
+func TestNextSessionEndOvernight(t *testing.T) {
+	checker := newNightSessionChecker(t)
+	start, end, ok := checker.NextSessionEnd(time.Date(2025, 6, 10, 0, 30, 0, 0, chinaTZ))
+	if !ok {
+		t.Fatal("应找到当前所在的夜盘时段")
+	}
+	wantStart := time.Date(2025, 6, 9, 21, 0, 0, 0, chinaTZ)
+	wantEnd := time.Date(2025, 6, 10, 2, 30, 0, 0, chinaTZ)
+	if !start.Equal(wantStart) || !end.Equal(wantEnd) {
+		t.Fatalf("夜盘时段应为 %s ~ %s，实际 %s ~ %s", wantStart, wantEnd, start, end)
+	}
+}
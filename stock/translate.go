@@ -0,0 +1,58 @@
+package stock
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"strings"
+	"unicode"
+)
+
+// ReasoningTranslator 通知前把分析理由翻译为目标语言（复用AI客户端），翻译失败时由调用方回退原文
+type ReasoningTranslator struct {
+	Client         *mcp.Client
+	TargetLanguage string // 目标语言（如"中文"、"English"）
+}
+
+// chineseLanguageNames 视为中文的目标语言名称（原文已是中文时跳过翻译，不消耗AI额度）
+var chineseLanguageNames = map[string]bool{
+	"中文": true, "简体中文": true, "chinese": true, "zh": true, "zh-cn": true,
+}
+
+// Translate 翻译文本，同时返回本次调用消耗的token数（未调用AI或API未返回usage时为nil，翻译失败时也可能有用量）；
+// 目标语言为中文且原文已主要是中文时原样返回
+func (t *ReasoningTranslator) Translate(text string) (string, *mcp.Usage, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil, nil
+	}
+	if chineseLanguageNames[strings.ToLower(t.TargetLanguage)] && hanRatio(text) >= 0.3 {
+		return text, nil, nil
+	}
+
+	systemPrompt := fmt.Sprintf("你是专业的金融翻译，把用户提供的股票分析文本翻译成%s。保留数字、价格、股票代码和技术指标名称（如MA5、RSI、MACD），只输出译文，不要添加任何解释。", t.TargetLanguage)
+	translated, usage, err := t.Client.CallWithMessagesUsage(systemPrompt, text)
+	if err != nil {
+		return "", usage, err
+	}
+	translated = strings.TrimSpace(translated)
+	if translated == "" {
+		return "", usage, fmt.Errorf("翻译结果为空")
+	}
+	return translated, usage, nil
+}
+
+// hanRatio 文本中汉字占字母和汉字总数的比例
+func hanRatio(text string) float64 {
+	han, letters := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	if han+letters == 0 {
+		return 0
+	}
+	return float64(han) / float64(han+letters)
+}
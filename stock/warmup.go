@@ -0,0 +1,72 @@
+package stock
+
+import (
+	"log"
+	"time"
+)
+
+// KlineWarmer 开盘前暖机：在每个交易日开盘前预拉所有股票的K线到缓存（不调用AI），加快开盘首轮分析
+type KlineWarmer struct {
+	TDXClient          *TDXClient
+	TradingTimeChecker *TradingTimeChecker
+	Codes              []string
+	DayKlineCount      int           // 日K线数量，与分析配置（day_kline_count）一致才能命中缓存，0表示默认60根
+	MinuteKlineCount   int           // 30分钟K线数量，与分析配置（minute_kline_count）一致，0表示默认100根
+	BeforeOpen         time.Duration // 开盘前多久开始暖机
+	ValidAfterOpen     time.Duration // 暖机数据在开盘后的有效时长（之后回源获取最新数据）
+}
+
+// Start 启动暖机后台协程，非交易日不暖机
+func (w *KlineWarmer) Start() {
+	go func() {
+		after := w.TradingTimeChecker.Now()
+		for {
+			open := w.TradingTimeChecker.NextMarketOpen(after)
+			warmAt := open.Add(-w.BeforeOpen)
+			if wait := time.Until(warmAt); wait > 0 {
+				log.Printf("🔥 下次暖机时间: %s（开盘 %s）", warmAt.Format("2006-01-02 15:04"), open.Format("15:04"))
+				time.Sleep(wait)
+			}
+
+			// 启动时已错过暖机时间但尚未开盘，也立即暖机
+			if w.TradingTimeChecker.Now().Before(open) {
+				w.warm(open.Add(w.ValidAfterOpen))
+			}
+			after = open
+		}
+	}()
+}
+
+// warmupKline 暖机预拉的一种K线
+type warmupKline struct {
+	klineType string
+	limit     int
+}
+
+// klines 暖机预拉的K线，与Analyze中的拉取参数保持一致，才能命中缓存
+func (w *KlineWarmer) klines() []warmupKline {
+	return []warmupKline{
+		{"day", klineCount(w.DayKlineCount, defaultDayKlineCount)},
+		{"minute30", klineCount(w.MinuteKlineCount, defaultMinuteKlineCount)},
+	}
+}
+
+// warm 预拉所有股票的K线写入缓存
+func (w *KlineWarmer) warm(expiresAt time.Time) {
+	start := time.Now()
+	success := 0
+	for _, code := range w.Codes {
+		ok := true
+		for _, kline := range w.klines() {
+			if err := w.TDXClient.WarmKline(code, kline.klineType, kline.limit, expiresAt); err != nil {
+				log.Printf("⚠️  暖机预拉 %s %s K线失败: %v", code, kline.klineType, err)
+				ok = false
+			}
+		}
+		if ok {
+			success++
+		}
+	}
+	log.Printf("🔥 暖机完成: %d/%d 只股票，耗时 %v，缓存有效至 %s",
+		success, len(w.Codes), time.Since(start).Round(time.Millisecond), expiresAt.Format("15:04"))
+}
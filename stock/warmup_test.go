@@ -0,0 +1,14 @@
+package stock
+
+import "testing"
+
+func TestWarmerKlinesMatchAnalyzerCounts(t *testing.T) {
+	for _, counts := range [][2]int{{0, 0}, {120, 240}} {
+		analyzer := &StockAnalyzer{AnalysisConfig: &AnalysisConfig{DayKlineCount: counts[0], MinuteKlineCount: counts[1]}}
+		warmer := &KlineWarmer{DayKlineCount: counts[0], MinuteKlineCount: counts[1]}
+		want := []warmupKline{{"day", analyzer.dayKlineCount()}, {"minute30", analyzer.minuteKlineCount()}}
+		if got := warmer.klines(); got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("配置 %v: 暖机预拉 %v 与分析拉取 %v 不一致，无法命中缓存", counts, got, want)
+		}
+	}
+}